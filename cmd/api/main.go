@@ -1,16 +1,24 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
 	"github.com/Talos-hub/BooksRestApi/internal/handlers"
+	"github.com/Talos-hub/BooksRestApi/internal/openapi"
 	"github.com/Talos-hub/BooksRestApi/internal/services"
 	"github.com/Talos-hub/BooksRestApi/internal/storages/config"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/jsonfile"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/memory"
 	"github.com/Talos-hub/BooksRestApi/internal/storages/postgresql"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/sqlite"
 )
 
 func main() {
@@ -23,8 +31,16 @@ func main() {
 	conf := config.LoadConfig()
 	port := os.Getenv("PORT")
 
+	// api migrate ... manages the database schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], conf, storagelogger); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	//database
-	storage, err := postgresql.NewPostgresStorage(conf, storagelogger)
+	storage, err := NewStorage(conf, storagelogger)
 	if err != nil {
 		log.Printf("Cannot create database connect: %v\n", err)
 		log.Fatal(err)
@@ -33,15 +49,47 @@ func main() {
 	//Book Service
 	bookservice := services.NewBookService(servicelogger, storage)
 
-	//Handler
-	handler := handlers.NewHandlerBooks(bookservice, hanlderslogger)
+	//Handlers, both versions share the service
+	handlerV1 := handlers.NewHandlerBooks(bookservice, hanlderslogger)
+	handlerV1.Deprecation, err = v1Deprecation()
+	if err != nil {
+		log.Fatal(err)
+	}
+	handlerV2 := handlers.NewHandlerBooksV2(bookservice, hanlderslogger)
+	for _, handler := range []*handlers.HandlerBooks{handlerV1, handlerV2} {
+		// REQUIRE_IF_MATCH=true rejects changes without ETag of a book
+		handler.RequireIfMatch, _ = strconv.ParseBool(os.Getenv("REQUIRE_IF_MATCH"))
+		// rejected rows of imports are kept there for an hour
+		handler.ImportErrorsDir = os.Getenv("IMPORT_ERRORS_DIR")
+	}
+
+	var v1, v2 http.Handler = handlerV1, handlerV2
+	// VALIDATE_REQUESTS=true checks requests against the OpenAPI document of a version
+	if validate, _ := strconv.ParseBool(os.Getenv("VALIDATE_REQUESTS")); validate {
+		v1 = validateRequests(openapi.V1, hanlderslogger, v1)
+		v2 = validateRequests(openapi.V2, hanlderslogger, v2)
+	}
 
 	//new router
 	mux := http.NewServeMux()
-	// set up routes
-	mux.Handle("/books", handler)
-	mux.Handle("/books/", handler)
+	// set up routes, unversioned /books is v1
+	mux.Handle("/books", v1)
+	mux.Handle("/books/", v1)
+	mux.Handle("/authors", v1)
+	mux.Handle("/authors/", v1)
+	mux.Handle("/genres", v1)
+	mux.Handle("/genres/", v1)
+	mux.Handle("/works", v1)
+	mux.Handle("/works/", v1)
+	mux.Handle("/series", v1)
+	mux.Handle("/series/", v1)
+	mux.Handle("/v1/", v1)
+	mux.Handle("/v2/", v2)
 	mux.HandleFunc("/health", healthCheck)
+	mux.Handle("GET /openapi.json", openapi.SpecHandler(openapi.V1))
+	mux.Handle("GET /v1/openapi.json", openapi.SpecHandler(openapi.V1))
+	mux.Handle("GET /v2/openapi.json", openapi.SpecHandler(openapi.V2))
+	mux.Handle("GET /docs", openapi.DocsHandler())
 
 	// create server
 	server := &http.Server{
@@ -63,6 +111,54 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 
 // there are helpers
 
+// dates of the deprecation of v1
+var (
+	v1DeprecatedAt = time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	v1SunsetAt     = time.Date(2027, time.April, 17, 0, 0, 0, 0, time.UTC)
+)
+
+// v1Deprecation returns headers of the deprecated v1,
+// V1_SUNSET (YYYY-MM-DD) changes the date when v1 is removed
+func v1Deprecation() (*handlers.Deprecation, error) {
+	sunset := v1SunsetAt
+	if s := os.Getenv("V1_SUNSET"); s != "" {
+		var err error
+		if sunset, err = time.Parse(time.DateOnly, s); err != nil {
+			return nil, fmt.Errorf("invalid V1_SUNSET: %w", err)
+		}
+	}
+	return &handlers.Deprecation{Since: v1DeprecatedAt, Sunset: sunset, Successor: "/v2/books"}, nil
+}
+
+// validateRequests wraps a handler by a validator of the document of a version
+func validateRequests(version string, logger *slog.Logger, next http.Handler) http.Handler {
+	doc, err := openapi.Load(version)
+	if err != nil {
+		log.Fatal(err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return handlers.ValidateRequests(validator, logger, next)
+}
+
+// NewStorage creates a storage that is chosen by the config driver
+func NewStorage(conf *config.DatabaseConfig, logger *slog.Logger) (abstraction.Storage, error) {
+	switch conf.Driver {
+	case config.DriverPostgres:
+		return postgresql.NewPostgresStorage(conf, logger)
+	case config.DriverMemory:
+		return memory.NewMemoryStorage(logger), nil
+	case config.DriverJson:
+		return jsonfile.NewJsonStorage(conf.FilePath, logger)
+	case config.DriverSqlite:
+		return sqlite.NewSqliteStorage(conf, logger)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", conf.Driver)
+	}
+}
+
 func SetLogger(path string) *slog.Logger {
 	// Extract directory from file path
 	dir := filepath.Dir(path)
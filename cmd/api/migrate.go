@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/storages/config"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/postgresql"
+)
+
+const migrateUsage = `usage:
+  api migrate up             apply all new migrations
+  api migrate down [steps]   roll back the last steps migrations (default 1)
+  api migrate status         show migrations and when they were applied`
+
+// runMigrate runs the migrate command, args are arguments after "migrate"
+func runMigrate(args []string, conf *config.DatabaseConfig, logger *slog.Logger) error {
+	if conf.Driver != config.DriverPostgres {
+		return fmt.Errorf("migrations are supported only by %s driver, got: %s", config.DriverPostgres, conf.Driver)
+	}
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	// Ctrl+C cancels a migration, it is rolled back by its transaction
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	migrator, err := postgresql.OpenMigrator(conf, logger)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+		fmt.Println("all migrations are applied")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("steps must be a positive number, got: %s", args[1])
+			}
+		}
+		if err := migrator.Down(ctx, steps); err != nil {
+			return err
+		}
+		fmt.Println("migrations are rolled back")
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if !s.AppliedAt.IsZero() {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
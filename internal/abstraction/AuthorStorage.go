@@ -0,0 +1,30 @@
+package abstraction
+
+import (
+	"context"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// AuthorStorage is a storage that keeps authors and links them to books.
+// It is optional, not every Storage implements it. A storage of a transaction
+// of WithTx implements it too, so books and their authors change together
+type AuthorStorage interface {
+	FindAuthors(ctx context.Context, query models.AuthorQuery) ([]models.Author, error) // returns authors ordered by sort name and id
+	GetAuthor(ctx context.Context, id uint64) (models.Author, error)
+	SaveAuthor(ctx context.Context, author models.Author) (models.Author, error) // adds an author and returns it with a new id
+	UpdateAuthor(ctx context.Context, author models.Author) error
+	// DeleteAuthor deletes an author, an author of books cannot be deleted, it is ErrAuthorHasBooks
+	DeleteAuthor(ctx context.Context, id uint64) error
+
+	// SetBookAuthors replaces authors of a book, the order is the order of credits.
+	// An author that doesn't exist is ErrAuthorNotFound
+	SetBookAuthors(ctx context.Context, bookID uint64, authorIDs []uint64) error
+	// BookAuthors returns authors of books in order of credits, a book without authors is not in the map
+	BookAuthors(ctx context.Context, bookIDs []uint64) (map[uint64][]models.BookAuthor, error)
+	// LinkAuthors links books to authors by their author strings (models.SplitAuthors),
+	// a name is matched case-insensitively and a missing author is created with createdAt.
+	// Authors of given books are replaced, nil links every book that has no authors
+	LinkAuthors(ctx context.Context, bookIDs []uint64, createdAt time.Time) error
+}
@@ -0,0 +1,18 @@
+package abstraction
+
+import (
+	"context"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// BulkSaver is a storage that can add many books at once faster than Save.
+// It is optional, not every Storage implements it
+type BulkSaver interface {
+	// SaveMany adds books with new ids and returns how many are added.
+	// Either all books are added or none of them
+	SaveMany(ctx context.Context, books []models.Book) (int64, error)
+	// ExistingISBNs returns which of isbns are already stored,
+	// SaveMany fails on them, so they are checked before
+	ExistingISBNs(ctx context.Context, isbns []string) ([]string, error)
+}
@@ -0,0 +1,31 @@
+package abstraction
+
+import (
+	"context"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// GenreStorage is a storage that keeps the genre tree and tags books with genres.
+// It is optional like AuthorStorage, a storage of a transaction implements it too
+type GenreStorage interface {
+	Genres(ctx context.Context) ([]models.Genre, error) // returns all genres ordered by name and id
+	GetGenre(ctx context.Context, id uint64) (models.Genre, error)
+	// SaveGenre adds a genre and returns it with a new id,
+	// a used slug is ErrSlugConflict and a missing parent is ErrGenreNotFound
+	SaveGenre(ctx context.Context, genre models.Genre) (models.Genre, error)
+	UpdateGenre(ctx context.Context, genre models.Genre) error // the same errors as SaveGenre
+	// DeleteGenre deletes a genre, a genre with books or subgenres cannot be deleted, it is ErrGenreInUse
+	DeleteGenre(ctx context.Context, id uint64) error
+
+	// SetBookGenres replaces genres of a book.
+	// A genre that doesn't exist is ErrGenreNotFound
+	SetBookGenres(ctx context.Context, bookID uint64, genreIDs []uint64) error
+	// BookGenres returns genres of books ordered by name, a book without genres is not in the map
+	BookGenres(ctx context.Context, bookIDs []uint64) (map[uint64][]models.BookGenre, error)
+	// LinkGenres tags books with a genre by their genre strings, a genre is matched
+	// by slug (models.Slugify) and a missing one is created as a root with createdAt.
+	// Genres of given books are replaced, nil links every book that has no genres
+	LinkGenres(ctx context.Context, bookIDs []uint64, createdAt time.Time) error
+}
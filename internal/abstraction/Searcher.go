@@ -0,0 +1,15 @@
+package abstraction
+
+import (
+	"context"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// Searcher is a storage that can do full-text search.
+// It is optional, not every Storage implements it
+type Searcher interface {
+	// Search returns up to limit books that match a text query,
+	// the most relevant go first
+	Search(ctx context.Context, query string, limit int) ([]models.SearchResult, error)
+}
@@ -0,0 +1,27 @@
+package abstraction
+
+import (
+	"context"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// SeriesStorage is a storage that keeps series and places books in them.
+// It is optional like AuthorStorage, a storage of a transaction implements it too
+type SeriesStorage interface {
+	FindSeries(ctx context.Context, query models.SeriesQuery) ([]models.Series, error) // returns series ordered by name and id
+	GetSeries(ctx context.Context, id uint64) (models.Series, error)                   // returns a series without volumes
+	SaveSeries(ctx context.Context, series models.Series) (models.Series, error)       // adds a series and returns it with a new id
+	UpdateSeries(ctx context.Context, series models.Series) error
+	// DeleteSeries deletes a series, a series with volumes cannot be deleted, it is ErrSeriesHasVolumes
+	DeleteSeries(ctx context.Context, id uint64) error
+
+	// SetBookSeries places a book at a position of a series, its old place is replaced.
+	// Zero seriesID takes a book out of its series. A series that doesn't exist
+	// is ErrSeriesNotFound and a position of another book is ErrPositionConflict
+	SetBookSeries(ctx context.Context, bookID, seriesID uint64, position float64) error
+	// SeriesVolumes returns books of a series ordered by position
+	SeriesVolumes(ctx context.Context, seriesID uint64) ([]models.SeriesVolume, error)
+	// BookSeries returns series of books with their neighbours, a book without series is not in the map
+	BookSeries(ctx context.Context, bookIDs []uint64) (map[uint64]models.BookSeries, error)
+}
@@ -1,15 +1,34 @@
 package abstraction
 
-import "github.com/Talos-hub/BooksRestApi/internal/models"
+import (
+	"context"
+	"iter"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
 
 // It  interface that provides Storage.
 // It has all methods for work with any storage:
 // SqlLite, Postgresql, json file, etc.
+// Every method takes a context of a request,
+// so work is stopped when a client has gone away
 type Storage interface {
-	GetAll() ([]models.Book, error)         // returns all elements from a storage
-	GetById(id uint64) (models.Book, error) // returns one item from a storage by id
-	Save(book models.Book) error            // add a book to storage
-	Delete(id uint64) error                 // delete a item from storage
-	Update(book models.Book) error          // update a item in storage
-	Close() error                           // For proper resource cleanup
+	GetAll(ctx context.Context) ([]models.Book, error)                                   // returns all elements from a storage
+	Find(ctx context.Context, query models.BookQuery) ([]models.Book, error)             // returns elements that match a query in its order
+	GetById(ctx context.Context, id uint64) (models.Book, error)                         // returns one item from a storage by id
+	GetByISBN(ctx context.Context, isbn string) (models.Book, error)                     // returns one item by a normalized ISBN-13
+	Save(ctx context.Context, book models.Book) (models.Book, error)                     // add a book to storage and returns it with a new id
+	SaveWithID(ctx context.Context, book models.Book) (models.Book, error)               // add a book with an id chosen by a caller
+	Delete(ctx context.Context, id uint64) error                                         // delete a item from storage
+	Update(ctx context.Context, book models.Book) error                                  // update a item in storage
+	UpdateFields(ctx context.Context, book models.Book, fields []models.BookField) error // update only given fields and updated_at of a item, no fields only increase the version
+	// Stream returns elements that match a query in its order one by one,
+	// so they are never loaded into memory all at once. It is stopped by ctx
+	// or when a caller stops iterating
+	Stream(ctx context.Context, query models.BookQuery) iter.Seq2[models.Book, error]
+	// WithTx runs fn in a transaction, fn must use only tx that it gets.
+	// If fn returns an error all changes are rolled back, otherwise they are committed.
+	// Reads inside a transaction lock items, so read-check-write is atomic
+	WithTx(ctx context.Context, fn func(tx Storage) error) error
+	Close() error // For proper resource cleanup
 }
@@ -0,0 +1,28 @@
+package abstraction
+
+import (
+	"context"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// WorkStorage is a storage that keeps works, books are their editions by WorkID.
+// A work is linked to authors by its author string like a book (AuthorStorage.LinkAuthors),
+// a missing author is created. Works are returned with their authors.
+// It is optional like AuthorStorage, a storage of a transaction implements it too
+type WorkStorage interface {
+	FindWorks(ctx context.Context, query models.WorkQuery) ([]models.Work, error) // returns works ordered by title and id
+	GetWork(ctx context.Context, id uint64) (models.Work, error)
+	SaveWork(ctx context.Context, work models.Work) (models.Work, error) // adds a work and returns it with a new id
+	UpdateWork(ctx context.Context, work models.Work) error              // links authors of a new author string too
+	// DeleteWork deletes a work, a work with editions cannot be deleted, it is ErrWorkHasEditions
+	DeleteWork(ctx context.Context, id uint64) error
+
+	// EnsureWork returns a work with the same title in any case and the same authors
+	// as the author string has, a missing work is saved
+	EnsureWork(ctx context.Context, work models.Work) (models.Work, error)
+	// LinkWorks makes every book without a work an edition of a work with the same title
+	// and authors of the book, a missing work is created with createdAt
+	LinkWorks(ctx context.Context, createdAt time.Time) error
+}
@@ -1,14 +1,69 @@
 package apperrors
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
+// ErrNotFound is wrapped by storages when an item doesn't exist,
+// so callers can tell it from other errors with errors.Is
+var ErrNotFound = errors.New("not found")
+
+// ErrBookNotFound is wrapped by storages when a book doesn't exist, it is ErrNotFound too
+var ErrBookNotFound = fmt.Errorf("book %w", ErrNotFound)
+
+// ErrConflict is wrapped by storages when an item already exists
+var ErrConflict = errors.New("already exists")
+
+// ErrISBNConflict is wrapped when another book has the same ISBN,
+// it is ErrConflict too
+var ErrISBNConflict = fmt.Errorf("isbn %w", ErrConflict)
+
+// ErrAuthorNotFound is wrapped when an author doesn't exist,
+// it is ErrNotFound too, so a caller can tell it from a missing book
+var ErrAuthorNotFound = fmt.Errorf("author %w", ErrNotFound)
+
+// ErrAuthorHasBooks is wrapped when an author of books or works is deleted
+var ErrAuthorHasBooks = errors.New("author has books")
+
+// ErrGenreNotFound is wrapped when a genre doesn't exist, it is ErrNotFound too
+var ErrGenreNotFound = fmt.Errorf("genre %w", ErrNotFound)
+
+// ErrSlugConflict is wrapped when another genre has the same slug,
+// it is ErrConflict too
+var ErrSlugConflict = fmt.Errorf("slug %w", ErrConflict)
+
+// ErrGenreInUse is wrapped when a genre with books or subgenres is deleted
+var ErrGenreInUse = errors.New("genre has books or subgenres")
+
+// ErrWorkNotFound is wrapped when a work doesn't exist, it is ErrNotFound too
+var ErrWorkNotFound = fmt.Errorf("work %w", ErrNotFound)
+
+// ErrWorkHasEditions is wrapped when a work with editions is deleted
+var ErrWorkHasEditions = errors.New("work has editions")
+
+// ErrSeriesNotFound is wrapped when a series doesn't exist, it is ErrNotFound too
+var ErrSeriesNotFound = fmt.Errorf("series %w", ErrNotFound)
+
+// ErrSeriesHasVolumes is wrapped when a series with books is deleted
+var ErrSeriesHasVolumes = errors.New("series has volumes")
+
+// ErrPositionConflict is wrapped when another book is at the same position
+// of a series, it is ErrConflict too
+var ErrPositionConflict = fmt.Errorf("series position %w", ErrConflict)
+
+// StatusClientClosedRequest is non-standard code (it came from nginx),
+// it is used when a client closed a connection before a response was sent
+const StatusClientClosedRequest = 499
+
 type AppError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Err     error  `json:"-"`
+	// ErrCode is a stable machine-readable code like "book_not_found",
+	// if it is empty a code is taken from the status code
+	ErrCode string `json:"-"`
 }
 
 func (e *AppError) Error() string {
@@ -30,10 +85,24 @@ func NewAppError(code int, msg string, err error) *AppError {
 	}
 }
 
+// WithCode sets a machine-readable code and returns the same error
+func (e *AppError) WithCode(errCode string) *AppError {
+	e.ErrCode = errCode
+	return e
+}
+
+// FieldError is one failed rule of one field
+type FieldError struct {
+	Field   string `json:"field"`   // json path of a field, for example book.title
+	Rule    string `json:"rule"`    // a failed rule, for example required or max_length
+	Message string `json:"message"` // a message for a human
+}
+
 type ValidateErr struct {
 	Message string
 	Fields  []string
 	Err     error
+	Details []FieldError // the same errors as Fields but for machines
 }
 
 func (v *ValidateErr) Error() string {
@@ -87,6 +156,21 @@ func (v *ValidationReflectErr) Error() string {
 	return v.Message
 }
 
+// NewFieldsValidateErr creates ValidateErr from field errors,
+// Fields are filled by their messages
+func NewFieldsValidateErr(message string, details []FieldError, err error) *ValidateErr {
+	fields := make([]string, 0, len(details))
+	for _, d := range details {
+		fields = append(fields, d.Message)
+	}
+	return &ValidateErr{
+		Message: message,
+		Fields:  fields,
+		Err:     err,
+		Details: details,
+	}
+}
+
 func NewValidateReflectErr(message string) *ValidateErr {
 	return &ValidateErr{Message: message}
 }
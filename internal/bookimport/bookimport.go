@@ -0,0 +1,272 @@
+// bookimport reads books from uploaded files: CSV with a header
+// or a JSON array of books. Files are read row by row, so a big file
+// is never loaded into memory. Like patch it is written without dependencies
+package bookimport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// media types of imported files
+const (
+	CSVType  = "text/csv"
+	JSONType = "application/json"
+)
+
+// rules of field errors that are found while reading
+const (
+	RuleRequired = "required"
+	RuleType     = "type"
+	RuleFormat   = "format" // a value cannot be parsed, for example a date
+	RuleSyntax   = "syntax" // a file is broken, it cannot be read further
+	RuleUnique   = "unique" // an ISBN is used twice in a file or by a stored book, it is checked by an import
+)
+
+// columns of CSV, they are the same as json names of GeneralBook.
+// Other columns (id, createdAt...) are ignored, so an exported file can be imported
+const (
+	columnTitle           = "title"
+	columnAuthor          = "author"
+	columnGenre           = "genre"
+	columnPublicationDate = "publicationDate"
+	columnISBN            = "isbn" // it is optional
+)
+
+var requiredColumns = []string{columnTitle, columnAuthor, columnGenre, columnPublicationDate}
+
+// errorColumns are columns of a file of rejected rows
+var errorColumns = append(requiredColumns, columnISBN)
+
+// ErrMalformed means a file is broken and the rest of it cannot be read
+var ErrMalformed = errors.New("malformed file")
+
+// Row is one book of an imported file, values are as they are in the file.
+// An id of a file is ignored, imported books get new ids
+type Row struct {
+	Number          int    `json:"-"` // number of a row from 1, the header of CSV is not counted
+	Title           string `json:"title"`
+	Author          string `json:"author"`
+	Genre           string `json:"genre"`
+	PublicationDate string `json:"publicationDate"`
+	ISBN            string `json:"isbn"`
+}
+
+// Book makes a new book of a row, it returns errors if the date cannot be parsed.
+// Other fields are checked by validations
+func (r Row) Book(createdAt time.Time) (models.Book, []apperrors.FieldError) {
+	if r.PublicationDate == "" {
+		return models.Book{}, []apperrors.FieldError{{Field: columnPublicationDate, Rule: RuleRequired, Message: "publicationDate: cannot be empty"}}
+	}
+	date, err := ParseDate(r.PublicationDate)
+	if err != nil {
+		return models.Book{}, []apperrors.FieldError{{Field: columnPublicationDate, Rule: RuleFormat, Message: "publicationDate: must be RFC 3339 or YYYY-MM-DD"}}
+	}
+
+	return models.Book{
+		General: models.GeneralBook{
+			Title:           r.Title,
+			Author:          r.Author,
+			Genre:           r.Genre,
+			PublicationDate: date,
+			ISBN:            r.ISBN,
+		},
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}, nil
+}
+
+// ParseDate parses a date as RFC 3339 or as YYYY-MM-DD
+func ParseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// RowError means a row cannot be read, but next rows can
+type RowError struct {
+	Errors []apperrors.FieldError
+}
+
+func (e *RowError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		messages[i] = fieldErr.Message
+	}
+	return "invalid row: " + strings.Join(messages, "; ")
+}
+
+// Rows reads rows of a file of a media type one by one.
+// A row that cannot be read comes with *RowError and reading goes on.
+// If a file is broken, the last error wraps ErrMalformed
+func Rows(mediaType string, r io.Reader) (iter.Seq2[Row, error], error) {
+	switch mediaType {
+	case CSVType:
+		return csvRows(r), nil
+	case JSONType:
+		return jsonRows(r), nil
+	}
+	return nil, fmt.Errorf("unsupported file type: %s", mediaType)
+}
+
+func csvRows(r io.Reader) iter.Seq2[Row, error] {
+	return func(yield func(Row, error) bool) {
+		reader := csv.NewReader(r)
+		reader.ReuseRecord = true
+
+		header, err := reader.Read()
+		if err != nil {
+			yield(Row{}, fmt.Errorf("%w: cannot read the header: %w", ErrMalformed, err))
+			return
+		}
+		// Excel puts BOM before the first column
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+		// the header is overwritten by next rows because of ReuseRecord
+		headerLen := len(header)
+		columns := make(map[string]int, len(header))
+		for i, name := range header {
+			columns[strings.TrimSpace(name)] = i
+		}
+		for _, name := range requiredColumns {
+			if _, ok := columns[name]; !ok {
+				yield(Row{}, fmt.Errorf("%w: the header has no %s column", ErrMalformed, name))
+				return
+			}
+		}
+
+		for number := 1; ; number++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+
+			var parseErr *csv.ParseError
+			if err != nil && !(errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount)) {
+				yield(Row{Number: number}, fmt.Errorf("%w: row %d: %w", ErrMalformed, number, err))
+				return
+			}
+
+			value := func(name string) string {
+				if i, ok := columns[name]; ok && i < len(record) {
+					return record[i]
+				}
+				return ""
+			}
+			row := Row{
+				Number:          number,
+				Title:           value(columnTitle),
+				Author:          value(columnAuthor),
+				Genre:           value(columnGenre),
+				PublicationDate: value(columnPublicationDate),
+				ISBN:            value(columnISBN),
+			}
+
+			var rowErr error
+			if err != nil {
+				rowErr = &RowError{Errors: []apperrors.FieldError{{Rule: RuleSyntax,
+					Message: fmt.Sprintf("row has %d columns, the header has %d", len(record), headerLen)}}}
+			}
+			if !yield(row, rowErr) {
+				return
+			}
+		}
+	}
+}
+
+func jsonRows(r io.Reader) iter.Seq2[Row, error] {
+	return func(yield func(Row, error) bool) {
+		decoder := json.NewDecoder(r)
+
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			yield(Row{}, fmt.Errorf("%w: expected a JSON array of books", ErrMalformed))
+			return
+		}
+
+		for number := 1; decoder.More(); number++ {
+			row := Row{Number: number}
+			err := decoder.Decode(&row)
+
+			// a value of a wrong type is skipped by the decoder, so next rows can be read
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				field := typeErr.Field
+				if field == "" {
+					field = "book"
+				}
+				rowErr := &RowError{Errors: []apperrors.FieldError{{Field: field, Rule: RuleType,
+					Message: fmt.Sprintf("%s: must be %s", field, typeErr.Type)}}}
+				if !yield(row, rowErr) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(row, fmt.Errorf("%w: row %d: %w", ErrMalformed, number, err))
+				return
+			}
+
+			if !yield(row, nil) {
+				return
+			}
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			yield(Row{}, fmt.Errorf("%w: expected the end of the array: %w", ErrMalformed, err))
+		}
+	}
+}
+
+// ErrorWriter writes rejected rows to a CSV file. It has the same columns as
+// an imported CSV, so the file can be fixed and imported again, and why rows are rejected
+type ErrorWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+// NewErrorWriter returns an ErrorWriter that writes to w
+func NewErrorWriter(w io.Writer) *ErrorWriter {
+	return &ErrorWriter{writer: csv.NewWriter(w)}
+}
+
+// Write writes a rejected row and its errors
+func (e *ErrorWriter) Write(row Row, errs []apperrors.FieldError) error {
+	if !e.header {
+		e.header = true
+		header := append([]string{"row"}, errorColumns...)
+		if err := e.writer.Write(append(header, "errors")); err != nil {
+			return err
+		}
+	}
+
+	messages := make([]string, len(errs))
+	for i, fieldErr := range errs {
+		messages[i] = fieldErr.Message
+	}
+	return e.writer.Write([]string{
+		strconv.Itoa(row.Number),
+		row.Title,
+		row.Author,
+		row.Genre,
+		row.PublicationDate,
+		row.ISBN,
+		strings.Join(messages, "; "),
+	})
+}
+
+// Flush writes buffered rows
+func (e *ErrorWriter) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
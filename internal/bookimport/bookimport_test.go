@@ -0,0 +1,127 @@
+package bookimport
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+)
+
+// readAll returns all rows and errors of a file
+func readAll(t *testing.T, mediaType, file string) ([]Row, []error) {
+	t.Helper()
+	rows, err := Rows(mediaType, strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var got []Row
+	var errs []error
+	for row, err := range rows {
+		got = append(got, row)
+		errs = append(errs, err)
+	}
+	return got, errs
+}
+
+func TestRows_CSV(t *testing.T) {
+	// columns in any order, unknown ones are ignored
+	file := "\ufeffid,author,title,genre,publicationDate\n" +
+		"7,Robert C. Martin,Clean Code,Programming,2008-08-01\n" +
+		"8,\"Tolkien, J.R.R.\",The Hobbit,Fantasy,1937-09-21T00:00:00Z\n" +
+		"9,too,few\n"
+
+	rows, errs := readAll(t, CSVType, file)
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got: %+v", rows)
+	}
+	if errs[0] != nil || rows[0].Title != "Clean Code" || rows[0].Number != 1 {
+		t.Errorf("Unexpected first row: %+v %v", rows[0], errs[0])
+	}
+	if errs[1] != nil || rows[1].Author != "Tolkien, J.R.R." {
+		t.Errorf("Unexpected second row: %+v %v", rows[1], errs[1])
+	}
+	var rowErr *RowError
+	if !errors.As(errs[2], &rowErr) || rows[2].Number != 3 {
+		t.Errorf("Expected a row error for wrong amount of columns, got: %+v %v", rows[2], errs[2])
+	}
+
+	_, errs = readAll(t, CSVType, "title,author\nClean Code,Robert C. Martin\n")
+	if len(errs) != 1 || !errors.Is(errs[0], ErrMalformed) {
+		t.Errorf("Expected malformed file for missing columns, got: %v", errs)
+	}
+
+	_, errs = readAll(t, CSVType, "title,author,genre,publicationDate\n\"Clean \"Code\",a,b,2008-08-01\n")
+	if len(errs) != 1 || !errors.Is(errs[0], ErrMalformed) {
+		t.Errorf("Expected malformed file for a bare quote, got: %v", errs)
+	}
+}
+
+func TestRows_JSON(t *testing.T) {
+	file := `[
+		{"title": "Clean Code", "author": "Robert C. Martin", "genre": "Programming", "publicationDate": "2008-08-01"},
+		{"title": 42, "author": "Robert C. Martin", "genre": "Programming", "publicationDate": "2008-08-01"},
+		{"title": "Refactoring", "author": "Martin Fowler", "genre": "Programming", "publicationDate": "1999-07-08"}
+	]`
+
+	rows, errs := readAll(t, JSONType, file)
+	if len(rows) != 3 || errs[0] != nil || errs[2] != nil || rows[2].Title != "Refactoring" || rows[2].Number != 3 {
+		t.Fatalf("Unexpected rows: %+v %v", rows, errs)
+	}
+	var rowErr *RowError
+	if !errors.As(errs[1], &rowErr) || rowErr.Errors[0].Field != "title" {
+		t.Errorf("Expected a type error of title, got: %v", errs[1])
+	}
+
+	for _, file := range []string{`{"title": "Clean Code"}`, `[{"title": "Clean Code"},`, `[{"title": "Clean Code"} {}]`} {
+		_, errs := readAll(t, JSONType, file)
+		if len(errs) == 0 || !errors.Is(errs[len(errs)-1], ErrMalformed) {
+			t.Errorf("%s: expected malformed file, got: %v", file, errs)
+		}
+	}
+}
+
+func TestRows_UnsupportedType(t *testing.T) {
+	if _, err := Rows("text/plain", strings.NewReader("")); err == nil {
+		t.Error("Expected error for unsupported type")
+	}
+}
+
+func TestRow_Book(t *testing.T) {
+	createdAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	book, errs := Row{Title: "Clean Code", PublicationDate: "2008-08-01"}.Book(createdAt)
+	if errs != nil || book.General.PublicationDate.Year() != 2008 || !book.UpdatedAt.Equal(createdAt) {
+		t.Errorf("Unexpected book: %+v %v", book, errs)
+	}
+
+	for date, rule := range map[string]string{"": RuleRequired, "01.08.2008": RuleFormat} {
+		if _, errs := (Row{PublicationDate: date}).Book(createdAt); len(errs) != 1 || errs[0].Rule != rule {
+			t.Errorf("%q: expected %s error, got: %v", date, rule, errs)
+		}
+	}
+}
+
+func TestErrorWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewErrorWriter(&buf)
+	w.Write(Row{Number: 2, Title: "", Author: "Robert C. Martin", Genre: "Programming", PublicationDate: "2008-08-01", ISBN: "0132350882"},
+		[]apperrors.FieldError{{Field: "title", Rule: RuleRequired, Message: "title: cannot be empty"}})
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := "row,title,author,genre,publicationDate,isbn,errors\n" +
+		"2,,Robert C. Martin,Programming,2008-08-01,0132350882,title: cannot be empty\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got: %q", want, buf.String())
+	}
+
+	// the error file can be imported again
+	rows, errs := readAll(t, CSVType, buf.String())
+	if len(rows) != 1 || errs[0] != nil || rows[0].Author != "Robert C. Martin" || rows[0].ISBN != "0132350882" {
+		t.Errorf("Expected the rejected row, got: %+v %v", rows, errs)
+	}
+}
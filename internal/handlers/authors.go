@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+const authorsRoute = "authors"
+
+// GetAuthors send one page of authors ordered by sort name,
+// it takes limit and cursor like GetAllBooks
+func (h *HandlerBooks) GetAuthors(w http.ResponseWriter, r *http.Request) {
+	sendKeyPage(h, w, r, authorsRoute, h.Service.GetAuthors, func(page models.AuthorPage) string { return page.NextCursor })
+}
+
+// GetAuthor send an author by an ID
+func (h *HandlerBooks) GetAuthor(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid author id", err))
+		return
+	}
+
+	author, appErr := h.Service.GetAuthor(r.Context(), id)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, author)
+}
+
+// CreateAuthor create new author, id and times are given by the server
+func (h *HandlerBooks) CreateAuthor(w http.ResponseWriter, r *http.Request) {
+	var author models.Author
+	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	author.CreatedAt = time.Now()
+
+	author, appErr := h.Service.CreateAuthor(r.Context(), author)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	w.Header().Set("Location", h.authorLocation(r, author.ID))
+	h.sendJsonResponse(w, http.StatusCreated, author)
+}
+
+// ReplaceAuthor replaces an author by an ID from the path (PUT /authors/{id}),
+// the replaced author is sent back
+func (h *HandlerBooks) ReplaceAuthor(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid author id", err))
+		return
+	}
+
+	var author models.Author
+	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	author.UpdatedAt = time.Now()
+
+	author, appErr := h.Service.ReplaceAuthor(r.Context(), id, author)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, author)
+}
+
+// DeleteAuthor delete an author who has no books
+func (h *HandlerBooks) DeleteAuthor(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid author id", err))
+		return
+	}
+
+	if appErr := h.Service.DeleteAuthor(r.Context(), id); appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, map[string]string{"message": "Author deleted successfully"})
+}
+
+// GetAuthorBooks send one page of books of an author,
+// it takes the same query parameters as GetAllBooks
+func (h *HandlerBooks) GetAuthorBooks(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid author id", err))
+		return
+	}
+	encoder, appErr := h.negotiate(r, h.Encoders)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	params := r.URL.Query()
+
+	query, appErr := parseBookQuery(params)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	page, appErr := h.Service.GetAuthorBooks(r.Context(), id, query, params.Get("cursor"))
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	if page.NextCursor != "" {
+		params.Set("cursor", page.NextCursor)
+		w.Header().Add("Link", fmt.Sprintf(`<%s/%s?%s>; rel="next"`, h.authorLocation(r, id), booksRoute, params.Encode()))
+	}
+	h.sendEncodedResponse(w, encoder, http.StatusOK, page)
+}
+
+// SetBookAuthors replaces authors of a book (PUT /books/{id}/authors),
+// the body is {"authorIds": [...]} in order of credits. The book is sent back
+func (h *HandlerBooks) SetBookAuthors(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid book id", err))
+		return
+	}
+
+	var req models.SetBookAuthorsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	req.UpdatedAt = time.Now()
+
+	book, appErr := h.Service.SetBookAuthors(r.Context(), id, req)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	w.Header().Set("ETag", bookETag(book))
+	h.sendJsonResponse(w, http.StatusOK, h.version.view(book))
+}
+
+// authorLocation returns a path of an author resource in the version of a request
+func (h *HandlerBooks) authorLocation(r *http.Request, id uint64) string {
+	return h.basePath(r) + "/" + authorsRoute + "/" + strconv.FormatUint(id, 10)
+}
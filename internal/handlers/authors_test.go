@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+func TestHandlerBooks_Authors(t *testing.T) {
+	h := newTestHandler()
+
+	// a book gets authors from its author string
+	w := serve(h, http.MethodPost, "/books", `{"book": {"id": 1, "title": "Refactoring", "genre": "Programming", "author": "Martin Fowler and Kent Beck", "publicationDate": "1999-07-08T00:00:00Z"}}`)
+	var book models.Book
+	json.NewDecoder(w.Body).Decode(&book)
+	if w.Code != http.StatusCreated || len(book.Authors) != 2 || book.Authors[0].Name != "Martin Fowler" || book.Authors[1].Name != "Kent Beck" {
+		t.Fatalf("Expected a book with two authors, got: %d %+v", w.Code, book)
+	}
+
+	w = serve(h, http.MethodGet, "/authors", "")
+	var page models.AuthorPage
+	json.NewDecoder(w.Body).Decode(&page)
+	if w.Code != http.StatusOK || len(page.Authors) != 2 || page.Authors[0].SortName != "Beck, Kent" {
+		t.Fatalf("Expected authors ordered by sort name, got: %d %+v", w.Code, page)
+	}
+
+	w = serve(h, http.MethodPost, "/authors", `{"name": "J.R.R. Tolkien", "birthYear": 1892, "deathYear": 1973}`)
+	var author models.Author
+	json.NewDecoder(w.Body).Decode(&author)
+	if w.Code != http.StatusCreated || author.SortName != "Tolkien, J.R.R." || w.Header().Get("Location") != "/authors/3" {
+		t.Fatalf("Expected a created author, got: %d %+v %s", w.Code, author, w.Header().Get("Location"))
+	}
+
+	w = serve(h, http.MethodPut, "/authors/3", `{"name": "John Ronald Reuel Tolkien", "sortName": "Tolkien, J.R.R.", "birthYear": 1973, "deathYear": 1892}`)
+	var p Problem
+	json.NewDecoder(w.Body).Decode(&p)
+	if w.Code != http.StatusBadRequest || len(p.Errors) != 1 || p.Errors[0].Field != "deathYear" {
+		t.Errorf("Expected an error of deathYear, got: %d %+v", w.Code, p)
+	}
+
+	// authors are replaced in a new order, the book gets a new ETag
+	w = serve(h, http.MethodPut, "/books/1/authors", `{"authorIds": [2, 3]}`)
+	book = models.Book{}
+	json.NewDecoder(w.Body).Decode(&book)
+	if w.Code != http.StatusOK || len(book.Authors) != 2 || book.Authors[0].ID != 2 || book.Authors[1].ID != 3 {
+		t.Fatalf("Expected authors 2 and 3, got: %d %+v", w.Code, book)
+	}
+	if book.Version != 2 || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("Expected version 2, got: %d %s", book.Version, w.Header().Get("ETag"))
+	}
+
+	// a renamed author changes books of the author
+	serve(h, http.MethodPut, "/authors/3", `{"name": "John Ronald Reuel Tolkien", "sortName": "Tolkien, J.R.R."}`)
+	w = serve(h, http.MethodGet, "/books/1", "")
+	book = models.Book{}
+	json.NewDecoder(w.Body).Decode(&book)
+	if book.Version != 3 || book.Authors[1].Name != "John Ronald Reuel Tolkien" {
+		t.Errorf("Expected version 3 with the new name, got: %+v", book)
+	}
+
+	w = serve(h, http.MethodGet, "/authors/3/books", "")
+	var books models.BookPage
+	json.NewDecoder(w.Body).Decode(&books)
+	if w.Code != http.StatusOK || len(books.Books) != 1 || books.Books[0].General.ID != 1 {
+		t.Errorf("Expected the book of the author, got: %d %+v", w.Code, books)
+	}
+
+	tests := []struct {
+		method, target, body string
+		status               int
+		code                 string
+	}{
+		{http.MethodGet, "/authors/99", "", http.StatusNotFound, "author_not_found"},
+		{http.MethodGet, "/authors/99/books", "", http.StatusNotFound, "author_not_found"},
+		{http.MethodPut, "/books/1/authors", `{"authorIds": [99]}`, http.StatusNotFound, "author_not_found"},
+		{http.MethodPut, "/books/99/authors", `{"authorIds": [1]}`, http.StatusNotFound, "book_not_found"},
+		{http.MethodDelete, "/authors/3", "", http.StatusConflict, "author_has_books"},
+	}
+	for _, tt := range tests {
+		w = serve(h, tt.method, tt.target, tt.body)
+		p = Problem{}
+		json.NewDecoder(w.Body).Decode(&p)
+		if w.Code != tt.status || p.Code != tt.code {
+			t.Errorf("Expected %d %s for %s %s, got: %d %+v", tt.status, tt.code, tt.method, tt.target, w.Code, p)
+		}
+	}
+
+	// the first author has no books now, but the work of the book still has him
+	w = serve(h, http.MethodDelete, "/authors/1", "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for an author of a work, got: %d %s", w.Code, w.Body.String())
+	}
+	serve(h, http.MethodPost, "/authors", `{"name": "Erich Gamma"}`)
+	w = serve(h, http.MethodDelete, "/authors/4", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for an author without books, got: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+const batchRoute = "batch"
+
+// maxBatchBodySize is the biggest body of a batch request
+const maxBatchBodySize = 10 << 20
+
+// batchResponse is a body of a response of POST /books/batch
+type batchResponse struct {
+	Committed bool              `json:"committed"`
+	Results   []batchItemResult `json:"results"`
+}
+
+// batchItemResult is a result of one operation, it has a book or an error as problem details
+type batchItemResult struct {
+	Index  int            `json:"index"`
+	Op     models.BatchOp `json:"op"`
+	Status int            `json:"status"`
+	ID     uint64         `json:"id,omitempty"`
+	Book   any            `json:"book,omitempty"` // a book of the version of a request
+	Error  *Problem       `json:"error,omitempty"`
+}
+
+// Batch runs many create, update and delete operations in one request (POST /books/batch).
+// It responds 200 with a result of every operation if the batch is committed,
+// a rolled back atomic batch responds with a status of the failed operation
+func (h *HandlerBooks) Batch(w http.ResponseWriter, r *http.Request) {
+	req, appErr := h.version.decodeBatch(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	result, appErr := h.Service.Batch(r.Context(), req, time.Now())
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	status := http.StatusOK
+	resp := batchResponse{Committed: result.Committed, Results: make([]batchItemResult, len(result.Items))}
+	for i, item := range result.Items {
+		res := batchItemResult{Index: i, Op: item.Op, Status: item.Status, ID: req.Operations[i].ID}
+		if item.Book != nil {
+			res.ID = item.Book.General.ID
+			res.Book = h.version.view(*item.Book)
+		}
+		if item.Err != nil {
+			problem := newProblem(r, h.versionError(item.Err))
+			res.Error = &problem
+			// the whole batch has a status of the operation that rolled it back
+			if !result.Committed && item.Status != http.StatusFailedDependency {
+				status = item.Status
+			}
+		}
+		resp.Results[i] = res
+	}
+
+	h.sendJsonResponse(w, status, resp)
+}
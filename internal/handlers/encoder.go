@@ -0,0 +1,510 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// media types of book representations
+const (
+	MediaTypeJSON   = "application/json"
+	MediaTypeCSV    = "text/csv"
+	MediaTypeXML    = "application/xml"
+	MediaTypeYAML   = "application/yaml"
+	MediaTypeNDJSON = "application/x-ndjson"
+)
+
+// Encoder writes a response in one media type.
+// v is models.Book or models.BookPage, an encoder that cannot write
+// a value returns an error
+type Encoder interface {
+	MediaType() string   // for example text/csv, it is used to match Accept
+	ContentType() string // Content-Type of a response, it might have parameters
+	Encode(w io.Writer, v any) error
+}
+
+// StreamEncoder is an Encoder that can write books one by one,
+// so a response of any size takes constant memory. It is optional,
+// only stream encoders can be used by the export
+type StreamEncoder interface {
+	Encoder
+	NewBookWriter(w io.Writer) (BookWriter, error) // it writes a header if the media type has it
+}
+
+// BookWriter writes books of a stream
+type BookWriter interface {
+	WriteBook(book models.Book) error
+	Flush() error // writes buffered books to the underlying writer
+}
+
+// EncoderRegistry chooses an encoder by Accept header of a request.
+// The first registered encoder is used when a client accepts anything
+type EncoderRegistry struct {
+	encoders []Encoder
+}
+
+// NewEncoderRegistry returns a registry with given encoders
+func NewEncoderRegistry(encoders ...Encoder) *EncoderRegistry {
+	r := &EncoderRegistry{}
+	for _, e := range encoders {
+		r.Register(e)
+	}
+	return r
+}
+
+// DefaultEncoders returns a registry with JSON (default), CSV, XML, YAML and NDJSON
+func DefaultEncoders() *EncoderRegistry {
+	return NewEncoderRegistry(jsonEncoder{}, csvEncoder{}, xmlEncoder{}, yamlEncoder{}, ndjsonEncoder{})
+}
+
+// V2Encoders returns a registry of v2 with JSON (default), CSV, YAML and NDJSON.
+// XML is only in v1
+func V2Encoders() *EncoderRegistry {
+	view := apiV2{}.view
+	return NewEncoderRegistry(
+		viewEncoder{jsonEncoder{}, view},
+		csvEncoder{header: csvHeaderV2},
+		viewEncoder{yamlEncoder{}, view},
+		ndjsonEncoder{view: view},
+	)
+}
+
+// Register adds an encoder, it replaces an encoder of the same media type
+func (r *EncoderRegistry) Register(e Encoder) {
+	i := slices.IndexFunc(r.encoders, func(old Encoder) bool { return old.MediaType() == e.MediaType() })
+	if i >= 0 {
+		r.encoders[i] = e
+		return
+	}
+	r.encoders = append(r.encoders, e)
+}
+
+// MediaTypes returns media types of all encoders
+func (r *EncoderRegistry) MediaTypes() []string {
+	types := make([]string, 0, len(r.encoders))
+	for _, e := range r.encoders {
+		types = append(types, e.MediaType())
+	}
+	return types
+}
+
+// Negotiate returns the best encoder for Accept header (RFC 9110),
+// it returns false if a client accepts none of them.
+// Quality of an encoder is taken from the most specific range that matches it,
+// so "application/json;q=0, */*" excludes JSON
+func (r *EncoderRegistry) Negotiate(accept string) (Encoder, bool) {
+	if len(r.encoders) == 0 {
+		return nil, false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return r.encoders[0], true
+	}
+
+	ranges := parseAccept(accept)
+	var best Encoder
+	bestQ, bestSpecificity := 0.0, -1
+	for _, e := range r.encoders {
+		q, specificity := 0.0, -1
+		for _, m := range ranges {
+			if m.matches(e.MediaType()) && m.specificity() > specificity {
+				q, specificity = m.q, m.specificity()
+			}
+		}
+		// the first registered encoder wins a tie
+		if q > bestQ || q == bestQ && q > 0 && specificity > bestSpecificity {
+			best, bestQ, bestSpecificity = e, q, specificity
+		}
+	}
+	return best, best != nil
+}
+
+// mediaRange is one element of Accept header
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func (m mediaRange) matches(mediaType string) bool {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	return (m.typ == "*" || m.typ == typ) && (m.subtype == "*" || m.subtype == subtype)
+}
+
+// specificity is 0 for */*, 1 for type/* and 2 for type/subtype
+func (m mediaRange) specificity() int {
+	switch {
+	case m.typ == "*":
+		return 0
+	case m.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+// parseAccept returns media ranges of Accept header, invalid ones are skipped
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+		m := mediaRange{typ: typ, subtype: subtype, q: 1}
+		if q, ok := params["q"]; ok {
+			if m.q, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, m)
+	}
+	return ranges
+}
+
+// booksOf returns books of a value and a cursor of the next page
+func booksOf(v any) ([]models.Book, string, error) {
+	switch v := v.(type) {
+	case models.Book:
+		return []models.Book{v}, "", nil
+	case models.BookPage:
+		return v.Books, v.NextCursor, nil
+	}
+	return nil, "", fmt.Errorf("cannot encode %T", v)
+}
+
+// encodeStream writes a book or a page by a BookWriter of a stream encoder
+func encodeStream(e StreamEncoder, w io.Writer, v any) error {
+	books, _, err := booksOf(v)
+	if err != nil {
+		return err
+	}
+	writer, err := e.NewBookWriter(w)
+	if err != nil {
+		return err
+	}
+	for _, book := range books {
+		if err := writer.WriteBook(book); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// there are encoders
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) MediaType() string   { return MediaTypeJSON }
+func (jsonEncoder) ContentType() string { return MediaTypeJSON }
+func (jsonEncoder) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// viewEncoder writes a value of a version of the API by another encoder
+type viewEncoder struct {
+	Encoder
+	view func(v any) any
+}
+
+func (e viewEncoder) Encode(w io.Writer, v any) error {
+	return e.Encoder.Encode(w, e.view(v))
+}
+
+// ndjsonEncoder writes one book per line
+type ndjsonEncoder struct {
+	view func(v any) any // converts a book to json of a version, nil writes models.Book
+}
+
+func (ndjsonEncoder) MediaType() string   { return MediaTypeNDJSON }
+func (ndjsonEncoder) ContentType() string { return MediaTypeNDJSON }
+func (e ndjsonEncoder) Encode(w io.Writer, v any) error {
+	return encodeStream(e, w, v)
+}
+func (e ndjsonEncoder) NewBookWriter(w io.Writer) (BookWriter, error) {
+	return ndjsonWriter{json.NewEncoder(w), e.view}, nil
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+	view    func(v any) any
+}
+
+func (n ndjsonWriter) WriteBook(book models.Book) error {
+	if n.view != nil {
+		return n.encoder.Encode(n.view(book))
+	}
+	return n.encoder.Encode(book)
+}
+func (n ndjsonWriter) Flush() error { return nil } // every book is written at once
+
+// csvHeader is the first row of CSV, names are the same as json names
+var csvHeader = []string{"id", "title", "author", "genre", "publicationDate", "createdAt", "updateAt", "version", "isbn"}
+
+// csvHeaderV2 is csvHeader with names of v2
+var csvHeaderV2 = []string{"id", "title", "author", "genre", "publicationDate", "createdAt", "updatedAt", "version", "isbn"}
+
+// csvEncoder writes a header and one row per book
+type csvEncoder struct {
+	header []string // names of columns in order of csvRecord, nil is csvHeader
+}
+
+func (csvEncoder) MediaType() string   { return MediaTypeCSV }
+func (csvEncoder) ContentType() string { return MediaTypeCSV + "; charset=utf-8; header=present" }
+func (e csvEncoder) Encode(w io.Writer, v any) error {
+	return encodeStream(e, w, v)
+}
+func (e csvEncoder) NewBookWriter(w io.Writer) (BookWriter, error) {
+	header := e.header
+	if header == nil {
+		header = csvHeader
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	return csvWriter{writer}, nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (c csvWriter) WriteBook(book models.Book) error { return c.writer.Write(csvRecord(book)) }
+func (c csvWriter) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// csvRecord returns a row of a book in order of csvHeader
+func csvRecord(book models.Book) []string {
+	return []string{
+		strconv.FormatUint(book.General.ID, 10),
+		csvText(book.General.Title),
+		csvText(book.General.Author),
+		csvText(book.General.Genre),
+		book.General.PublicationDate.Format(time.RFC3339),
+		book.CreatedAt.Format(time.RFC3339),
+		book.UpdatedAt.Format(time.RFC3339),
+		strconv.FormatUint(book.Version, 10),
+		book.General.ISBN,
+	}
+}
+
+// csvText protects spreadsheets from formulas (CSV injection),
+// a text that starts like a formula gets a leading apostrophe
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// xmlBook is a book in XML, it is flat because XML has no reason
+// to have the general element
+type xmlBook struct {
+	XMLName         xml.Name  `xml:"book"`
+	ID              uint64    `xml:"id,attr"`
+	Version         uint64    `xml:"version,attr"`
+	Title           string    `xml:"title"`
+	Author          string    `xml:"author"`
+	Genre           string    `xml:"genre"`
+	PublicationDate time.Time `xml:"publicationDate"`
+	CreatedAt       time.Time `xml:"createdAt"`
+	UpdatedAt       time.Time `xml:"updateAt"`
+	ISBN            string    `xml:"isbn,omitempty"`
+	WorkID          uint64    `xml:"workId,omitempty"`
+	Publisher       string    `xml:"publisher,omitempty"`
+	Edition         string    `xml:"edition,omitempty"`
+	Format          string    `xml:"format,omitempty"`
+	PageCount       int       `xml:"pageCount,omitempty"`
+	Language        string    `xml:"language,omitempty"`
+}
+
+type xmlBooks struct {
+	XMLName    xml.Name  `xml:"books"`
+	NextCursor string    `xml:"nextCursor,attr,omitempty"`
+	Books      []xmlBook `xml:"book"`
+}
+
+type xmlEncoder struct{}
+
+func (xmlEncoder) MediaType() string   { return MediaTypeXML }
+func (xmlEncoder) ContentType() string { return MediaTypeXML + "; charset=utf-8" }
+func (xmlEncoder) Encode(w io.Writer, v any) error {
+	books, nextCursor, err := booksOf(v)
+	if err != nil {
+		return err
+	}
+	xmlList := make([]xmlBook, 0, len(books))
+	for _, book := range books {
+		xmlList = append(xmlList, xmlBook{
+			ID:              book.General.ID,
+			Version:         book.Version,
+			Title:           book.General.Title,
+			Author:          book.General.Author,
+			Genre:           book.General.Genre,
+			PublicationDate: book.General.PublicationDate,
+			CreatedAt:       book.CreatedAt,
+			UpdatedAt:       book.UpdatedAt,
+			ISBN:            book.General.ISBN,
+			WorkID:          book.General.WorkID,
+			Publisher:       book.General.Publisher,
+			Edition:         book.General.Edition,
+			Format:          book.General.Format,
+			PageCount:       book.General.PageCount,
+			Language:        book.General.Language,
+		})
+	}
+
+	var root any = xmlBooks{NextCursor: nextCursor, Books: xmlList}
+	if _, ok := v.(models.Book); ok {
+		root = xmlList[0]
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// yamlEncoder writes the same structure as JSON. YAML 1.2 is a superset of JSON,
+// so json strings and numbers are valid YAML scalars and no library is needed
+type yamlEncoder struct{}
+
+func (yamlEncoder) MediaType() string   { return MediaTypeYAML }
+func (yamlEncoder) ContentType() string { return MediaTypeYAML + "; charset=utf-8" }
+func (yamlEncoder) Encode(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	root, err := readYAMLNode(decoder)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	root.write(&buf, 0)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// yamlNode is a json value that keeps order of keys
+type yamlNode struct {
+	scalar   string      // a scalar or an empty collection
+	keys     []string    // keys of a mapping
+	children []*yamlNode // values of a mapping or items of a sequence
+	sequence bool
+}
+
+// readYAMLNode reads one json value from a decoder
+func readYAMLNode(decoder *json.Decoder) (*yamlNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'), json.Delim('['):
+		node := &yamlNode{sequence: token == json.Delim('[')}
+		for decoder.More() {
+			if !node.sequence {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key.(string))
+			}
+			child, err := readYAMLNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+		}
+		if _, err := decoder.Token(); err != nil { // } or ]
+			return nil, err
+		}
+		if len(node.children) == 0 {
+			node.scalar = "{}"
+			if node.sequence {
+				node.scalar = "[]"
+			}
+		}
+		return node, nil
+	case nil:
+		return &yamlNode{scalar: "null"}, nil
+	}
+
+	switch v := token.(type) {
+	case string:
+		return &yamlNode{scalar: yamlString(v)}, nil
+	case json.Number:
+		return &yamlNode{scalar: v.String()}, nil
+	case bool:
+		return &yamlNode{scalar: strconv.FormatBool(v)}, nil
+	}
+	return nil, fmt.Errorf("unexpected json token %v", token)
+}
+
+// write writes a node in block style
+func (n *yamlNode) write(buf *bytes.Buffer, indent int) {
+	if len(n.children) == 0 {
+		buf.WriteString(strings.Repeat("  ", indent) + n.scalar + "\n")
+		return
+	}
+
+	prefix := strings.Repeat("  ", indent)
+	for i, child := range n.children {
+		if n.sequence {
+			buf.WriteString(prefix + "-")
+		} else {
+			buf.WriteString(prefix + yamlKey(n.keys[i]) + ":")
+		}
+		if len(child.children) == 0 {
+			buf.WriteString(" " + child.scalar + "\n")
+			continue
+		}
+		buf.WriteString("\n")
+		child.write(buf, indent+1)
+	}
+}
+
+// yamlKey writes simple keys as they are and quotes others
+func yamlKey(key string) string {
+	for i, r := range key {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return yamlString(key)
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
+
+// yamlString quotes a string like json does, it is a valid double-quoted YAML scalar
+func yamlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
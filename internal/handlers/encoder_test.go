@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+var testBook = models.Book{
+	General: models.GeneralBook{
+		ID:              1,
+		Title:           "=SUM(A1)",
+		Genre:           "Programming",
+		Author:          "Robert C. Martin",
+		PublicationDate: time.Date(2008, 8, 1, 0, 0, 0, 0, time.UTC),
+	},
+	CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	UpdatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	Version:   1,
+}
+
+func TestEncoderRegistry_Negotiate(t *testing.T) {
+	registry := DefaultEncoders()
+
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", MediaTypeJSON},
+		{"*/*", MediaTypeJSON},
+		{"text/csv", MediaTypeCSV},
+		{"text/*", MediaTypeCSV},
+		{"application/xml;q=0.5, application/yaml", MediaTypeYAML},
+		{"application/json;q=0, */*;q=0.1", MediaTypeCSV},
+		{"text/html, application/x-ndjson;q=0.9", MediaTypeNDJSON},
+		{"image/png", ""},
+	}
+
+	for _, tt := range tests {
+		encoder, ok := registry.Negotiate(tt.accept)
+		if tt.expected == "" {
+			if ok {
+				t.Errorf("Expected no encoder for %q, got: %s", tt.accept, encoder.MediaType())
+			}
+			continue
+		}
+		if !ok || encoder.MediaType() != tt.expected {
+			t.Errorf("Expected %s for %q, got: %v", tt.expected, tt.accept, encoder)
+		}
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	page := models.BookPage{Books: []models.Book{testBook}, NextCursor: "abc"}
+
+	tests := []struct {
+		encoder  Encoder
+		value    any
+		expected string
+	}{
+		{csvEncoder{}, page, "id,title,author,genre,publicationDate,createdAt,updateAt,version,isbn\n" +
+			"1,'=SUM(A1),Robert C. Martin,Programming,2008-08-01T00:00:00Z,2023-01-01T00:00:00Z,2023-01-01T00:00:00Z,1,\n"},
+		{ndjsonEncoder{}, page, `{"general":{"id":1,"title":"=SUM(A1)","genre":"Programming","publicationDate":"2008-08-01T00:00:00Z","author":"Robert C. Martin"},"createdAt":"2023-01-01T00:00:00Z","updateAt":"2023-01-01T00:00:00Z","version":1}` + "\n"},
+		{yamlEncoder{}, page, `books:
+  -
+    general:
+      id: 1
+      title: "=SUM(A1)"
+      genre: "Programming"
+      publicationDate: "2008-08-01T00:00:00Z"
+      author: "Robert C. Martin"
+    createdAt: "2023-01-01T00:00:00Z"
+    updateAt: "2023-01-01T00:00:00Z"
+    version: 1
+next_cursor: "abc"
+`},
+		{yamlEncoder{}, models.BookPage{Books: []models.Book{}}, "books: []\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.encoder.Encode(&buf, tt.value); err != nil {
+			t.Fatalf("Unexpected error of %s: %v", tt.encoder.MediaType(), err)
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("Unexpected %s:\n%s\nexpected:\n%s", tt.encoder.MediaType(), buf.String(), tt.expected)
+		}
+	}
+}
+
+func TestXMLEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (xmlEncoder{}).Encode(&buf, testBook); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, part := range []string{`<?xml`, `<book id="1" version="1">`, `<title>=SUM(A1)</title>`, `<publicationDate>2008-08-01T00:00:00Z</publicationDate>`} {
+		if !strings.Contains(buf.String(), part) {
+			t.Errorf("Expected %s in XML, got:\n%s", part, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := (xmlEncoder{}).Encode(&buf, models.BookPage{Books: []models.Book{testBook}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<books>") {
+		t.Errorf("Expected books element, got:\n%s", buf.String())
+	}
+}
+
+func TestHandlerBooks_Accept(t *testing.T) {
+	h := newTestHandler()
+	serve(h, http.MethodPost, "/books", testBookJson)
+
+	r := httptest.NewRequest(http.MethodGet, "/books", nil)
+	r.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("Expected CSV, got: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if lines := strings.Count(w.Body.String(), "\n"); lines != 2 {
+		t.Errorf("Expected header and one row, got: %s", w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/books/1", nil)
+	r.Header.Set("Accept", "application/yaml")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `W/"1"` || w.Header().Get("Vary") != "Accept" {
+		t.Errorf("Expected YAML with weak ETag, got: %d %v", w.Code, w.Header())
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/books/1", nil)
+	r.Header.Set("Accept", "image/png")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected 406, got: %d", w.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/services"
+)
+
+// bookETag returns a strong ETag of a book, it is its version in quotes
+func bookETag(book models.Book) string {
+	return strconv.Quote(strconv.FormatUint(book.Version, 10))
+}
+
+// splitETags splits a list of entity tags from If-Match or If-None-Match
+func splitETags(header string) []string {
+	var tags []string
+	for tag := range strings.SplitSeq(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// notModified reports if If-None-Match of a request matches etag.
+// It uses weak comparison, so W/"1" matches "1"
+func notModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range splitETags(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrecondition converts If-Match (or If-None-Match: * when createAllowed)
+// of a request to a condition of the service, it returns nil if there are no headers.
+// Weak tags and tags that are not versions never match, like RFC 9110 says.
+// If RequireIfMatch is set a request without a condition gets 428
+func (h *HandlerBooks) parsePrecondition(r *http.Request, createAllowed bool) (*services.Precondition, *apperrors.AppError) {
+	if createAllowed && strings.TrimSpace(r.Header.Get("If-None-Match")) == "*" {
+		return &services.Precondition{None: true}, nil
+	}
+
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if h.RequireIfMatch {
+			return nil, apperrors.NewAppError(http.StatusPreconditionRequired, "If-Match header is required",
+				errors.New("send ETag of the book in If-Match"))
+		}
+		return nil, nil
+	}
+
+	cond := &services.Precondition{}
+	for _, tag := range splitETags(header) {
+		if tag == "*" {
+			cond.Any = true
+			continue
+		}
+		unquoted, err := strconv.Unquote(tag)
+		if err != nil {
+			continue
+		}
+		version, err := strconv.ParseUint(unquoted, 10, 64)
+		if err != nil {
+			continue
+		}
+		cond.Versions = append(cond.Versions, version)
+	}
+	return cond, nil
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+)
+
+const exportRoute = "export"
+
+// exportFlushEvery is how many books are written between flushes of a response,
+// so a client gets books while the rest of them are read from a storage
+const exportFlushEvery = 100
+
+// exportExtensions are extensions of a downloaded file by media types
+var exportExtensions = map[string]string{
+	MediaTypeNDJSON: ".ndjson",
+	MediaTypeCSV:    ".csv",
+}
+
+// ExportEncoders returns a registry of representations of the export: NDJSON (default) and CSV
+func ExportEncoders() *EncoderRegistry {
+	return NewEncoderRegistry(ndjsonEncoder{}, csvEncoder{})
+}
+
+// V2ExportEncoders returns ExportEncoders with names of v2
+func V2ExportEncoders() *EncoderRegistry {
+	return NewEncoderRegistry(ndjsonEncoder{view: apiV2{}.view}, csvEncoder{header: csvHeaderV2})
+}
+
+// ExportBooks streams all books that match filters (GET /books/export).
+// Filters and sort are the same as GET /books has, limit is optional.
+// Books go from a storage to a client one by one and the response is flushed
+// every exportFlushEvery books, so memory doesn't depend on size of the catalog.
+// It is compressed by gzip if a client accepts it.
+// If a storage fails in the middle, the response is aborted, so a client sees a broken download
+func (h *HandlerBooks) ExportBooks(w http.ResponseWriter, r *http.Request) {
+	encoder, appErr := h.negotiate(r, h.ExportEncoders)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	streamEncoder, ok := encoder.(StreamEncoder)
+	if !ok {
+		h.logger.Error("export encoder cannot stream", "type", encoder.MediaType())
+		h.sendErrorResponse(w, r, apperrors.NewAppError(500, "error export books", nil))
+		return
+	}
+
+	query, appErr := parseBookQuery(r.URL.Query())
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	books, appErr := h.Service.ExportBooks(r.Context(), query)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	var (
+		writer BookWriter
+		zw     *gzip.Writer
+	)
+	// the response starts with the first book, so an error before it is still a problem response
+	start := func() error {
+		header := w.Header()
+		header.Set("Content-Type", encoder.ContentType())
+		header.Set("Vary", "Accept, Accept-Encoding")
+		header.Set("Content-Disposition", `attachment; filename="books`+exportExtensions[encoder.MediaType()]+`"`)
+
+		var out io.Writer = w
+		if acceptsEncoding(r.Header.Get("Accept-Encoding"), "gzip") {
+			header.Set("Content-Encoding", "gzip")
+			zw = gzip.NewWriter(w)
+			out = zw
+		}
+		w.WriteHeader(http.StatusOK)
+
+		var err error
+		writer, err = streamEncoder.NewBookWriter(out)
+		return err
+	}
+	flush := func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if zw != nil {
+			if err := zw.Flush(); err != nil {
+				return err
+			}
+		}
+		return http.NewResponseController(w).Flush()
+	}
+
+	count := 0
+	for book, err := range books {
+		if err != nil {
+			var appErr *apperrors.AppError
+			if writer == nil && errors.As(err, &appErr) {
+				h.sendErrorResponse(w, r, appErr)
+				return
+			}
+			h.abortExport(r, err, count)
+		}
+
+		if writer == nil {
+			if err := start(); err != nil {
+				h.abortExport(r, err, count)
+			}
+		}
+		if err := writer.WriteBook(book); err != nil {
+			h.abortExport(r, err, count)
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				h.abortExport(r, err, count)
+			}
+		}
+	}
+
+	// an empty export still has a header of CSV
+	if writer == nil {
+		if err := start(); err != nil {
+			h.abortExport(r, err, count)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		h.abortExport(r, err, count)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			h.abortExport(r, err, count)
+		}
+	}
+}
+
+// abortExport stops a response that is already started,
+// a client gets a broken response instead of a complete but short one
+func (h *HandlerBooks) abortExport(r *http.Request, err error, count int) {
+	// a client that has gone away doesn't need a log
+	if r.Context().Err() == nil {
+		h.logger.Error("error export books", "error", err, "written", count)
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// acceptsEncoding reports whether Accept-Encoding header allows a content coding.
+// A coding with q=0 is not allowed, * allows any coding that is not listed
+func acceptsEncoding(header, coding string) bool {
+	wildcard := false
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(item, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		switch name {
+		case coding:
+			return q > 0
+		case "*":
+			wildcard = q > 0
+		}
+	}
+	return wildcard
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+const genresRoute = "genres"
+
+// GetGenres send the whole genre tree
+func (h *HandlerBooks) GetGenres(w http.ResponseWriter, r *http.Request) {
+	tree, appErr := h.Service.GetGenres(r.Context())
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, tree)
+}
+
+// GetGenre send a genre by an ID with its subgenres
+func (h *HandlerBooks) GetGenre(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid genre id", err))
+		return
+	}
+
+	genre, appErr := h.Service.GetGenre(r.Context(), id)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, genre)
+}
+
+// CreateGenre create new genre, id and times are given by the server
+func (h *HandlerBooks) CreateGenre(w http.ResponseWriter, r *http.Request) {
+	var genre models.Genre
+	if err := json.NewDecoder(r.Body).Decode(&genre); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	genre.CreatedAt = time.Now()
+
+	genre, appErr := h.Service.CreateGenre(r.Context(), genre)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	w.Header().Set("Location", h.genreLocation(r, genre.ID))
+	h.sendJsonResponse(w, http.StatusCreated, genre)
+}
+
+// ReplaceGenre replaces a genre by an ID from the path (PUT /genres/{id}),
+// a genre is moved by another parentId. The replaced genre is sent back
+func (h *HandlerBooks) ReplaceGenre(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid genre id", err))
+		return
+	}
+
+	var genre models.Genre
+	if err := json.NewDecoder(r.Body).Decode(&genre); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	genre.UpdatedAt = time.Now()
+
+	genre, appErr := h.Service.ReplaceGenre(r.Context(), id, genre)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, genre)
+}
+
+// DeleteGenre delete a genre without books and subgenres
+func (h *HandlerBooks) DeleteGenre(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid genre id", err))
+		return
+	}
+
+	if appErr := h.Service.DeleteGenre(r.Context(), id); appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, map[string]string{"message": "Genre deleted successfully"})
+}
+
+// GetGenreBooks send one page of books of a genre and its subgenres,
+// it takes the same query parameters as GetAllBooks
+func (h *HandlerBooks) GetGenreBooks(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid genre id", err))
+		return
+	}
+	encoder, appErr := h.negotiate(r, h.Encoders)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	params := r.URL.Query()
+
+	query, appErr := parseBookQuery(params)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	page, appErr := h.Service.GetGenreBooks(r.Context(), id, query, params.Get("cursor"))
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	if page.NextCursor != "" {
+		params.Set("cursor", page.NextCursor)
+		w.Header().Add("Link", fmt.Sprintf(`<%s/%s?%s>; rel="next"`, h.genreLocation(r, id), booksRoute, params.Encode()))
+	}
+	h.sendEncodedResponse(w, encoder, http.StatusOK, page)
+}
+
+// SetBookGenres replaces genres of a book (PUT /books/{id}/genres),
+// the body is {"genreIds": [...]}. The book is sent back
+func (h *HandlerBooks) SetBookGenres(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid book id", err))
+		return
+	}
+
+	var req models.SetBookGenresRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	req.UpdatedAt = time.Now()
+
+	book, appErr := h.Service.SetBookGenres(r.Context(), id, req)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	w.Header().Set("ETag", bookETag(book))
+	h.sendJsonResponse(w, http.StatusOK, h.version.view(book))
+}
+
+// genreLocation returns a path of a genre resource in the version of a request
+func (h *HandlerBooks) genreLocation(r *http.Request, id uint64) string {
+	return h.basePath(r) + "/" + genresRoute + "/" + strconv.FormatUint(id, 10)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+func TestHandlerBooks_Genres(t *testing.T) {
+	h := newTestHandler()
+
+	// a book gets a genre from its genre string
+	w := serve(h, http.MethodPost, "/books", `{"book": {"id": 1, "title": "Dune", "genre": "Science Fiction", "author": "Frank Herbert", "publicationDate": "1965-08-01T00:00:00Z"}}`)
+	var book models.Book
+	json.NewDecoder(w.Body).Decode(&book)
+	if w.Code != http.StatusCreated || len(book.Genres) != 1 || book.Genres[0].Slug != "science-fiction" {
+		t.Fatalf("Expected a book with a genre, got: %d %+v", w.Code, book)
+	}
+
+	w = serve(h, http.MethodPost, "/genres", `{"name": "Fiction"}`)
+	var fiction models.Genre
+	json.NewDecoder(w.Body).Decode(&fiction)
+	if w.Code != http.StatusCreated || fiction.Slug != "fiction" || w.Header().Get("Location") != "/genres/2" {
+		t.Fatalf("Expected a created genre, got: %d %+v %s", w.Code, fiction, w.Header().Get("Location"))
+	}
+
+	// science fiction is moved under fiction
+	w = serve(h, http.MethodPut, "/genres/1", `{"name": "Science Fiction", "slug": "science-fiction", "parentId": 2}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for a moved genre, got: %d %s", w.Code, w.Body.String())
+	}
+
+	w = serve(h, http.MethodGet, "/genres", "")
+	var tree models.GenreTree
+	json.NewDecoder(w.Body).Decode(&tree)
+	if w.Code != http.StatusOK || len(tree.Genres) != 1 || len(tree.Genres[0].Children) != 1 || tree.Genres[0].Children[0].ID != 1 {
+		t.Fatalf("Expected fiction with one subgenre, got: %d %+v", w.Code, tree)
+	}
+
+	// books of subgenres are books of a parent
+	w = serve(h, http.MethodGet, "/genres/2/books", "")
+	var books models.BookPage
+	json.NewDecoder(w.Body).Decode(&books)
+	if w.Code != http.StatusOK || len(books.Books) != 1 || books.Books[0].General.ID != 1 {
+		t.Errorf("Expected the book of the subgenre, got: %d %+v", w.Code, books)
+	}
+
+	// a book might have several genres
+	w = serve(h, http.MethodPost, "/genres", `{"name": "Classics", "slug": "classics"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected a created genre, got: %d %s", w.Code, w.Body.String())
+	}
+	w = serve(h, http.MethodPut, "/books/1/genres", `{"genreIds": [1, 3]}`)
+	book = models.Book{}
+	json.NewDecoder(w.Body).Decode(&book)
+	if w.Code != http.StatusOK || len(book.Genres) != 2 || book.Genres[0].Slug != "classics" || book.Genres[1].Slug != "science-fiction" {
+		t.Fatalf("Expected genres ordered by name, got: %d %+v", w.Code, book)
+	}
+	if book.Version != 2 || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("Expected version 2, got: %d %s", book.Version, w.Header().Get("ETag"))
+	}
+
+	// a renamed genre changes its books, so the cached version is stale
+	serve(h, http.MethodPut, "/genres/3", `{"name": "Great Classics", "slug": "classics"}`)
+	r := httptest.NewRequest(http.MethodGet, "/books/1", nil)
+	r.Header.Set("If-None-Match", `"2"`)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	book = models.Book{}
+	json.NewDecoder(w.Body).Decode(&book)
+	if w.Code != http.StatusOK || book.Version != 3 || book.Genres[0].Name != "Great Classics" {
+		t.Errorf("Expected version 3 with the new name, got: %d %+v", w.Code, book)
+	}
+
+	tests := []struct {
+		method, target, body string
+		status               int
+		code                 string
+	}{
+		{http.MethodGet, "/genres/99", "", http.StatusNotFound, "genre_not_found"},
+		{http.MethodGet, "/genres/99/books", "", http.StatusNotFound, "genre_not_found"},
+		{http.MethodPost, "/genres", `{"name": "Fiction"}`, http.StatusConflict, "genre_exists"},
+		{http.MethodPost, "/genres", `{"name": "Poetry", "parentId": 99}`, http.StatusBadRequest, "invalid_genre_parent"},
+		{http.MethodPut, "/genres/2", `{"name": "Fiction", "parentId": 1}`, http.StatusBadRequest, "genre_cycle"},
+		{http.MethodPut, "/books/1/genres", `{"genreIds": [99]}`, http.StatusNotFound, "genre_not_found"},
+		{http.MethodPut, "/books/1/genres", `{}`, http.StatusBadRequest, "invalid_book_genres"},
+		{http.MethodDelete, "/genres/2", "", http.StatusConflict, "genre_in_use"},
+	}
+	for _, tt := range tests {
+		w = serve(h, tt.method, tt.target, tt.body)
+		var p Problem
+		json.NewDecoder(w.Body).Decode(&p)
+		if w.Code != tt.status || p.Code != tt.code {
+			t.Errorf("Expected %d %s for %s %s, got: %d %+v", tt.status, tt.code, tt.method, tt.target, w.Code, p)
+		}
+	}
+
+	w = serve(h, http.MethodDelete, "/genres/3", "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a genre of a book, got: %d %s", w.Code, w.Body.String())
+	}
+	serve(h, http.MethodPut, "/books/1/genres", `{"genreIds": [1]}`)
+	w = serve(h, http.MethodDelete, "/genres/3", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a genre without books, got: %d %s", w.Code, w.Body.String())
+	}
+}
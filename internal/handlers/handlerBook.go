@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -11,10 +16,18 @@ import (
 	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
 	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
 	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/patch"
 	"github.com/Talos-hub/BooksRestApi/internal/services"
 )
 
-const booksRoute = "books"
+const (
+	booksRoute  = "books"
+	searchRoute = "search"
+	isbnRoute   = "isbn"
+)
+
+// maxPatchSize is the biggest patch a client can send
+const maxPatchSize = 1 << 20
 
 // HandlerBooks is struct that contains methods
 // for handle clients requests
@@ -22,75 +35,192 @@ const booksRoute = "books"
 type HandlerBooks struct {
 	Service *services.BookService
 	logger  abstraction.Logger
+
+	// RequireIfMatch makes If-Match required for PUT, PATCH and DELETE,
+	// a request without it gets 428
+	RequireIfMatch bool
+
+	// Encoders are representations of books that GET requests can have by Accept header
+	Encoders *EncoderRegistry
+	// ExportEncoders are representations of the export, all of them must be StreamEncoder
+	ExportEncoders *EncoderRegistry
+
+	// ImportErrorsDir is where error files of imports are kept, os.TempDir() if it is empty
+	ImportErrorsDir string
+	importErrors    importErrorFiles
+
+	// Deprecation is set when the version is deprecated, nil means it is not
+	Deprecation *Deprecation
+	version     apiVersion
 }
 
-// NewHandlerBooks return new HandlerBooks
+// NewHandlerBooks return new HandlerBooks of v1.
+// It serves /v1/books and unversioned /books
 func NewHandlerBooks(service *services.BookService, logger abstraction.Logger) *HandlerBooks {
 	return &HandlerBooks{
-		Service: service,
-		logger:  logger,
+		Service:        service,
+		logger:         logger,
+		Encoders:       DefaultEncoders(),
+		ExportEncoders: ExportEncoders(),
+		version:        apiV1{},
+	}
+}
+
+// NewHandlerBooksV2 return new HandlerBooks of v2, it serves /v2/books.
+// It has the same routes as v1 except the legacy PUT /books, a book is flat
+func NewHandlerBooksV2(service *services.BookService, logger abstraction.Logger) *HandlerBooks {
+	return &HandlerBooks{
+		Service:        service,
+		logger:         logger,
+		Encoders:       V2Encoders(),
+		ExportEncoders: V2ExportEncoders(),
+		version:        apiV2{},
 	}
 }
 
 // ServeHTTP Route based on HTTP method and path
 func (h *HandlerBooks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.Deprecation.setHeaders(w)
+
 	path := strings.Trim(r.URL.Path, "/")
 	parts := strings.Split(path, "/")
+	if len(parts) > 1 && parts[0] == h.version.route() {
+		parts = parts[1:]
+	}
 
 	// Route
 	switch {
 	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == booksRoute:
-		h.GetAllBooks(w)
+		h.GetAllBooks(w, r)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == booksRoute && parts[1] == searchRoute:
+		h.SearchBooks(w, r)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == booksRoute && parts[1] == exportRoute:
+		h.ExportBooks(w, r)
 	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == booksRoute:
-		h.GetBookById(w, parts[1])
+		h.GetBookById(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == booksRoute && parts[1] == isbnRoute:
+		h.GetBookByISBN(w, r, parts[2])
 	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == booksRoute:
 		h.CreateBook(w, r)
-	case r.Method == http.MethodPut && len(parts) == 1 && parts[0] == booksRoute:
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[0] == booksRoute && parts[1] == batchRoute:
+		h.Batch(w, r)
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[0] == booksRoute && parts[1] == importRoute:
+		h.ImportBooks(w, r)
+	case r.Method == http.MethodGet && len(parts) == 4 && parts[0] == booksRoute && parts[1] == importRoute && parts[2] == importErrorsRoute:
+		h.GetImportErrors(w, r, parts[3])
+	case r.Method == http.MethodPut && len(parts) == 1 && parts[0] == booksRoute && h.version.route() == v1Route:
 		h.UpdateBook(w, r)
+	case r.Method == http.MethodPut && len(parts) == 2 && parts[0] == booksRoute:
+		h.ReplaceBook(w, r, parts[1])
+	case r.Method == http.MethodPatch && len(parts) == 2 && parts[0] == booksRoute:
+		h.PatchBook(w, r, parts[1])
 	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == booksRoute:
-		h.DeleteBook(w, parts[1])
+		h.DeleteBook(w, r, parts[1])
+	case r.Method == http.MethodPut && len(parts) == 3 && parts[0] == booksRoute && parts[2] == authorsRoute:
+		h.SetBookAuthors(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == authorsRoute:
+		h.GetAuthors(w, r)
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == authorsRoute:
+		h.CreateAuthor(w, r)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == authorsRoute:
+		h.GetAuthor(w, r, parts[1])
+	case r.Method == http.MethodPut && len(parts) == 2 && parts[0] == authorsRoute:
+		h.ReplaceAuthor(w, r, parts[1])
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == authorsRoute:
+		h.DeleteAuthor(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == authorsRoute && parts[2] == booksRoute:
+		h.GetAuthorBooks(w, r, parts[1])
+	case r.Method == http.MethodPut && len(parts) == 3 && parts[0] == booksRoute && parts[2] == genresRoute:
+		h.SetBookGenres(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == genresRoute:
+		h.GetGenres(w, r)
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == genresRoute:
+		h.CreateGenre(w, r)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == genresRoute:
+		h.GetGenre(w, r, parts[1])
+	case r.Method == http.MethodPut && len(parts) == 2 && parts[0] == genresRoute:
+		h.ReplaceGenre(w, r, parts[1])
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == genresRoute:
+		h.DeleteGenre(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == genresRoute && parts[2] == booksRoute:
+		h.GetGenreBooks(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == worksRoute:
+		h.GetWorks(w, r)
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == worksRoute:
+		h.CreateWork(w, r)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == worksRoute:
+		h.GetWork(w, r, parts[1])
+	case r.Method == http.MethodPut && len(parts) == 2 && parts[0] == worksRoute:
+		h.ReplaceWork(w, r, parts[1])
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == worksRoute:
+		h.DeleteWork(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == worksRoute && parts[2] == editionsRoute:
+		h.GetWorkEditions(w, r, parts[1])
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == worksRoute && parts[2] == editionsRoute:
+		h.CreateEdition(w, r, parts[1])
+	case r.Method == http.MethodPut && len(parts) == 3 && parts[0] == booksRoute && parts[2] == seriesRoute:
+		h.SetBookSeries(w, r, parts[1])
+	case r.Method == http.MethodDelete && len(parts) == 3 && parts[0] == booksRoute && parts[2] == seriesRoute:
+		h.RemoveBookSeries(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == seriesRoute:
+		h.GetAllSeries(w, r)
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == seriesRoute:
+		h.CreateSeries(w, r)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == seriesRoute:
+		h.GetSeries(w, r, parts[1])
+	case r.Method == http.MethodPut && len(parts) == 2 && parts[0] == seriesRoute:
+		h.ReplaceSeries(w, r, parts[1])
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == seriesRoute:
+		h.DeleteSeries(w, r, parts[1])
 	default:
-		h.sendErrorResponse(w, apperrors.NewAppError(404, "not found", nil))
+		h.sendErrorResponse(w, r, apperrors.NewAppError(404, "not found", nil).WithCode("route_not_found"))
 
 	}
 }
 
 // CreateBook create new book and save it in a storage
 func (h *HandlerBooks) CreateBook(w http.ResponseWriter, r *http.Request) {
-	var createdBook models.CreateBookRequest
-
-	err := json.NewDecoder(r.Body).Decode(&createdBook)
-	if err != nil {
-		h.sendErrorResponse(w, apperrors.NewAppError(400, "invalid JSON", err))
+	createdBook, appErr := h.version.decodeCreate(r.Body)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
 		return
 	}
 	t := time.Now()
 
 	createdBook.CreatedAt = t
 
-	apperr := h.Service.CreateBook(createdBook)
+	book, apperr := h.Service.CreateBook(r.Context(), createdBook)
 	if apperr != nil {
-		h.sendErrorResponse(w, apperr)
+		h.sendErrorResponse(w, r, apperr)
 		return
 	}
 
-	h.sendJsonResponse(w, http.StatusCreated, map[string]string{"message": "create new book successfully"})
+	// client gets the created resource and where it lives
+	w.Header().Set("Location", h.bookLocation(r, book.General.ID))
+	h.sendJsonResponse(w, http.StatusCreated, h.version.view(book))
 }
 
-// UpdateBook update a book from a storage by id
+// UpdateBook update a book from a storage by id.
+// Deprecated: the id is taken from the body, use ReplaceBook (PUT /books/{id})
 func (h *HandlerBooks) UpdateBook(w http.ResponseWriter, r *http.Request) {
 	var updateBook models.UpdateBookRequest
 	err := json.NewDecoder(r.Body).Decode(&updateBook)
 	if err != nil {
-		h.sendErrorResponse(w, apperrors.NewAppError(400, "invalid JSON", err))
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
 		return
 	}
 	t := time.Now()
 
+	cond, appErr := h.parsePrecondition(r, false)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
 	updateBook.UpdatedAt = t
-	appErr := h.Service.UpdateBook(updateBook.Book.ID, updateBook)
+	_, appErr = h.Service.UpdateBook(r.Context(), updateBook.Book.ID, updateBook, cond)
 	if appErr != nil {
-		h.sendErrorResponse(w, appErr)
+		h.sendErrorResponse(w, r, appErr)
 		return
 	}
 
@@ -98,58 +228,236 @@ func (h *HandlerBooks) UpdateBook(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// GetAllBooks send all books from a storage to a client
-func (h *HandlerBooks) GetAllBooks(w http.ResponseWriter) {
-	books, err := h.Service.GetBooks()
+// ReplaceBook replaces a book by an ID from the path (PUT /books/{id}).
+// If the book doesn't exist it is created with this ID, then it responds 201
+// with Location, otherwise 200. The replaced or created book is sent back
+func (h *HandlerBooks) ReplaceBook(w http.ResponseWriter, r *http.Request, strID string) {
+	// parse str to uint64
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid book id", err))
+		return
+	}
+
+	updateBook, appErr := h.version.decodeReplace(r.Body)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	updateBook.UpdatedAt = time.Now()
+
+	cond, appErr := h.parsePrecondition(r, true)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	book, created, appErr := h.Service.ReplaceBook(r.Context(), id, updateBook, cond)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	w.Header().Set("ETag", bookETag(book))
+	if created {
+		w.Header().Set("Location", h.bookLocation(r, book.General.ID))
+		h.sendJsonResponse(w, http.StatusCreated, h.version.view(book))
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, h.version.view(book))
+}
+
+// PatchBook changes some fields of a book by an ID.
+// The body is JSON Merge Patch (application/merge-patch+json)
+// or JSON Patch (application/json-patch+json), the patched book is sent back
+func (h *HandlerBooks) PatchBook(w http.ResponseWriter, r *http.Request, strID string) {
+	// parse str to uint64
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid book id", err))
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != patch.MergePatchType && mediaType != patch.JSONPatchType) {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(http.StatusUnsupportedMediaType, "unsupported patch type",
+			fmt.Errorf("Content-Type must be %s or %s", patch.MergePatchType, patch.JSONPatchType)))
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
 	if err != nil {
-		h.sendErrorResponse(w, err)
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid patch", err))
+		return
+	}
+	data, appErr := h.version.patch(mediaType, data)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	cond, appErr := h.parsePrecondition(r, false)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	book, appErr := h.Service.PatchBook(r.Context(), id, mediaType, data, time.Now(), cond)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	w.Header().Set("ETag", bookETag(book))
+	h.sendJsonResponse(w, http.StatusOK, h.version.view(book))
+}
+
+// GetAllBooks send one page of books to a client.
+// It takes query parameters for filtering (author, genre, title,
+// published_after, published_before), sorting (sort=-publicationDate)
+// and pagination (limit, cursor).
+// A link to the next page is sent in the Link header and in next_cursor
+func (h *HandlerBooks) GetAllBooks(w http.ResponseWriter, r *http.Request) {
+	encoder, appErr := h.negotiate(r, h.Encoders)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	params := r.URL.Query()
+
+	query, appErr := parseBookQuery(params)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	page, appErr := h.Service.GetBooks(r.Context(), query, params.Get("cursor"))
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	if page.NextCursor != "" {
+		// the next page has the same filters and order
+		params.Set("cursor", page.NextCursor)
+		w.Header().Add("Link", fmt.Sprintf(`<%s/%s?%s>; rel="next"`, h.basePath(r), booksRoute, params.Encode()))
+	}
+	h.sendEncodedResponse(w, encoder, http.StatusOK, page)
+}
+
+// SearchBooks send books that match a text query in the q parameter,
+// the most relevant go first. It also takes limit
+func (h *HandlerBooks) SearchBooks(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	limit := 0
+	if strLimit := params.Get("limit"); strLimit != "" {
+		var err error
+		if limit, err = strconv.Atoi(strLimit); err != nil {
+			h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid limit", err))
+			return
+		}
+	}
+
+	results, appErr := h.Service.SearchBooks(r.Context(), params.Get("q"), limit)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
 		return
 	}
-	h.sendJsonResponse(w, http.StatusOK, books)
+
+	h.sendJsonResponse(w, http.StatusOK, map[string]any{"results": h.version.view(results)})
 }
 
 // GetById send a book by an ID
-func (h *HandlerBooks) GetBookById(w http.ResponseWriter, strID string) {
+func (h *HandlerBooks) GetBookById(w http.ResponseWriter, r *http.Request, strID string) {
 	if len(strID) == 0 {
-		h.sendErrorResponse(w, apperrors.NewAppError(400, "invalid book id", errors.New("id cannot be empty")))
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid book id", errors.New("id cannot be empty")))
 		return
 	}
 	// parse str to uint64
 	id, err := strconv.ParseUint(strID, 10, 64)
 	if err != nil {
-		h.sendErrorResponse(w, apperrors.NewAppError(400, "invalid book id", errors.New("id cannot be empty")))
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid book id", errors.New("id cannot be empty")))
+		return
+	}
+
+	encoder, appError := h.negotiate(r, h.Encoders)
+	if appError != nil {
+		h.sendErrorResponse(w, r, appError)
 		return
 	}
 
 	// get a book
-	book, appError := h.Service.GetBook(id)
+	book, appError := h.Service.GetBook(r.Context(), id)
 	if appError != nil {
-		h.sendErrorResponse(w, appError)
+		h.sendErrorResponse(w, r, appError)
 		return
 	}
 
-	h.sendJsonResponse(w, http.StatusOK, book)
+	h.sendBook(w, r, encoder, book)
+}
+
+// GetBookByISBN send a book by ISBN-10 or ISBN-13,
+// Content-Location is the link to the book by its id
+func (h *HandlerBooks) GetBookByISBN(w http.ResponseWriter, r *http.Request, isbn string) {
+	encoder, appError := h.negotiate(r, h.Encoders)
+	if appError != nil {
+		h.sendErrorResponse(w, r, appError)
+		return
+	}
 
+	book, appError := h.Service.GetBookByISBN(r.Context(), isbn)
+	if appError != nil {
+		h.sendErrorResponse(w, r, appError)
+		return
+	}
+
+	w.Header().Set("Content-Location", h.bookLocation(r, book.General.ID))
+	h.sendBook(w, r, encoder, book)
+}
+
+// sendBook sends a book with its ETag or 304 if a client has it
+func (h *HandlerBooks) sendBook(w http.ResponseWriter, r *http.Request, encoder Encoder, book models.Book) {
+	// the strong ETag belongs to JSON, other representations
+	// are only semantically the same, so their ETag is weak
+	etag := bookETag(book)
+	if encoder.MediaType() != MediaTypeJSON {
+		etag = "W/" + etag
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept")
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.sendEncodedResponse(w, encoder, http.StatusOK, book)
 }
 
 // DeleteBook delete a book from a storage
 // Where is strId, it's an ID of a book
-func (h *HandlerBooks) DeleteBook(w http.ResponseWriter, strID string) {
+func (h *HandlerBooks) DeleteBook(w http.ResponseWriter, r *http.Request, strID string) {
 	if len(strID) == 0 {
-		h.sendErrorResponse(w, apperrors.NewAppError(400, "invalid book id", errors.New("id cannot be empty")))
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid book id", errors.New("id cannot be empty")))
 		return
 	}
 	// parse str to uint64
 	id, err := strconv.ParseUint(strID, 10, 64)
 	if err != nil {
-		h.sendErrorResponse(w, apperrors.NewAppError(400, "invalid book id", errors.New("id cannot be empty")))
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid book id", errors.New("id cannot be empty")))
+		return
+	}
+
+	cond, appErr := h.parsePrecondition(r, false)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
 		return
 	}
 
 	// if it has an error, send it
-	appErr := h.Service.DeleteBook(id)
+	appErr = h.Service.DeleteBook(r.Context(), id, cond, time.Now())
 	if appErr != nil {
-		h.sendErrorResponse(w, appErr)
+		h.sendErrorResponse(w, r, appErr)
 		return
 	}
 
@@ -157,6 +465,105 @@ func (h *HandlerBooks) DeleteBook(w http.ResponseWriter, strID string) {
 
 }
 
+// parseBookQuery reads filters, sort order and limit from query parameters
+func parseBookQuery(params url.Values) (models.BookQuery, *apperrors.AppError) {
+	query := models.BookQuery{
+		Author: params.Get("author"),
+		Genre:  params.Get("genre"),
+		Title:  params.Get("title"),
+	}
+
+	if sort := params.Get("sort"); sort != "" {
+		query.Desc = strings.HasPrefix(sort, "-")
+		query.SortBy = models.SortField(strings.TrimPrefix(sort, "-"))
+		if !query.SortBy.Valid() {
+			return query, apperrors.NewAppError(400, "invalid sort field", fmt.Errorf("cannot sort by %q", sort))
+		}
+	}
+
+	var err error
+	if query.PublishedAfter, err = parseDate(params.Get("published_after")); err != nil {
+		return query, apperrors.NewAppError(400, "invalid published_after", err)
+	}
+	if query.PublishedBefore, err = parseDate(params.Get("published_before")); err != nil {
+		return query, apperrors.NewAppError(400, "invalid published_before", err)
+	}
+
+	if strLimit := params.Get("limit"); strLimit != "" {
+		if query.Limit, err = strconv.Atoi(strLimit); err != nil {
+			return query, apperrors.NewAppError(400, "invalid limit", err)
+		}
+	}
+
+	return query, nil
+}
+
+// parseDate parses a date like 1990-01-01 or a full RFC 3339 time.
+// An empty string is zero time
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// sendKeyPage sends one page of authors, works or series got by limit and cursor
+// parameters, a Link header points to the next page with the same parameters
+func sendKeyPage[P any](h *HandlerBooks, w http.ResponseWriter, r *http.Request, route string,
+	get func(ctx context.Context, limit int, cursor string) (P, *apperrors.AppError), nextCursor func(P) string) {
+	params := r.URL.Query()
+
+	limit := 0
+	if strLimit := params.Get("limit"); strLimit != "" {
+		var err error
+		if limit, err = strconv.Atoi(strLimit); err != nil {
+			h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid limit", err))
+			return
+		}
+	}
+
+	page, appErr := get(r.Context(), limit, params.Get("cursor"))
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	if next := nextCursor(page); next != "" {
+		params.Set("cursor", next)
+		w.Header().Add("Link", fmt.Sprintf(`<%s/%s?%s>; rel="next"`, h.basePath(r), route, params.Encode()))
+	}
+	h.sendJsonResponse(w, http.StatusOK, h.version.view(page))
+}
+
+// bookLocation returns a path of a book resource in the version of a request
+func (h *HandlerBooks) bookLocation(r *http.Request, id uint64) string {
+	return h.basePath(r) + "/" + booksRoute + "/" + strconv.FormatUint(id, 10)
+}
+
+// negotiate chooses a representation by Accept header, it is 406 if there is no one
+func (h *HandlerBooks) negotiate(r *http.Request, encoders *EncoderRegistry) (Encoder, *apperrors.AppError) {
+	encoder, ok := encoders.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		return nil, apperrors.NewAppError(http.StatusNotAcceptable, "representation is not available",
+			fmt.Errorf("available types: %s", strings.Join(encoders.MediaTypes(), ", ")))
+	}
+	return encoder, nil
+}
+
+// sendEncodedResponse send to client a response in a negotiated representation
+func (h *HandlerBooks) sendEncodedResponse(w http.ResponseWriter, encoder Encoder, statusCode int, data any) {
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.Header().Set("Vary", "Accept")
+	w.WriteHeader(statusCode)
+
+	if err := encoder.Encode(w, data); err != nil {
+		h.logger.Error("error encode response", "error", err, "type", encoder.MediaType())
+	}
+}
+
 // SendJsonResponse send to client a json response.
 // If data is nil it send bad status code
 func (h *HandlerBooks) sendJsonResponse(w http.ResponseWriter, statusCode int, data any) {
@@ -190,26 +597,8 @@ func (h *HandlerBooks) sendJsonResponse(w http.ResponseWriter, statusCode int, d
 
 }
 
-// sendErrorResponse send to cliend an error, if error is nil,
-// it write log and returna
-func (h *HandlerBooks) sendErrorResponse(w http.ResponseWriter, appErr *apperrors.AppError) {
-	if appErr == nil {
-		h.logger.Warn("Error send a app error, error is nil", "appErr", appErr)
-		return
-	}
-	h.logger.Info("API error", "code", appErr.Code, "message", appErr.Message, "error", appErr.Err)
-
-	w.Header().Set("Content-type", "application/json")
-	w.WriteHeader(appErr.Code)
-
-	err := json.NewEncoder(w).Encode(map[string]any{
-		"code":    appErr.Code,
-		"message": appErr.Message,
-	})
-
-	if err != nil {
-		h.logger.Error("Error send an erro response", "error", err)
-		return
-	}
-
+// sendErrorResponse send to cliend an error as problem details (RFC 7807),
+// if error is nil, it write log and returna
+func (h *HandlerBooks) sendErrorResponse(w http.ResponseWriter, r *http.Request, appErr *apperrors.AppError) {
+	writeProblem(w, r, h.versionError(appErr), h.logger)
 }
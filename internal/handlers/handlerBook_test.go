@@ -0,0 +1,615 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/services"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/memory"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+const testBookJson = `{"book": {"id": 1, "title": "Clean Code", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z"}}`
+
+func newTestHandler() *HandlerBooks {
+	service := services.NewBookService(testLogger, memory.NewMemoryStorage(testLogger))
+	return NewHandlerBooks(service, testLogger)
+}
+
+// serve sends a request to a handler and returns a response
+func serve(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandlerBooks_CreateBook(t *testing.T) {
+	h := newTestHandler()
+
+	w := serve(h, http.MethodPost, "/books", testBookJson)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got: %d %s", w.Code, w.Body.String())
+	}
+	if location := w.Header().Get("Location"); location != "/books/1" {
+		t.Errorf("Expected Location /books/1, got: %s", location)
+	}
+
+	var book models.Book
+	if err := json.NewDecoder(w.Body).Decode(&book); err != nil {
+		t.Fatalf("Expected a book in response, got error: %v", err)
+	}
+	if book.General.ID != 1 || book.General.Title != "Clean Code" || book.CreatedAt.IsZero() {
+		t.Errorf("Unexpected created book: %+v", book)
+	}
+
+	// the created book is really there
+	w = serve(h, http.MethodGet, "/books/1", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for created book, got: %d", w.Code)
+	}
+}
+
+func TestHandlerBooks_CreateInvalidBook(t *testing.T) {
+	h := newTestHandler()
+
+	w := serve(h, http.MethodPost, "/books", `{"book": {"title": ""}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got: %d", w.Code)
+	}
+	if location := w.Header().Get("Location"); location != "" {
+		t.Errorf("Expected no Location header, got: %s", location)
+	}
+}
+
+func TestHandlerBooks_GetAllBooksPages(t *testing.T) {
+	h := newTestHandler()
+	for i := 0; i < 3; i++ {
+		serve(h, http.MethodPost, "/books", testBookJson)
+	}
+
+	w := serve(h, http.MethodGet, "/books?limit=2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d %s", w.Code, w.Body.String())
+	}
+
+	var page models.BookPage
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("Expected a page in response, got error: %v", err)
+	}
+	if len(page.Books) != 2 || page.NextCursor == "" {
+		t.Fatalf("Expected 2 books and a next cursor, got: %+v", page)
+	}
+
+	link := w.Header().Get("Link")
+	if !strings.Contains(link, "cursor="+page.NextCursor) || !strings.Contains(link, `rel="next"`) {
+		t.Errorf("Expected Link to the next page, got: %s", link)
+	}
+}
+
+func TestHandlerBooks_GetAllBooksInvalidLimit(t *testing.T) {
+	h := newTestHandler()
+
+	w := serve(h, http.MethodGet, "/books?limit=many", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got: %d", w.Code)
+	}
+}
+
+func TestHandlerBooks_GetAllBooksFilters(t *testing.T) {
+	h := newTestHandler()
+	serve(h, http.MethodPost, "/books", testBookJson)
+	serve(h, http.MethodPost, "/books", strings.Replace(testBookJson, "Programming", "Fantasy", 1))
+
+	w := serve(h, http.MethodGet, "/books?genre=fantasy&published_after=2000-01-01&sort=-publicationDate", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d %s", w.Code, w.Body.String())
+	}
+
+	var page models.BookPage
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("Expected a page in response, got error: %v", err)
+	}
+	if len(page.Books) != 1 || page.Books[0].General.Genre != "Fantasy" {
+		t.Errorf("Expected only the Fantasy book, got: %+v", page.Books)
+	}
+}
+
+func TestHandlerBooks_GetAllBooksInvalidParams(t *testing.T) {
+	h := newTestHandler()
+
+	for _, target := range []string{
+		"/books?sort=price",
+		"/books?published_after=yesterday",
+		"/books?cursor=broken",
+	} {
+		w := serve(h, http.MethodGet, target, "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got: %d", target, w.Code)
+		}
+	}
+}
+
+func TestHandlerBooks_SearchBooks(t *testing.T) {
+	h := newTestHandler()
+	serve(h, http.MethodPost, "/books", testBookJson)
+
+	w := serve(h, http.MethodGet, "/books/search?q=clean", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Results []models.SearchResult `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Expected results in response, got error: %v", err)
+	}
+	if len(response.Results) != 1 || response.Results[0].Highlight.Title != "<mark>Clean</mark> Code" {
+		t.Errorf("Unexpected search results: %+v", response.Results)
+	}
+
+	w = serve(h, http.MethodGet, "/books/search", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty query, got: %d", w.Code)
+	}
+}
+
+// servePatch sends a PATCH request with a content type
+func servePatch(h http.Handler, target, contentType, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPatch, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandlerBooks_PatchBook(t *testing.T) {
+	h := newTestHandler()
+	serve(h, http.MethodPost, "/books", testBookJson)
+
+	w := servePatch(h, "/books/1", "application/merge-patch+json", `{"general": {"genre": "Software"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d %s", w.Code, w.Body.String())
+	}
+	var book models.Book
+	if err := json.NewDecoder(w.Body).Decode(&book); err != nil {
+		t.Fatalf("Expected a book in response, got error: %v", err)
+	}
+	if book.General.Genre != "Software" || book.General.Title != "Clean Code" {
+		t.Errorf("Expected only genre to be changed, got: %+v", book.General)
+	}
+
+	w = servePatch(h, "/books/1", "application/json-patch+json",
+		`[{"op": "test", "path": "/general/genre", "value": "Software"}, {"op": "replace", "path": "/general/title", "value": "Clean Coder"}]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d %s", w.Code, w.Body.String())
+	}
+
+	w = serve(h, http.MethodGet, "/books/1", "")
+	book = models.Book{}
+	json.NewDecoder(w.Body).Decode(&book)
+	if book.General.Title != "Clean Coder" || book.General.Genre != "Software" {
+		t.Errorf("Expected patched book to be saved, got: %+v", book.General)
+	}
+}
+
+func TestHandlerBooks_PatchBookErrors(t *testing.T) {
+	h := newTestHandler()
+	serve(h, http.MethodPost, "/books", testBookJson)
+
+	tests := []struct {
+		name, target, contentType, body string
+		code                            int
+	}{
+		{"wrong content type", "/books/1", "application/json", `{}`, http.StatusUnsupportedMediaType},
+		{"missing book", "/books/42", "application/merge-patch+json", `{}`, http.StatusNotFound},
+		{"failed test", "/books/1", "application/json-patch+json", `[{"op": "test", "path": "/general/genre", "value": "Poetry"}]`, http.StatusConflict},
+		{"read-only id", "/books/1", "application/merge-patch+json", `{"general": {"id": 2}}`, http.StatusUnprocessableEntity},
+		{"unknown field", "/books/1", "application/merge-patch+json", `{"price": 10}`, http.StatusUnprocessableEntity},
+		{"invalid result", "/books/1", "application/merge-patch+json", `{"general": {"title": ""}}`, http.StatusBadRequest},
+		{"broken patch", "/books/1", "application/json-patch+json", `{"op": "add"}`, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		w := servePatch(h, test.target, test.contentType, test.body)
+		if w.Code != test.code {
+			t.Errorf("%s: expected %d, got: %d %s", test.name, test.code, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandlerBooks_ReplaceBook(t *testing.T) {
+	h := newTestHandler()
+	body := `{"book": {"title": "Clean Code", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z"}}`
+
+	w := serve(h, http.MethodPut, "/books/5", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got: %d %s", w.Code, w.Body.String())
+	}
+	if location := w.Header().Get("Location"); location != "/books/5" {
+		t.Errorf("Expected Location /books/5, got: %s", location)
+	}
+
+	w = serve(h, http.MethodPut, "/books/5", body)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 replacing a book, got: %d %s", w.Code, w.Body.String())
+	}
+
+	w = serve(h, http.MethodPut, "/books/5", testBookJson)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for id mismatch, got: %d", w.Code)
+	}
+
+	w = serve(h, http.MethodPut, "/books/abc", body)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid id, got: %d", w.Code)
+	}
+}
+
+func TestHandlerBooks_ETag(t *testing.T) {
+	h := newTestHandler()
+	serve(h, http.MethodPost, "/books", testBookJson)
+
+	w := serve(h, http.MethodGet, "/books/1", "")
+	etag := w.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf(`Expected ETag "1", got: %s`, etag)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/books/1", nil)
+	r.Header.Set("If-None-Match", "W/"+etag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Expected 304 without body, got: %d %s", w.Code, w.Body.String())
+	}
+
+	// the first editor changes the book
+	r = httptest.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(`{"general": {"genre": "Classic"}}`))
+	r.Header.Set("Content-Type", "application/merge-patch+json")
+	r.Header.Set("If-Match", etag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf(`Expected 200 with ETag "2", got: %d %s`, w.Code, w.Header().Get("ETag"))
+	}
+
+	// the second one still has the old ETag
+	r = httptest.NewRequest(http.MethodDelete, "/books/1", nil)
+	r.Header.Set("If-Match", etag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for old ETag, got: %d", w.Code)
+	}
+}
+
+func TestHandlerBooks_RequireIfMatch(t *testing.T) {
+	h := newTestHandler()
+	h.RequireIfMatch = true
+	serve(h, http.MethodPost, "/books", testBookJson)
+
+	w := serve(h, http.MethodDelete, "/books/1", "")
+	if w.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected 428 without If-Match, got: %d", w.Code)
+	}
+
+	// a new book might be created with If-None-Match: *
+	body := `{"book": {"title": "Clean Code", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z"}}`
+	r := httptest.NewRequest(http.MethodPut, "/books/5", strings.NewReader(body))
+	r.Header.Set("If-None-Match", "*")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected 201 with If-None-Match: *, got: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandlerBooks_ProblemDetails(t *testing.T) {
+	h := newTestHandler()
+
+	w := serve(h, http.MethodPost, "/books", `{"book": {"id": 1, "title": "", "genre": "Programming", "author": "Robert C. Martin"}}`)
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json" {
+		t.Errorf("Expected problem+json, got: %s", contentType)
+	}
+
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("Expected problem in response, got error: %v", err)
+	}
+	if p.Status != http.StatusBadRequest || p.Code != "validation_failed" || p.Type != "/problems/validation_failed" || p.Instance != "/books" {
+		t.Errorf("Unexpected problem: %+v", p)
+	}
+	if len(p.Errors) != 1 || p.Errors[0].Field != "book.title" || p.Errors[0].Rule != "required" {
+		t.Errorf("Expected required error of book.title, got: %+v", p.Errors)
+	}
+
+	w = serve(h, http.MethodGet, "/books/42", "")
+	p = Problem{}
+	json.NewDecoder(w.Body).Decode(&p)
+	if p.Status != http.StatusNotFound || p.Code != "book_not_found" || p.Title != "Not Found" {
+		t.Errorf("Unexpected problem for missing book: %+v", p)
+	}
+
+	w = serve(h, http.MethodGet, "/books?limit=abc", "")
+	p = Problem{}
+	json.NewDecoder(w.Body).Decode(&p)
+	if p.Code != "bad_request" {
+		t.Errorf("Expected code from status, got: %+v", p)
+	}
+}
+
+func TestHandlerBooks_GetBookByISBN(t *testing.T) {
+	h := newTestHandler()
+
+	w := serve(h, http.MethodPost, "/books", `{"book": {"id": 1, "title": "Clean Code", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z", "isbn": "0-13-235088-2"}}`)
+	var book models.Book
+	json.NewDecoder(w.Body).Decode(&book)
+	if w.Code != http.StatusCreated || book.General.ISBN != "9780132350884" {
+		t.Fatalf("Expected a book with ISBN-13, got: %d %+v", w.Code, book)
+	}
+
+	for _, isbn := range []string{"9780132350884", "978-0-13-235088-4", "0132350882"} {
+		w = serve(h, http.MethodGet, "/books/isbn/"+isbn, "")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Location") != "/books/1" {
+			t.Errorf("Expected the book by %s, got: %d %s", isbn, w.Code, w.Body.String())
+		}
+	}
+
+	tests := []struct {
+		isbn   string
+		status int
+		code   string
+	}{
+		{"9780132350885", http.StatusBadRequest, "invalid_isbn"},
+		{"9780137081073", http.StatusNotFound, "book_not_found"},
+	}
+	for _, tt := range tests {
+		w = serve(h, http.MethodGet, "/books/isbn/"+tt.isbn, "")
+		var p Problem
+		json.NewDecoder(w.Body).Decode(&p)
+		if w.Code != tt.status || p.Code != tt.code {
+			t.Errorf("Expected %d %s for %s, got: %d %+v", tt.status, tt.code, tt.isbn, w.Code, p)
+		}
+	}
+
+	// the same ISBN in another spelling is a duplicate
+	w = serve(h, http.MethodPost, "/books", `{"book": {"id": 2, "title": "Clean Code", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z", "isbn": "9780132350884"}}`)
+	var p Problem
+	json.NewDecoder(w.Body).Decode(&p)
+	if w.Code != http.StatusConflict || p.Code != "isbn_exists" {
+		t.Errorf("Expected 409 isbn_exists, got: %d %+v", w.Code, p)
+	}
+
+	w = serve(h, http.MethodPost, "/books", `{"book": {"id": 2, "title": "Clean Code", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z", "isbn": "0132350883"}}`)
+	p = Problem{}
+	json.NewDecoder(w.Body).Decode(&p)
+	if w.Code != http.StatusBadRequest || len(p.Errors) != 1 || p.Errors[0].Field != "book.isbn" || p.Errors[0].Rule != "isbn" {
+		t.Errorf("Expected an isbn error of book.isbn, got: %d %+v", w.Code, p)
+	}
+}
+
+func TestHandlerBooks_Batch(t *testing.T) {
+	h := newTestHandler()
+	serve(h, http.MethodPost, "/books", testBookJson)
+
+	// an atomic batch has a status of the failed operation and changes nothing
+	body := `{"operations": [
+		{"op": "update", "id": 1, "book": {"title": "Clean Code 2", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z"}},
+		{"op": "delete", "id": 42}
+	]}`
+	w := serve(h, http.MethodPost, "/books/batch", body)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404, got: %d %s", w.Code, w.Body.String())
+	}
+	var resp batchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Expected batch results, got error: %v", err)
+	}
+	if resp.Committed || len(resp.Results) != 2 || resp.Results[0].Status != http.StatusFailedDependency ||
+		resp.Results[1].Error == nil || resp.Results[1].Error.Code != "book_not_found" {
+		t.Errorf("Unexpected batch results: %+v", resp)
+	}
+
+	// best effort runs the update anyway
+	body = strings.Replace(body, `{"operations"`, `{"mode": "best_effort", "operations"`, 1)
+	w = serve(h, http.MethodPost, "/books/batch", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d %s", w.Code, w.Body.String())
+	}
+	var committed struct {
+		Committed bool
+		Results   []struct {
+			Status int
+			Book   *models.Book
+		}
+	}
+	json.NewDecoder(w.Body).Decode(&committed)
+	if !committed.Committed || committed.Results[0].Book == nil || committed.Results[0].Book.General.Title != "Clean Code 2" ||
+		committed.Results[1].Status != http.StatusNotFound {
+		t.Errorf("Unexpected batch results: %+v", committed)
+	}
+
+	w = serve(h, http.MethodPost, "/books/batch", `{"operations": []}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty batch, got: %d", w.Code)
+	}
+}
+
+// serveImport uploads a file to POST /books/import
+func serveImport(t *testing.T, h http.Handler, target, fileName, file string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	part.Write([]byte(file))
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, target, &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandlerBooks_ImportBooks(t *testing.T) {
+	h := newTestHandler()
+	h.ImportErrorsDir = t.TempDir()
+	file := "title,author,genre,publicationDate\n" +
+		"Clean Code,Robert C. Martin,Programming,2008-08-01\n" +
+		",Martin Fowler,Programming,1999-07-08\n"
+
+	w := serveImport(t, h, "/books/import?dryRun=true", "books.csv", file)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d %s", w.Code, w.Body.String())
+	}
+	var report models.ImportReport
+	json.NewDecoder(w.Body).Decode(&report)
+	if !report.DryRun || report.Accepted != 1 || report.Rejected != 1 || report.Imported != 0 || report.ErrorFile == "" {
+		t.Errorf("Unexpected dry run report: %+v", report)
+	}
+
+	w = serveImport(t, h, "/books/import", "books.csv", file)
+	report = models.ImportReport{}
+	json.NewDecoder(w.Body).Decode(&report)
+	if w.Code != http.StatusOK || report.Imported != 1 {
+		t.Fatalf("Expected 1 imported book, got: %d %+v", w.Code, report)
+	}
+
+	// rejected rows can be downloaded
+	w = serve(h, http.MethodGet, report.ErrorFile, "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") ||
+		!strings.Contains(w.Body.String(), "2,,Martin Fowler,Programming,1999-07-08,,title: cannot be empty") {
+		t.Errorf("Unexpected error file: %d %s", w.Code, w.Body.String())
+	}
+	if w = serve(h, http.MethodGet, "/books/import/errors/unknown", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown error file, got: %d", w.Code)
+	}
+
+	if w = serveImport(t, h, "/books/import", "books.txt", file); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for unknown file type, got: %d", w.Code)
+	}
+	if w = serve(h, http.MethodPost, "/books/import", file); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for not multipart body, got: %d", w.Code)
+	}
+}
+
+// failingLinkStorage is a memory storage that cannot link authors
+type failingLinkStorage struct {
+	*memory.MemoryStorage
+}
+
+func (failingLinkStorage) LinkAuthors(ctx context.Context, bookIDs []uint64, createdAt time.Time) error {
+	return errors.New("storage is down")
+}
+
+func TestHandlerBooks_ImportFailure(t *testing.T) {
+	service := services.NewBookService(testLogger, failingLinkStorage{memory.NewMemoryStorage(testLogger)})
+	h := NewHandlerBooks(service, testLogger)
+	h.ImportErrorsDir = t.TempDir()
+	file := "title,author,genre,publicationDate\n" +
+		"Clean Code,Robert C. Martin,Programming,2008-08-01\n" +
+		",Martin Fowler,Programming,1999-07-08\n"
+
+	// the book is written before linking fails, the problem has the report of it
+	w := serveImport(t, h, "/books/import", "books.csv", file)
+	var p Problem
+	json.NewDecoder(w.Body).Decode(&p)
+	if w.Code != http.StatusInternalServerError || p.Report == nil || p.Report.Imported != 1 || p.Report.Complete || p.Report.ErrorFile == "" {
+		t.Fatalf("Expected 500 with a report, got: %d %+v", w.Code, p)
+	}
+}
+
+func TestHandlerBooks_ExportBooks(t *testing.T) {
+	h := newTestHandler()
+	for range exportFlushEvery + 1 {
+		serve(h, http.MethodPost, "/books", testBookJson)
+	}
+	serve(h, http.MethodPost, "/books", strings.Replace(testBookJson, "Programming", "Fantasy", 1))
+
+	// NDJSON by default, one book per line
+	w := serve(h, http.MethodGet, "/books/export", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != MediaTypeNDJSON {
+		t.Fatalf("Expected 200 with NDJSON, got: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if lines := strings.Count(w.Body.String(), "\n"); lines != exportFlushEvery+2 {
+		t.Errorf("Expected %d lines, got: %d", exportFlushEvery+2, lines)
+	}
+	if !w.Flushed {
+		t.Error("Expected a flushed response")
+	}
+
+	// CSV with filters, compressed
+	r := httptest.NewRequest(http.MethodGet, "/books/export?genre=fantasy", nil)
+	r.Header.Set("Accept", "text/csv")
+	r.Header.Set("Accept-Encoding", "gzip, deflate")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip, got: %q", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Expected gzip body, got error: %v", err)
+	}
+	body, _ := io.ReadAll(zr)
+	want := "id,title,author,genre,publicationDate,createdAt,updateAt,version,isbn\n102,Clean Code,"
+	if !strings.HasPrefix(string(body), want) || strings.Count(string(body), "\n") != 2 {
+		t.Errorf("Expected the header and one book, got: %s", body)
+	}
+
+	// an empty CSV still has the header
+	r = httptest.NewRequest(http.MethodGet, "/books/export?genre=poetry", nil)
+	r.Header.Set("Accept", "text/csv")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Body.String() != strings.Join(csvHeader, ",")+"\n" {
+		t.Errorf("Expected only the header, got: %q", w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/books/export", nil)
+	r.Header.Set("Accept", "application/xml")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected 406 for XML, got: %d", w.Code)
+	}
+	if w = serve(h, http.MethodGet, "/books/export?sort=price", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid sort, got: %d", w.Code)
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := map[string]bool{
+		"":              false,
+		"gzip":          true,
+		"deflate, gzip": true,
+		"GZIP;q=0.5":    true,
+		"gzip;q=0":      false,
+		"*":             true,
+		"gzip;q=0, *":   false,
+		"br, *;q=0":     false,
+		"identity, br":  false,
+	}
+	for header, want := range tests {
+		if got := acceptsEncoding(header, "gzip"); got != want {
+			t.Errorf("%q: expected %v, got: %v", header, want, got)
+		}
+	}
+}
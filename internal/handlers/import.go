@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/bookimport"
+	"github.com/Talos-hub/BooksRestApi/internal/services"
+	"github.com/Talos-hub/BooksRestApi/internal/validations"
+)
+
+const (
+	importRoute       = "import"
+	importErrorsRoute = "errors"
+	importFileField   = "file" // a form field of a multipart body with the file
+)
+
+const (
+	// maxImportSize is the biggest body of an import request
+	maxImportSize = 100 << 20
+	// importErrorsTTL is how long error files of imports can be downloaded
+	importErrorsTTL = time.Hour
+)
+
+// ImportBooks imports books from a CSV or JSON file of a multipart body (POST /books/import).
+// The file is read as a stream and every row is validated, valid books are written in chunks.
+// With dryRun=true nothing is written. Rejected rows are in an error file,
+// a link to it is in errorFile of the report
+func (h *HandlerBooks) ImportBooks(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if s := r.URL.Query().Get("dryRun"); s != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(s); err != nil {
+			h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid dryRun", err))
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(http.StatusUnsupportedMediaType, "body must be multipart/form-data", err))
+		return
+	}
+
+	// the file is read from the body directly, other fields are skipped
+	var file *multipartFile
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid multipart body", err))
+			return
+		}
+		if part.FormName() == importFileField {
+			file = &multipartFile{Reader: part, mediaType: importMediaType(part.Header.Get("Content-Type"), part.FileName())}
+			break
+		}
+	}
+	if file == nil {
+		details := []apperrors.FieldError{{Field: importFileField, Rule: validations.RuleRequired, Message: "file: a file is required"}}
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid import request",
+			apperrors.NewFieldsValidateErr("error validation", details, errors.New("file is missing"))))
+		return
+	}
+
+	errorFile, err := os.CreateTemp(h.ImportErrorsDir, "books-import-*.csv")
+	if err != nil {
+		h.logger.Error("error create an import error file", "error", err)
+		h.sendErrorResponse(w, r, apperrors.NewAppError(500, "error import books", err))
+		return
+	}
+	keep := false
+	defer func() {
+		errorFile.Close()
+		if !keep {
+			os.Remove(errorFile.Name())
+		}
+	}()
+
+	report, appErr := h.Service.ImportBooks(r.Context(), file.mediaType, file, services.ImportOptions{
+		DryRun:    dryRun,
+		CreatedAt: time.Now(),
+		Errors:    errorFile,
+	})
+	if appErr != nil && report.Imported == 0 {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	if report.Rejected > 0 {
+		keep = true
+		id := h.importErrors.add(errorFile.Name())
+		report.ErrorFile = h.basePath(r) + "/" + path.Join(booksRoute, importRoute, importErrorsRoute, id)
+	}
+
+	if appErr != nil {
+		// written chunks stay, the problem has their report, so a client doesn't import them again
+		h.logger.Info("API error", "code", appErr.Code, "message", appErr.Message, "error", appErr.Err, "imported", report.Imported)
+		p := newProblem(r, h.versionError(appErr))
+		p.Report = &report
+		encodeProblem(w, p, h.logger)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, report)
+}
+
+// GetImportErrors sends an error file of an import (GET /books/import/errors/{id})
+func (h *HandlerBooks) GetImportErrors(w http.ResponseWriter, r *http.Request, id string) {
+	name, ok := h.importErrors.get(id)
+	if !ok {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(404, "error file is not found",
+			errors.New("it is expired or never existed")).WithCode("import_errors_not_found"))
+		return
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		h.logger.Error("error open an import error file", "error", err)
+		h.sendErrorResponse(w, r, apperrors.NewAppError(404, "error file is not found", nil).WithCode("import_errors_not_found"))
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", MediaTypeCSV+"; charset=utf-8; header=present")
+	w.Header().Set("Content-Disposition", `attachment; filename="import-errors.csv"`)
+	if _, err := io.Copy(w, file); err != nil {
+		h.logger.Error("error send an import error file", "error", err)
+	}
+}
+
+// multipartFile is an uploaded file and its media type
+type multipartFile struct {
+	io.Reader
+	mediaType string
+}
+
+// importMediaType returns a media type of an uploaded file by its Content-Type,
+// or by its extension, because browsers often send a generic or a strange one for CSV
+func importMediaType(contentType, fileName string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == bookimport.CSVType || mediaType == bookimport.JSONType {
+		return mediaType
+	}
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".csv":
+		return bookimport.CSVType
+	case ".json":
+		return bookimport.JSONType
+	}
+	return mediaType
+}
+
+// importErrorFiles keeps error files of imports for importErrorsTTL.
+// Files are on local disk, so they can be downloaded only from the same instance
+type importErrorFiles struct {
+	mu    sync.Mutex
+	files map[string]importErrorFile
+}
+
+type importErrorFile struct {
+	name    string
+	created time.Time
+}
+
+// add keeps a file and returns its id
+func (f *importErrorFiles) add(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeExpired()
+
+	if f.files == nil {
+		f.files = make(map[string]importErrorFile)
+	}
+	id := rand.Text()
+	f.files[id] = importErrorFile{name: name, created: time.Now()}
+	return id
+}
+
+// get returns a name of a file by its id
+func (f *importErrorFiles) get(id string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeExpired()
+
+	file, ok := f.files[id]
+	return file.name, ok
+}
+
+// removeExpired removes files that are older than importErrorsTTL
+func (f *importErrorFiles) removeExpired() {
+	for id, file := range f.files {
+		if time.Since(file.created) > importErrorsTTL {
+			os.Remove(file.name)
+			delete(f.files, id)
+		}
+	}
+}
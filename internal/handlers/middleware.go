@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/openapi"
+)
+
+// ValidateRequests is a middleware that checks requests against the OpenAPI document
+// before next gets them. A request that doesn't match gets a problem with field errors,
+// so a handler that isn't described by the document cannot be reached
+func ValidateRequests(validator *openapi.Validator, logger abstraction.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if appErr := validator.Validate(r); appErr != nil {
+			writeProblem(w, r, appErr, logger)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/openapi"
+)
+
+func newTestValidator(t *testing.T, version string) (*openapi.Document, *openapi.Validator) {
+	t.Helper()
+	doc, err := openapi.Load(version)
+	if err != nil {
+		t.Fatalf("Unexpected error loading document: %v", err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatalf("Unexpected error creating validator: %v", err)
+	}
+	return doc, validator
+}
+
+// every documented route of books, authors, genres, works and series must be routed by HandlerBooks of its version
+func TestOpenAPI_DocumentedRoutesExist(t *testing.T) {
+	versions := []struct {
+		version string
+		prefix  string
+		handler *HandlerBooks
+	}{
+		{openapi.V1, "", newTestHandler()},
+		{openapi.V1, "/" + v1Route, newTestHandler()},
+		{openapi.V2, "/" + v2Route, newTestHandlerV2()},
+	}
+	for _, v := range versions {
+		doc, _ := newTestValidator(t, v.version)
+		for _, endpoint := range doc.Endpoints() {
+			resource, _, _ := strings.Cut(strings.TrimPrefix(endpoint.Path, "/"), "/")
+			if !slices.Contains([]string{booksRoute, authorsRoute, genresRoute, worksRoute, seriesRoute}, resource) {
+				continue
+			}
+			target := v.prefix + strings.ReplaceAll(endpoint.Path, "{id}", "1")
+			w := serve(v.handler, endpoint.Method, target, "")
+
+			var p Problem
+			json.NewDecoder(w.Body).Decode(&p)
+			if p.Code == "route_not_found" {
+				t.Errorf("%s %s is documented but not routed", endpoint.Method, target)
+			}
+		}
+	}
+}
+
+func TestValidateRequests(t *testing.T) {
+	_, validator := newTestValidator(t, openapi.V1)
+	h := ValidateRequests(validator, testLogger, newTestHandler())
+
+	w := serve(h, http.MethodPost, "/books", testBookJson)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 for a valid request, got: %d %s", w.Code, w.Body.String())
+	}
+
+	// every route of the handler is documented
+	for _, target := range []string{"/books", "/books/search?q=code", "/books/1"} {
+		if w := serve(h, http.MethodGet, target, ""); w.Code != http.StatusOK {
+			t.Errorf("Expected 200 for GET %s, got: %d %s", target, w.Code, w.Body.String())
+		}
+	}
+
+	w = serve(h, http.MethodGet, "/books?sort=price&limit=1000", "")
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("Expected problem in response, got error: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || p.Code != "invalid_request" || len(p.Errors) != 2 {
+		t.Errorf("Expected 400 with two field errors, got: %d %+v", w.Code, p)
+	}
+}
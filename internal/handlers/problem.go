@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// problemContentType is a media type of errors (RFC 7807)
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix is a prefix of problem types, a type is the prefix and an error code
+const problemTypePrefix = "/problems/"
+
+// codeValidationFailed is an error code of invalid book data
+const codeValidationFailed = "validation_failed"
+
+// Problem is an error response (RFC 7807 problem details)
+type Problem struct {
+	Type     string                 `json:"type"`             // URI reference that identifies a kind of a problem
+	Title    string                 `json:"title"`            // short summary of the kind, it is the same for one type
+	Status   int                    `json:"status"`           // HTTP status code
+	Detail   string                 `json:"detail,omitempty"` // what happened in this request
+	Instance string                 `json:"instance"`         // path of the request
+	Code     string                 `json:"code"`             // stable machine-readable error code
+	Errors   []apperrors.FieldError `json:"errors,omitempty"` // fields that didn't pass validation
+	Report   *models.ImportReport   `json:"report,omitempty"` // what a failed import wrote before the failure
+}
+
+// writeProblem writes an application error as problem details,
+// if error is nil, it write log and returns
+func writeProblem(w http.ResponseWriter, r *http.Request, appErr *apperrors.AppError, logger abstraction.Logger) {
+	if appErr == nil {
+		logger.Warn("Error send a app error, error is nil", "appErr", appErr)
+		return
+	}
+	logger.Info("API error", "code", appErr.Code, "message", appErr.Message, "error", appErr.Err)
+	encodeProblem(w, newProblem(r, appErr), logger)
+}
+
+// encodeProblem writes problem details with their status
+func encodeProblem(w http.ResponseWriter, p Problem, logger abstraction.Logger) {
+	w.Header().Set("Content-type", problemContentType)
+	w.WriteHeader(p.Status)
+
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logger.Error("Error send an erro response", "error", err)
+	}
+}
+
+// newProblem converts an application error to problem details
+func newProblem(r *http.Request, appErr *apperrors.AppError) Problem {
+	p := Problem{
+		Title:    http.StatusText(appErr.Code),
+		Status:   appErr.Code,
+		Detail:   appErr.Message,
+		Instance: r.URL.Path,
+		Code:     appErr.ErrCode,
+	}
+	if p.Title == "" {
+		p.Title = "Client Closed Request"
+	}
+
+	var validateErr *apperrors.ValidateErr
+	if errors.As(appErr.Err, &validateErr) && len(validateErr.Details) > 0 {
+		p.Errors = validateErr.Details
+		if p.Code == "" {
+			p.Code = codeValidationFailed
+		}
+	} else if appErr.Err != nil && appErr.Code < http.StatusInternalServerError {
+		// a client error explains what is wrong, a server one might show internals
+		p.Detail += ": " + appErr.Err.Error()
+	}
+
+	if p.Code == "" {
+		p.Code = statusCode(p.Title)
+	}
+	p.Type = problemTypePrefix + p.Code
+	return p
+}
+
+// statusCode makes an error code from a status text, Not Found is not_found
+func statusCode(title string) string {
+	title = strings.ReplaceAll(strings.ToLower(title), "-", " ")
+	title = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r == ' ' {
+			return r
+		}
+		return -1
+	}, title)
+	return strings.Join(strings.Fields(title), "_")
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+const seriesRoute = "series"
+
+// GetAllSeries send one page of series ordered by name,
+// it takes limit and cursor like GetAllBooks
+func (h *HandlerBooks) GetAllSeries(w http.ResponseWriter, r *http.Request) {
+	sendKeyPage(h, w, r, seriesRoute, h.Service.GetAllSeries, func(page models.SeriesPage) string { return page.NextCursor })
+}
+
+// GetSeries send a series by an ID with its volumes in order
+func (h *HandlerBooks) GetSeries(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid series id", err))
+		return
+	}
+
+	series, appErr := h.Service.GetSeries(r.Context(), id)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, series)
+}
+
+// CreateSeries create new series, id and times are given by the server
+func (h *HandlerBooks) CreateSeries(w http.ResponseWriter, r *http.Request) {
+	var series models.Series
+	if err := json.NewDecoder(r.Body).Decode(&series); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	series.CreatedAt = time.Now()
+
+	series, appErr := h.Service.CreateSeries(r.Context(), series)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	w.Header().Set("Location", h.seriesLocation(r, series.ID))
+	h.sendJsonResponse(w, http.StatusCreated, series)
+}
+
+// ReplaceSeries replaces a series by an ID from the path (PUT /series/{id}),
+// the replaced series is sent back without volumes
+func (h *HandlerBooks) ReplaceSeries(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid series id", err))
+		return
+	}
+
+	var series models.Series
+	if err := json.NewDecoder(r.Body).Decode(&series); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	series.UpdatedAt = time.Now()
+
+	series, appErr := h.Service.ReplaceSeries(r.Context(), id, series)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, series)
+}
+
+// DeleteSeries delete a series that has no volumes
+func (h *HandlerBooks) DeleteSeries(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid series id", err))
+		return
+	}
+
+	if appErr := h.Service.DeleteSeries(r.Context(), id); appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, map[string]string{"message": "Series deleted successfully"})
+}
+
+// SetBookSeries places a book in a series (PUT /books/{id}/series),
+// the body is {"seriesId": 1, "position": 2.5}. The book is sent back
+func (h *HandlerBooks) SetBookSeries(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid book id", err))
+		return
+	}
+
+	var req models.SetBookSeriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	req.UpdatedAt = time.Now()
+
+	book, appErr := h.Service.SetBookSeries(r.Context(), id, req)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	w.Header().Set("ETag", bookETag(book))
+	h.sendJsonResponse(w, http.StatusOK, h.version.view(book))
+}
+
+// RemoveBookSeries takes a book out of its series (DELETE /books/{id}/series),
+// the book is sent back
+func (h *HandlerBooks) RemoveBookSeries(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid book id", err))
+		return
+	}
+
+	book, appErr := h.Service.RemoveBookSeries(r.Context(), id, time.Now())
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	w.Header().Set("ETag", bookETag(book))
+	h.sendJsonResponse(w, http.StatusOK, h.version.view(book))
+}
+
+// seriesLocation returns a path of a series resource in the version of a request
+func (h *HandlerBooks) seriesLocation(r *http.Request, id uint64) string {
+	return h.basePath(r) + "/" + seriesRoute + "/" + strconv.FormatUint(id, 10)
+}
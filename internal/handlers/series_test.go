@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+func TestHandlerBooks_Series(t *testing.T) {
+	h := newTestHandler()
+
+	for i, title := range []string{"The Fellowship of the Ring", "The Two Towers", "The Return of the King"} {
+		body := fmt.Sprintf(`{"book": {"id": %d, "title": %q, "genre": "Fantasy", "author": "J.R.R. Tolkien", "publicationDate": "1954-07-29T00:00:00Z"}}`, i+1, title)
+		if w := serve(h, http.MethodPost, "/books", body); w.Code != http.StatusCreated {
+			t.Fatalf("Expected a created book, got: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	w := serve(h, http.MethodPost, "/series", `{"name": "The Lord of the Rings"}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/series/1" {
+		t.Fatalf("Expected a created series, got: %d %s", w.Code, w.Body.String())
+	}
+
+	// the third book is placed first, so the order is by position
+	for _, place := range []struct{ book, position string }{{"1", "1"}, {"3", "3"}, {"2", "2.5"}} {
+		w = serve(h, http.MethodPut, "/books/"+place.book+"/series", `{"seriesId": 1, "position": `+place.position+`}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for a placed book, got: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	w = serve(h, http.MethodGet, "/series/1", "")
+	var series models.Series
+	json.NewDecoder(w.Body).Decode(&series)
+	if w.Code != http.StatusOK || len(series.Volumes) != 3 || series.Volumes[1].ID != 2 || series.Volumes[1].Position != 2.5 {
+		t.Fatalf("Expected volumes in order, got: %d %+v", w.Code, series)
+	}
+
+	w = serve(h, http.MethodGet, "/books/2", "")
+	var book models.Book
+	json.NewDecoder(w.Body).Decode(&book)
+	if w.Code != http.StatusOK || book.Series == nil || book.Series.Previous == nil || book.Series.Previous.ID != 1 ||
+		book.Series.Next == nil || book.Series.Next.ID != 3 {
+		t.Fatalf("Expected a book with its neighbours, got: %d %+v", w.Code, book.Series)
+	}
+
+	// v2 shows the series of a flat book too
+	w = serve(NewHandlerBooksV2(h.Service, testLogger), http.MethodGet, "/v2/books/3", "")
+	var flat bookV2
+	json.NewDecoder(w.Body).Decode(&flat)
+	if w.Code != http.StatusOK || flat.Series == nil || flat.Series.Name != "The Lord of the Rings" || flat.Series.Next != nil {
+		t.Fatalf("Expected the last volume in v2, got: %d %+v", w.Code, flat.Series)
+	}
+
+	tests := []struct {
+		method, target, body string
+		status               int
+		code                 string
+	}{
+		{http.MethodGet, "/series/99", "", http.StatusNotFound, "series_not_found"},
+		{http.MethodPut, "/books/1/series", `{"seriesId": 99, "position": 1}`, http.StatusNotFound, "series_not_found"},
+		{http.MethodPut, "/books/1/series", `{"seriesId": 1, "position": 3}`, http.StatusConflict, "position_taken"},
+		{http.MethodPut, "/books/99/series", `{"seriesId": 1, "position": 4}`, http.StatusNotFound, "book_not_found"},
+		{http.MethodPut, "/books/1/series", `{"seriesId": 1}`, http.StatusBadRequest, "validation_failed"},
+		{http.MethodPut, "/series/1", `{"id": 2, "name": "The Lord of the Rings"}`, http.StatusBadRequest, "id_mismatch"},
+		{http.MethodDelete, "/series/1", "", http.StatusConflict, "series_has_volumes"},
+	}
+	for _, tt := range tests {
+		w = serve(h, tt.method, tt.target, tt.body)
+		var p Problem
+		json.NewDecoder(w.Body).Decode(&p)
+		if w.Code != tt.status || p.Code != tt.code {
+			t.Errorf("Expected %d %s for %s %s, got: %d %+v", tt.status, tt.code, tt.method, tt.target, w.Code, p)
+		}
+	}
+
+	for _, id := range []string{"1", "2", "3"} {
+		w = serve(h, http.MethodDelete, "/books/"+id+"/series", "")
+		book = models.Book{}
+		json.NewDecoder(w.Body).Decode(&book)
+		if w.Code != http.StatusOK || book.Series != nil {
+			t.Fatalf("Expected a book without series, got: %d %+v", w.Code, book.Series)
+		}
+	}
+	w = serve(h, http.MethodDelete, "/series/1", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a series without volumes, got: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandlerBooks_SeriesVersions(t *testing.T) {
+	h := newTestHandler()
+
+	for i, title := range []string{"Dune", "Dune Messiah", "Children of Dune"} {
+		body := fmt.Sprintf(`{"book": {"id": %d, "title": %q, "genre": "Science Fiction", "author": "Frank Herbert", "publicationDate": "1965-08-01T00:00:00Z"}}`, i+1, title)
+		serve(h, http.MethodPost, "/books", body)
+	}
+	serve(h, http.MethodPost, "/series", `{"name": "Dune"}`)
+	for i := range 3 {
+		serve(h, http.MethodPut, fmt.Sprintf("/books/%d/series", i+1), fmt.Sprintf(`{"seriesId": 1, "position": %d}`, i+1))
+	}
+
+	// version returns the version of a book and checks its ETag
+	version := func(id string) uint64 {
+		t.Helper()
+		w := serve(h, http.MethodGet, "/books/"+id, "")
+		var book models.Book
+		json.NewDecoder(w.Body).Decode(&book)
+		if w.Header().Get("ETag") != fmt.Sprintf(`"%d"`, book.Version) {
+			t.Fatalf("Expected ETag of version %d, got: %s", book.Version, w.Header().Get("ETag"))
+		}
+		return book.Version
+	}
+
+	// neighbours of a removed book show each other now
+	first, third := version("1"), version("3")
+	w := serve(h, http.MethodDelete, "/books/2/series", "")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == "" {
+		t.Fatalf("Expected a book with ETag, got: %d %s", w.Code, w.Body.String())
+	}
+	if version("1") <= first || version("3") <= third {
+		t.Errorf("Expected new versions of neighbours of a removed book")
+	}
+
+	// a neighbour shows the title of a book
+	third = version("3")
+	serve(h, http.MethodPut, "/books/1", `{"book": {"id": 1, "title": "Dune (Deluxe)", "genre": "Science Fiction", "author": "Frank Herbert", "publicationDate": "1965-08-01T00:00:00Z"}}`)
+	if version("3") <= third {
+		t.Errorf("Expected a new version of a neighbour of a renamed book")
+	}
+
+	// and it has no neighbour when the book is deleted
+	first = version("1")
+	serve(h, http.MethodDelete, "/books/3", "")
+	if version("1") <= first {
+		t.Errorf("Expected a new version of a neighbour of a deleted book")
+	}
+
+	// volumes show the name of their series
+	first = version("1")
+	serve(h, http.MethodPut, "/series/1", `{"name": "Dune Chronicles"}`)
+	if version("1") <= first {
+		t.Errorf("Expected a new version of a volume of a renamed series")
+	}
+}
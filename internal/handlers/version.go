@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/patch"
+	"github.com/Talos-hub/BooksRestApi/internal/validations"
+)
+
+// prefixes of route trees of versions
+const (
+	v1Route = "v1"
+	v2Route = "v2"
+)
+
+// apiVersion maps books between json of one version of the API and models.
+// All versions share BookService and handlers, so a version is only a mapping
+type apiVersion interface {
+	route() string // a prefix of routes of the version, like v1
+	// view converts models.Book, models.BookPage, []models.SearchResult
+	// or a page of authors, works or series to json of the version
+	view(v any) any
+	decodeCreate(r io.Reader) (models.CreateBookRequest, *apperrors.AppError)
+	decodeReplace(r io.Reader) (models.UpdateBookRequest, *apperrors.AppError)
+	decodeBatch(r io.Reader) (models.BatchRequest, *apperrors.AppError)
+	// patch converts a patch of json of the version to a patch of json of models.Book
+	patch(patchType string, data []byte) ([]byte, *apperrors.AppError)
+	// fieldPath converts a path of a field error to a path of json of the version
+	fieldPath(path string) string
+}
+
+// Deprecation describes a deprecated version, its headers are sent with every response of it
+type Deprecation struct {
+	Since     time.Time // Deprecation header (RFC 9745)
+	Sunset    time.Time // Sunset header (RFC 8594), when the version is removed, it might be zero
+	Successor string    // a link to the next version, it is sent with rel="successor-version"
+}
+
+// setHeaders writes headers of a deprecation, nothing is written for nil
+func (d *Deprecation) setHeaders(w http.ResponseWriter) {
+	if d == nil {
+		return
+	}
+	header := w.Header()
+	header.Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+	if !d.Sunset.IsZero() {
+		header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		header.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, d.Successor))
+	}
+}
+
+// basePath returns a prefix of links of a response, a request to /v1/books
+// gets links to /v1/..., a request to unversioned /books gets /...
+func (h *HandlerBooks) basePath(r *http.Request) string {
+	prefix := "/" + h.version.route()
+	if r.URL.Path == prefix || strings.HasPrefix(r.URL.Path, prefix+"/") {
+		return prefix
+	}
+	return ""
+}
+
+// versionError converts paths of field errors to paths of the version
+func (h *HandlerBooks) versionError(appErr *apperrors.AppError) *apperrors.AppError {
+	var validateErr *apperrors.ValidateErr
+	if appErr == nil || !errors.As(appErr.Err, &validateErr) || len(validateErr.Details) == 0 {
+		return appErr
+	}
+
+	details := make([]apperrors.FieldError, len(validateErr.Details))
+	for i, d := range validateErr.Details {
+		d.Field = h.version.fieldPath(d.Field)
+		details[i] = d
+	}
+	mapped := *appErr
+	mapped.Err = apperrors.NewFieldsValidateErr(validateErr.Message, details, validateErr.Err)
+	return &mapped
+}
+
+// apiV1 is the first version, json is models as they are. It has mistakes
+// (the general object, updateAt) that cannot be fixed without breaking clients
+type apiV1 struct{}
+
+func (apiV1) route() string { return v1Route }
+
+func (apiV1) view(v any) any { return v }
+
+func (apiV1) decodeCreate(r io.Reader) (models.CreateBookRequest, *apperrors.AppError) {
+	var req models.CreateBookRequest
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return req, apperrors.NewAppError(400, "invalid JSON", err)
+	}
+	// v1 always required an id of a new book, though a storage gives another one
+	if err := validations.Validate(req); err != nil {
+		return req, apperrors.NewAppError(400, "invalid book data", err)
+	}
+	return req, nil
+}
+
+func (apiV1) decodeReplace(r io.Reader) (models.UpdateBookRequest, *apperrors.AppError) {
+	var req models.UpdateBookRequest
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return req, apperrors.NewAppError(400, "invalid JSON", err)
+	}
+	return req, nil
+}
+
+func (apiV1) decodeBatch(r io.Reader) (models.BatchRequest, *apperrors.AppError) {
+	var req models.BatchRequest
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return req, apperrors.NewAppError(400, "invalid JSON", err)
+	}
+	return req, nil
+}
+
+func (apiV1) patch(_ string, data []byte) ([]byte, *apperrors.AppError) { return data, nil }
+
+func (apiV1) fieldPath(path string) string { return path }
+
+// apiV2 is a flat book with consistent names
+type apiV2 struct{}
+
+// bookV2 is a book of v2
+type bookV2 struct {
+	ID              uint64              `json:"id"`
+	Title           string              `json:"title"`
+	Author          string              `json:"author"`
+	Genre           string              `json:"genre"`
+	PublicationDate time.Time           `json:"publicationDate"`
+	ISBN            string              `json:"isbn,omitempty"`
+	WorkID          uint64              `json:"workId,omitempty"`
+	Publisher       string              `json:"publisher,omitempty"`
+	Edition         string              `json:"edition,omitempty"`
+	Format          string              `json:"format,omitempty"`
+	PageCount       int                 `json:"pageCount,omitempty"`
+	Language        string              `json:"language,omitempty"`
+	Authors         []models.BookAuthor `json:"authors,omitempty"`
+	Genres          []models.BookGenre  `json:"genres,omitempty"`
+	Series          *models.BookSeries  `json:"series,omitempty"`
+	CreatedAt       time.Time           `json:"createdAt"`
+	UpdatedAt       time.Time           `json:"updatedAt"`
+	Version         uint64              `json:"version"`
+}
+
+type bookPageV2 struct {
+	Books      []bookV2 `json:"books"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// pages of authors, works and series of v2, they have the same items as v1
+type authorPageV2 struct {
+	Authors    []models.Author `json:"authors"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+type workPageV2 struct {
+	Works      []models.Work `json:"works"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+type seriesPageV2 struct {
+	Series     []models.Series `json:"series"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+type searchResultV2 struct {
+	Book      bookV2               `json:"book"`
+	Score     float64              `json:"score"`
+	Highlight models.BookHighlight `json:"highlight"`
+}
+
+// bookInputV2 is a body of POST and PUT, an id is optional,
+// fields that are managed by the server are not allowed
+type bookInputV2 struct {
+	ID              uint64    `json:"id,omitempty"`
+	Title           string    `json:"title"`
+	Author          string    `json:"author"`
+	Genre           string    `json:"genre"`
+	PublicationDate time.Time `json:"publicationDate"`
+	ISBN            string    `json:"isbn,omitempty"`
+	WorkID          uint64    `json:"workId,omitempty"`
+	Publisher       string    `json:"publisher,omitempty"`
+	Edition         string    `json:"edition,omitempty"`
+	Format          string    `json:"format,omitempty"`
+	PageCount       int       `json:"pageCount,omitempty"`
+	Language        string    `json:"language,omitempty"`
+}
+
+// v2Fields are names of v2 and where they are in json of models.Book
+var v2Fields = map[string]string{
+	"id":              "/general/id",
+	"title":           "/general/title",
+	"author":          "/general/author",
+	"genre":           "/general/genre",
+	"publicationDate": "/general/publicationDate",
+	"isbn":            "/general/isbn",
+	"workId":          "/general/workId",
+	"publisher":       "/general/publisher",
+	"edition":         "/general/edition",
+	"format":          "/general/format",
+	"pageCount":       "/general/pageCount",
+	"language":        "/general/language",
+	"createdAt":       "/createdAt",
+	"updatedAt":       "/updateAt",
+	"version":         "/version",
+}
+
+func toBookV2(book models.Book) bookV2 {
+	return bookV2{
+		ID:              book.General.ID,
+		Title:           book.General.Title,
+		Author:          book.General.Author,
+		Genre:           book.General.Genre,
+		PublicationDate: book.General.PublicationDate,
+		ISBN:            book.General.ISBN,
+		WorkID:          book.General.WorkID,
+		Publisher:       book.General.Publisher,
+		Edition:         book.General.Edition,
+		Format:          book.General.Format,
+		PageCount:       book.General.PageCount,
+		Language:        book.General.Language,
+		Authors:         book.Authors,
+		Genres:          book.Genres,
+		Series:          book.Series,
+		CreatedAt:       book.CreatedAt,
+		UpdatedAt:       book.UpdatedAt,
+		Version:         book.Version,
+	}
+}
+
+func (apiV2) route() string { return v2Route }
+
+func (apiV2) view(v any) any {
+	switch v := v.(type) {
+	case models.Book:
+		return toBookV2(v)
+	case models.BookPage:
+		page := bookPageV2{Books: make([]bookV2, 0, len(v.Books)), NextCursor: v.NextCursor}
+		for _, book := range v.Books {
+			page.Books = append(page.Books, toBookV2(book))
+		}
+		return page
+	case models.AuthorPage:
+		return authorPageV2{Authors: v.Authors, NextCursor: v.NextCursor}
+	case models.WorkPage:
+		return workPageV2{Works: v.Works, NextCursor: v.NextCursor}
+	case models.SeriesPage:
+		return seriesPageV2{Series: v.Series, NextCursor: v.NextCursor}
+	case []models.SearchResult:
+		results := make([]searchResultV2, 0, len(v))
+		for _, result := range v {
+			results = append(results, searchResultV2{Book: toBookV2(result.Book), Score: result.Score, Highlight: result.Highlight})
+		}
+		return results
+	}
+	return v
+}
+
+// decodeBook reads a book, unknown fields are errors, so a body of v1 is not taken silently
+func (apiV2) decodeBook(r io.Reader) (models.GeneralBook, *apperrors.AppError) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var input bookInputV2
+	if err := decoder.Decode(&input); err != nil {
+		return models.GeneralBook{}, apperrors.NewAppError(400, "invalid JSON", err)
+	}
+	return models.GeneralBook{
+		ID:              input.ID,
+		Title:           input.Title,
+		Author:          input.Author,
+		Genre:           input.Genre,
+		PublicationDate: input.PublicationDate,
+		ISBN:            input.ISBN,
+		WorkID:          input.WorkID,
+		Publisher:       input.Publisher,
+		Edition:         input.Edition,
+		Format:          input.Format,
+		PageCount:       input.PageCount,
+		Language:        input.Language,
+	}, nil
+}
+
+func (v apiV2) decodeCreate(r io.Reader) (models.CreateBookRequest, *apperrors.AppError) {
+	book, appErr := v.decodeBook(r)
+	return models.CreateBookRequest{Book: book}, appErr
+}
+
+func (v apiV2) decodeReplace(r io.Reader) (models.UpdateBookRequest, *apperrors.AppError) {
+	book, appErr := v.decodeBook(r)
+	return models.UpdateBookRequest{Book: book}, appErr
+}
+
+// decodeBatch reads a batch, a book of an update gets an id of its operation
+func (apiV2) decodeBatch(r io.Reader) (models.BatchRequest, *apperrors.AppError) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var req models.BatchRequest
+	if err := decoder.Decode(&req); err != nil {
+		return req, apperrors.NewAppError(400, "invalid JSON", err)
+	}
+	for i, op := range req.Operations {
+		if op.Op == models.BatchUpdate && op.Book.ID == 0 {
+			req.Operations[i].Book.ID = op.ID
+		}
+	}
+	return req, nil
+}
+
+// patch moves fields of a merge patch to their places and changes paths of JSON Patch.
+// Names of v1 are unknown fields here, like the service rejects unknown fields of v1
+func (apiV2) patch(patchType string, data []byte) ([]byte, *apperrors.AppError) {
+	invalid := func(err error) *apperrors.AppError {
+		return apperrors.NewAppError(400, "invalid patch", err).WithCode("invalid_patch")
+	}
+
+	switch patchType {
+	case patch.MergePatchType:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, invalid(err)
+		}
+		result := map[string]any{}
+		general := map[string]json.RawMessage{}
+		for name, value := range fields {
+			pointer, ok := v2Fields[name]
+			switch {
+			case !ok:
+				return nil, apperrors.NewAppError(http.StatusUnprocessableEntity, "patched book is invalid",
+					fmt.Errorf("unknown field %q", name)).WithCode("invalid_patched_book")
+			case strings.HasPrefix(pointer, "/general/"):
+				general[strings.TrimPrefix(pointer, "/general/")] = value
+			default:
+				result[strings.TrimPrefix(pointer, "/")] = value
+			}
+		}
+		if len(general) > 0 {
+			result["general"] = general
+		}
+		converted, err := json.Marshal(result)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return converted, nil
+
+	case patch.JSONPatchType:
+		var ops []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &ops); err != nil {
+			return nil, invalid(err)
+		}
+		for _, op := range ops {
+			for _, key := range []string{"path", "from"} {
+				raw, ok := op[key]
+				if !ok {
+					continue
+				}
+				var pointer string
+				if err := json.Unmarshal(raw, &pointer); err != nil {
+					return nil, invalid(fmt.Errorf("%s must be a string", key))
+				}
+				pointer, appErr := v2Pointer(pointer)
+				if appErr != nil {
+					return nil, appErr
+				}
+				op[key], _ = json.Marshal(pointer)
+			}
+		}
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(ops); err != nil {
+			return nil, invalid(err)
+		}
+		return buf.Bytes(), nil
+	}
+	return data, nil
+}
+
+// v2Pointer converts a JSON Pointer of v2 to a pointer of json of models.Book.
+// The whole book cannot be a target, it is a book of v1
+func v2Pointer(pointer string) (string, *apperrors.AppError) {
+	name, rest, _ := strings.Cut(strings.TrimPrefix(pointer, "/"), "/")
+	mapped, ok := v2Fields[name]
+	if !ok {
+		return "", apperrors.NewAppError(http.StatusUnprocessableEntity, "patch cannot be applied",
+			fmt.Errorf("path %q is not a field of a book", pointer)).WithCode("patch_path_not_found")
+	}
+	if rest != "" {
+		mapped += "/" + rest
+	}
+	return mapped, nil
+}
+
+// fieldPath removes the book and general objects and fixes updateAt
+func (apiV2) fieldPath(path string) string {
+	path = strings.TrimPrefix(path, "book.")
+	path = strings.TrimPrefix(path, "general.")
+	if path == "updateAt" {
+		return "updatedAt"
+	}
+	return path
+}
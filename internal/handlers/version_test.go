@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/services"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/memory"
+)
+
+const testBookJsonV2 = `{"title": "Clean Code", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z"}`
+
+func newTestHandlerV2() *HandlerBooks {
+	service := services.NewBookService(testLogger, memory.NewMemoryStorage(testLogger))
+	return NewHandlerBooksV2(service, testLogger)
+}
+
+func TestHandlerBooksV2_CreateAndGet(t *testing.T) {
+	h := newTestHandlerV2()
+
+	w := serve(h, http.MethodPost, "/v2/books", testBookJsonV2)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got: %d %s", w.Code, w.Body.String())
+	}
+	if location := w.Header().Get("Location"); location != "/v2/books/1" {
+		t.Errorf("Expected Location /v2/books/1, got: %s", location)
+	}
+
+	var book bookV2
+	if err := json.NewDecoder(w.Body).Decode(&book); err != nil {
+		t.Fatalf("Expected a book in response, got error: %v", err)
+	}
+	if book.ID != 1 || book.Title != "Clean Code" || book.UpdatedAt.IsZero() || book.Version != 1 {
+		t.Errorf("Unexpected created book: %+v", book)
+	}
+
+	w = serve(h, http.MethodGet, "/v2/books/1", "")
+	var fields map[string]any
+	json.NewDecoder(w.Body).Decode(&fields)
+	if _, ok := fields["updatedAt"]; !ok || fields["general"] != nil || fields["title"] != "Clean Code" {
+		t.Errorf("Expected a flat book with updatedAt, got: %v", fields)
+	}
+
+	// a body of v1 is rejected
+	w = serve(h, http.MethodPost, "/v2/books", testBookJson)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a body of v1, got: %d", w.Code)
+	}
+}
+
+func TestHandlerBooksV2_FieldErrors(t *testing.T) {
+	h := newTestHandlerV2()
+
+	w := serve(h, http.MethodPost, "/v2/books", `{"title": "", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z"}`)
+	var p Problem
+	json.NewDecoder(w.Body).Decode(&p)
+	if w.Code != http.StatusBadRequest || len(p.Errors) != 1 || p.Errors[0].Field != "title" {
+		t.Errorf("Expected an error of title, got: %d %+v", w.Code, p)
+	}
+
+	// v1 has paths of its json
+	w = serve(newTestHandler(), http.MethodPost, "/v1/books", `{"book": {"id": 1, "title": "", "genre": "Programming", "author": "Robert C. Martin"}}`)
+	p = Problem{}
+	json.NewDecoder(w.Body).Decode(&p)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "book.title" {
+		t.Errorf("Expected an error of book.title, got: %+v", p)
+	}
+}
+
+func TestHandlerBooksV2_Patch(t *testing.T) {
+	h := newTestHandlerV2()
+	serve(h, http.MethodPost, "/v2/books", testBookJsonV2)
+
+	patches := []struct {
+		contentType string
+		body        string
+		status      int
+		title       string
+	}{
+		{"application/merge-patch+json", `{"title": "Clean Code 2"}`, http.StatusOK, "Clean Code 2"},
+		{"application/json-patch+json", `[{"op": "replace", "path": "/title", "value": "Clean Code 3"}]`, http.StatusOK, "Clean Code 3"},
+		{"application/json-patch+json", `[{"op": "test", "path": "/title", "value": "Clean Code 3"}, {"op": "copy", "from": "/author", "path": "/genre"}]`, http.StatusOK, "Clean Code 3"},
+		{"application/merge-patch+json", `{"updatedAt": "2020-01-01T00:00:00Z"}`, http.StatusUnprocessableEntity, ""},
+		{"application/merge-patch+json", `{"general": {"title": "x"}}`, http.StatusUnprocessableEntity, ""},
+		{"application/json-patch+json", `[{"op": "replace", "path": "/general/title", "value": "x"}]`, http.StatusUnprocessableEntity, ""},
+		{"application/json-patch+json", `[{"op": "replace", "path": "", "value": {}}]`, http.StatusUnprocessableEntity, ""},
+	}
+	for _, tt := range patches {
+		w := servePatch(h, "/v2/books/1", tt.contentType, tt.body)
+		if w.Code != tt.status {
+			t.Errorf("Expected %d for %s, got: %d %s", tt.status, tt.body, w.Code, w.Body.String())
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var book bookV2
+		json.NewDecoder(w.Body).Decode(&book)
+		if book.Title != tt.title {
+			t.Errorf("Expected title %q after %s, got: %+v", tt.title, tt.body, book)
+		}
+	}
+}
+
+func TestHandlerBooksV2_Routes(t *testing.T) {
+	h := newTestHandlerV2()
+	for range 3 {
+		serve(h, http.MethodPost, "/v2/books", testBookJsonV2)
+	}
+
+	w := serve(h, http.MethodGet, "/v2/books?limit=2", "")
+	var page bookPageV2
+	json.NewDecoder(w.Body).Decode(&page)
+	if len(page.Books) != 2 || page.NextCursor == "" {
+		t.Fatalf("Expected a page with nextCursor, got: %+v", page)
+	}
+	if link := w.Header().Get("Link"); !strings.HasPrefix(link, "</v2/books?") {
+		t.Errorf("Expected a link to /v2/books, got: %s", link)
+	}
+
+	// the legacy update is only in v1
+	w = serve(h, http.MethodPut, "/v2/books", testBookJsonV2)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for PUT /v2/books, got: %d", w.Code)
+	}
+
+	// a batch update takes an id of its operation
+	body := `{"operations": [{"op": "update", "id": 1, "book": {"title": "Clean Code 2", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z"}}]}`
+	w = serve(h, http.MethodPost, "/v2/books/batch", body)
+	var batch struct {
+		Results []struct{ Book bookV2 }
+	}
+	json.NewDecoder(w.Body).Decode(&batch)
+	if w.Code != http.StatusOK || len(batch.Results) != 1 || batch.Results[0].Book.Title != "Clean Code 2" {
+		t.Errorf("Expected an updated book of v2, got: %d %+v", w.Code, batch)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/v2/books/export", nil)
+	r.Header.Set("Accept", MediaTypeCSV)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if header, _, _ := strings.Cut(w.Body.String(), "\n"); !strings.Contains(header, "updatedAt") {
+		t.Errorf("Expected updatedAt in CSV header, got: %s", header)
+	}
+}
+
+func TestHandlerBooks_Deprecation(t *testing.T) {
+	h := newTestHandler()
+	h.Deprecation = &Deprecation{
+		Since:     time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2027, time.April, 17, 0, 0, 0, 0, time.UTC),
+		Successor: "/v2/books",
+	}
+
+	w := serve(h, http.MethodPost, "/v1/books", testBookJson)
+	if location := w.Header().Get("Location"); location != "/v1/books/1" {
+		t.Errorf("Expected Location /v1/books/1, got: %s", location)
+	}
+
+	w = serve(h, http.MethodGet, "/books", "")
+	if got := w.Header().Get("Deprecation"); got != "@1792195200" {
+		t.Errorf("Expected Deprecation @1792195200, got: %s", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Sat, 17 Apr 2027 00:00:00 GMT" {
+		t.Errorf("Expected Sunset of 17 Apr 2027, got: %s", got)
+	}
+	if got := w.Header().Get("Link"); got != `</v2/books>; rel="successor-version"` {
+		t.Errorf("Expected a link to the successor, got: %s", got)
+	}
+
+	if w := serve(newTestHandlerV2(), http.MethodGet, "/v2/books", ""); w.Header().Get("Deprecation") != "" {
+		t.Error("Expected no Deprecation header in v2")
+	}
+}
+
+func TestHandlerBooks_PageCursorNames(t *testing.T) {
+	tests := []struct {
+		h      *HandlerBooks
+		prefix string
+		name   string
+	}{
+		{newTestHandler(), "/v1", "next_cursor"},
+		{newTestHandlerV2(), "/v2", "nextCursor"},
+	}
+	for _, tt := range tests {
+		for _, name := range []string{"Discworld", "Dune"} {
+			serve(tt.h, http.MethodPost, tt.prefix+"/series", `{"name": "`+name+`"}`)
+		}
+
+		// pages of authors, works and series name a cursor like pages of books
+		w := serve(tt.h, http.MethodGet, tt.prefix+"/series?limit=1", "")
+		var page map[string]any
+		json.NewDecoder(w.Body).Decode(&page)
+		if cursor, _ := page[tt.name].(string); cursor == "" || len(page) != 2 {
+			t.Errorf("Expected a page of %s with %s, got: %v", tt.prefix, tt.name, page)
+		}
+	}
+}
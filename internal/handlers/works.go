@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+const (
+	worksRoute    = "works"
+	editionsRoute = "editions"
+)
+
+// GetWorks send one page of works ordered by title,
+// it takes limit and cursor like GetAllBooks
+func (h *HandlerBooks) GetWorks(w http.ResponseWriter, r *http.Request) {
+	sendKeyPage(h, w, r, worksRoute, h.Service.GetWorks, func(page models.WorkPage) string { return page.NextCursor })
+}
+
+// GetWork send a work by an ID
+func (h *HandlerBooks) GetWork(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid work id", err))
+		return
+	}
+
+	work, appErr := h.Service.GetWork(r.Context(), id)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, work)
+}
+
+// CreateWork create new work, id and times are given by the server
+func (h *HandlerBooks) CreateWork(w http.ResponseWriter, r *http.Request) {
+	var work models.Work
+	if err := json.NewDecoder(r.Body).Decode(&work); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	work.CreatedAt = time.Now()
+
+	work, appErr := h.Service.CreateWork(r.Context(), work)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	w.Header().Set("Location", h.workLocation(r, work.ID))
+	h.sendJsonResponse(w, http.StatusCreated, work)
+}
+
+// ReplaceWork replaces a work by an ID from the path (PUT /works/{id}),
+// the replaced work is sent back
+func (h *HandlerBooks) ReplaceWork(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid work id", err))
+		return
+	}
+
+	var work models.Work
+	if err := json.NewDecoder(r.Body).Decode(&work); err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid JSON", err))
+		return
+	}
+	work.UpdatedAt = time.Now()
+
+	work, appErr := h.Service.ReplaceWork(r.Context(), id, work)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, work)
+}
+
+// DeleteWork delete a work that has no editions
+func (h *HandlerBooks) DeleteWork(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid work id", err))
+		return
+	}
+
+	if appErr := h.Service.DeleteWork(r.Context(), id); appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	h.sendJsonResponse(w, http.StatusOK, map[string]string{"message": "Work deleted successfully"})
+}
+
+// GetWorkEditions send one page of editions of a work,
+// it takes the same query parameters as GetAllBooks
+func (h *HandlerBooks) GetWorkEditions(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid work id", err))
+		return
+	}
+	encoder, appErr := h.negotiate(r, h.Encoders)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+	params := r.URL.Query()
+
+	query, appErr := parseBookQuery(params)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	page, appErr := h.Service.GetWorkEditions(r.Context(), id, query, params.Get("cursor"))
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	if page.NextCursor != "" {
+		params.Set("cursor", page.NextCursor)
+		w.Header().Add("Link", fmt.Sprintf(`<%s/%s?%s>; rel="next"`, h.workLocation(r, id), editionsRoute, params.Encode()))
+	}
+	h.sendEncodedResponse(w, encoder, http.StatusOK, page)
+}
+
+// CreateEdition create new book that is an edition of a work (POST /works/{id}/editions).
+// The body is a book of the version, an empty title or author is taken from the work.
+// The edition is a book, so Location points to /books/{id}
+func (h *HandlerBooks) CreateEdition(w http.ResponseWriter, r *http.Request, strID string) {
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		h.sendErrorResponse(w, r, apperrors.NewAppError(400, "invalid work id", err))
+		return
+	}
+
+	// a body of PUT is a book without the legacy checks of POST /books
+	req, appErr := h.version.decodeReplace(r.Body)
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	book, appErr := h.Service.CreateEdition(r.Context(), id, models.CreateBookRequest{Book: req.Book, CreatedAt: time.Now()})
+	if appErr != nil {
+		h.sendErrorResponse(w, r, appErr)
+		return
+	}
+
+	w.Header().Set("Location", h.bookLocation(r, book.General.ID))
+	h.sendJsonResponse(w, http.StatusCreated, h.version.view(book))
+}
+
+// workLocation returns a path of a work resource in the version of a request
+func (h *HandlerBooks) workLocation(r *http.Request, id uint64) string {
+	return h.basePath(r) + "/" + worksRoute + "/" + strconv.FormatUint(id, 10)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+func TestHandlerBooks_Works(t *testing.T) {
+	h := newTestHandler()
+
+	// a book becomes an edition of a work with its title and author
+	w := serve(h, http.MethodPost, "/books", `{"book": {"id": 1, "title": "The Hobbit", "genre": "Fantasy", "author": "J.R.R. Tolkien", "publicationDate": "1937-09-21T00:00:00Z", "format": "hardcover", "pageCount": 310}}`)
+	var book models.Book
+	json.NewDecoder(w.Body).Decode(&book)
+	if w.Code != http.StatusCreated || book.General.WorkID != 1 || book.General.Format != "hardcover" || book.General.PageCount != 310 {
+		t.Fatalf("Expected an edition of a new work, got: %d %+v", w.Code, book)
+	}
+
+	w = serve(h, http.MethodGet, "/works/1", "")
+	var work models.Work
+	json.NewDecoder(w.Body).Decode(&work)
+	if w.Code != http.StatusOK || work.Title != "The Hobbit" || work.Author != "J.R.R. Tolkien" ||
+		len(work.Authors) != 1 || work.Authors[0].Name != "J.R.R. Tolkien" {
+		t.Fatalf("Expected the work of the book, got: %d %+v", w.Code, work)
+	}
+
+	// another edition takes the title and author of the work
+	w = serve(h, http.MethodPost, "/works/1/editions", `{"book": {"genre": "Fantasy", "publicationDate": "2012-09-18T00:00:00Z", "format": "paperback", "language": "en"}}`)
+	book = models.Book{}
+	json.NewDecoder(w.Body).Decode(&book)
+	if w.Code != http.StatusCreated || book.General.Title != "The Hobbit" || book.General.WorkID != 1 || w.Header().Get("Location") != "/books/2" {
+		t.Fatalf("Expected a created edition, got: %d %+v %s", w.Code, book, w.Header().Get("Location"))
+	}
+
+	// a book without workId keeps its work
+	w = serve(h, http.MethodPut, "/books/2", `{"book": {"title": "Der Hobbit", "genre": "Fantasy", "author": "J.R.R. Tolkien", "publicationDate": "2012-09-18T00:00:00Z", "language": "de"}}`)
+	book = models.Book{}
+	json.NewDecoder(w.Body).Decode(&book)
+	if w.Code != http.StatusOK || book.General.WorkID != 1 || book.General.Language != "de" {
+		t.Fatalf("Expected the edition to keep its work, got: %d %+v", w.Code, book)
+	}
+
+	w = serve(h, http.MethodGet, "/works/1/editions", "")
+	var books models.BookPage
+	json.NewDecoder(w.Body).Decode(&books)
+	if w.Code != http.StatusOK || len(books.Books) != 2 {
+		t.Fatalf("Expected two editions, got: %d %+v", w.Code, books)
+	}
+
+	w = serve(h, http.MethodPost, "/works", `{"title": "The Silmarillion", "author": "J.R.R. Tolkien"}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/works/2" {
+		t.Fatalf("Expected a created work, got: %d %s", w.Code, w.Body.String())
+	}
+	w = serve(h, http.MethodGet, "/works?limit=1", "")
+	var page models.WorkPage
+	json.NewDecoder(w.Body).Decode(&page)
+	if w.Code != http.StatusOK || len(page.Works) != 1 || page.Works[0].Title != "The Hobbit" || page.NextCursor == "" {
+		t.Fatalf("Expected the first page of works, got: %d %+v", w.Code, page)
+	}
+
+	tests := []struct {
+		method, target, body string
+		status               int
+		code                 string
+	}{
+		{http.MethodGet, "/works/99", "", http.StatusNotFound, "work_not_found"},
+		{http.MethodGet, "/works/99/editions", "", http.StatusNotFound, "work_not_found"},
+		{http.MethodPost, "/works/99/editions", `{"book": {"genre": "Fantasy", "publicationDate": "2012-09-18T00:00:00Z"}}`, http.StatusNotFound, "work_not_found"},
+		{http.MethodPost, "/works/1/editions", `{"book": {"genre": "Fantasy", "publicationDate": "2012-09-18T00:00:00Z", "workId": 2}}`, http.StatusBadRequest, "id_mismatch"},
+		{http.MethodPut, "/books/1", `{"book": {"title": "The Hobbit", "genre": "Fantasy", "author": "J.R.R. Tolkien", "publicationDate": "1937-09-21T00:00:00Z", "workId": 99}}`, http.StatusBadRequest, "invalid_work"},
+		{http.MethodDelete, "/works/1", "", http.StatusConflict, "work_has_editions"},
+	}
+	for _, tt := range tests {
+		w = serve(h, tt.method, tt.target, tt.body)
+		var p Problem
+		json.NewDecoder(w.Body).Decode(&p)
+		if w.Code != tt.status || p.Code != tt.code {
+			t.Errorf("Expected %d %s for %s %s, got: %d %+v", tt.status, tt.code, tt.method, tt.target, w.Code, p)
+		}
+	}
+
+	w = serve(h, http.MethodPost, "/books", `{"book": {"id": 1, "title": "The Hobbit", "genre": "Fantasy", "author": "J.R.R. Tolkien", "publicationDate": "1937-09-21T00:00:00Z", "format": "scroll"}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown format, got: %d %s", w.Code, w.Body.String())
+	}
+
+	w = serve(h, http.MethodDelete, "/works/2", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a work without editions, got: %d %s", w.Code, w.Body.String())
+	}
+}
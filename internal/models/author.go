@@ -0,0 +1,86 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Author is a person who wrote books, a book might have several authors
+type Author struct {
+	ID        uint64    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`                      // for example J.R.R. Tolkien
+	SortName  string    `json:"sortName" db:"sort_name"`             // for example Tolkien, J.R.R.
+	BirthYear *int      `json:"birthYear,omitempty" db:"birth_year"` // nil if it is unknown
+	DeathYear *int      `json:"deathYear,omitempty" db:"death_year"` // nil if it is unknown or the author is alive
+	Bio       string    `json:"bio,omitempty" db:"bio"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// BookAuthor is an author in a response of a book
+type BookAuthor struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+// AuthorPage is one page of authors ordered by sort name.
+// NextCursor is empty when there are no more authors
+type AuthorPage struct {
+	Authors    []Author `json:"authors"`
+	NextCursor string   `json:"next_cursor,omitempty"` // v2 calls it nextCursor
+}
+
+// AuthorQuery is a page of authors, they are ordered by sort name and id
+type AuthorQuery struct {
+	// After is the last author of a previous page,
+	// only its ID and SortName are used. Nil is the first page
+	After *Author
+	Limit int // max amount of authors, zero means no limit
+}
+
+// SetBookAuthorsRequest is a body of PUT /books/{id}/authors
+type SetBookAuthorsRequest struct {
+	AuthorIDs []uint64  `json:"authorIds"` // in order of credits
+	UpdatedAt time.Time `json:"-"`         // the book is updated at this time, so its ETag changes
+}
+
+// authorSeparator splits a free-text author of a book,
+// PostgreSQL has the same expressions in migrations and LinkAuthors
+var authorSeparator = regexp.MustCompile(`\s*(?:[;&]|\band\b)\s*`)
+
+// commaSeparator splits names that authorSeparator left together
+var commaSeparator = regexp.MustCompile(`\s*,\s*`)
+
+// invertedName is "Last, First": one word before the only comma
+var invertedName = regexp.MustCompile(`^([^[:space:],]+)\s*,\s*([^,]+)$`)
+
+// sortNameRegex moves the last word in front, PostgreSQL has the same expression
+var sortNameRegex = regexp.MustCompile(`^(.+)\s+(\S+)$`)
+
+// SplitAuthors splits an author string of a book into names in order,
+// "Kent Beck, Martin Fowler" and "Kent Beck and Martin Fowler" are two names.
+// A comma after one word is "Last, First", so "Tolkien, J.R.R." is one name
+// "J.R.R. Tolkien". A surname of two words ("Le Guin, Ursula K.") is split, it cannot be told apart
+func SplitAuthors(author string) []string {
+	var names []string
+	for _, part := range authorSeparator.Split(author, -1) {
+		part = strings.TrimSpace(part)
+		if invertedName.MatchString(part) {
+			names = append(names, invertedName.ReplaceAllString(part, "$2 $1"))
+			continue
+		}
+		for _, name := range commaSeparator.Split(part, -1) {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// SortName returns a default sort name, the last word goes first:
+// "Robert C. Martin" is "Martin, Robert C."
+func SortName(name string) string {
+	return sortNameRegex.ReplaceAllString(strings.TrimSpace(name), "$2, $1")
+}
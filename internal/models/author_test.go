@@ -0,0 +1,29 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitAuthors(t *testing.T) {
+	tests := []struct {
+		author string
+		names  []string
+	}{
+		{"Robert C. Martin", []string{"Robert C. Martin"}},
+		{"Kent Beck, Martin Fowler", []string{"Kent Beck", "Martin Fowler"}},
+		{"Kent Beck and Martin Fowler", []string{"Kent Beck", "Martin Fowler"}},
+		{"Gamma; Helm & Johnson", []string{"Gamma", "Helm", "Johnson"}},
+		// "Last, First" is one name
+		{"Tolkien, J.R.R.", []string{"J.R.R. Tolkien"}},
+		{"Tolkien, J.R.R. and Tolkien, Christopher", []string{"J.R.R. Tolkien", "Christopher Tolkien"}},
+		{"Beck, Fowler, Gamma", []string{"Beck", "Fowler", "Gamma"}},
+		{" Tolkien , ", []string{"Tolkien"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if names := SplitAuthors(tt.author); !slices.Equal(names, tt.names) {
+			t.Errorf("Expected %q for %q, got: %q", tt.names, tt.author, names)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+// BatchOp is a kind of operation of a batch
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchMode says what happens when an operation of a batch fails
+type BatchMode string
+
+const (
+	BatchAtomic     BatchMode = "atomic"      // all operations are one transaction, one failure rolls back everything
+	BatchBestEffort BatchMode = "best_effort" // every operation is its own transaction, failures don't stop others
+)
+
+// BatchRequest is a list of operations that are done in one request
+type BatchRequest struct {
+	Mode       BatchMode        `json:"mode"` // atomic if it is empty
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation is one create, update or delete of a batch
+type BatchOperation struct {
+	Op      BatchOp     `json:"op"`
+	ID      uint64      `json:"id,omitempty"`      // a book to update or delete
+	Book    GeneralBook `json:"book"`              // a new book or new data of a book, it isn't used by delete
+	Version uint64      `json:"version,omitempty"` // if it is set, a book must have this version like with If-Match
+}
@@ -10,6 +10,15 @@ type GeneralBook struct {
 	Genre           string    `json:"genre" db:"genre"`                      // for example Adnveture, Roman
 	PublicationDate time.Time `json:"publicationDate" db:"publication_date"` // for instance 1970
 	Author          string    `json:"author" db:"author"`
+	ISBN            string    `json:"isbn,omitempty" db:"isbn"` // ISBN-13 without hyphens, it is optional and unique
+
+	// a book is an edition of a work, the fields below describe the edition and are optional
+	WorkID    uint64 `json:"workId,omitempty" db:"work_id"` // zero if a storage doesn't keep works
+	Publisher string `json:"publisher,omitempty" db:"publisher"`
+	Edition   string `json:"edition,omitempty" db:"edition"` // edition statement, for example 2nd edition
+	Format    string `json:"format,omitempty" db:"format"`   // one of Formats
+	PageCount int    `json:"pageCount,omitempty" db:"page_count"`
+	Language  string `json:"language,omitempty" db:"language"` // ISO 639-1 code like en
 }
 
 // Book is model that implemented behavior a real book.
@@ -17,6 +26,14 @@ type Book struct {
 	General   GeneralBook `json:"general"`                   // it is simple book
 	CreatedAt time.Time   `json:"createdAt" db:"created_at"` // time when is was created
 	UpdatedAt time.Time   `json:"updateAt" db:"updated_at"`  // time when is was updated
+	Version   uint64      `json:"version" db:"version"`      // it is increased by every change, starts from 1
+	// Authors are linked authors in order of credits, storages don't keep them in a book.
+	// It is empty if a storage doesn't support authors
+	Authors []BookAuthor `json:"authors,omitempty"`
+	// Genres are tagged genres ordered by name, storages don't keep them in a book too
+	Genres []BookGenre `json:"genres,omitempty"`
+	// Series is a series of the book with its neighbours, nil if the book is not in a series
+	Series *BookSeries `json:"series,omitempty"`
 }
 
 type UpdateBookRequest struct {
@@ -28,3 +45,112 @@ type CreateBookRequest struct {
 	Book      GeneralBook `json:"book"`
 	CreatedAt time.Time   `json:"-"` // time when is was created
 }
+
+// BookPage is one page of books.
+// NextCursor is empty when there are no more books
+type BookPage struct {
+	Books      []Book `json:"books"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SearchResult is a book found by full-text search
+type SearchResult struct {
+	Book      Book          `json:"book"`
+	Score     float64       `json:"score"`     // relevance, more is better
+	Highlight BookHighlight `json:"highlight"` // fields with matched words in <mark></mark>
+}
+
+// BookHighlight contains searchable fields of a book
+// where matched words are wrapped in <mark></mark>,
+// the text is escaped as HTML, so it might be inserted into a page as is
+type BookHighlight struct {
+	Title  string `json:"title"`
+	Author string `json:"author"`
+	Genre  string `json:"genre"`
+}
+
+// BookField is a field of a book that might be changed by a client,
+// names are the same as json names of GeneralBook
+type BookField string
+
+const (
+	FieldTitle           BookField = "title"
+	FieldAuthor          BookField = "author"
+	FieldGenre           BookField = "genre"
+	FieldPublicationDate BookField = "publicationDate"
+	FieldISBN            BookField = "isbn"
+	FieldWorkID          BookField = "workId"
+	FieldPublisher       BookField = "publisher"
+	FieldEdition         BookField = "edition"
+	FieldFormat          BookField = "format"
+	FieldPageCount       BookField = "pageCount"
+	FieldLanguage        BookField = "language"
+)
+
+// ChangedFields returns fields that are different in two books
+func ChangedFields(old, new GeneralBook) []BookField {
+	var fields []BookField
+	if old.Title != new.Title {
+		fields = append(fields, FieldTitle)
+	}
+	if old.Author != new.Author {
+		fields = append(fields, FieldAuthor)
+	}
+	if old.Genre != new.Genre {
+		fields = append(fields, FieldGenre)
+	}
+	if !old.PublicationDate.Equal(new.PublicationDate) {
+		fields = append(fields, FieldPublicationDate)
+	}
+	if old.ISBN != new.ISBN {
+		fields = append(fields, FieldISBN)
+	}
+	if old.WorkID != new.WorkID {
+		fields = append(fields, FieldWorkID)
+	}
+	if old.Publisher != new.Publisher {
+		fields = append(fields, FieldPublisher)
+	}
+	if old.Edition != new.Edition {
+		fields = append(fields, FieldEdition)
+	}
+	if old.Format != new.Format {
+		fields = append(fields, FieldFormat)
+	}
+	if old.PageCount != new.PageCount {
+		fields = append(fields, FieldPageCount)
+	}
+	if old.Language != new.Language {
+		fields = append(fields, FieldLanguage)
+	}
+	return fields
+}
+
+// Value returns a value of the field from a book
+func (f BookField) Value(book Book) any {
+	switch f {
+	case FieldTitle:
+		return book.General.Title
+	case FieldAuthor:
+		return book.General.Author
+	case FieldGenre:
+		return book.General.Genre
+	case FieldPublicationDate:
+		return book.General.PublicationDate
+	case FieldISBN:
+		return book.General.ISBN
+	case FieldWorkID:
+		return book.General.WorkID
+	case FieldPublisher:
+		return book.General.Publisher
+	case FieldEdition:
+		return book.General.Edition
+	case FieldFormat:
+		return book.General.Format
+	case FieldPageCount:
+		return book.General.PageCount
+	case FieldLanguage:
+		return book.General.Language
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Genre is a node of the genre tree, for example Fantasy is a child of Fiction.
+// A book might have several genres
+type Genre struct {
+	ID        uint64    `json:"id" db:"id"`
+	ParentID  *uint64   `json:"parentId,omitempty" db:"parent_id"` // nil for a root genre
+	Slug      string    `json:"slug" db:"slug"`                    // unique, for example science-fiction
+	Name      string    `json:"name" db:"name"`                    // a display name, for example Science Fiction
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// GenreNode is a genre with its subgenres in a response of the tree
+type GenreNode struct {
+	Genre
+	Children []GenreNode `json:"children,omitempty"`
+}
+
+// GenreTree is a body of GET /genres, roots are ordered by name
+type GenreTree struct {
+	Genres []GenreNode `json:"genres"`
+}
+
+// BookGenre is a genre in a response of a book
+type BookGenre struct {
+	ID   uint64 `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// SetBookGenresRequest is a body of PUT /books/{id}/genres
+type SetBookGenresRequest struct {
+	GenreIDs  []uint64  `json:"genreIds"`
+	UpdatedAt time.Time `json:"-"` // the book is updated at this time, so its ETag changes
+}
+
+// slugSeparator is everything that is not a part of a slug,
+// PostgreSQL has the same expression in migrations and LinkGenres
+var slugSeparator = regexp.MustCompile(`[^a-z0-9]+`)
+
+// hashSlugPrefix starts a slug of a name without latin letters or digits
+const hashSlugPrefix = "genre-"
+
+// Slugify returns a slug of a genre name, "Science Fiction" is science-fiction.
+// A name without latin letters or digits ("Фантастика") gets genre- and 12 hex digits
+// of md5 of the name: letters of other scripts are not kept, because PostgreSQL with
+// the C locale doesn't know them, and md5 is the same everywhere. It is empty for an empty name
+func Slugify(name string) string {
+	name = strings.TrimSpace(name)
+	slug := strings.Trim(slugSeparator.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug != "" || name == "" {
+		return slug
+	}
+	sum := md5.Sum([]byte(name))
+	return hashSlugPrefix + hex.EncodeToString(sum[:])[:12]
+}
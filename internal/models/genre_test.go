@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name string
+		slug string
+	}{
+		{"Science Fiction", "science-fiction"},
+		{" science  fiction! ", "science-fiction"},
+		{"Sci-Fi 2", "sci-fi-2"},
+		// md5 of the trimmed name, PostgreSQL makes the same slug with md5()
+		{"Фантастика", "genre-6c5df1b43039"},
+		{" 推理 ", "genre-ed6f7ed808c4"},
+		{"Фантастика 2", "2"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if slug := Slugify(tt.name); slug != tt.slug {
+			t.Errorf("Expected %q for %q, got: %q", tt.slug, tt.name, slug)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import "github.com/Talos-hub/BooksRestApi/internal/apperrors"
+
+// ImportReport is a result of an import of books from a file
+type ImportReport struct {
+	DryRun    bool             `json:"dryRun"`              // nothing is written, rows are only validated
+	Complete  bool             `json:"complete"`            // false if the file is broken or the import failed and reading is stopped
+	Rows      int              `json:"rows"`                // amount of read rows
+	Accepted  int              `json:"accepted"`            // valid rows
+	Imported  int              `json:"imported"`            // written books, it is 0 for a dry run
+	Rejected  int              `json:"rejected"`            // invalid rows
+	Errors    []ImportRowError `json:"errors,omitempty"`    // the first errors, all of them are in the error file
+	ErrorFile string           `json:"errorFile,omitempty"` // where the error file can be downloaded
+}
+
+// ImportRowError is why a row of an imported file is rejected
+type ImportRowError struct {
+	Row    int                    `json:"row"` // number of a row from 1, the header of CSV is not counted
+	Errors []apperrors.FieldError `json:"errors"`
+}
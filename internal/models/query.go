@@ -0,0 +1,120 @@
+package models
+
+import (
+	"cmp"
+	"strings"
+	"time"
+)
+
+// SortField is a field that books might be sorted by
+type SortField string
+
+// sortable fields, names are the same as json names of GeneralBook
+const (
+	SortByID              SortField = "id"
+	SortByTitle           SortField = "title"
+	SortByAuthor          SortField = "author"
+	SortByGenre           SortField = "genre"
+	SortByPublicationDate SortField = "publicationDate"
+)
+
+// Valid reports whether books can be sorted by the field
+func (f SortField) Valid() bool {
+	switch f {
+	case SortByID, SortByTitle, SortByAuthor, SortByGenre, SortByPublicationDate:
+		return true
+	}
+	return false
+}
+
+// BookQuery describes which books are needed and in what order.
+// Empty fields mean "no filter". Books are always ordered by SortBy
+// and then by id, so the order is stable and might be used for keyset pagination
+type BookQuery struct {
+	Author          string    // exact match, case-insensitive
+	Title           string    // substring, case-insensitive
+	PublishedAfter  time.Time // publicationDate >= PublishedAfter
+	PublishedBefore time.Time // publicationDate < PublishedBefore
+	// AuthorID is a linked author, only storages with authors support it,
+	// Match doesn't check it
+	AuthorID uint64
+	// Genre is a slug or a name of a tagged genre with all its subgenres,
+	// storages without genres and Match compare it to the genre string, case-insensitive
+	Genre string
+	// GenreID is a tagged genre with all its subgenres,
+	// only storages with genres support it
+	GenreID uint64
+	// WorkID is a work, books of it are its editions
+	WorkID uint64
+
+	SortBy SortField // by id if empty
+	Desc   bool      // descending order
+
+	// After is the last book of a previous page,
+	// only its ID and the SortBy field are used. Nil is the first page
+	After *Book
+	Limit int // max amount of books, zero means no limit
+}
+
+// Match reports whether a book passes all filters of the query.
+// SQL storages do the same in WHERE
+func (q BookQuery) Match(book Book) bool {
+	g := book.General
+	if q.Author != "" && !strings.EqualFold(g.Author, q.Author) {
+		return false
+	}
+	if q.Genre != "" && !strings.EqualFold(g.Genre, q.Genre) {
+		return false
+	}
+	if q.Title != "" && !strings.Contains(strings.ToLower(g.Title), strings.ToLower(q.Title)) {
+		return false
+	}
+	if !q.PublishedAfter.IsZero() && g.PublicationDate.Before(q.PublishedAfter) {
+		return false
+	}
+	if !q.PublishedBefore.IsZero() && !g.PublicationDate.Before(q.PublishedBefore) {
+		return false
+	}
+	if q.After != nil && q.Compare(book, *q.After) <= 0 {
+		return false
+	}
+	return true
+}
+
+// Compare compares two books in order of the query,
+// it might be used with slices.SortFunc
+func (q BookQuery) Compare(a, b Book) int {
+	c := 0
+	switch q.SortBy {
+	case SortByTitle:
+		c = strings.Compare(a.General.Title, b.General.Title)
+	case SortByAuthor:
+		c = strings.Compare(a.General.Author, b.General.Author)
+	case SortByGenre:
+		c = strings.Compare(a.General.Genre, b.General.Genre)
+	case SortByPublicationDate:
+		c = a.General.PublicationDate.Compare(b.General.PublicationDate)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.General.ID, b.General.ID)
+	}
+	if q.Desc {
+		return -c
+	}
+	return c
+}
+
+// Value returns a value of the field from a book
+func (f SortField) Value(book Book) any {
+	switch f {
+	case SortByTitle:
+		return book.General.Title
+	case SortByAuthor:
+		return book.General.Author
+	case SortByGenre:
+		return book.General.Genre
+	case SortByPublicationDate:
+		return book.General.PublicationDate
+	}
+	return book.General.ID
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Series is an ordered set of books, for example The Lord of the Rings.
+// A book is a volume of one series at most
+type Series struct {
+	ID          uint64    `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description,omitempty" db:"description"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time `json:"updatedAt" db:"updated_at"`
+	// Volumes are books of the series ordered by position,
+	// storages don't keep them in a series, only GET /series/{id} has them
+	Volumes []SeriesVolume `json:"volumes,omitempty"`
+}
+
+// SeriesVolume is a book at a position of a series
+type SeriesVolume struct {
+	ID       uint64  `json:"id"` // id of a book
+	Title    string  `json:"title"`
+	Position float64 `json:"position"` // it might be fractional, 2.5 is a novella between 2 and 3
+}
+
+// BookSeries is a series in a response of a book with neighbours of the book,
+// Previous and Next are nil for the first and the last volumes
+type BookSeries struct {
+	ID       uint64        `json:"id"`
+	Name     string        `json:"name"`
+	Position float64       `json:"position"`
+	Previous *SeriesVolume `json:"previous,omitempty"`
+	Next     *SeriesVolume `json:"next,omitempty"`
+}
+
+// SeriesPage is one page of series ordered by name.
+// NextCursor is empty when there are no more series
+type SeriesPage struct {
+	Series     []Series `json:"series"`
+	NextCursor string   `json:"next_cursor,omitempty"` // v2 calls it nextCursor
+}
+
+// SeriesQuery is a page of series, they are ordered by name and id
+type SeriesQuery struct {
+	// After is the last series of a previous page,
+	// only its ID and Name are used. Nil is the first page
+	After *Series
+	Limit int // max amount of series, zero means no limit
+}
+
+// SetBookSeriesRequest is a body of PUT /books/{id}/series
+type SetBookSeriesRequest struct {
+	SeriesID  uint64    `json:"seriesId"`
+	Position  *float64  `json:"position"` // nil if it is missing, zero is a valid position
+	UpdatedAt time.Time `json:"-"`        // the book and its neighbours are updated at this time
+}
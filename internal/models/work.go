@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Work is an abstract book that might have several editions,
+// for example The Hobbit is one work with hardcover, paperback and ebook editions.
+// An edition is a Book with WorkID
+type Work struct {
+	ID     uint64 `json:"id" db:"id"`
+	Title  string `json:"title" db:"title"`
+	Author string `json:"author" db:"author"` // credits like an author of a book
+	// Authors are linked from Author like authors of a book, a client cannot set them.
+	// A work is the same work for a title in any case and the same authors
+	Authors   []BookAuthor `json:"authors,omitempty"`
+	CreatedAt time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time    `json:"updatedAt" db:"updated_at"`
+}
+
+// WorkPage is one page of works ordered by title.
+// NextCursor is empty when there are no more works
+type WorkPage struct {
+	Works      []Work `json:"works"`
+	NextCursor string `json:"next_cursor,omitempty"` // v2 calls it nextCursor
+}
+
+// WorkQuery is a page of works, they are ordered by title and id
+type WorkQuery struct {
+	// After is the last work of a previous page,
+	// only its ID and Title are used. Nil is the first page
+	After *Work
+	Limit int // max amount of works, zero means no limit
+}
+
+// Formats are formats of editions
+var Formats = []string{"hardcover", "paperback", "ebook", "audiobook"}
@@ -0,0 +1,226 @@
+// openapi contains the OpenAPI document of the API,
+// the docs page and a validator of requests.
+// The document is written by hand, tests check that it matches the handlers
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// versions of the API, every version has its own document
+const (
+	V1 = "v1"
+	V2 = "v2"
+)
+
+//go:embed openapi.json
+var documentV1 []byte
+
+//go:embed openapi-v2.json
+var documentV2 []byte
+
+var documents = map[string][]byte{V1: documentV1, V2: documentV2}
+
+//go:embed docs.html
+var docsPage []byte
+
+// Document is a part of OpenAPI 3.1 document that is needed to validate requests
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Servers    []Server             `json:"servers"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Server is a base path of routes, paths of the document are relative to it
+type Server struct {
+	URL string `json:"url"`
+}
+
+type PathItem struct {
+	Servers    []Server     `json:"servers"` // they override servers of the document
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Post       *Operation   `json:"post"`
+	Put        *Operation   `json:"put"`
+	Patch      *Operation   `json:"patch"`
+	Delete     *Operation   `json:"delete"`
+}
+
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []*Parameter `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // query, path or header
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"` // media type -> its schema
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Parameters map[string]*Parameter `json:"parameters"`
+}
+
+// Schema is a subset of JSON Schema that the validator understands,
+// other keywords are ignored
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"` // date or date-time
+	Enum       []any              `json:"enum"`
+	Properties map[string]*Schema `json:"properties"`
+	Required   []string           `json:"required"`
+	Items      *Schema            `json:"items"`
+	MinLength  *int               `json:"minLength"`
+	MaxLength  *int               `json:"maxLength"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+	AllOf      []*Schema          `json:"allOf"`
+	AnyOf      []*Schema          `json:"anyOf"`
+}
+
+// Endpoint is one operation of the document
+type Endpoint struct {
+	Method string
+	Path   string // path template like /books/{id}
+}
+
+const (
+	schemaRefPrefix    = "#/components/schemas/"
+	parameterRefPrefix = "#/components/parameters/"
+)
+
+// Load parses the embedded document of a version
+func Load(version string) (*Document, error) {
+	document, ok := documents[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown API version: %s", version)
+	}
+	var doc Document
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	return &doc, nil
+}
+
+// Endpoints returns all operations of the document sorted by path and method
+func (d *Document) Endpoints() []Endpoint {
+	var endpoints []Endpoint
+	for path, item := range d.Paths {
+		for method := range item.operations() {
+			endpoints = append(endpoints, Endpoint{Method: method, Path: path})
+		}
+	}
+	slices.SortFunc(endpoints, func(a, b Endpoint) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return endpoints
+}
+
+// servers returns base paths of a path, "/" if the document has no servers
+func (d *Document) servers(item *PathItem) []string {
+	servers := item.Servers
+	if len(servers) == 0 {
+		servers = d.Servers
+	}
+	if len(servers) == 0 {
+		return []string{"/"}
+	}
+	paths := make([]string, 0, len(servers))
+	for _, server := range servers {
+		paths = append(paths, server.URL)
+	}
+	return paths
+}
+
+// operations returns operations of a path by methods
+func (p *PathItem) operations() map[string]*Operation {
+	operations := make(map[string]*Operation, 5)
+	for method, op := range map[string]*Operation{
+		http.MethodGet:    p.Get,
+		http.MethodPost:   p.Post,
+		http.MethodPut:    p.Put,
+		http.MethodPatch:  p.Patch,
+		http.MethodDelete: p.Delete,
+	} {
+		if op != nil {
+			operations[method] = op
+		}
+	}
+	return operations
+}
+
+// schema returns a schema that is referenced by $ref or the schema itself
+func (d *Document) schema(s *Schema) (*Schema, error) {
+	for s != nil && s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, schemaRefPrefix)
+		if !ok {
+			return nil, fmt.Errorf("unsupported $ref: %s", s.Ref)
+		}
+		next, ok := d.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown schema: %s", s.Ref)
+		}
+		s = next
+	}
+	return s, nil
+}
+
+// parameter returns a parameter that is referenced by $ref or the parameter itself
+func (d *Document) parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, ok := strings.CutPrefix(p.Ref, parameterRefPrefix)
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref: %s", p.Ref)
+	}
+	param, ok := d.Components.Parameters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown parameter: %s", p.Ref)
+	}
+	return param, nil
+}
+
+// SpecHandler serves the OpenAPI document of a version, it panics if there is no such version
+func SpecHandler(version string) http.Handler {
+	document, ok := documents[version]
+	if !ok {
+		panic("openapi: unknown API version " + version)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(document)
+	})
+}
+
+// DocsHandler serves an interactive docs page, it reads the document from /openapi.json
+// or from ?spec=/v2/openapi.json
+func DocsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(docsPage)
+	})
+}
@@ -0,0 +1,180 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+)
+
+const testBookJson = `{"book": {"id": 1, "title": "Clean Code", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z"}}`
+
+func newTestValidator(t *testing.T) *Validator {
+	t.Helper()
+	doc, err := Load(V1)
+	if err != nil {
+		t.Fatalf("Unexpected error loading document: %v", err)
+	}
+	v, err := NewValidator(doc)
+	if err != nil {
+		t.Fatalf("Unexpected error creating validator: %v", err)
+	}
+	return v
+}
+
+func newRequest(method, target, contentType, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	return r
+}
+
+// fields returns fields and rules of validation errors
+func fields(appErr *apperrors.AppError) map[string]string {
+	result := make(map[string]string)
+	if appErr == nil {
+		return result
+	}
+	if validateErr, ok := appErr.Err.(*apperrors.ValidateErr); ok {
+		for _, d := range validateErr.Details {
+			result[d.Field] = d.Rule
+		}
+	}
+	return result
+}
+
+func TestLoad(t *testing.T) {
+	doc, err := Load(V1)
+	if err != nil {
+		t.Fatalf("Unexpected error loading document: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("Expected OpenAPI 3.1.0, got: %s", doc.OpenAPI)
+	}
+	if len(doc.Endpoints()) == 0 {
+		t.Error("Expected endpoints in the document")
+	}
+}
+
+func TestValidator_ValidRequests(t *testing.T) {
+	v := newTestValidator(t)
+
+	requests := []*http.Request{
+		newRequest(http.MethodGet, "/books?genre=Fantasy&sort=-publicationDate&published_after=1990-01-01&limit=10", "", ""),
+		newRequest(http.MethodGet, "/books/search?q=hobbit", "", ""),
+		newRequest(http.MethodGet, "/books/1", "", ""),
+		newRequest(http.MethodPost, "/books", "application/json", testBookJson),
+		newRequest(http.MethodPatch, "/books/1", "application/json-patch+json", `[{"op": "replace", "path": "/general/title", "value": "x"}]`),
+		newRequest(http.MethodDelete, "/books/1", "", ""),
+	}
+	for _, r := range requests {
+		if appErr := v.Validate(r); appErr != nil {
+			t.Errorf("Expected %s %s to be valid, got: %v", r.Method, r.URL, appErr)
+		}
+	}
+}
+
+func TestValidator_KeepsBody(t *testing.T) {
+	v := newTestValidator(t)
+
+	r := newRequest(http.MethodPost, "/books", "application/json", testBookJson)
+	if appErr := v.Validate(r); appErr != nil {
+		t.Fatalf("Unexpected error: %v", appErr)
+	}
+	body, _ := io.ReadAll(r.Body)
+	if string(body) != testBookJson {
+		t.Errorf("Expected the same body after validation, got: %s", body)
+	}
+}
+
+func TestValidator_InvalidRequests(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		status  int
+		field   string
+		rule    string
+	}{
+		{"missing query", newRequest(http.MethodGet, "/books/search", "", ""), 400, "q", RuleRequired},
+		{"sort enum", newRequest(http.MethodGet, "/books?sort=price", "", ""), 400, "sort", RuleEnum},
+		{"integer param", newRequest(http.MethodGet, "/books?limit=abc", "", ""), 400, "limit", RuleType},
+		{"path param", newRequest(http.MethodGet, "/books/0", "", ""), 400, "id", RuleMinimum},
+		{"date param", newRequest(http.MethodGet, "/books?published_after=yesterday", "", ""), 400, "published_after", RuleAnyOf},
+		{"empty title", newRequest(http.MethodPost, "/books", "application/json",
+			`{"book": {"id": 1, "title": "", "genre": "g", "author": "a", "publicationDate": "2008-08-01T00:00:00Z"}}`), 400, "book.title", RuleMinLength},
+		{"missing field", newRequest(http.MethodPost, "/books", "application/json", `{"book": {"id": 1}}`), 400, "book.genre", RuleRequired},
+		{"date-time", newRequest(http.MethodPut, "/books/1", "application/json",
+			`{"book": {"title": "t", "genre": "g", "author": "a", "publicationDate": "2008"}}`), 400, "book.publicationDate", RuleFormat},
+		{"patch op", newRequest(http.MethodPatch, "/books/1", "application/json-patch+json", `[{"op": "drop", "path": "/"}]`), 400, "[0].op", RuleEnum},
+		{"media type", newRequest(http.MethodPatch, "/books/1", "text/plain", "x"), 415, "", ""},
+		{"route", newRequest(http.MethodGet, "/magazines", "", ""), 404, "", ""},
+		{"method", newRequest(http.MethodPost, "/books/1", "", ""), 405, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appErr := v.Validate(tt.request)
+			if appErr == nil || appErr.Code != tt.status {
+				t.Fatalf("Expected %d, got: %v", tt.status, appErr)
+			}
+			if tt.field != "" && fields(appErr)[tt.field] != tt.rule {
+				t.Errorf("Expected %s error of %s, got: %v", tt.rule, tt.field, fields(appErr))
+			}
+		})
+	}
+}
+
+func TestValidator_Servers(t *testing.T) {
+	v := newTestValidator(t)
+	doc, err := Load(V2)
+	if err != nil {
+		t.Fatalf("Unexpected error loading document: %v", err)
+	}
+	v2, err := NewValidator(doc)
+	if err != nil {
+		t.Fatalf("Unexpected error creating validator: %v", err)
+	}
+
+	tests := []struct {
+		validator *Validator
+		request   *http.Request
+		status    int // 0 is a valid request
+	}{
+		{v, newRequest(http.MethodGet, "/v1/books/1", "", ""), 0},
+		{v, newRequest(http.MethodGet, "/health", "", ""), 0},
+		{v, newRequest(http.MethodGet, "/v1/health", "", ""), http.StatusNotFound},
+		{v2, newRequest(http.MethodPost, "/v2/books", "application/json", `{"title": "Clean Code", "genre": "Programming", "author": "Robert C. Martin", "publicationDate": "2008-08-01T00:00:00Z"}`), 0},
+		{v2, newRequest(http.MethodPost, "/v2/books", "application/json", testBookJson), http.StatusBadRequest},
+		{v2, newRequest(http.MethodPut, "/v2/books", "application/json", testBookJson), http.StatusMethodNotAllowed},
+		{v2, newRequest(http.MethodGet, "/books/1", "", ""), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		appErr := tt.validator.Validate(tt.request)
+		switch {
+		case tt.status == 0 && appErr != nil:
+			t.Errorf("Expected %s %s to be valid, got: %v", tt.request.Method, tt.request.URL, appErr)
+		case tt.status != 0 && (appErr == nil || appErr.Code != tt.status):
+			t.Errorf("Expected %d for %s %s, got: %v", tt.status, tt.request.Method, tt.request.URL, appErr)
+		}
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	w := httptest.NewRecorder()
+	SpecHandler(V1).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"openapi": "3.1.0"`) {
+		t.Errorf("Expected the document, got: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	DocsHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected the docs page, got: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,414 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+)
+
+// maxBodySize is the biggest json body that the validator reads
+const maxBodySize = 10 << 20
+
+// rules of field errors, they are the same as rules of the validations package where it is possible
+const (
+	RuleRequired  = "required"
+	RuleType      = "type"
+	RuleEnum      = "enum"
+	RuleFormat    = "format"
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleMinimum   = "minimum"
+	RuleMaximum   = "maximum"
+	RuleAnyOf     = "any_of"
+)
+
+// Validator checks that requests match the document
+type Validator struct {
+	doc    *Document
+	routes []route
+}
+
+// route is a path of the document split by "/"
+type route struct {
+	segments []string
+	params   int // amount of {param} segments, a route with less params wins
+	item     *PathItem
+}
+
+// NewValidator creates a validator, it fails if the document has
+// a broken $ref, so a mistake in the document is found on start
+func NewValidator(doc *Document) (*Validator, error) {
+	v := &Validator{doc: doc}
+	for path, item := range doc.Paths {
+		// a path is matched with every server, like /v1/books and /books
+		for _, server := range doc.servers(item) {
+			full := strings.TrimRight(server, "/") + "/" + strings.Trim(path, "/")
+			r := route{segments: strings.Split(strings.Trim(full, "/"), "/"), item: item}
+			for _, segment := range r.segments {
+				if isParam(segment) {
+					r.params++
+				}
+			}
+			v.routes = append(v.routes, r)
+		}
+
+		if err := v.checkRefs(path, item); err != nil {
+			return nil, err
+		}
+	}
+	slices.SortFunc(v.routes, func(a, b route) int { return a.params - b.params })
+
+	for name, s := range doc.Components.Schemas {
+		if err := v.checkSchema(s); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	return v, nil
+}
+
+// Validate checks a request, all problems of parameters and a body are returned at once.
+// A json body is read and replaced by a copy, so a handler can read it again
+func (v *Validator) Validate(r *http.Request) *apperrors.AppError {
+	item, pathParams := v.match(r.URL.Path)
+	if item == nil {
+		return apperrors.NewAppError(http.StatusNotFound, "route is not documented",
+			fmt.Errorf("there is no %s in the API specification", r.URL.Path)).WithCode("route_not_documented")
+	}
+	op, ok := item.operations()[r.Method]
+	if !ok {
+		return apperrors.NewAppError(http.StatusMethodNotAllowed, "method is not allowed",
+			fmt.Errorf("%s %s is not in the API specification", r.Method, r.URL.Path))
+	}
+
+	var fieldErrors []apperrors.FieldError
+	query := r.URL.Query()
+	for _, param := range v.parameters(item, op) {
+		var raw string
+		var present bool
+		switch param.In {
+		case "path":
+			raw, present = pathParams[param.Name]
+		case "query":
+			present = query.Has(param.Name)
+			raw = query.Get(param.Name)
+		case "header":
+			raw = r.Header.Get(param.Name)
+			present = raw != ""
+		}
+		if !present {
+			if param.Required {
+				fieldErrors = append(fieldErrors, fieldError(param.Name, RuleRequired, param.In+" parameter is required"))
+			}
+			continue
+		}
+		fieldErrors = append(fieldErrors, v.validateValue(param.Schema, v.paramValue(param.Schema, raw), param.Name)...)
+	}
+
+	if op.RequestBody != nil {
+		bodyErrors, appErr := v.validateBody(r, op.RequestBody)
+		if appErr != nil {
+			return appErr
+		}
+		fieldErrors = append(fieldErrors, bodyErrors...)
+	}
+
+	if len(fieldErrors) > 0 {
+		return apperrors.NewAppError(http.StatusBadRequest, "request doesn't match the API specification",
+			apperrors.NewFieldsValidateErr("invalid request", fieldErrors, errors.New("invalid request"))).WithCode("invalid_request")
+	}
+	return nil
+}
+
+// match finds a path of the document, it returns values of path parameters
+func (v *Validator) match(path string) (*PathItem, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+next:
+	for _, r := range v.routes {
+		if len(r.segments) != len(segments) {
+			continue
+		}
+		params := make(map[string]string, r.params)
+		for i, segment := range r.segments {
+			if isParam(segment) {
+				params[strings.Trim(segment, "{}")] = segments[i]
+			} else if segment != segments[i] {
+				continue next
+			}
+		}
+		return r.item, params
+	}
+	return nil, nil
+}
+
+// parameters returns parameters of a path and an operation,
+// a parameter of an operation overrides one with the same name
+func (v *Validator) parameters(item *PathItem, op *Operation) []*Parameter {
+	var params []*Parameter
+	for _, p := range slices.Concat(item.Parameters, op.Parameters) {
+		// refs were checked in NewValidator
+		p, _ = v.doc.parameter(p)
+		params = slices.DeleteFunc(params, func(old *Parameter) bool {
+			return old.Name == p.Name && old.In == p.In
+		})
+		params = append(params, p)
+	}
+	return params
+}
+
+// validateBody checks Content-Type and a json body of a request
+func (v *Validator) validateBody(r *http.Request, body *RequestBody) ([]apperrors.FieldError, *apperrors.AppError) {
+	if r.Header.Get("Content-Type") == "" && r.ContentLength == 0 {
+		if body.Required {
+			return []apperrors.FieldError{fieldError("body", RuleRequired, "request body is required")}, nil
+		}
+		return nil, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		// clients often send json without Content-Type, the same as handlers
+		mediaType = "application/json"
+	}
+	content, ok := body.Content[mediaType]
+	if !ok {
+		return nil, apperrors.NewAppError(http.StatusUnsupportedMediaType, "unsupported media type",
+			fmt.Errorf("Content-Type %s is not in the API specification", mediaType))
+	}
+	if !strings.HasSuffix(mediaType, "json") {
+		// other types like multipart are not read, a handler streams them
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		return nil, apperrors.NewAppError(http.StatusBadRequest, "cannot read request body", err)
+	}
+	if len(data) > maxBodySize {
+		return nil, apperrors.NewAppError(http.StatusRequestEntityTooLarge, "request body is too large", nil)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			return []apperrors.FieldError{fieldError("body", RuleRequired, "request body is required")}, nil
+		}
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, apperrors.NewAppError(http.StatusBadRequest, "invalid JSON", err)
+	}
+	return v.validateValue(content.Schema, value, ""), nil
+}
+
+// paramValue converts a string parameter to a type of its schema,
+// numbers are json.Number like in a json body
+func (v *Validator) paramValue(s *Schema, raw string) any {
+	s, _ = v.doc.schema(s)
+	if s == nil {
+		return raw
+	}
+	switch s.Type {
+	case "integer", "number":
+		return json.Number(raw)
+	case "boolean":
+		switch raw {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	}
+	return raw
+}
+
+// validateValue checks a decoded json value, path is a json path of the value
+func (v *Validator) validateValue(s *Schema, value any, path string) []apperrors.FieldError {
+	// refs were checked in NewValidator
+	s, _ = v.doc.schema(s)
+	if s == nil {
+		return nil
+	}
+
+	var errs []apperrors.FieldError
+	for _, sub := range s.AllOf {
+		errs = append(errs, v.validateValue(sub, value, path)...)
+	}
+	if len(s.AnyOf) > 0 && !slices.ContainsFunc(s.AnyOf, func(sub *Schema) bool {
+		return len(v.validateValue(sub, value, path)) == 0
+	}) {
+		errs = append(errs, fieldError(path, RuleAnyOf, "value doesn't match any of allowed schemas"))
+	}
+
+	if s.Type != "" && !hasType(value, s.Type) {
+		return append(errs, fieldError(path, RuleType, "must be "+s.Type))
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		errs = append(errs, fieldError(path, RuleEnum, fmt.Sprintf("must be one of %v", s.Enum)))
+	}
+
+	switch value := value.(type) {
+	case string:
+		errs = append(errs, validateString(s, value, path)...)
+	case json.Number:
+		n, _ := value.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			errs = append(errs, fieldError(path, RuleMinimum, fmt.Sprintf("must be at least %v", *s.Minimum)))
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			errs = append(errs, fieldError(path, RuleMaximum, fmt.Sprintf("must be at most %v", *s.Maximum)))
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				errs = append(errs, fieldError(joinPath(path, name), RuleRequired, name+" is required"))
+			}
+		}
+		for name, property := range s.Properties {
+			if field, ok := value[name]; ok {
+				errs = append(errs, v.validateValue(property, field, joinPath(path, name))...)
+			}
+		}
+	case []any:
+		for i, item := range value {
+			errs = append(errs, v.validateValue(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return errs
+}
+
+// validateString checks length and format of a string
+func validateString(s *Schema, value, path string) []apperrors.FieldError {
+	var errs []apperrors.FieldError
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		errs = append(errs, fieldError(path, RuleMinLength, fmt.Sprintf("must be at least %d characters", *s.MinLength)))
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		errs = append(errs, fieldError(path, RuleMaxLength, fmt.Sprintf("must be at most %d characters", *s.MaxLength)))
+	}
+
+	layout := ""
+	switch s.Format {
+	case "date":
+		layout = time.DateOnly
+	case "date-time":
+		layout = time.RFC3339
+	}
+	if layout != "" {
+		if _, err := time.Parse(layout, value); err != nil {
+			errs = append(errs, fieldError(path, RuleFormat, "must be "+s.Format))
+		}
+	}
+	return errs
+}
+
+// checkRefs checks that all refs of a path can be resolved
+func (v *Validator) checkRefs(path string, item *PathItem) error {
+	for method, op := range item.operations() {
+		for _, p := range slices.Concat(item.Parameters, op.Parameters) {
+			param, err := v.doc.parameter(p)
+			if err == nil {
+				err = v.checkSchema(param.Schema)
+			}
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", method, path, err)
+			}
+		}
+		if op.RequestBody == nil {
+			continue
+		}
+		for _, content := range op.RequestBody.Content {
+			if err := v.checkSchema(content.Schema); err != nil {
+				return fmt.Errorf("%s %s: %w", method, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkSchema checks that all refs of a schema can be resolved,
+// schemas of the document are not recursive
+func (v *Validator) checkSchema(s *Schema) error {
+	s, err := v.doc.schema(s)
+	if err != nil || s == nil {
+		return err
+	}
+	for _, sub := range slices.Concat(s.AllOf, s.AnyOf, []*Schema{s.Items}) {
+		if err := v.checkSchema(sub); err != nil {
+			return err
+		}
+	}
+	for _, property := range s.Properties {
+		if err := v.checkSchema(property); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// there are helpers
+
+// hasType reports if a decoded json value has a type of JSON Schema
+func hasType(value any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		n, ok := value.(json.Number)
+		if ok {
+			_, err := n.Float64()
+			return err == nil
+		}
+		return false
+	case "integer":
+		n, ok := value.(json.Number)
+		if ok {
+			_, err := n.Int64()
+			return err == nil
+		}
+		return false
+	}
+	return true
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func fieldError(path, rule, message string) apperrors.FieldError {
+	return apperrors.FieldError{Field: path, Rule: rule, Message: message}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
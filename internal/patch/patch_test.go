@@ -0,0 +1,114 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+)
+
+// assertJSON compares documents ignoring formatting and order of members
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	g, err := decode(got)
+	if err != nil {
+		t.Fatalf("Result is not a json: %v", err)
+	}
+	w, _ := decode([]byte(want))
+	if !equal(g, w) {
+		t.Errorf("Expected %s, got: %s", want, got)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// examples from RFC 7396
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`{"id":18446744073709551615}`, `{"a":1}`, `{"id":18446744073709551615,"a":1}`},
+	}
+
+	for _, test := range tests {
+		got, err := MergePatch([]byte(test.doc), []byte(test.patch))
+		if err != nil {
+			t.Errorf("%s + %s: unexpected error: %v", test.doc, test.patch, err)
+			continue
+		}
+		assertJSON(t, got, test.want)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	// examples from RFC 6902 appendix A
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{`{"foo":"bar"}`, `[{"op":"copy","from":"/foo","path":"/baz"}]`, `{"foo":"bar","baz":"bar"}`},
+	}
+
+	for _, test := range tests {
+		got, err := JSONPatch([]byte(test.doc), []byte(test.patch))
+		if err != nil {
+			t.Errorf("%s + %s: unexpected error: %v", test.doc, test.patch, err)
+			continue
+		}
+		assertJSON(t, got, test.want)
+	}
+}
+
+func TestJSONPatch_Errors(t *testing.T) {
+	tests := []struct {
+		doc, patch string
+		want       error
+	}{
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ErrTestFailed},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ErrPathNotFound},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, ErrPathNotFound},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/01","value":"qux"}]`, ErrPathNotFound},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"qux"}]`, ErrPathNotFound},
+		{`{"foo":"bar"}`, `[{"op":"jump","path":"/foo"}]`, ErrInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/foo"}]`, ErrInvalidPatch},
+		{`{"foo":"bar"}`, `{"op":"add"}`, ErrInvalidPatch},
+		{`{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, ErrInvalidPatch},
+	}
+
+	for _, test := range tests {
+		_, err := JSONPatch([]byte(test.doc), []byte(test.patch))
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s + %s: expected %v, got: %v", test.doc, test.patch, test.want, err)
+		}
+	}
+}
+
+func TestJSONPatch_AllOrNothing(t *testing.T) {
+	doc := []byte(`{"foo":"bar"}`)
+
+	_, err := JSONPatch(doc, []byte(`[{"op":"replace","path":"/foo","value":"baz"},{"op":"test","path":"/foo","value":"bar"}]`))
+	if !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("Expected ErrTestFailed, got: %v", err)
+	}
+	if string(doc) != `{"foo":"bar"}` {
+		t.Errorf("Expected document to be untouched, got: %s", doc)
+	}
+}
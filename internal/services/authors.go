@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/validations"
+)
+
+// authorsNotSupported is returned when a storage doesn't keep authors
+func authorsNotSupported() *apperrors.AppError {
+	return apperrors.NewAppError(http.StatusNotImplemented, "authors are not supported by the storage", nil).WithCode("authors_not_supported")
+}
+
+// GetAuthors returns one page of authors ordered by sort name.
+// If limit is zero DefaultPageSize is used, it is never more than MaxPageSize
+func (s *BookService) GetAuthors(ctx context.Context, limit int, pageCursor string) (models.AuthorPage, *apperrors.AppError) {
+	authors, ok := s.storage.(abstraction.AuthorStorage)
+	if !ok {
+		return models.AuthorPage{}, authorsNotSupported()
+	}
+	limit, appErr := pageLimit(limit)
+	if appErr != nil {
+		return models.AuthorPage{}, appErr
+	}
+
+	after, err := decodeKeyCursor(authorOrder, pageCursor)
+	if err != nil {
+		return models.AuthorPage{}, apperrors.NewAppError(400, "invalid cursor", err).WithCode("invalid_cursor")
+	}
+
+	// it takes one more author to know whether there is a next page
+	found, err := authors.FindAuthors(ctx, models.AuthorQuery{After: after, Limit: limit + 1})
+	if err != nil {
+		s.logger.Info("Error getting authors", "error", err)
+		return models.AuthorPage{}, authorError(err, 500, "error getting authors")
+	}
+
+	var page models.AuthorPage
+	page.Authors, page.NextCursor = keyPage(authorOrder, found, limit)
+	return page, nil
+}
+
+// GetAuthor return an author by id
+func (s *BookService) GetAuthor(ctx context.Context, id uint64) (models.Author, *apperrors.AppError) {
+	authors, ok := s.storage.(abstraction.AuthorStorage)
+	if !ok {
+		return models.Author{}, authorsNotSupported()
+	}
+
+	author, err := authors.GetAuthor(ctx, id)
+	if err != nil {
+		s.logger.Info("Failed to get author by ID", "id", id, "error", err)
+		return models.Author{}, authorError(err, 500, "failed to get an author")
+	}
+	return author, nil
+}
+
+// CreateAuthor validates and saves a new author, CreatedAt must be set.
+// An empty sort name is made from the name
+func (s *BookService) CreateAuthor(ctx context.Context, author models.Author) (models.Author, *apperrors.AppError) {
+	authors, ok := s.storage.(abstraction.AuthorStorage)
+	if !ok {
+		return models.Author{}, authorsNotSupported()
+	}
+	if err := validations.ValidateAuthor(author); err != nil {
+		return models.Author{}, apperrors.NewAppError(400, "invalid author data", err)
+	}
+	if author.SortName == "" {
+		author.SortName = models.SortName(author.Name)
+	}
+	author.ID = 0
+	author.UpdatedAt = author.CreatedAt
+
+	saved, err := authors.SaveAuthor(ctx, author)
+	if err != nil {
+		s.logger.Error("Error save an author", "error", err)
+		return models.Author{}, authorError(err, 500, "faild to create an author")
+	}
+	return saved, nil
+}
+
+// ReplaceAuthor replaces an author by id (PUT semantics), UpdatedAt must be set.
+// Authors are created only by POST, so a missing author is 404
+func (s *BookService) ReplaceAuthor(ctx context.Context, id uint64, author models.Author) (models.Author, *apperrors.AppError) {
+	if _, ok := s.storage.(abstraction.AuthorStorage); !ok {
+		return models.Author{}, authorsNotSupported()
+	}
+	if author.ID != 0 && author.ID != id {
+		return models.Author{}, apperrors.NewAppError(400, "invalid author id",
+			errors.New("id in body doesn't match id in path")).WithCode("id_mismatch")
+	}
+	if err := validations.ValidateAuthor(author); err != nil {
+		return models.Author{}, apperrors.NewAppError(400, "invalid author data", err)
+	}
+	if author.SortName == "" {
+		author.SortName = models.SortName(author.Name)
+	}
+	author.ID = id
+
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		authors := tx.(abstraction.AuthorStorage)
+		old, err := authors.GetAuthor(ctx, id)
+		if err != nil {
+			return err
+		}
+		author.CreatedAt = old.CreatedAt
+		if err := authors.UpdateAuthor(ctx, author); err != nil {
+			return err
+		}
+		if author.Name == old.Name {
+			return nil
+		}
+		// books show names of their authors
+		ids, err := bookIDs(ctx, tx, models.BookQuery{AuthorID: id})
+		if err != nil {
+			return err
+		}
+		return touchBooks(ctx, tx, ids, author.UpdatedAt)
+	})
+	if err != nil {
+		s.logger.Info("faild to replace an author", "id", id, "error", err)
+		return models.Author{}, authorError(err, 500, "error replace an author")
+	}
+	return author, nil
+}
+
+// DeleteAuthor delete an author, an author of books or works cannot be deleted
+func (s *BookService) DeleteAuthor(ctx context.Context, id uint64) *apperrors.AppError {
+	authors, ok := s.storage.(abstraction.AuthorStorage)
+	if !ok {
+		return authorsNotSupported()
+	}
+
+	if err := authors.DeleteAuthor(ctx, id); err != nil {
+		s.logger.Info("Failed to delete author", "id", id, "error", err)
+		return authorError(err, 500, "Failed to delete author")
+	}
+	return nil
+}
+
+// GetAuthorBooks returns one page of books of an author, like GetBooks does
+func (s *BookService) GetAuthorBooks(ctx context.Context, id uint64, query models.BookQuery, pageCursor string) (models.BookPage, *apperrors.AppError) {
+	if _, appErr := s.GetAuthor(ctx, id); appErr != nil {
+		return models.BookPage{}, appErr
+	}
+	query.AuthorID = id
+	return s.GetBooks(ctx, query, pageCursor)
+}
+
+// SetBookAuthors replaces authors of a book, ids are in order of credits.
+// The author string of the book is not changed, but its version is increased
+// in the same transaction, UpdatedAt must be set
+func (s *BookService) SetBookAuthors(ctx context.Context, bookID uint64, req models.SetBookAuthorsRequest) (models.Book, *apperrors.AppError) {
+	if _, ok := s.storage.(abstraction.AuthorStorage); !ok {
+		return models.Book{}, authorsNotSupported()
+	}
+	if req.AuthorIDs == nil {
+		return models.Book{}, apperrors.NewAppError(400, "invalid book authors",
+			errors.New("authorIds is required")).WithCode("invalid_book_authors")
+	}
+
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		if err := tx.(abstraction.AuthorStorage).SetBookAuthors(ctx, bookID, req.AuthorIDs); err != nil {
+			return err
+		}
+		return touchBooks(ctx, tx, []uint64{bookID}, req.UpdatedAt)
+	})
+	if err != nil {
+		s.logger.Info("Failed to set book authors", "id", bookID, "error", err)
+		return models.Book{}, authorError(err, 500, "failed to set book authors")
+	}
+	return s.GetBook(ctx, bookID)
+}
+
+// linkAuthors links a book to authors from its author string,
+// it is called in the transaction that wrote the book.
+// A storage without authors has nothing to link
+func linkAuthors(ctx context.Context, tx abstraction.Storage, book models.Book) error {
+	authors, ok := tx.(abstraction.AuthorStorage)
+	if !ok {
+		return nil
+	}
+	return authors.LinkAuthors(ctx, []uint64{book.General.ID}, book.UpdatedAt)
+}
+
+// withAuthors sets authors of books from the storage
+func (s *BookService) withAuthors(ctx context.Context, books []models.Book) *apperrors.AppError {
+	authors, ok := s.storage.(abstraction.AuthorStorage)
+	if !ok || len(books) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, len(books))
+	for i, book := range books {
+		ids[i] = book.General.ID
+	}
+	bookAuthors, err := authors.BookAuthors(ctx, ids)
+	if err != nil {
+		s.logger.Info("Error getting book authors", "error", err)
+		return storageError(err, 500, "error getting book authors")
+	}
+	for i := range books {
+		books[i].Authors = bookAuthors[books[i].General.ID]
+	}
+	return nil
+}
+
+// authorError is storageError for authors,
+// a missing author or an author of books have their own codes
+func authorError(err error, code int, msg string) *apperrors.AppError {
+	switch {
+	case errors.Is(err, apperrors.ErrAuthorNotFound):
+		return apperrors.NewAppError(http.StatusNotFound, "author not found", err).WithCode("author_not_found")
+	case errors.Is(err, apperrors.ErrAuthorHasBooks):
+		return apperrors.NewAppError(http.StatusConflict, "author has books", err).WithCode("author_has_books")
+	}
+	return storageError(err, code, msg)
+}
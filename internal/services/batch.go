@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// MaxBatchSize is the biggest amount of operations in one batch
+const MaxBatchSize = 1000
+
+// BatchResult is a result of a batch, Items are in order of operations
+type BatchResult struct {
+	Committed bool // false if an atomic batch was rolled back
+	Items     []BatchItemResult
+}
+
+// BatchItemResult is a result of one operation
+type BatchItemResult struct {
+	Op     models.BatchOp
+	Status int          // HTTP status of the operation, like the same single request would have
+	Book   *models.Book // a created or updated book
+	Err    *apperrors.AppError
+}
+
+// errBatchFailed rolls back an atomic batch, an error of the operation is in its result
+var errBatchFailed = errors.New("batch operation failed")
+
+// Batch runs create, update and delete operations.
+// An atomic batch is one transaction: if an operation fails, everything is rolled back,
+// the failed operation has its error and others get 424 Failed Dependency.
+// A best effort batch runs every operation in its own transaction
+func (s *BookService) Batch(ctx context.Context, req models.BatchRequest, now time.Time) (BatchResult, *apperrors.AppError) {
+	mode := req.Mode
+	if mode == "" {
+		mode = models.BatchAtomic
+	}
+	if mode != models.BatchAtomic && mode != models.BatchBestEffort {
+		return BatchResult{}, apperrors.NewAppError(400, "invalid batch mode",
+			fmt.Errorf("mode must be %s or %s", models.BatchAtomic, models.BatchBestEffort)).WithCode("invalid_batch")
+	}
+	if len(req.Operations) == 0 {
+		return BatchResult{}, apperrors.NewAppError(400, "invalid batch",
+			errors.New("operations cannot be empty")).WithCode("invalid_batch")
+	}
+	if len(req.Operations) > MaxBatchSize {
+		return BatchResult{}, apperrors.NewAppError(http.StatusRequestEntityTooLarge, "batch is too large",
+			fmt.Errorf("batch cannot have more than %d operations", MaxBatchSize)).WithCode("batch_too_large")
+	}
+
+	result := BatchResult{Items: make([]BatchItemResult, len(req.Operations))}
+
+	if mode == models.BatchBestEffort {
+		for i, op := range req.Operations {
+			result.Items[i] = s.batchOperation(ctx, op, now)
+		}
+		result.Committed = true
+		return result, nil
+	}
+
+	failed := -1
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		// the same service on the transaction, every operation is a nested transaction of it
+		txService := &BookService{logger: s.logger, storage: tx}
+		for i, op := range req.Operations {
+			result.Items[i] = txService.batchOperation(ctx, op, now)
+			if result.Items[i].Err != nil {
+				failed = i
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errBatchFailed):
+		for i := range result.Items {
+			switch {
+			case i < failed:
+				result.Items[i] = BatchItemResult{Op: req.Operations[i].Op, Status: http.StatusFailedDependency,
+					Err: apperrors.NewAppError(http.StatusFailedDependency, "operation was rolled back",
+						fmt.Errorf("operation %d failed", failed)).WithCode("rolled_back")}
+			case i > failed:
+				result.Items[i] = BatchItemResult{Op: req.Operations[i].Op, Status: http.StatusFailedDependency,
+					Err: apperrors.NewAppError(http.StatusFailedDependency, "operation was not executed",
+						fmt.Errorf("operation %d failed", failed)).WithCode("not_executed")}
+			}
+		}
+		return result, nil
+	case err != nil:
+		s.logger.Error("Failed to run a batch", "error", err)
+		return BatchResult{}, storageError(err, 500, "error run a batch")
+	}
+
+	result.Committed = true
+	return result, nil
+}
+
+// batchOperation runs one operation with the service methods,
+// so it has the same validation and errors as a single request
+func (s *BookService) batchOperation(ctx context.Context, op models.BatchOperation, now time.Time) BatchItemResult {
+	item := BatchItemResult{Op: op.Op}
+
+	var cond *Precondition
+	if op.Version != 0 {
+		cond = &Precondition{Versions: []uint64{op.Version}}
+	}
+
+	var book models.Book
+	var appErr *apperrors.AppError
+	switch op.Op {
+	case models.BatchCreate:
+		item.Status = http.StatusCreated
+		book, appErr = s.CreateBook(ctx, models.CreateBookRequest{Book: op.Book, CreatedAt: now})
+	case models.BatchUpdate:
+		item.Status = http.StatusOK
+		op.Book.ID = op.ID
+		book, appErr = s.UpdateBook(ctx, op.ID, models.UpdateBookRequest{Book: op.Book, UpdatedAt: now}, cond)
+	case models.BatchDelete:
+		item.Status = http.StatusNoContent
+		appErr = s.DeleteBook(ctx, op.ID, cond, now)
+	default:
+		appErr = apperrors.NewAppError(400, "invalid batch operation",
+			fmt.Errorf("op must be %s, %s or %s", models.BatchCreate, models.BatchUpdate, models.BatchDelete)).WithCode("invalid_batch")
+	}
+
+	if appErr != nil {
+		item.Status = appErr.Code
+		item.Err = appErr
+		return item
+	}
+	if op.Op != models.BatchDelete {
+		item.Book = &book
+	}
+	return item
+}
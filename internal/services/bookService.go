@@ -1,11 +1,20 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
 	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
 	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/patch"
 	"github.com/Talos-hub/BooksRestApi/internal/validations"
 )
 
@@ -24,40 +33,121 @@ func NewBookService(logger abstraction.Logger, storage abstraction.Storage) *Boo
 	}
 }
 
-// GetBooks returns all books from storage
-func (s *BookService) GetBooks() ([]models.Book, *apperrors.AppError) {
-	books, err := s.storage.GetAll()
+// GetBooks returns one page of books that match a query and go after a cursor.
+// If query.Limit is zero DefaultPageSize is used, it is never more than MaxPageSize
+func (s *BookService) GetBooks(ctx context.Context, query models.BookQuery, pageCursor string) (models.BookPage, *apperrors.AppError) {
+	if query.SortBy != "" && !query.SortBy.Valid() {
+		return models.BookPage{}, apperrors.NewAppError(400, "invalid sort field", fmt.Errorf("cannot sort by %q", query.SortBy)).WithCode("invalid_sort")
+	}
+	limit, appErr := pageLimit(query.Limit)
+	if appErr != nil {
+		return models.BookPage{}, appErr
+	}
+
+	after, err := decodeCursor(query, pageCursor)
+	if err != nil {
+		return models.BookPage{}, apperrors.NewAppError(400, "invalid cursor", err).WithCode("invalid_cursor")
+	}
+	query.After = after
+
+	// it takes one more book to know whether there is a next page
+	query.Limit = limit + 1
+	books, err := s.storage.Find(ctx, query)
+	if err != nil {
+		s.logger.Info("Error getting books", "error", err)
+		return models.BookPage{}, storageError(err, 500, "error getting books")
+	}
+
+	page := models.BookPage{Books: books}
+	if len(books) > limit {
+		page.Books = books[:limit]
+		page.NextCursor = encodeCursor(query, page.Books[limit-1])
+	}
+	if appErr := s.withLinks(ctx, page.Books); appErr != nil {
+		return models.BookPage{}, appErr
+	}
+
+	return page, nil
+}
+
+// SearchBooks returns up to limit books that match a text query, the most relevant go first.
+// If limit is zero DefaultPageSize is used, it is never more than MaxPageSize
+func (s *BookService) SearchBooks(ctx context.Context, text string, limit int) ([]models.SearchResult, *apperrors.AppError) {
+	searcher, ok := s.storage.(abstraction.Searcher)
+	if !ok {
+		return nil, apperrors.NewAppError(http.StatusNotImplemented, "search is not supported by the storage", nil).WithCode("search_not_supported")
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return nil, apperrors.NewAppError(400, "invalid search query", errors.New("query cannot be empty"))
+	}
+	limit, appErr := pageLimit(limit)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	results, err := searcher.Search(ctx, text, limit)
 	if err != nil {
-		s.logger.Info("Error getting all books", "error", err)
-		return nil, apperrors.NewAppError(404, "error getting all books", err)
+		s.logger.Error("Error searching books", "error", err)
+		return nil, storageError(err, 500, "error searching books")
+	}
+
+	books := make([]models.Book, len(results))
+	for i, result := range results {
+		books[i] = result.Book
+	}
+	if appErr := s.withLinks(ctx, books); appErr != nil {
+		return nil, appErr
 	}
-	return books, nil
+	for i := range results {
+		results[i].Book = books[i]
+	}
+
+	return results, nil
 }
 
 // GetBook return a book by id
-func (s *BookService) GetBook(id uint64) (models.Book, *apperrors.AppError) {
-	book, err := s.storage.GetById(id)
+func (s *BookService) GetBook(ctx context.Context, id uint64) (models.Book, *apperrors.AppError) {
+	book, err := s.storage.GetById(ctx, id)
 	if err != nil {
 		s.logger.Info("Failed to get book by ID", "id", id, "error", err)
-		return models.Book{}, apperrors.NewAppError(404, "book not found", err)
+		return models.Book{}, storageError(err, 500, "failed to get a book")
 	}
 
-	return book, nil
+	return s.withBookLinks(ctx, book)
 }
 
-// Created created new book and save it to storage
-func (s *BookService) CreateBook(book models.CreateBookRequest) *apperrors.AppError {
-	// validation
-	err := validations.Validate(book)
+// GetBookByISBN return a book by ISBN-10 or ISBN-13, hyphens are allowed
+func (s *BookService) GetBookByISBN(ctx context.Context, isbn string) (models.Book, *apperrors.AppError) {
+	normalized, err := validations.NormalizeISBN(isbn)
+	if err != nil {
+		return models.Book{}, apperrors.NewAppError(400, "invalid isbn",
+			fmt.Errorf("%q is not ISBN-10 or ISBN-13: %w", isbn, err)).WithCode("invalid_isbn")
+	}
+
+	book, err := s.storage.GetByISBN(ctx, normalized)
+	if err != nil {
+		s.logger.Info("Failed to get book by ISBN", "isbn", normalized, "error", err)
+		return models.Book{}, storageError(err, 500, "failed to get a book")
+	}
+
+	return s.withBookLinks(ctx, book)
+}
+
+// Created created new book, save it to storage and returns the saved book
+func (s *BookService) CreateBook(ctx context.Context, book models.CreateBookRequest) (models.Book, *apperrors.AppError) {
+	// validation, an id is not required because a storage gives it
+	err := validations.ValidateNew(book)
 	if err != nil {
 		// if someone use it worng it returns ValidationReflectErr
 		// For instance: if parameter is func it returns the error
 		if errors.Is(err, &apperrors.ValidationReflectErr{}) {
 			s.logger.Error("Error validation", "error", err)
-			return apperrors.NewAppError(500, "error creating a book", err)
+			return models.Book{}, apperrors.NewAppError(500, "error creating a book", err)
 		}
-		return apperrors.NewAppError(400, "invalid book data", err)
+		return models.Book{}, apperrors.NewAppError(400, "invalid book data", err)
 	}
+	normalizeISBN(&book.Book)
 
 	// created new book
 	newBook := models.Book{
@@ -65,61 +155,284 @@ func (s *BookService) CreateBook(book models.CreateBookRequest) *apperrors.AppEr
 		CreatedAt: book.CreatedAt,
 		UpdatedAt: book.CreatedAt,
 	}
-	// save a book
-	err = s.storage.Save(newBook)
+	// save a book with its work, authors and genres
+	var saved models.Book
+	err = s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		if err := linkWork(ctx, tx, models.Book{}, &newBook); err != nil {
+			return err
+		}
+		var err error
+		if saved, err = tx.Save(ctx, newBook); err != nil {
+			return err
+		}
+		return linkBook(ctx, tx, models.Book{}, saved)
+	})
 	if err != nil {
 		s.logger.Error("Error save a book", "error", err)
-		return apperrors.NewAppError(500, "faild to create a book", err)
+		return models.Book{}, storageError(err, 500, "faild to create a book")
 	}
 
-	return nil
+	return s.withBookLinks(ctx, saved)
 }
 
-// UpdateBook update a book in storage
-func (s *BookService) UpdateBook(id uint64, update models.UpdateBookRequest) *apperrors.AppError {
-	// get a old book
-	book, err := s.storage.GetById(id)
+// UpdateBook update a book in storage.
+// Reading of the old book and update are done in one transaction,
+// so a concurrent delete cannot get in between.
+// If cond is set and the book has another version it responds 412.
+// It returns the updated book
+func (s *BookService) UpdateBook(ctx context.Context, id uint64, update models.UpdateBookRequest, cond *Precondition) (models.Book, *apperrors.AppError) {
+	// validation
+	err := validations.Validate(update)
+	if err != nil {
+		// if someone use it worng it returns ValidationReflectErr
+		// For instance: if parameter is func it returns the error
+		if errors.Is(err, &apperrors.ValidationReflectErr{}) {
+			s.logger.Error("Error validation", "error", err)
+			return models.Book{}, apperrors.NewAppError(500, "error update a book", err)
+		}
+		return models.Book{}, apperrors.NewAppError(400, "invalid book data", err)
+	}
+	normalizeISBN(&update.Book)
+
+	var newBook models.Book
+	err = s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		// get a old book
+		book, err := tx.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !cond.check(book, true) {
+			return preconditionFailed(id)
+		}
+
+		// created new book
+		newBook = models.Book{
+			General:   update.Book,
+			CreatedAt: book.CreatedAt,
+			UpdatedAt: update.UpdatedAt,
+			Version:   book.Version + 1, // the row is locked by the transaction
+		}
+		newBook.General.ID = id
+		if err := linkWork(ctx, tx, book, &newBook); err != nil {
+			return err
+		}
+
+		if err := tx.Update(ctx, newBook); err != nil {
+			return err
+		}
+		return linkBook(ctx, tx, book, newBook)
+	})
 	if err != nil {
-		s.logger.Info("faild to update a book")
-		return apperrors.NewAppError(404, "a book not found", err)
+		s.logger.Info("faild to update a book", "id", id, "error", err)
+		return models.Book{}, storageError(err, 500, "error update a book")
+	}
+
+	return s.withBookLinks(ctx, newBook)
+}
+
+// ReplaceBook replaces a book by id with a new one (PUT semantics).
+// If there is no book with the id, it is created with this id and created is true.
+// An id in the request must be empty or equal to id.
+// With cond the book must exist and match it, so If-Match never creates a book
+func (s *BookService) ReplaceBook(ctx context.Context, id uint64, update models.UpdateBookRequest, cond *Precondition) (book models.Book, created bool, appErr *apperrors.AppError) {
+	if update.Book.ID != 0 && update.Book.ID != id {
+		return models.Book{}, false, apperrors.NewAppError(400, "invalid book id",
+			fmt.Errorf("id in body %d doesn't match id in path %d", update.Book.ID, id)).WithCode("id_mismatch")
 	}
+	update.Book.ID = id
 
 	// validation
-	err = validations.Validate(update)
+	err := validations.Validate(update)
 	if err != nil {
 		// if someone use it worng it returns ValidationReflectErr
 		// For instance: if parameter is func it returns the error
 		if errors.Is(err, &apperrors.ValidationReflectErr{}) {
 			s.logger.Error("Error validation", "error", err)
-			return apperrors.NewAppError(500, "error update a book", err)
+			return models.Book{}, false, apperrors.NewAppError(500, "error replace a book", err)
 		}
-		return apperrors.NewAppError(400, "invalid book data", err)
+		return models.Book{}, false, apperrors.NewAppError(400, "invalid book data", err)
 	}
+	normalizeISBN(&update.Book)
 
-	// created new book
-	newBook := models.Book{
-		General:   update.Book,
-		CreatedAt: book.CreatedAt,
-		UpdatedAt: update.UpdatedAt,
+	err = s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		old, err := tx.GetById(ctx, id)
+		exists := err == nil
+		if (exists || errors.Is(err, apperrors.ErrNotFound)) && !cond.check(old, exists) {
+			return preconditionFailed(id)
+		}
+
+		switch {
+		case errors.Is(err, apperrors.ErrNotFound):
+			// upsert, the client chose the id
+			created = true
+			book = models.Book{
+				General:   update.Book,
+				CreatedAt: update.UpdatedAt,
+				UpdatedAt: update.UpdatedAt,
+			}
+			if err := linkWork(ctx, tx, models.Book{}, &book); err != nil {
+				return err
+			}
+			book, err = tx.SaveWithID(ctx, book)
+			if err != nil {
+				return err
+			}
+			return linkBook(ctx, tx, models.Book{}, book)
+		case err != nil:
+			return err
+		}
+
+		book = models.Book{
+			General:   update.Book,
+			CreatedAt: old.CreatedAt,
+			UpdatedAt: update.UpdatedAt,
+			Version:   old.Version + 1, // the row is locked by the transaction
+		}
+		if err := linkWork(ctx, tx, old, &book); err != nil {
+			return err
+		}
+		if err := tx.Update(ctx, book); err != nil {
+			return err
+		}
+		return linkBook(ctx, tx, old, book)
+	})
+	if err != nil {
+		s.logger.Info("faild to replace a book", "id", id, "error", err)
+		return models.Book{}, false, storageError(err, 500, "error replace a book")
 	}
 
-	err = s.storage.Update(newBook)
+	book, appErr = s.withBookLinks(ctx, book)
+	return book, created, appErr
+}
+
+// PatchBook applies a patch to a book and returns the patched book.
+// patchType is patch.MergePatchType or patch.JSONPatchType,
+// the patch is applied to the json representation of a book (like GET returns it).
+// Only changed fields are written, it is done in one transaction with reading
+// and checking cond
+func (s *BookService) PatchBook(ctx context.Context, id uint64, patchType string, data []byte, updatedAt time.Time, cond *Precondition) (models.Book, *apperrors.AppError) {
+	var result models.Book
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		book, err := tx.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !cond.check(book, true) {
+			return preconditionFailed(id)
+		}
+
+		patched, appErr := applyPatch(book, patchType, data)
+		if appErr != nil {
+			return appErr
+		}
+
+		// validation
+		if err := validations.Validate(patched); err != nil {
+			return apperrors.NewAppError(400, "invalid book data", err)
+		}
+		normalizeISBN(&patched.General)
+		patched.UpdatedAt = updatedAt
+		if err := linkWork(ctx, tx, book, &patched); err != nil {
+			return err
+		}
+
+		fields := models.ChangedFields(book.General, patched.General)
+		if len(fields) == 0 {
+			result = book
+			return nil
+		}
+
+		if err := tx.UpdateFields(ctx, patched, fields); err != nil {
+			return err
+		}
+		patched.Version++
+		result = patched
+		return linkBook(ctx, tx, book, patched)
+	})
+
 	if err != nil {
-		return apperrors.NewAppError(500, "error update a book", err)
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) {
+			return models.Book{}, appErr
+		}
+		s.logger.Info("faild to patch a book", "id", id, "error", err)
+		return models.Book{}, storageError(err, 500, "error patch a book")
 	}
 
-	return nil
+	return s.withBookLinks(ctx, result)
 }
 
-// DeleteBook delete a book by id
-func (s *BookService) DeleteBook(id uint64) *apperrors.AppError {
-	if _, err := s.storage.GetById(id); err != nil {
-		return apperrors.NewAppError(404, "a book not found", err)
+// applyPatch applies a patch to a book. Fields that are managed
+// by the server (id, createdAt, updateAt) cannot be changed
+func applyPatch(book models.Book, patchType string, data []byte) (models.Book, *apperrors.AppError) {
+	doc, err := json.Marshal(book)
+	if err != nil {
+		return models.Book{}, apperrors.NewAppError(500, "error patch a book", err)
+	}
+
+	patchedDoc, err := patch.Apply(patchType, doc, data)
+	switch {
+	case errors.Is(err, patch.ErrTestFailed):
+		return models.Book{}, apperrors.NewAppError(http.StatusConflict, "patch test failed", err).WithCode("patch_test_failed")
+	case errors.Is(err, patch.ErrPathNotFound):
+		return models.Book{}, apperrors.NewAppError(http.StatusUnprocessableEntity, "patch cannot be applied", err).WithCode("patch_path_not_found")
+	case err != nil:
+		return models.Book{}, apperrors.NewAppError(400, "invalid patch", err).WithCode("invalid_patch")
+	}
+
+	// a patch must not add fields that a book doesn't have
+	decoder := json.NewDecoder(bytes.NewReader(patchedDoc))
+	decoder.DisallowUnknownFields()
+	var patched models.Book
+	if err := decoder.Decode(&patched); err != nil {
+		return models.Book{}, apperrors.NewAppError(http.StatusUnprocessableEntity, "patched book is invalid", err).WithCode("invalid_patched_book")
 	}
 
-	if err := s.storage.Delete(id); err != nil {
+	if patched.General.ID != book.General.ID ||
+		!patched.CreatedAt.Equal(book.CreatedAt) ||
+		!patched.UpdatedAt.Equal(book.UpdatedAt) ||
+		patched.Version != book.Version {
+		return models.Book{}, apperrors.NewAppError(http.StatusUnprocessableEntity, "patched book is invalid",
+			errors.New("id, createdAt, updateAt and version cannot be changed")).WithCode("invalid_patched_book")
+	}
+	// a book from a storage has no authors, genres and series, so they are only in a patch
+	if patched.Authors != nil {
+		return models.Book{}, apperrors.NewAppError(http.StatusUnprocessableEntity, "patched book is invalid",
+			errors.New("authors are changed by PUT /books/{id}/authors")).WithCode("invalid_patched_book")
+	}
+	if patched.Genres != nil {
+		return models.Book{}, apperrors.NewAppError(http.StatusUnprocessableEntity, "patched book is invalid",
+			errors.New("genres are changed by PUT /books/{id}/genres")).WithCode("invalid_patched_book")
+	}
+	if patched.Series != nil {
+		return models.Book{}, apperrors.NewAppError(http.StatusUnprocessableEntity, "patched book is invalid",
+			errors.New("series is changed by PUT /books/{id}/series")).WithCode("invalid_patched_book")
+	}
+
+	return patched, nil
+}
+
+// DeleteBook delete a book by id.
+// The check and delete are done in one transaction,
+// the book is deleted only if it matches cond.
+// Its neighbours in a series are updated at deletedAt
+func (s *BookService) DeleteBook(ctx context.Context, id uint64, cond *Precondition, deletedAt time.Time) *apperrors.AppError {
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		book, err := tx.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !cond.check(book, true) {
+			return preconditionFailed(id)
+		}
+		if err := touchNeighbours(ctx, tx, id, deletedAt); err != nil {
+			return err
+		}
+		return tx.Delete(ctx, id)
+	})
+	if err != nil {
 		s.logger.Error("Failed to delete book", "id", id, "error", err)
-		return apperrors.NewAppError(500, "Failed to delete book", err)
+		return storageError(err, 500, "Failed to delete book")
 	}
 
 	return nil
@@ -130,3 +443,116 @@ func (s *BookService) CloseStorage() error {
 	err := s.storage.Close()
 	return err
 }
+
+// linkBook links a book to authors and genres from its strings when they changed,
+// old is an empty book for a new one. It is called in the transaction that wrote the book.
+// Neighbours of a renamed book in a series show its title, so they are changed too
+func linkBook(ctx context.Context, tx abstraction.Storage, old, book models.Book) error {
+	if old.General.ID != 0 && book.General.Title != old.General.Title {
+		if err := touchNeighbours(ctx, tx, book.General.ID, book.UpdatedAt); err != nil {
+			return err
+		}
+	}
+	if book.General.Author != old.General.Author {
+		if err := linkAuthors(ctx, tx, book); err != nil {
+			return err
+		}
+	}
+	if book.General.Genre != old.General.Genre {
+		return linkGenres(ctx, tx, book)
+	}
+	return nil
+}
+
+// touchBooks increases versions and sets updated_at of books whose response changed
+// without their own fields, like new authors of a book, so their ETags change too.
+// It is called in the transaction of the change, zero and repeated ids are skipped
+func touchBooks(ctx context.Context, tx abstraction.Storage, ids []uint64, updatedAt time.Time) error {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	for _, id := range slices.Compact(ids) {
+		if id == 0 {
+			continue
+		}
+		book := models.Book{General: models.GeneralBook{ID: id}, UpdatedAt: updatedAt}
+		if err := tx.UpdateFields(ctx, book, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bookIDs returns ids of books that match a query in the transaction
+func bookIDs(ctx context.Context, tx abstraction.Storage, query models.BookQuery) ([]uint64, error) {
+	books, err := tx.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, len(books))
+	for i, book := range books {
+		ids[i] = book.General.ID
+	}
+	return ids, nil
+}
+
+// withLinks sets authors, genres and series of books from the storage
+func (s *BookService) withLinks(ctx context.Context, books []models.Book) *apperrors.AppError {
+	if appErr := s.withAuthors(ctx, books); appErr != nil {
+		return appErr
+	}
+	if appErr := s.withGenres(ctx, books); appErr != nil {
+		return appErr
+	}
+	return s.withSeries(ctx, books)
+}
+
+// withBookLinks sets authors, genres and series of one book
+func (s *BookService) withBookLinks(ctx context.Context, book models.Book) (models.Book, *apperrors.AppError) {
+	books := []models.Book{book}
+	if appErr := s.withLinks(ctx, books); appErr != nil {
+		return models.Book{}, appErr
+	}
+	return books[0], nil
+}
+
+// storageError wraps an error from a storage into AppError.
+// If a book (or another item) doesn't exist or already exists, a request was canceled by a client or the storage
+// timeout expired it is not a storage failure, so code and message are replaced
+func storageError(err error, code int, msg string) *apperrors.AppError {
+	// an error that was returned by the service itself inside a transaction
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	switch {
+	case errors.Is(err, apperrors.ErrBookNotFound):
+		return apperrors.NewAppError(http.StatusNotFound, "book not found", err).WithCode("book_not_found")
+	case errors.Is(err, apperrors.ErrNotFound):
+		// something else is missing, a mapper of its resource has its own code
+		return apperrors.NewAppError(http.StatusNotFound, "not found", err).WithCode("not_found")
+	case errors.Is(err, apperrors.ErrISBNConflict):
+		return apperrors.NewAppError(http.StatusConflict, "book with this isbn already exists", err).WithCode("isbn_exists")
+	case errors.Is(err, apperrors.ErrConflict):
+		return apperrors.NewAppError(http.StatusConflict, "book already exists", err).WithCode("book_exists")
+	case errors.Is(err, context.Canceled):
+		return apperrors.NewAppError(apperrors.StatusClientClosedRequest, "request canceled", err).WithCode("request_canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return apperrors.NewAppError(http.StatusServiceUnavailable, "storage is not available, try again later", err).WithCode("storage_unavailable")
+	}
+	return apperrors.NewAppError(code, msg, err)
+}
+
+// normalizeISBN stores ISBN as ISBN-13 without hyphens, so the same book
+// cannot be saved twice with another spelling. The book must be validated
+func normalizeISBN(book *models.GeneralBook) {
+	if isbn, err := validations.NormalizeISBN(book.ISBN); err == nil {
+		book.ISBN = isbn
+	}
+}
+
+// preconditionFailed is returned when a book was changed by somebody else
+func preconditionFailed(id uint64) *apperrors.AppError {
+	return apperrors.NewAppError(http.StatusPreconditionFailed, "book was changed",
+		fmt.Errorf("book with id %d doesn't match If-Match", id)).WithCode("book_changed")
+}
@@ -0,0 +1,556 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/bookimport"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/jsonfile"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/memory"
+)
+
+// Test data
+var (
+	testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	testTime   = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newTestService() *BookService {
+	return NewBookService(testLogger, memory.NewMemoryStorage(testLogger))
+}
+
+func newTestRequest(title string) models.CreateBookRequest {
+	return models.CreateBookRequest{
+		Book: models.GeneralBook{
+			ID:              1,
+			Title:           title,
+			Genre:           "Programming",
+			Author:          "Robert C. Martin",
+			PublicationDate: testTime,
+		},
+		CreatedAt: testTime,
+	}
+}
+
+func TestBookService_CreateAndGet(t *testing.T) {
+	s := newTestService()
+
+	created, appErr := s.CreateBook(t.Context(), newTestRequest("Clean Code"))
+	if appErr != nil {
+		t.Fatalf("Unexpected error creating a book: %v", appErr)
+	}
+
+	book, appErr := s.GetBook(t.Context(), created.General.ID)
+	if appErr != nil {
+		t.Fatalf("Unexpected error getting a book: %v", appErr)
+	}
+	if book.General.Title != "Clean Code" {
+		t.Errorf("Expected title Clean Code, got: %s", book.General.Title)
+	}
+}
+
+func TestBookService_InvalidBook(t *testing.T) {
+	s := newTestService()
+
+	_, appErr := s.CreateBook(t.Context(), newTestRequest(""))
+	if appErr == nil || appErr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid book, got: %v", appErr)
+	}
+}
+
+func TestBookService_CanceledRequest(t *testing.T) {
+	s := newTestService()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, appErr := s.GetBooks(ctx, models.BookQuery{}, "")
+	if appErr == nil || appErr.Code != apperrors.StatusClientClosedRequest {
+		t.Errorf("Expected 499 for canceled request, got: %v", appErr)
+	}
+}
+
+func TestBookService_Timeout(t *testing.T) {
+	s := newTestService()
+
+	ctx, cancel := context.WithDeadline(t.Context(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, appErr := s.GetBook(ctx, 1)
+	if appErr == nil || appErr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for expired timeout, got: %v", appErr)
+	}
+}
+
+func TestStorageError_NotFound(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{fmt.Errorf("book with id: 1 %w", apperrors.ErrBookNotFound), "book_not_found"},
+		{fmt.Errorf("author with id: 1 %w", apperrors.ErrAuthorNotFound), "not_found"},
+		{fmt.Errorf("series with id: 1 %w", apperrors.ErrSeriesNotFound), "not_found"},
+	}
+	for _, tt := range tests {
+		appErr := storageError(tt.err, 500, "error")
+		if appErr.Code != http.StatusNotFound || appErr.ErrCode != tt.code {
+			t.Errorf("Expected 404 %s for %v, got: %d %s", tt.code, tt.err, appErr.Code, appErr.ErrCode)
+		}
+	}
+}
+
+func TestBookService_WorkIDWithoutWorks(t *testing.T) {
+	storage, err := jsonfile.NewJsonStorage(filepath.Join(t.TempDir(), "books.json"), testLogger)
+	if err != nil {
+		t.Fatalf("Unexpected error opening storage: %v", err)
+	}
+	s := NewBookService(testLogger, storage)
+
+	// the storage has no works, a workId is rejected and not dropped
+	req := newTestRequest("Clean Code")
+	req.Book.WorkID = 7
+	if _, appErr := s.CreateBook(t.Context(), req); appErr == nil || appErr.ErrCode != "works_not_supported" {
+		t.Errorf("Expected works_not_supported, got: %v", appErr)
+	}
+
+	created, appErr := s.CreateBook(t.Context(), newTestRequest("Clean Code"))
+	if appErr != nil || created.General.WorkID != 0 {
+		t.Fatalf("Expected a book without a work, got: %+v %v", created, appErr)
+	}
+	update := models.UpdateBookRequest{Book: created.General, UpdatedAt: testTime}
+	update.Book.WorkID = 7
+	if _, appErr := s.UpdateBook(t.Context(), created.General.ID, update, nil); appErr == nil || appErr.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 for an update with a workId, got: %v", appErr)
+	}
+}
+
+func TestBookService_GetBooksPages(t *testing.T) {
+	s := newTestService()
+	for _, title := range []string{"Clean Code", "Clean Architecture", "Refactoring"} {
+		if _, appErr := s.CreateBook(t.Context(), newTestRequest(title)); appErr != nil {
+			t.Fatalf("Unexpected error creating a book: %v", appErr)
+		}
+	}
+
+	page, appErr := s.GetBooks(t.Context(), models.BookQuery{Limit: 2}, "")
+	if appErr != nil {
+		t.Fatalf("Unexpected error getting books: %v", appErr)
+	}
+	if len(page.Books) != 2 || page.NextCursor == "" {
+		t.Fatalf("Expected 2 books and a next cursor, got: %+v", page)
+	}
+
+	page, appErr = s.GetBooks(t.Context(), models.BookQuery{Limit: 2}, page.NextCursor)
+	if appErr != nil {
+		t.Fatalf("Unexpected error getting the next page: %v", appErr)
+	}
+	if len(page.Books) != 1 || page.Books[0].General.Title != "Refactoring" || page.NextCursor != "" {
+		t.Errorf("Expected the last book without a next cursor, got: %+v", page)
+	}
+}
+
+func TestBookService_GetBooksInvalidCursor(t *testing.T) {
+	s := newTestService()
+
+	_, appErr := s.GetBooks(t.Context(), models.BookQuery{}, "not a cursor")
+	if appErr == nil || appErr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid cursor, got: %v", appErr)
+	}
+}
+
+func TestPageLimit(t *testing.T) {
+	tests := []struct{ limit, want int }{{0, DefaultPageSize}, {5, 5}, {1000, MaxPageSize}}
+	for _, tt := range tests {
+		if limit, appErr := pageLimit(tt.limit); appErr != nil || limit != tt.want {
+			t.Errorf("Expected %d for %d, got: %d %v", tt.want, tt.limit, limit, appErr)
+		}
+	}
+	if _, appErr := pageLimit(-1); appErr == nil || appErr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative limit, got: %v", appErr)
+	}
+}
+
+func TestKeyCursor(t *testing.T) {
+	after, err := decodeKeyCursor(workOrder, encodeKeyCursor(workOrder, models.Work{ID: 7, Title: "The Hobbit"}))
+	if err != nil || after.ID != 7 || after.Title != "The Hobbit" {
+		t.Errorf("Expected the work back, got: %+v %v", after, err)
+	}
+	if after, err := decodeKeyCursor(authorOrder, ""); after != nil || err != nil {
+		t.Errorf("Expected the beginning of a list, got: %+v %v", after, err)
+	}
+	// a cursor of works is not a cursor of authors
+	if _, err := decodeKeyCursor(authorOrder, encodeKeyCursor(workOrder, models.Work{ID: 7})); !errors.Is(err, errForeignCursor) {
+		t.Errorf("Expected errForeignCursor, got: %v", err)
+	}
+	if _, err := decodeKeyCursor(seriesOrder, "not a cursor"); !errors.Is(err, errMalformedCursor) {
+		t.Errorf("Expected errMalformedCursor, got: %v", err)
+	}
+}
+
+func TestBookService_GetAllSeriesPages(t *testing.T) {
+	s := newTestService()
+	for _, name := range []string{"Dune", "Discworld", "Earthsea"} {
+		if _, appErr := s.CreateSeries(t.Context(), models.Series{Name: name, CreatedAt: testTime}); appErr != nil {
+			t.Fatalf("Unexpected error creating a series: %v", appErr)
+		}
+	}
+
+	page, appErr := s.GetAllSeries(t.Context(), 2, "")
+	if appErr != nil || len(page.Series) != 2 || page.Series[0].Name != "Discworld" || page.NextCursor == "" {
+		t.Fatalf("Expected the first page of series, got: %+v %v", page, appErr)
+	}
+	page, appErr = s.GetAllSeries(t.Context(), 2, page.NextCursor)
+	if appErr != nil || len(page.Series) != 1 || page.Series[0].Name != "Earthsea" || page.NextCursor != "" {
+		t.Errorf("Expected the last page of series, got: %+v %v", page, appErr)
+	}
+	if _, appErr := s.GetAllSeries(t.Context(), 0, "not a cursor"); appErr == nil || appErr.ErrCode != "invalid_cursor" {
+		t.Errorf("Expected invalid_cursor, got: %v", appErr)
+	}
+}
+
+func TestBookService_GetBooksSortedPages(t *testing.T) {
+	s := newTestService()
+	for _, title := range []string{"B", "A", "C", "A"} {
+		if _, appErr := s.CreateBook(t.Context(), newTestRequest(title)); appErr != nil {
+			t.Fatalf("Unexpected error creating a book: %v", appErr)
+		}
+	}
+
+	query := models.BookQuery{SortBy: models.SortByTitle, Desc: true, Limit: 3}
+	page, appErr := s.GetBooks(t.Context(), query, "")
+	if appErr != nil {
+		t.Fatalf("Unexpected error getting books: %v", appErr)
+	}
+	// equal titles are ordered by id in the same direction
+	want := []uint64{3, 1, 4}
+	for i, book := range page.Books {
+		if book.General.ID != want[i] {
+			t.Fatalf("Expected ids %v, got: %+v", want, page.Books)
+		}
+	}
+
+	page, appErr = s.GetBooks(t.Context(), query, page.NextCursor)
+	if appErr != nil {
+		t.Fatalf("Unexpected error getting the next page: %v", appErr)
+	}
+	if len(page.Books) != 1 || page.Books[0].General.ID != 2 {
+		t.Errorf("Expected the last book with id 2, got: %+v", page.Books)
+	}
+
+	// a cursor cannot be used with another order
+	_, appErr = s.GetBooks(t.Context(), models.BookQuery{SortBy: models.SortByGenre}, encodeCursor(query, page.Books[0]))
+	if appErr == nil || appErr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for cursor of another order, got: %v", appErr)
+	}
+}
+
+func TestBookService_UpdateAndDeleteMissingBook(t *testing.T) {
+	s := newTestService()
+
+	update := models.UpdateBookRequest{Book: newTestRequest("Clean Code").Book, UpdatedAt: testTime}
+	if _, appErr := s.UpdateBook(t.Context(), 42, update, nil); appErr == nil || appErr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 updating missing book, got: %v", appErr)
+	}
+	if appErr := s.DeleteBook(t.Context(), 42, nil, testTime); appErr == nil || appErr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting missing book, got: %v", appErr)
+	}
+}
+
+func TestBookService_UpdateBook(t *testing.T) {
+	s := newTestService()
+	created, _ := s.CreateBook(t.Context(), newTestRequest("Clean Code"))
+
+	update := models.UpdateBookRequest{Book: newTestRequest("Clean Code 2").Book, UpdatedAt: time.Now()}
+	if _, appErr := s.UpdateBook(t.Context(), created.General.ID, update, nil); appErr != nil {
+		t.Fatalf("Unexpected error updating a book: %v", appErr)
+	}
+
+	book, _ := s.GetBook(t.Context(), created.General.ID)
+	if book.General.Title != "Clean Code 2" || !book.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("Unexpected updated book: %+v", book)
+	}
+}
+
+func TestBookService_ReplaceBook(t *testing.T) {
+	s := newTestService()
+
+	// upsert with a client chosen id
+	update := models.UpdateBookRequest{Book: newTestRequest("Clean Code").Book, UpdatedAt: testTime}
+	update.Book.ID = 0
+	book, created, appErr := s.ReplaceBook(t.Context(), 7, update, nil)
+	if appErr != nil || !created || book.General.ID != 7 {
+		t.Fatalf("Expected a created book with id 7, got: %+v %v %v", book, created, appErr)
+	}
+
+	// replace the existing book
+	update = models.UpdateBookRequest{Book: newTestRequest("Clean Code 2").Book, UpdatedAt: time.Now()}
+	update.Book.ID = 7
+	book, created, appErr = s.ReplaceBook(t.Context(), 7, update, nil)
+	if appErr != nil || created || book.General.Title != "Clean Code 2" || !book.CreatedAt.Equal(testTime) {
+		t.Errorf("Unexpected replaced book: %+v %v %v", book, created, appErr)
+	}
+
+	// next created book doesn't collide with the chosen id
+	next, appErr := s.CreateBook(t.Context(), newTestRequest("Refactoring"))
+	if appErr != nil || next.General.ID <= 7 {
+		t.Errorf("Expected id after 7, got: %+v %v", next, appErr)
+	}
+
+	// id mismatch
+	update.Book.ID = 8
+	if _, _, appErr := s.ReplaceBook(t.Context(), 7, update, nil); appErr == nil || appErr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for id mismatch, got: %v", appErr)
+	}
+}
+
+func TestBookService_Precondition(t *testing.T) {
+	s := newTestService()
+	created, _ := s.CreateBook(t.Context(), newTestRequest("Clean Code"))
+	id := created.General.ID
+
+	update := models.UpdateBookRequest{Book: newTestRequest("Clean Code 2").Book, UpdatedAt: time.Now()}
+	stale := &Precondition{Versions: []uint64{created.Version + 1}}
+	if _, appErr := s.UpdateBook(t.Context(), id, update, stale); appErr == nil || appErr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for another version, got: %v", appErr)
+	}
+	if appErr := s.DeleteBook(t.Context(), id, stale, testTime); appErr == nil || appErr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 deleting another version, got: %v", appErr)
+	}
+
+	book, _, appErr := s.ReplaceBook(t.Context(), id, update, &Precondition{Versions: []uint64{created.Version}})
+	if appErr != nil || book.Version != created.Version+1 {
+		t.Fatalf("Expected replaced book with next version, got: %+v %v", book, appErr)
+	}
+
+	// If-Match never creates a book and If-None-Match: * never replaces it
+	update.Book.ID = 0
+	if _, _, appErr := s.ReplaceBook(t.Context(), 42, update, &Precondition{Any: true}); appErr == nil || appErr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for missing book with If-Match: *, got: %v", appErr)
+	}
+	if _, _, appErr := s.ReplaceBook(t.Context(), id, update, &Precondition{None: true}); appErr == nil || appErr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for existing book with If-None-Match: *, got: %v", appErr)
+	}
+}
+
+func TestBookService_BatchAtomic(t *testing.T) {
+	s := newTestService()
+	created, _ := s.CreateBook(t.Context(), newTestRequest("Clean Code"))
+
+	// the last operation fails, so the created book and the update are rolled back
+	req := models.BatchRequest{Operations: []models.BatchOperation{
+		{Op: models.BatchCreate, Book: newTestRequest("Refactoring").Book},
+		{Op: models.BatchUpdate, ID: created.General.ID, Book: newTestRequest("Clean Code 2").Book},
+		{Op: models.BatchDelete, ID: 42},
+	}}
+	result, appErr := s.Batch(t.Context(), req, testTime)
+	if appErr != nil {
+		t.Fatalf("Unexpected error running a batch: %v", appErr)
+	}
+	if result.Committed {
+		t.Errorf("Expected rolled back batch, got: %+v", result)
+	}
+	for i, want := range []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound} {
+		if result.Items[i].Status != want || result.Items[i].Err == nil {
+			t.Errorf("Expected status %d of operation %d, got: %+v", want, i, result.Items[i])
+		}
+	}
+
+	page, _ := s.GetBooks(t.Context(), models.BookQuery{}, "")
+	if len(page.Books) != 1 || page.Books[0].General.Title != "Clean Code" {
+		t.Errorf("Expected unchanged books, got: %+v", page.Books)
+	}
+
+	// without the failed operation everything is committed
+	req.Operations = req.Operations[:2]
+	result, appErr = s.Batch(t.Context(), req, testTime)
+	if appErr != nil || !result.Committed {
+		t.Fatalf("Expected committed batch, got: %+v %v", result, appErr)
+	}
+	if result.Items[0].Status != http.StatusCreated || result.Items[1].Book.General.Title != "Clean Code 2" {
+		t.Errorf("Unexpected results: %+v", result.Items)
+	}
+}
+
+func TestBookService_BatchBestEffort(t *testing.T) {
+	s := newTestService()
+	created, _ := s.CreateBook(t.Context(), newTestRequest("Clean Code"))
+
+	req := models.BatchRequest{Mode: models.BatchBestEffort, Operations: []models.BatchOperation{
+		{Op: models.BatchCreate, Book: newTestRequest("").Book},
+		{Op: models.BatchUpdate, ID: created.General.ID, Book: newTestRequest("Clean Code 2").Book, Version: created.Version + 1},
+		{Op: models.BatchDelete, ID: created.General.ID, Version: created.Version},
+	}}
+	result, appErr := s.Batch(t.Context(), req, testTime)
+	if appErr != nil || !result.Committed {
+		t.Fatalf("Expected committed batch, got: %+v %v", result, appErr)
+	}
+	for i, want := range []int{http.StatusBadRequest, http.StatusPreconditionFailed, http.StatusNoContent} {
+		if result.Items[i].Status != want {
+			t.Errorf("Expected status %d of operation %d, got: %+v", want, i, result.Items[i])
+		}
+	}
+	if _, appErr := s.GetBook(t.Context(), created.General.ID); appErr == nil || appErr.Code != http.StatusNotFound {
+		t.Errorf("Expected deleted book, got: %v", appErr)
+	}
+
+	// invalid batches
+	for _, req := range []models.BatchRequest{
+		{},
+		{Mode: "sometimes", Operations: req.Operations},
+		{Operations: make([]models.BatchOperation, MaxBatchSize+1)},
+	} {
+		if _, appErr := s.Batch(t.Context(), req, testTime); appErr == nil {
+			t.Errorf("Expected error for invalid batch %q with %d operations", req.Mode, len(req.Operations))
+		}
+	}
+}
+
+func TestBookService_ImportBooks(t *testing.T) {
+	s := newTestService()
+
+	// more rows than a chunk, every third row is invalid
+	var file strings.Builder
+	file.WriteString("title,author,genre,publicationDate\n")
+	for i := range ImportChunkSize + 500 {
+		title := fmt.Sprintf("Book %d", i)
+		if i%3 == 0 {
+			title = ""
+		}
+		fmt.Fprintf(&file, "%s,Robert C. Martin,Programming,2008-08-01\n", title)
+	}
+
+	// dry run writes nothing
+	report, appErr := s.ImportBooks(t.Context(), bookimport.CSVType, strings.NewReader(file.String()), ImportOptions{DryRun: true, CreatedAt: testTime})
+	if appErr != nil {
+		t.Fatalf("Unexpected error importing books: %v", appErr)
+	}
+	if report.Rows != 1500 || report.Accepted != 1000 || report.Rejected != 500 || report.Imported != 0 || !report.Complete {
+		t.Errorf("Unexpected dry run report: %+v", report)
+	}
+	if len(report.Errors) != MaxReportedErrors || report.Errors[0].Row != 1 || report.Errors[0].Errors[0].Field != "title" {
+		t.Errorf("Expected the first %d errors, got: %+v", MaxReportedErrors, report.Errors[:1])
+	}
+	if page, _ := s.GetBooks(t.Context(), models.BookQuery{}, ""); len(page.Books) != 0 {
+		t.Fatalf("Expected no books after dry run, got: %d", len(page.Books))
+	}
+
+	var errorFile bytes.Buffer
+	report, appErr = s.ImportBooks(t.Context(), bookimport.CSVType, strings.NewReader(file.String()), ImportOptions{CreatedAt: testTime, Errors: &errorFile})
+	if appErr != nil || report.Imported != 1000 {
+		t.Fatalf("Expected 1000 imported books, got: %+v %v", report, appErr)
+	}
+	if lines := strings.Count(errorFile.String(), "\n"); lines != 501 {
+		t.Errorf("Expected the header and 500 rows in the error file, got: %d lines", lines)
+	}
+	book, appErr := s.GetBook(t.Context(), 1000)
+	if appErr != nil || book.General.Title != "Book 1499" || book.Version != 1 {
+		t.Errorf("Unexpected imported book: %+v %v", book, appErr)
+	}
+}
+
+func TestBookService_ImportDuplicateISBN(t *testing.T) {
+	s := newTestService()
+	req := newTestRequest("Clean Code")
+	req.Book.ISBN = "9780132350884"
+	if _, appErr := s.CreateBook(t.Context(), req); appErr != nil {
+		t.Fatalf("Unexpected error creating a book: %v", appErr)
+	}
+
+	// the first row has an ISBN of the stored book, the last one has an ISBN of the second row
+	file := "title,author,genre,publicationDate,isbn\n" +
+		"Clean Code,Robert C. Martin,Programming,2008-08-01,978-0-13-235088-4\n" +
+		"Design Patterns,Erich Gamma,Programming,1994-10-31,9780201633610\n" +
+		"Refactoring,Martin Fowler,Programming,1999-07-08,\n" +
+		"Design Patterns,Erich Gamma,Programming,1994-10-31,0-201-63361-2\n"
+
+	for _, dryRun := range []bool{true, false} {
+		report, appErr := s.ImportBooks(t.Context(), bookimport.CSVType, strings.NewReader(file), ImportOptions{DryRun: dryRun, CreatedAt: testTime})
+		if appErr != nil {
+			t.Fatalf("Unexpected error importing books: %v", appErr)
+		}
+		if report.Rows != 4 || report.Accepted != 2 || report.Rejected != 2 || len(report.Errors) != 2 {
+			t.Fatalf("Unexpected report: %+v", report)
+		}
+		for _, rowErr := range report.Errors {
+			if rowErr.Errors[0].Rule != bookimport.RuleUnique || rowErr.Errors[0].Field != "isbn" {
+				t.Errorf("Expected unique isbn error, got: %+v", rowErr)
+			}
+		}
+		// a row of the file is rejected at once, a stored ISBN when its chunk is written
+		if report.Errors[0].Row != 4 || report.Errors[1].Row != 1 {
+			t.Errorf("Expected rejected rows 4 and 1, got: %+v", report.Errors)
+		}
+	}
+
+	page, _ := s.GetBooks(t.Context(), models.BookQuery{}, "")
+	if len(page.Books) != 3 {
+		t.Errorf("Expected 2 imported books, got: %d", len(page.Books)-1)
+	}
+}
+
+// failingTxStorage is a memory storage whose transactions fail after the first ones
+type failingTxStorage struct {
+	*memory.MemoryStorage
+	txs int // transactions that succeed
+}
+
+func (f *failingTxStorage) WithTx(ctx context.Context, fn func(tx abstraction.Storage) error) error {
+	if f.txs == 0 {
+		return errors.New("storage is down")
+	}
+	f.txs--
+	return f.MemoryStorage.WithTx(ctx, fn)
+}
+
+func TestBookService_ImportStorageFailure(t *testing.T) {
+	storage := &failingTxStorage{MemoryStorage: memory.NewMemoryStorage(testLogger), txs: 1}
+	s := NewBookService(testLogger, storage)
+
+	var file strings.Builder
+	file.WriteString("title,author,genre,publicationDate\n")
+	for i := range ImportChunkSize + 1 {
+		fmt.Fprintf(&file, "Book %d,Robert C. Martin,Programming,2008-08-01\n", i)
+	}
+
+	// the first chunk is written before the storage fails, the report says so
+	report, appErr := s.ImportBooks(t.Context(), bookimport.CSVType, strings.NewReader(file.String()), ImportOptions{CreatedAt: testTime})
+	if appErr == nil || appErr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got: %v", appErr)
+	}
+	if report.Complete || report.Imported != ImportChunkSize || report.Accepted != ImportChunkSize+1 {
+		t.Errorf("Expected a report of the written chunk, got: %+v", report)
+	}
+	if books, _ := storage.GetAll(t.Context()); len(books) != ImportChunkSize {
+		t.Errorf("Expected %d stored books, got: %d", ImportChunkSize, len(books))
+	}
+}
+
+func TestBookService_ImportBrokenFile(t *testing.T) {
+	s := newTestService()
+
+	file := `[{"title": "Clean Code", "author": "Robert C. Martin", "genre": "Programming", "publicationDate": "2008-08-01"}, {"title": `
+	report, appErr := s.ImportBooks(t.Context(), bookimport.JSONType, strings.NewReader(file), ImportOptions{CreatedAt: testTime})
+	if appErr != nil {
+		t.Fatalf("Unexpected error importing books: %v", appErr)
+	}
+	// rows before the broken one are imported
+	if report.Complete || report.Imported != 1 || report.Rejected != 1 || report.Errors[0].Errors[0].Rule != bookimport.RuleSyntax {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	if _, appErr := s.ImportBooks(t.Context(), "text/plain", strings.NewReader(file), ImportOptions{}); appErr == nil || appErr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for unsupported type, got: %v", appErr)
+	}
+}
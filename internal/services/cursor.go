@@ -0,0 +1,175 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// page sizes for listing books
+const (
+	DefaultPageSize = 20  // it is used when a client doesn't set a limit
+	MaxPageSize     = 100 // a client cannot get more books per request
+)
+
+var (
+	errMalformedCursor = errors.New("cursor is malformed")
+	errForeignCursor   = errors.New("cursor was made for another sort order")
+)
+
+// cursor is a position in a list of books.
+// Clients get it as an opaque string and must not parse it,
+// so its content might be changed without breaking them
+type cursor struct {
+	ID    uint64 `json:"id"`          // the last book id on a previous page
+	Sort  string `json:"s,omitempty"` // sort order the cursor was made for
+	Value string `json:"v,omitempty"` // value of the sorted field of the last book
+}
+
+// sortKey returns a sort order of a query like it is written in a request
+func sortKey(q models.BookQuery) string {
+	key := string(q.SortBy)
+	if key == "" {
+		key = string(models.SortByID)
+	}
+	if q.Desc {
+		return "-" + key
+	}
+	return key
+}
+
+// encodeCursor returns opaque string that points after a book in order of a query
+func encodeCursor(q models.BookQuery, last models.Book) string {
+	c := cursor{ID: last.General.ID, Sort: sortKey(q)}
+
+	switch value := q.SortBy.Value(last).(type) {
+	case string:
+		c.Value = value
+	case time.Time:
+		c.Value = value.Format(time.RFC3339Nano)
+	}
+
+	// json of the struct never fails
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a string that was made by encodeCursor and returns
+// the last book of a previous page, it has only ID and the sorted field.
+// An empty string is the beginning of a list, so it returns nil
+func decodeCursor(q models.BookQuery, s string) (*models.Book, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errMalformedCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == 0 {
+		return nil, errMalformedCursor
+	}
+	// a value of another field cannot be compared with this order
+	if c.Sort != sortKey(q) {
+		return nil, errForeignCursor
+	}
+
+	last := &models.Book{General: models.GeneralBook{ID: c.ID}}
+	switch q.SortBy {
+	case models.SortByTitle:
+		last.General.Title = c.Value
+	case models.SortByAuthor:
+		last.General.Author = c.Value
+	case models.SortByGenre:
+		last.General.Genre = c.Value
+	case models.SortByPublicationDate:
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, errMalformedCursor
+		}
+		last.General.PublicationDate = t
+	}
+
+	return last, nil
+}
+
+// pageLimit checks a limit of a page, zero is DefaultPageSize
+// and it is never more than MaxPageSize
+func pageLimit(limit int) (int, *apperrors.AppError) {
+	if limit < 0 {
+		return 0, apperrors.NewAppError(400, "invalid limit", errors.New("limit cannot be negative"))
+	}
+	if limit == 0 {
+		return DefaultPageSize, nil
+	}
+	return min(limit, MaxPageSize), nil
+}
+
+// keyOrder is an order of a list by one string field and id,
+// authors, works and series have one order each
+type keyOrder[T any] struct {
+	sort  string                          // Sort of a cursor
+	key   func(T) (uint64, string)        // id and the sorted field of an item
+	after func(id uint64, value string) T // the last item of a previous page
+}
+
+var (
+	authorOrder = keyOrder[models.Author]{
+		sort:  "sortName",
+		key:   func(a models.Author) (uint64, string) { return a.ID, a.SortName },
+		after: func(id uint64, value string) models.Author { return models.Author{ID: id, SortName: value} },
+	}
+	workOrder = keyOrder[models.Work]{
+		sort:  "title",
+		key:   func(w models.Work) (uint64, string) { return w.ID, w.Title },
+		after: func(id uint64, value string) models.Work { return models.Work{ID: id, Title: value} },
+	}
+	seriesOrder = keyOrder[models.Series]{
+		sort:  "name",
+		key:   func(s models.Series) (uint64, string) { return s.ID, s.Name },
+		after: func(id uint64, value string) models.Series { return models.Series{ID: id, Name: value} },
+	}
+)
+
+// encodeKeyCursor returns opaque string that points after an item in an order
+func encodeKeyCursor[T any](order keyOrder[T], last T) string {
+	id, value := order.key(last)
+	raw, _ := json.Marshal(cursor{ID: id, Sort: order.sort, Value: value})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeKeyCursor parses a string that was made by encodeKeyCursor with the same order,
+// an empty string is the beginning of a list, so it returns nil
+func decodeKeyCursor[T any](order keyOrder[T], s string) (*T, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errMalformedCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == 0 {
+		return nil, errMalformedCursor
+	}
+	if c.Sort != order.sort {
+		return nil, errForeignCursor
+	}
+	last := order.after(c.ID, c.Value)
+	return &last, nil
+}
+
+// keyPage cuts one more item that was taken to know whether there is a next page
+// and returns a cursor after the last item of the page when there is one
+func keyPage[T any](order keyOrder[T], found []T, limit int) ([]T, string) {
+	if len(found) <= limit {
+		return found, ""
+	}
+	return found[:limit], encodeKeyCursor(order, found[limit-1])
+}
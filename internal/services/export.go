@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// ExportBooks returns all books that match a query one by one, in order of the query.
+// Limit of the query is optional, zero means all books.
+// Books are streamed from the storage, so an export of any size takes constant memory.
+// An error of the iterator is *apperrors.AppError
+func (s *BookService) ExportBooks(ctx context.Context, query models.BookQuery) (iter.Seq2[models.Book, error], *apperrors.AppError) {
+	if query.SortBy != "" && !query.SortBy.Valid() {
+		return nil, apperrors.NewAppError(400, "invalid sort field", fmt.Errorf("cannot sort by %q", query.SortBy)).WithCode("invalid_sort")
+	}
+	if query.Limit < 0 {
+		return nil, apperrors.NewAppError(400, "invalid limit", errors.New("limit cannot be negative"))
+	}
+	query.After = nil
+
+	return func(yield func(models.Book, error) bool) {
+		for book, err := range s.storage.Stream(ctx, query) {
+			if err != nil {
+				s.logger.Info("Error export books", "error", err)
+				yield(models.Book{}, storageError(err, 500, "error export books"))
+				return
+			}
+			if !yield(book, nil) {
+				return
+			}
+		}
+	}, nil
+}
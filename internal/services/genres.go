@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/validations"
+)
+
+// genresNotSupported is returned when a storage doesn't keep genres
+func genresNotSupported() *apperrors.AppError {
+	return apperrors.NewAppError(http.StatusNotImplemented, "genres are not supported by the storage", nil).WithCode("genres_not_supported")
+}
+
+// GetGenres returns the whole genre tree, genres of one level are ordered by name
+func (s *BookService) GetGenres(ctx context.Context) (models.GenreTree, *apperrors.AppError) {
+	genres, ok := s.storage.(abstraction.GenreStorage)
+	if !ok {
+		return models.GenreTree{}, genresNotSupported()
+	}
+
+	all, err := genres.Genres(ctx)
+	if err != nil {
+		s.logger.Info("Error getting genres", "error", err)
+		return models.GenreTree{}, genreError(err, 500, "error getting genres")
+	}
+	return models.GenreTree{Genres: genreChildren(all, nil)}, nil
+}
+
+// GetGenre return a genre by id with all its subgenres
+func (s *BookService) GetGenre(ctx context.Context, id uint64) (models.GenreNode, *apperrors.AppError) {
+	genres, ok := s.storage.(abstraction.GenreStorage)
+	if !ok {
+		return models.GenreNode{}, genresNotSupported()
+	}
+
+	genre, err := genres.GetGenre(ctx, id)
+	if err != nil {
+		s.logger.Info("Failed to get genre by ID", "id", id, "error", err)
+		return models.GenreNode{}, genreError(err, 500, "failed to get a genre")
+	}
+	all, err := genres.Genres(ctx)
+	if err != nil {
+		s.logger.Info("Error getting genres", "error", err)
+		return models.GenreNode{}, genreError(err, 500, "failed to get a genre")
+	}
+	return models.GenreNode{Genre: genre, Children: genreChildren(all, &genre.ID)}, nil
+}
+
+// CreateGenre validates and saves a new genre, CreatedAt must be set.
+// An empty slug is made from the name
+func (s *BookService) CreateGenre(ctx context.Context, genre models.Genre) (models.Genre, *apperrors.AppError) {
+	if _, ok := s.storage.(abstraction.GenreStorage); !ok {
+		return models.Genre{}, genresNotSupported()
+	}
+	if err := validations.ValidateGenre(genre); err != nil {
+		return models.Genre{}, apperrors.NewAppError(400, "invalid genre data", err)
+	}
+	if genre.Slug == "" {
+		genre.Slug = models.Slugify(genre.Name)
+	}
+	genre.ID = 0
+	genre.UpdatedAt = genre.CreatedAt
+
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		genres := tx.(abstraction.GenreStorage)
+		if err := checkGenreParent(ctx, genres, genre); err != nil {
+			return err
+		}
+		var err error
+		genre, err = genres.SaveGenre(ctx, genre)
+		return err
+	})
+	if err != nil {
+		s.logger.Info("Error save a genre", "error", err)
+		return models.Genre{}, genreError(err, 500, "faild to create a genre")
+	}
+	return genre, nil
+}
+
+// ReplaceGenre replaces a genre by id (PUT semantics), UpdatedAt must be set.
+// A genre might be moved to another parent, but not under itself
+func (s *BookService) ReplaceGenre(ctx context.Context, id uint64, genre models.Genre) (models.Genre, *apperrors.AppError) {
+	if _, ok := s.storage.(abstraction.GenreStorage); !ok {
+		return models.Genre{}, genresNotSupported()
+	}
+	if genre.ID != 0 && genre.ID != id {
+		return models.Genre{}, apperrors.NewAppError(400, "invalid genre id",
+			errors.New("id in body doesn't match id in path")).WithCode("id_mismatch")
+	}
+	if err := validations.ValidateGenre(genre); err != nil {
+		return models.Genre{}, apperrors.NewAppError(400, "invalid genre data", err)
+	}
+	if genre.Slug == "" {
+		genre.Slug = models.Slugify(genre.Name)
+	}
+	genre.ID = id
+
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		genres := tx.(abstraction.GenreStorage)
+		old, err := genres.GetGenre(ctx, id)
+		if err != nil {
+			return err
+		}
+		genre.CreatedAt = old.CreatedAt
+		if err := checkGenreParent(ctx, genres, genre); err != nil {
+			return err
+		}
+		if err := genres.UpdateGenre(ctx, genre); err != nil {
+			return err
+		}
+		if genre.Name == old.Name && genre.Slug == old.Slug {
+			return nil
+		}
+		// books show names and slugs of their genres, books of subgenres are changed too
+		ids, err := bookIDs(ctx, tx, models.BookQuery{GenreID: id})
+		if err != nil {
+			return err
+		}
+		return touchBooks(ctx, tx, ids, genre.UpdatedAt)
+	})
+	if err != nil {
+		s.logger.Info("faild to replace a genre", "id", id, "error", err)
+		return models.Genre{}, genreError(err, 500, "error replace a genre")
+	}
+	return genre, nil
+}
+
+// DeleteGenre delete a genre, a genre with books or subgenres cannot be deleted
+func (s *BookService) DeleteGenre(ctx context.Context, id uint64) *apperrors.AppError {
+	genres, ok := s.storage.(abstraction.GenreStorage)
+	if !ok {
+		return genresNotSupported()
+	}
+
+	if err := genres.DeleteGenre(ctx, id); err != nil {
+		s.logger.Info("Failed to delete genre", "id", id, "error", err)
+		return genreError(err, 500, "Failed to delete genre")
+	}
+	return nil
+}
+
+// GetGenreBooks returns one page of books of a genre and all its subgenres,
+// like GetBooks does
+func (s *BookService) GetGenreBooks(ctx context.Context, id uint64, query models.BookQuery, pageCursor string) (models.BookPage, *apperrors.AppError) {
+	genres, ok := s.storage.(abstraction.GenreStorage)
+	if !ok {
+		return models.BookPage{}, genresNotSupported()
+	}
+	if _, err := genres.GetGenre(ctx, id); err != nil {
+		s.logger.Info("Failed to get genre by ID", "id", id, "error", err)
+		return models.BookPage{}, genreError(err, 500, "failed to get a genre")
+	}
+	query.GenreID = id
+	return s.GetBooks(ctx, query, pageCursor)
+}
+
+// SetBookGenres replaces genres of a book.
+// The genre string of the book is not changed, but its version is increased
+// in the same transaction, UpdatedAt must be set
+func (s *BookService) SetBookGenres(ctx context.Context, bookID uint64, req models.SetBookGenresRequest) (models.Book, *apperrors.AppError) {
+	if _, ok := s.storage.(abstraction.GenreStorage); !ok {
+		return models.Book{}, genresNotSupported()
+	}
+	if req.GenreIDs == nil {
+		return models.Book{}, apperrors.NewAppError(400, "invalid book genres",
+			errors.New("genreIds is required")).WithCode("invalid_book_genres")
+	}
+
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		if err := tx.(abstraction.GenreStorage).SetBookGenres(ctx, bookID, req.GenreIDs); err != nil {
+			return err
+		}
+		return touchBooks(ctx, tx, []uint64{bookID}, req.UpdatedAt)
+	})
+	if err != nil {
+		s.logger.Info("Failed to set book genres", "id", bookID, "error", err)
+		return models.Book{}, genreError(err, 500, "failed to set book genres")
+	}
+	return s.GetBook(ctx, bookID)
+}
+
+// checkGenreParent walks up from a parent of a genre, the parent must exist
+// and the genre cannot be its own ancestor
+func checkGenreParent(ctx context.Context, genres abstraction.GenreStorage, genre models.Genre) error {
+	for parentID := genre.ParentID; parentID != nil; {
+		if *parentID == genre.ID {
+			return apperrors.NewAppError(400, "invalid genre parent",
+				errors.New("a genre cannot be under itself")).WithCode("genre_cycle")
+		}
+		parent, err := genres.GetGenre(ctx, *parentID)
+		if errors.Is(err, apperrors.ErrGenreNotFound) {
+			return apperrors.NewAppError(400, "invalid genre parent", err).WithCode("invalid_genre_parent")
+		}
+		if err != nil {
+			return err
+		}
+		parentID = parent.ParentID
+	}
+	return nil
+}
+
+// genreChildren builds subtrees of genres under a parent, nil is roots.
+// genres are ordered by name, so children are too
+func genreChildren(genres []models.Genre, parentID *uint64) []models.GenreNode {
+	nodes := []models.GenreNode{}
+	for _, genre := range genres {
+		if (parentID == nil) != (genre.ParentID == nil) || parentID != nil && *parentID != *genre.ParentID {
+			continue
+		}
+		nodes = append(nodes, models.GenreNode{Genre: genre, Children: genreChildren(genres, &genre.ID)})
+	}
+	return nodes
+}
+
+// linkGenres tags a book with a genre from its genre string,
+// it is called in the transaction that wrote the book.
+// A storage without genres has nothing to link
+func linkGenres(ctx context.Context, tx abstraction.Storage, book models.Book) error {
+	genres, ok := tx.(abstraction.GenreStorage)
+	if !ok {
+		return nil
+	}
+	return genres.LinkGenres(ctx, []uint64{book.General.ID}, book.UpdatedAt)
+}
+
+// withGenres sets genres of books from the storage
+func (s *BookService) withGenres(ctx context.Context, books []models.Book) *apperrors.AppError {
+	genres, ok := s.storage.(abstraction.GenreStorage)
+	if !ok || len(books) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, len(books))
+	for i, book := range books {
+		ids[i] = book.General.ID
+	}
+	bookGenres, err := genres.BookGenres(ctx, ids)
+	if err != nil {
+		s.logger.Info("Error getting book genres", "error", err)
+		return storageError(err, 500, "error getting book genres")
+	}
+	for i := range books {
+		books[i].Genres = bookGenres[books[i].General.ID]
+	}
+	return nil
+}
+
+// genreError is storageError for genres,
+// a missing genre, a used slug or a genre in use have their own codes
+func genreError(err error, code int, msg string) *apperrors.AppError {
+	// an error of checkGenreParent wraps ErrGenreNotFound too
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	switch {
+	case errors.Is(err, apperrors.ErrGenreNotFound):
+		return apperrors.NewAppError(http.StatusNotFound, "genre not found", err).WithCode("genre_not_found")
+	case errors.Is(err, apperrors.ErrSlugConflict):
+		return apperrors.NewAppError(http.StatusConflict, "genre with the slug already exists", err).WithCode("genre_exists")
+	case errors.Is(err, apperrors.ErrGenreInUse):
+		return apperrors.NewAppError(http.StatusConflict, "genre has books or subgenres", err).WithCode("genre_in_use")
+	}
+	return storageError(err, code, msg)
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/bookimport"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/validations"
+)
+
+const (
+	// ImportChunkSize is how many books are written to a storage at once
+	ImportChunkSize = 1000
+	// MaxReportedErrors is how many row errors a report has, all of them are in the error file
+	MaxReportedErrors = 100
+)
+
+// ImportOptions are options of an import of books
+type ImportOptions struct {
+	DryRun    bool      // rows are only validated, nothing is written
+	CreatedAt time.Time // createdAt of imported books
+	Errors    io.Writer // rejected rows are written here as CSV, it might be nil
+}
+
+// ImportBooks reads books from a file of a media type (bookimport.CSVType or bookimport.JSONType)
+// row by row, validates every row and writes valid books to a storage in chunks.
+// Invalid rows are rejected and don't stop the import, an ISBN that is used twice in the file
+// or by a stored book is rejected too (when its chunk is written). If the file is broken,
+// reading is stopped and the report is not complete.
+// Every chunk is its own transaction, so if the storage fails, written chunks stay
+// and the report of them is returned with the error, it is not complete
+func (s *BookService) ImportBooks(ctx context.Context, mediaType string, file io.Reader, opts ImportOptions) (models.ImportReport, *apperrors.AppError) {
+	rows, err := bookimport.Rows(mediaType, file)
+	if err != nil {
+		return models.ImportReport{}, apperrors.NewAppError(http.StatusUnsupportedMediaType, "unsupported file type", err).WithCode("unsupported_import_type")
+	}
+
+	var errorWriter *bookimport.ErrorWriter
+	if opts.Errors != nil {
+		errorWriter = bookimport.NewErrorWriter(opts.Errors)
+	}
+
+	report := models.ImportReport{DryRun: opts.DryRun, Complete: true}
+	// fail stops the import, a client learns from the report what was written before
+	fail := func(appErr *apperrors.AppError) (models.ImportReport, *apperrors.AppError) {
+		report.Complete = false
+		if errorWriter != nil {
+			if err := errorWriter.Flush(); err != nil {
+				s.logger.Error("Failed to write import errors", "error", err)
+			}
+		}
+		return report, appErr
+	}
+	reject := func(row bookimport.Row, errs []apperrors.FieldError) *apperrors.AppError {
+		report.Rejected++
+		if len(report.Errors) < MaxReportedErrors {
+			report.Errors = append(report.Errors, models.ImportRowError{Row: row.Number, Errors: errs})
+		}
+		if errorWriter != nil {
+			if err := errorWriter.Write(row, errs); err != nil {
+				s.logger.Error("Failed to write an import error", "error", err)
+				return apperrors.NewAppError(500, "error import books", err)
+			}
+		}
+		return nil
+	}
+
+	// rows of books in the chunk, a book with a stored ISBN is rejected by its row
+	chunk := make([]models.Book, 0, ImportChunkSize)
+	chunkRows := make([]bookimport.Row, 0, ImportChunkSize)
+	flush := func() *apperrors.AppError {
+		if len(chunk) == 0 {
+			return nil
+		}
+		existing, err := s.existingISBNs(ctx, chunk)
+		if err != nil {
+			s.logger.Error("Failed to check isbns of imported books", "error", err)
+			return storageError(err, 500, "error import books")
+		}
+		valid := chunk[:0]
+		for i, book := range chunk {
+			if existing[book.General.ISBN] {
+				appErr := reject(chunkRows[i], []apperrors.FieldError{{Field: "isbn", Rule: bookimport.RuleUnique,
+					Message: "isbn: " + book.General.ISBN + " is used by another book"}})
+				if appErr != nil {
+					return appErr
+				}
+				continue
+			}
+			valid = append(valid, book)
+		}
+		report.Accepted += len(valid)
+		chunk, chunkRows = chunk[:0], chunkRows[:0]
+
+		if opts.DryRun || len(valid) == 0 {
+			return nil
+		}
+		imported, err := s.saveMany(ctx, valid)
+		if err != nil {
+			s.logger.Error("Failed to import books", "error", err, "imported", report.Imported)
+			return storageError(err, 500, "error import books")
+		}
+		report.Imported += int(imported)
+		return nil
+	}
+	// ISBNs of the file and their rows, a row cannot have an ISBN of another one
+	isbnRows := make(map[string]int)
+
+	for row, err := range rows {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fail(apperrors.NewAppError(http.StatusRequestEntityTooLarge, "file is too large", err).WithCode("file_too_large"))
+		}
+		if errors.Is(err, bookimport.ErrMalformed) {
+			// the rest of the file cannot be read
+			report.Complete = false
+			if row.Number > 0 {
+				report.Rows++
+			}
+			appErr := reject(row, []apperrors.FieldError{{Rule: bookimport.RuleSyntax, Message: err.Error()}})
+			if appErr != nil {
+				return fail(appErr)
+			}
+			break
+		}
+		report.Rows++
+
+		var rowErr *bookimport.RowError
+		if errors.As(err, &rowErr) {
+			if appErr := reject(row, rowErr.Errors); appErr != nil {
+				return fail(appErr)
+			}
+			continue
+		}
+
+		// the same validation as a single book has
+		var fieldErrs []apperrors.FieldError
+		var validateErr *apperrors.ValidateErr
+		if err := validations.Validate(row); errors.As(err, &validateErr) {
+			fieldErrs = validateErr.Details
+		}
+		book, dateErrs := row.Book(opts.CreatedAt)
+		fieldErrs = append(fieldErrs, dateErrs...)
+		if len(fieldErrs) > 0 {
+			if appErr := reject(row, fieldErrs); appErr != nil {
+				return fail(appErr)
+			}
+			continue
+		}
+		normalizeISBN(&book.General)
+		if isbn := book.General.ISBN; isbn != "" {
+			if first, ok := isbnRows[isbn]; ok {
+				appErr := reject(row, []apperrors.FieldError{{Field: "isbn", Rule: bookimport.RuleUnique,
+					Message: fmt.Sprintf("isbn: %s is used by row %d", isbn, first)}})
+				if appErr != nil {
+					return fail(appErr)
+				}
+				continue
+			}
+			isbnRows[isbn] = row.Number
+		}
+
+		chunk = append(chunk, book)
+		chunkRows = append(chunkRows, row)
+		if len(chunk) == ImportChunkSize {
+			if appErr := flush(); appErr != nil {
+				return fail(appErr)
+			}
+		}
+	}
+
+	if appErr := flush(); appErr != nil {
+		return fail(appErr)
+	}
+	if ctx.Err() != nil {
+		return fail(storageError(ctx.Err(), 500, "error import books"))
+	}
+	// imported books get authors, works and genres at once, chunks are not linked one by one
+	if authors, ok := s.storage.(abstraction.AuthorStorage); ok && report.Imported > 0 {
+		if err := authors.LinkAuthors(ctx, nil, opts.CreatedAt); err != nil {
+			s.logger.Error("Failed to link authors of imported books", "error", err)
+			return fail(storageError(err, 500, "error import books"))
+		}
+	}
+	if works, ok := s.storage.(abstraction.WorkStorage); ok && report.Imported > 0 {
+		if err := works.LinkWorks(ctx, opts.CreatedAt); err != nil {
+			s.logger.Error("Failed to link works of imported books", "error", err)
+			return fail(storageError(err, 500, "error import books"))
+		}
+	}
+	if genres, ok := s.storage.(abstraction.GenreStorage); ok && report.Imported > 0 {
+		if err := genres.LinkGenres(ctx, nil, opts.CreatedAt); err != nil {
+			s.logger.Error("Failed to link genres of imported books", "error", err)
+			return fail(storageError(err, 500, "error import books"))
+		}
+	}
+	if errorWriter != nil {
+		if err := errorWriter.Flush(); err != nil {
+			s.logger.Error("Failed to write import errors", "error", err)
+			report.Complete = false
+			return report, apperrors.NewAppError(500, "error import books", err)
+		}
+	}
+
+	return report, nil
+}
+
+// saveMany writes books at once if the storage is BulkSaver,
+// otherwise one by one in a transaction
+func (s *BookService) saveMany(ctx context.Context, books []models.Book) (int64, error) {
+	if saver, ok := s.storage.(abstraction.BulkSaver); ok {
+		return saver.SaveMany(ctx, books)
+	}
+
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		for _, book := range books {
+			if _, err := tx.Save(ctx, book); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(books)), nil
+}
+
+// existingISBNs returns which ISBNs of books are already stored, by one query
+// if the storage is BulkSaver, otherwise one by one
+func (s *BookService) existingISBNs(ctx context.Context, books []models.Book) (map[string]bool, error) {
+	isbns := make([]string, 0, len(books))
+	for _, book := range books {
+		if book.General.ISBN != "" {
+			isbns = append(isbns, book.General.ISBN)
+		}
+	}
+	existing := make(map[string]bool)
+	if len(isbns) == 0 {
+		return existing, nil
+	}
+
+	if saver, ok := s.storage.(abstraction.BulkSaver); ok {
+		found, err := saver.ExistingISBNs(ctx, isbns)
+		if err != nil {
+			return nil, err
+		}
+		for _, isbn := range found {
+			existing[isbn] = true
+		}
+		return existing, nil
+	}
+
+	for _, isbn := range isbns {
+		_, err := s.storage.GetByISBN(ctx, isbn)
+		switch {
+		case err == nil:
+			existing[isbn] = true
+		case !errors.Is(err, apperrors.ErrNotFound):
+			return nil, err
+		}
+	}
+	return existing, nil
+}
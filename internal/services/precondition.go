@@ -0,0 +1,31 @@
+package services
+
+import (
+	"slices"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// Precondition is a condition of a client (If-Match header)
+// that a book must satisfy before it is changed.
+// nil Precondition means there is no condition
+type Precondition struct {
+	Any      bool     // If-Match: *, a book only must exist
+	Versions []uint64 // a book must have one of these versions
+	None     bool     // If-None-Match: *, a book must not exist
+}
+
+// check reports if a book satisfies a condition,
+// exists is false when there is no such book
+func (p *Precondition) check(book models.Book, exists bool) bool {
+	if p == nil {
+		return true
+	}
+	if p.None {
+		return !exists
+	}
+	if !exists {
+		return false
+	}
+	return p.Any || slices.Contains(p.Versions, book.Version)
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/validations"
+)
+
+// seriesNotSupported is returned when a storage doesn't keep series
+func seriesNotSupported() *apperrors.AppError {
+	return apperrors.NewAppError(http.StatusNotImplemented, "series are not supported by the storage", nil).WithCode("series_not_supported")
+}
+
+// GetAllSeries returns one page of series ordered by name, without volumes.
+// If limit is zero DefaultPageSize is used, it is never more than MaxPageSize
+func (s *BookService) GetAllSeries(ctx context.Context, limit int, pageCursor string) (models.SeriesPage, *apperrors.AppError) {
+	series, ok := s.storage.(abstraction.SeriesStorage)
+	if !ok {
+		return models.SeriesPage{}, seriesNotSupported()
+	}
+	limit, appErr := pageLimit(limit)
+	if appErr != nil {
+		return models.SeriesPage{}, appErr
+	}
+
+	after, err := decodeKeyCursor(seriesOrder, pageCursor)
+	if err != nil {
+		return models.SeriesPage{}, apperrors.NewAppError(400, "invalid cursor", err).WithCode("invalid_cursor")
+	}
+
+	// it takes one more series to know whether there is a next page
+	found, err := series.FindSeries(ctx, models.SeriesQuery{After: after, Limit: limit + 1})
+	if err != nil {
+		s.logger.Info("Error getting series", "error", err)
+		return models.SeriesPage{}, seriesError(err, 500, "error getting series")
+	}
+
+	var page models.SeriesPage
+	page.Series, page.NextCursor = keyPage(seriesOrder, found, limit)
+	return page, nil
+}
+
+// GetSeries return a series by id with its volumes ordered by position
+func (s *BookService) GetSeries(ctx context.Context, id uint64) (models.Series, *apperrors.AppError) {
+	if _, ok := s.storage.(abstraction.SeriesStorage); !ok {
+		return models.Series{}, seriesNotSupported()
+	}
+
+	// the series and its volumes are read in one transaction,
+	// so volumes are never of a series that was changed in between
+	var found models.Series
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		series := tx.(abstraction.SeriesStorage)
+		var err error
+		if found, err = series.GetSeries(ctx, id); err != nil {
+			return err
+		}
+		found.Volumes, err = series.SeriesVolumes(ctx, id)
+		return err
+	})
+	if err != nil {
+		s.logger.Info("Failed to get series by ID", "id", id, "error", err)
+		return models.Series{}, seriesError(err, 500, "failed to get a series")
+	}
+	if found.Volumes == nil {
+		found.Volumes = []models.SeriesVolume{}
+	}
+	return found, nil
+}
+
+// CreateSeries validates and saves a new series, CreatedAt must be set.
+// Books are placed in a series by SetBookSeries
+func (s *BookService) CreateSeries(ctx context.Context, series models.Series) (models.Series, *apperrors.AppError) {
+	storage, ok := s.storage.(abstraction.SeriesStorage)
+	if !ok {
+		return models.Series{}, seriesNotSupported()
+	}
+	if err := validations.ValidateSeries(series); err != nil {
+		return models.Series{}, apperrors.NewAppError(400, "invalid series data", err)
+	}
+	series.ID = 0
+	series.UpdatedAt = series.CreatedAt
+	series.Volumes = nil
+
+	saved, err := storage.SaveSeries(ctx, series)
+	if err != nil {
+		s.logger.Error("Error save a series", "error", err)
+		return models.Series{}, seriesError(err, 500, "faild to create a series")
+	}
+	return saved, nil
+}
+
+// ReplaceSeries replaces a series by id (PUT semantics), UpdatedAt must be set.
+// Series are created only by POST, so a missing series is 404. Volumes are not changed
+func (s *BookService) ReplaceSeries(ctx context.Context, id uint64, series models.Series) (models.Series, *apperrors.AppError) {
+	if _, ok := s.storage.(abstraction.SeriesStorage); !ok {
+		return models.Series{}, seriesNotSupported()
+	}
+	if series.ID != 0 && series.ID != id {
+		return models.Series{}, apperrors.NewAppError(400, "invalid series id",
+			errors.New("id in body doesn't match id in path")).WithCode("id_mismatch")
+	}
+	if err := validations.ValidateSeries(series); err != nil {
+		return models.Series{}, apperrors.NewAppError(400, "invalid series data", err)
+	}
+	series.ID = id
+	series.Volumes = nil
+
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		storage := tx.(abstraction.SeriesStorage)
+		old, err := storage.GetSeries(ctx, id)
+		if err != nil {
+			return err
+		}
+		series.CreatedAt = old.CreatedAt
+		if err := storage.UpdateSeries(ctx, series); err != nil {
+			return err
+		}
+		if series.Name == old.Name {
+			return nil
+		}
+		// volumes show the name of their series
+		volumes, err := storage.SeriesVolumes(ctx, id)
+		if err != nil {
+			return err
+		}
+		ids := make([]uint64, len(volumes))
+		for i, volume := range volumes {
+			ids[i] = volume.ID
+		}
+		return touchBooks(ctx, tx, ids, series.UpdatedAt)
+	})
+	if err != nil {
+		s.logger.Info("faild to replace a series", "id", id, "error", err)
+		return models.Series{}, seriesError(err, 500, "error replace a series")
+	}
+	return series, nil
+}
+
+// DeleteSeries delete a series, a series with volumes cannot be deleted
+func (s *BookService) DeleteSeries(ctx context.Context, id uint64) *apperrors.AppError {
+	series, ok := s.storage.(abstraction.SeriesStorage)
+	if !ok {
+		return seriesNotSupported()
+	}
+
+	if err := series.DeleteSeries(ctx, id); err != nil {
+		s.logger.Info("Failed to delete series", "id", id, "error", err)
+		return seriesError(err, 500, "Failed to delete series")
+	}
+	return nil
+}
+
+// SetBookSeries places a book at a position of a series,
+// a book that was in another series is moved. UpdatedAt must be set
+func (s *BookService) SetBookSeries(ctx context.Context, bookID uint64, req models.SetBookSeriesRequest) (models.Book, *apperrors.AppError) {
+	if _, ok := s.storage.(abstraction.SeriesStorage); !ok {
+		return models.Book{}, seriesNotSupported()
+	}
+	if err := validations.ValidateBookSeries(req); err != nil {
+		return models.Book{}, apperrors.NewAppError(400, "invalid book series", err)
+	}
+
+	if err := s.placeBook(ctx, bookID, req.SeriesID, *req.Position, req.UpdatedAt); err != nil {
+		s.logger.Info("Failed to set book series", "id", bookID, "error", err)
+		return models.Book{}, seriesError(err, 500, "failed to set book series")
+	}
+	return s.GetBook(ctx, bookID)
+}
+
+// RemoveBookSeries takes a book out of its series at updatedAt,
+// a book that is not in a series is not changed
+func (s *BookService) RemoveBookSeries(ctx context.Context, bookID uint64, updatedAt time.Time) (models.Book, *apperrors.AppError) {
+	if _, ok := s.storage.(abstraction.SeriesStorage); !ok {
+		return models.Book{}, seriesNotSupported()
+	}
+
+	if err := s.placeBook(ctx, bookID, 0, 0, updatedAt); err != nil {
+		s.logger.Info("Failed to remove book series", "id", bookID, "error", err)
+		return models.Book{}, seriesError(err, 500, "failed to remove book series")
+	}
+	return s.GetBook(ctx, bookID)
+}
+
+// placeBook places a book in a series or takes it out with zero seriesID.
+// A book shows its neighbours and they show it, so versions of the book,
+// its old and its new neighbours are increased in the same transaction
+func (s *BookService) placeBook(ctx context.Context, bookID, seriesID uint64, position float64, updatedAt time.Time) error {
+	return s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		series := tx.(abstraction.SeriesStorage)
+		before, err := series.BookSeries(ctx, []uint64{bookID})
+		if err != nil {
+			return err
+		}
+		if seriesID == 0 && len(before) == 0 {
+			return nil
+		}
+		if err := series.SetBookSeries(ctx, bookID, seriesID, position); err != nil {
+			return err
+		}
+		after, err := series.BookSeries(ctx, []uint64{bookID})
+		if err != nil {
+			return err
+		}
+
+		ids := append(neighbourIDs(before[bookID]), neighbourIDs(after[bookID])...)
+		return touchBooks(ctx, tx, append(ids, bookID), updatedAt)
+	})
+}
+
+// touchNeighbours increases versions of neighbours of a book in its series,
+// they show its title, so it is called when the book is renamed or deleted
+func touchNeighbours(ctx context.Context, tx abstraction.Storage, bookID uint64, updatedAt time.Time) error {
+	series, ok := tx.(abstraction.SeriesStorage)
+	if !ok {
+		return nil
+	}
+	found, err := series.BookSeries(ctx, []uint64{bookID})
+	if err != nil {
+		return err
+	}
+	return touchBooks(ctx, tx, neighbourIDs(found[bookID]), updatedAt)
+}
+
+// neighbourIDs returns ids of the previous and the next volumes,
+// a book without a series has no neighbours
+func neighbourIDs(series models.BookSeries) []uint64 {
+	var ids []uint64
+	if series.Previous != nil {
+		ids = append(ids, series.Previous.ID)
+	}
+	if series.Next != nil {
+		ids = append(ids, series.Next.ID)
+	}
+	return ids
+}
+
+// withSeries sets series of books with their neighbours from the storage
+func (s *BookService) withSeries(ctx context.Context, books []models.Book) *apperrors.AppError {
+	series, ok := s.storage.(abstraction.SeriesStorage)
+	if !ok || len(books) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, len(books))
+	for i, book := range books {
+		ids[i] = book.General.ID
+	}
+	bookSeries, err := series.BookSeries(ctx, ids)
+	if err != nil {
+		s.logger.Info("Error getting book series", "error", err)
+		return storageError(err, 500, "error getting book series")
+	}
+	for i := range books {
+		if found, ok := bookSeries[books[i].General.ID]; ok {
+			books[i].Series = &found
+		}
+	}
+	return nil
+}
+
+// seriesError is storageError for series, a missing series,
+// a series with volumes or a taken position have their own codes
+func seriesError(err error, code int, msg string) *apperrors.AppError {
+	switch {
+	case errors.Is(err, apperrors.ErrSeriesNotFound):
+		return apperrors.NewAppError(http.StatusNotFound, "series not found", err).WithCode("series_not_found")
+	case errors.Is(err, apperrors.ErrSeriesHasVolumes):
+		return apperrors.NewAppError(http.StatusConflict, "series has volumes", err).WithCode("series_has_volumes")
+	case errors.Is(err, apperrors.ErrPositionConflict):
+		return apperrors.NewAppError(http.StatusConflict, "another book is at the position", err).WithCode("position_taken")
+	}
+	return storageError(err, code, msg)
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/validations"
+)
+
+// worksNotSupported is returned when a storage doesn't keep works
+func worksNotSupported() *apperrors.AppError {
+	return apperrors.NewAppError(http.StatusNotImplemented, "works are not supported by the storage", nil).WithCode("works_not_supported")
+}
+
+// GetWorks returns one page of works ordered by title.
+// If limit is zero DefaultPageSize is used, it is never more than MaxPageSize
+func (s *BookService) GetWorks(ctx context.Context, limit int, pageCursor string) (models.WorkPage, *apperrors.AppError) {
+	works, ok := s.storage.(abstraction.WorkStorage)
+	if !ok {
+		return models.WorkPage{}, worksNotSupported()
+	}
+	limit, appErr := pageLimit(limit)
+	if appErr != nil {
+		return models.WorkPage{}, appErr
+	}
+
+	after, err := decodeKeyCursor(workOrder, pageCursor)
+	if err != nil {
+		return models.WorkPage{}, apperrors.NewAppError(400, "invalid cursor", err).WithCode("invalid_cursor")
+	}
+
+	// it takes one more work to know whether there is a next page
+	found, err := works.FindWorks(ctx, models.WorkQuery{After: after, Limit: limit + 1})
+	if err != nil {
+		s.logger.Info("Error getting works", "error", err)
+		return models.WorkPage{}, workError(err, 500, "error getting works")
+	}
+
+	var page models.WorkPage
+	page.Works, page.NextCursor = keyPage(workOrder, found, limit)
+	return page, nil
+}
+
+// GetWork return a work by id
+func (s *BookService) GetWork(ctx context.Context, id uint64) (models.Work, *apperrors.AppError) {
+	works, ok := s.storage.(abstraction.WorkStorage)
+	if !ok {
+		return models.Work{}, worksNotSupported()
+	}
+
+	work, err := works.GetWork(ctx, id)
+	if err != nil {
+		s.logger.Info("Failed to get work by ID", "id", id, "error", err)
+		return models.Work{}, workError(err, 500, "failed to get a work")
+	}
+	return work, nil
+}
+
+// CreateWork validates and saves a new work, CreatedAt must be set
+func (s *BookService) CreateWork(ctx context.Context, work models.Work) (models.Work, *apperrors.AppError) {
+	works, ok := s.storage.(abstraction.WorkStorage)
+	if !ok {
+		return models.Work{}, worksNotSupported()
+	}
+	if err := validations.ValidateWork(work); err != nil {
+		return models.Work{}, apperrors.NewAppError(400, "invalid work data", err)
+	}
+	work.ID = 0
+	work.UpdatedAt = work.CreatedAt
+
+	saved, err := works.SaveWork(ctx, work)
+	if err != nil {
+		s.logger.Error("Error save a work", "error", err)
+		return models.Work{}, workError(err, 500, "faild to create a work")
+	}
+	return saved, nil
+}
+
+// ReplaceWork replaces a work by id (PUT semantics), UpdatedAt must be set.
+// Works are created only by POST, so a missing work is 404.
+// Titles of editions are not changed, a translation might have another one
+func (s *BookService) ReplaceWork(ctx context.Context, id uint64, work models.Work) (models.Work, *apperrors.AppError) {
+	if _, ok := s.storage.(abstraction.WorkStorage); !ok {
+		return models.Work{}, worksNotSupported()
+	}
+	if work.ID != 0 && work.ID != id {
+		return models.Work{}, apperrors.NewAppError(400, "invalid work id",
+			errors.New("id in body doesn't match id in path")).WithCode("id_mismatch")
+	}
+	if err := validations.ValidateWork(work); err != nil {
+		return models.Work{}, apperrors.NewAppError(400, "invalid work data", err)
+	}
+	work.ID = id
+
+	err := s.storage.WithTx(ctx, func(tx abstraction.Storage) error {
+		works := tx.(abstraction.WorkStorage)
+		old, err := works.GetWork(ctx, id)
+		if err != nil {
+			return err
+		}
+		work.CreatedAt = old.CreatedAt
+		return works.UpdateWork(ctx, work)
+	})
+	if err != nil {
+		s.logger.Info("faild to replace a work", "id", id, "error", err)
+		return models.Work{}, workError(err, 500, "error replace a work")
+	}
+	return work, nil
+}
+
+// DeleteWork delete a work, a work with editions cannot be deleted
+func (s *BookService) DeleteWork(ctx context.Context, id uint64) *apperrors.AppError {
+	works, ok := s.storage.(abstraction.WorkStorage)
+	if !ok {
+		return worksNotSupported()
+	}
+
+	if err := works.DeleteWork(ctx, id); err != nil {
+		s.logger.Info("Failed to delete work", "id", id, "error", err)
+		return workError(err, 500, "Failed to delete work")
+	}
+	return nil
+}
+
+// GetWorkEditions returns one page of editions of a work, like GetBooks does
+func (s *BookService) GetWorkEditions(ctx context.Context, id uint64, query models.BookQuery, pageCursor string) (models.BookPage, *apperrors.AppError) {
+	if _, appErr := s.GetWork(ctx, id); appErr != nil {
+		return models.BookPage{}, appErr
+	}
+	query.WorkID = id
+	return s.GetBooks(ctx, query, pageCursor)
+}
+
+// CreateEdition creates a book that is an edition of a work,
+// an empty title or author is taken from the work
+func (s *BookService) CreateEdition(ctx context.Context, id uint64, book models.CreateBookRequest) (models.Book, *apperrors.AppError) {
+	work, appErr := s.GetWork(ctx, id)
+	if appErr != nil {
+		return models.Book{}, appErr
+	}
+	if book.Book.WorkID != 0 && book.Book.WorkID != id {
+		return models.Book{}, apperrors.NewAppError(400, "invalid work id",
+			errors.New("workId in body doesn't match id in path")).WithCode("id_mismatch")
+	}
+	if book.Book.Title == "" {
+		book.Book.Title = work.Title
+	}
+	if book.Book.Author == "" {
+		book.Book.Author = work.Author
+	}
+	book.Book.WorkID = id
+	return s.CreateBook(ctx, book)
+}
+
+// linkWork makes a book an edition before it is written, it is called
+// in the transaction that writes the book, old is an empty book for a new one.
+// A book without workId keeps its work or gets a work with its title and author.
+// A storage without works cannot keep a workId, it is 501 and not dropped silently
+func linkWork(ctx context.Context, tx abstraction.Storage, old models.Book, book *models.Book) error {
+	works, ok := tx.(abstraction.WorkStorage)
+	if !ok {
+		if book.General.WorkID != 0 {
+			return worksNotSupported()
+		}
+		return nil
+	}
+
+	switch {
+	case book.General.WorkID == 0 && old.General.WorkID != 0:
+		book.General.WorkID = old.General.WorkID
+	case book.General.WorkID == 0:
+		work, err := works.EnsureWork(ctx, models.Work{
+			Title:     book.General.Title,
+			Author:    book.General.Author,
+			CreatedAt: book.UpdatedAt,
+			UpdatedAt: book.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		book.General.WorkID = work.ID
+	case book.General.WorkID != old.General.WorkID:
+		_, err := works.GetWork(ctx, book.General.WorkID)
+		if errors.Is(err, apperrors.ErrWorkNotFound) {
+			return apperrors.NewAppError(400, "invalid book data", err).WithCode("invalid_work")
+		}
+		return err
+	}
+	return nil
+}
+
+// workError is storageError for works,
+// a missing work or a work with editions have their own codes
+func workError(err error, code int, msg string) *apperrors.AppError {
+	switch {
+	case errors.Is(err, apperrors.ErrWorkNotFound):
+		return apperrors.NewAppError(http.StatusNotFound, "work not found", err).WithCode("work_not_found")
+	case errors.Is(err, apperrors.ErrWorkHasEditions):
+		return apperrors.NewAppError(http.StatusConflict, "work has editions", err).WithCode("work_has_editions")
+	}
+	return storageError(err, code, msg)
+}
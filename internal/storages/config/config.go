@@ -26,7 +26,18 @@ const (
 	db_life         = "DB_CONN_MAX_LIFETIME"
 	db_idle_time    = "DB_CONN_MAX_IDLE_TIME"
 	db_timeout      = "DB_TIMEOUT"
+	db_tx_timeout   = "DB_TX_TIMEOUT"
 	db_health_check = "DB_HEALTH_CHECK_PERIOD"
+	storage_driver  = "STORAGE_DRIVER"
+	storage_file    = "STORAGE_FILE"
+)
+
+// storage drivers
+const (
+	DriverPostgres = "postgres" // PostgreSQL, it is used by default
+	DriverMemory   = "memory"   // in-memory storage, data is lost after restart
+	DriverJson     = "json"     // json file storage, see FilePath
+	DriverSqlite   = "sqlite"   // embedded SQLite database, see FilePath
 )
 
 // default values
@@ -42,7 +53,11 @@ const (
 	df_lifetime            = time.Hour
 	df_lifeidletime        = 30 * time.Minute
 	df_timeout             = 5 * time.Second
+	df_tx_timeout          = time.Minute
 	df_health_check_period = time.Minute
+	df_driver              = DriverPostgres
+	df_json_file           = "data/books.json"
+	df_sqlite_file         = "data/books.db"
 )
 
 // DatabaseConfig
@@ -57,12 +72,23 @@ type DatabaseConfig struct {
 	MinConns          int
 	ConnMaxLifeTime   time.Duration
 	ConnMaxIdleTime   time.Duration
-	Timeout           time.Duration
+	Timeout           time.Duration // of one statement
+	TxTimeout         time.Duration // of a whole transaction, zero is no limit
 	HealthCheckPeriod time.Duration
+	Driver            string // which storage is used: postgres, memory, json, sqlite
+	FilePath          string // path to a file for file storages
 }
 
 // LoadConfig returns data base config
 func LoadConfig() *DatabaseConfig {
+	driver := getEnv(storage_driver, df_driver)
+
+	// every file storage has its own default file
+	defaultFile := df_json_file
+	if driver == DriverSqlite {
+		defaultFile = df_sqlite_file
+	}
+
 	return &DatabaseConfig{
 		Host:              getEnv(db_host, df_host),
 		Port:              getEnvAsInt(db_port, df_port),
@@ -75,7 +101,10 @@ func LoadConfig() *DatabaseConfig {
 		ConnMaxLifeTime:   getEnvAsDuration(db_life, df_lifetime),
 		ConnMaxIdleTime:   getEnvAsDuration(db_idle_time, df_lifeidletime),
 		Timeout:           getEnvAsDuration(db_timeout, df_timeout),
+		TxTimeout:         getEnvAsDuration(db_tx_timeout, df_tx_timeout),
 		HealthCheckPeriod: getEnvAsDuration(db_health_check, df_health_check_period),
+		Driver:            driver,
+		FilePath:          getEnv(storage_file, defaultFile),
 	}
 }
 
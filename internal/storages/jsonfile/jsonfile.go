@@ -0,0 +1,556 @@
+// jsonfile contains JsonStorage, a storage that keeps books in a json file.
+// It is made for small deployments (kiosks, demos) where PostgreSQL cannot run.
+//
+// Every change rewrites the whole file: data is written to a temporary file
+// in the same directory, synced to disk and then renamed over the old one.
+// Rename is atomic, so after a crash the file contains either the old
+// or the new data, but never a torn mix of both.
+//
+// It keeps only books with their edition fields, authors, genres, works and series
+// are not supported, so their routes and a book with a workId respond 501.
+package jsonfile
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// ErrClosed is returned when someone uses a storage after Close
+var ErrClosed = errors.New("storage is closed")
+
+// fileData is what is actually written to the file
+type fileData struct {
+	NextID uint64        `json:"nextId"` // works like SERIAL in PostgreSQL
+	Books  []models.Book `json:"books"`
+}
+
+// JsonStorage implemented Storage interface.
+// It is safe for concurrent use inside one process,
+// but the file must not be shared between several processes.
+type JsonStorage struct {
+	mu     sync.RWMutex
+	path   string
+	books  map[uint64]models.Book
+	nextID uint64
+	inTx   bool // changes of a transaction are written by WithTx at the end
+	closed bool
+	logger abstraction.Logger
+}
+
+// NewJsonStorage opens a json file storage, if the file doesn't exist
+// it will be created with the first change
+func NewJsonStorage(path string, logger abstraction.Logger) (*JsonStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	s := &JsonStorage{
+		path:   path,
+		books:  make(map[uint64]models.Book),
+		nextID: 1,
+		logger: logger,
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	// a temporary file might be left after a crash, it is useless now
+	s.removeTempFiles()
+
+	return s, nil
+}
+
+// GetAll return all books from storage ordered by id
+func (s *JsonStorage) GetAll(ctx context.Context) ([]models.Book, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.sortedBooks(s.books), nil
+}
+
+// Find return books that match a query in its order
+func (s *JsonStorage) Find(ctx context.Context, q models.BookQuery) ([]models.Book, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	books := make([]models.Book, 0, q.Limit)
+	for _, book := range s.books {
+		if q.Match(book) {
+			books = append(books, book)
+		}
+	}
+	slices.SortFunc(books, q.Compare)
+
+	if q.Limit > 0 && len(books) > q.Limit {
+		books = books[:q.Limit]
+	}
+	return books, nil
+}
+
+// Stream returns books that match a query one by one.
+// Books are already in memory, so they are taken by Find and the lock
+// is not held while a caller handles them
+func (s *JsonStorage) Stream(ctx context.Context, q models.BookQuery) iter.Seq2[models.Book, error] {
+	return func(yield func(models.Book, error) bool) {
+		books, err := s.Find(ctx, q)
+		if err != nil {
+			yield(models.Book{}, err)
+			return
+		}
+		for _, book := range books {
+			if err := ctx.Err(); err != nil {
+				yield(models.Book{}, err)
+				return
+			}
+			if !yield(book, nil) {
+				return
+			}
+		}
+	}
+}
+
+// GetById return a book by id
+func (s *JsonStorage) GetById(ctx context.Context, id uint64) (models.Book, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return models.Book{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Book{}, err
+	}
+
+	book, ok := s.books[id]
+	if !ok {
+		return models.Book{}, fmt.Errorf("book with id %d %w", id, apperrors.ErrBookNotFound)
+	}
+	return book, nil
+}
+
+// GetByISBN return a book by its normalized ISBN
+func (s *JsonStorage) GetByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return models.Book{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Book{}, err
+	}
+
+	for _, book := range s.books {
+		if isbn != "" && book.General.ISBN == isbn {
+			return book, nil
+		}
+	}
+	return models.Book{}, fmt.Errorf("book with isbn %s %w", isbn, apperrors.ErrBookNotFound)
+}
+
+// Save add a book to the file and returns it with a new id,
+// an id of the given book is ignored and a new one is taken from the sequence
+func (s *JsonStorage) Save(ctx context.Context, book models.Book) (models.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return models.Book{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Book{}, err
+	}
+
+	if err := s.checkISBN(book); err != nil {
+		return models.Book{}, err
+	}
+
+	books := maps.Clone(s.books)
+	book.General.ID = s.nextID
+	book.Version = 1
+	books[book.General.ID] = book
+
+	if err := s.commit(books, s.nextID+1); err != nil {
+		s.logger.Error("Failed to save book", "error", err)
+		return models.Book{}, fmt.Errorf("failed to save book: %w", err)
+	}
+
+	return book, nil
+}
+
+// SaveWithID add a book with its own id, the sequence
+// is moved forward, so Save never generates the same id
+func (s *JsonStorage) SaveWithID(ctx context.Context, book models.Book) (models.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return models.Book{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Book{}, err
+	}
+
+	if _, ok := s.books[book.General.ID]; ok {
+		return models.Book{}, fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrConflict)
+	}
+	if err := s.checkISBN(book); err != nil {
+		return models.Book{}, err
+	}
+
+	books := maps.Clone(s.books)
+	book.Version = 1
+	books[book.General.ID] = book
+
+	if err := s.commit(books, max(s.nextID, book.General.ID+1)); err != nil {
+		s.logger.Error("Failed to save book", "error", err)
+		return models.Book{}, fmt.Errorf("failed to save book: %w", err)
+	}
+
+	return book, nil
+}
+
+// Update update a book in the file and increases its version
+func (s *JsonStorage) Update(ctx context.Context, book models.Book) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	old, ok := s.books[book.General.ID]
+	if !ok {
+		return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrBookNotFound)
+	}
+	if err := s.checkISBN(book); err != nil {
+		return err
+	}
+
+	books := maps.Clone(s.books)
+	// created_at is never changed by update, the same as in PostgresStorage
+	book.CreatedAt = old.CreatedAt
+	book.Version = old.Version + 1
+	books[book.General.ID] = book
+
+	if err := s.commit(books, s.nextID); err != nil {
+		s.logger.Error("Failed to update book", "error", err)
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateFields update only given fields and updated_at of a book
+func (s *JsonStorage) UpdateFields(ctx context.Context, book models.Book, fields []models.BookField) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	old, ok := s.books[book.General.ID]
+	if !ok {
+		return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrBookNotFound)
+	}
+
+	for _, field := range fields {
+		switch field {
+		case models.FieldTitle:
+			old.General.Title = book.General.Title
+		case models.FieldAuthor:
+			old.General.Author = book.General.Author
+		case models.FieldGenre:
+			old.General.Genre = book.General.Genre
+		case models.FieldPublicationDate:
+			old.General.PublicationDate = book.General.PublicationDate
+		case models.FieldISBN:
+			if err := s.checkISBN(book); err != nil {
+				return err
+			}
+			old.General.ISBN = book.General.ISBN
+		case models.FieldWorkID:
+			old.General.WorkID = book.General.WorkID
+		case models.FieldPublisher:
+			old.General.Publisher = book.General.Publisher
+		case models.FieldEdition:
+			old.General.Edition = book.General.Edition
+		case models.FieldFormat:
+			old.General.Format = book.General.Format
+		case models.FieldPageCount:
+			old.General.PageCount = book.General.PageCount
+		case models.FieldLanguage:
+			old.General.Language = book.General.Language
+		default:
+			return fmt.Errorf("cannot update field %q", field)
+		}
+	}
+	old.UpdatedAt = book.UpdatedAt
+	old.Version++
+	books := maps.Clone(s.books)
+	books[book.General.ID] = old
+
+	if err := s.commit(books, s.nextID); err != nil {
+		s.logger.Error("Failed to update book", "error", err)
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+
+	return nil
+}
+
+// Delete delete a book by id
+func (s *JsonStorage) Delete(ctx context.Context, id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := s.books[id]; !ok {
+		return fmt.Errorf("book with id: %d %w", id, apperrors.ErrBookNotFound)
+	}
+
+	books := maps.Clone(s.books)
+	delete(books, id)
+
+	if err := s.commit(books, s.nextID); err != nil {
+		s.logger.Error("Failed to delete a book", "error", err)
+		return fmt.Errorf("failed to delete a book: %w", err)
+	}
+
+	return nil
+}
+
+// WithTx runs fn in a transaction. The storage is locked while fn works
+// and fn changes a copy of data that is written to the file once at the end.
+// fn must use only tx, the storage itself is locked and would wait forever
+func (s *JsonStorage) WithTx(ctx context.Context, fn func(tx abstraction.Storage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx := &JsonStorage{
+		path:   s.path,
+		books:  maps.Clone(s.books),
+		nextID: s.nextID,
+		inTx:   true,
+		logger: s.logger,
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := s.commit(tx.books, tx.nextID); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Close close a storage, all data is already on disk
+func (s *JsonStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.books = nil
+	return nil
+}
+
+// there are helpers
+
+// load reads the file into memory
+func (s *JsonStorage) load() error {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read storage file: %w", err)
+	}
+
+	var data fileData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("failed to parse storage file: %w", err)
+	}
+
+	for _, book := range data.Books {
+		// files written before versions were added
+		if book.Version == 0 {
+			book.Version = 1
+		}
+		s.books[book.General.ID] = book
+		// protect the sequence from a file that was edited by hand
+		if book.General.ID >= data.NextID {
+			data.NextID = book.General.ID + 1
+		}
+	}
+	s.nextID = max(data.NextID, 1)
+
+	return nil
+}
+
+// commit writes new state to the file and only if it succeeded
+// replaces the state in memory, so memory never differs from disk.
+// The file is replaced by the rename, so a failed sync of the directory
+// after it doesn't fail the commit, it is only logged
+func (s *JsonStorage) commit(books map[uint64]models.Book, nextID uint64) error {
+	// a transaction writes everything at once in WithTx
+	if s.inTx {
+		s.books = books
+		s.nextID = nextID
+		return nil
+	}
+
+	data := fileData{
+		NextID: nextID,
+		Books:  s.sortedBooks(books),
+	}
+
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode books: %w", err)
+	}
+
+	if err := writeFileAtomic(s.path, raw); err != nil {
+		return err
+	}
+
+	s.books = books
+	s.nextID = nextID
+
+	// otherwise the rename itself might be lost after a power failure
+	if err := syncDir(filepath.Dir(s.path)); err != nil {
+		s.logger.Warn("Storage file is replaced but might not be durable", "file", s.path, "error", err)
+	}
+	return nil
+}
+
+// checkISBN returns ErrISBNConflict when another book has the same ISBN,
+// like the unique index of databases. A caller holds the lock
+func (s *JsonStorage) checkISBN(book models.Book) error {
+	if book.General.ISBN == "" {
+		return nil
+	}
+	for id, other := range s.books {
+		if id != book.General.ID && other.General.ISBN == book.General.ISBN {
+			return fmt.Errorf("book with isbn: %s %w", book.General.ISBN, apperrors.ErrISBNConflict)
+		}
+	}
+	return nil
+}
+
+// sortedBooks returns books ordered by id
+func (s *JsonStorage) sortedBooks(books map[uint64]models.Book) []models.Book {
+	result := make([]models.Book, 0, len(books))
+	for _, book := range books {
+		result = append(result, book)
+	}
+	slices.SortFunc(result, func(a, b models.Book) int {
+		return cmp.Compare(a.General.ID, b.General.ID)
+	})
+	return result
+}
+
+// removeTempFiles deletes temporary files that were left after a crash
+func (s *JsonStorage) removeTempFiles() {
+	matches, err := filepath.Glob(s.path + ".tmp-*")
+	if err != nil {
+		return
+	}
+	for _, match := range matches {
+		if err := os.Remove(match); err != nil {
+			s.logger.Warn("Failed to remove temporary file", "file", match, "error", err)
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file, syncs it
+// and renames it to path. The directory isn't synced,
+// a caller does it with syncDir after the file is replaced
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	// it does nothing when rename succeeded
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace storage file: %w", err)
+	}
+	return nil
+}
+
+// syncDir flushes a directory entry to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open storage directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync storage directory: %w", err)
+	}
+	return nil
+}
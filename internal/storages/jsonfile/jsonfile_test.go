@@ -0,0 +1,140 @@
+package jsonfile
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// Test data
+var (
+	testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	testTime   = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newTestBook(title string) models.Book {
+	return models.Book{
+		General: models.GeneralBook{
+			Title:           title,
+			Genre:           "Programming",
+			Author:          "Robert C. Martin",
+			PublicationDate: testTime,
+		},
+		CreatedAt: testTime,
+		UpdatedAt: testTime,
+	}
+}
+
+func TestJsonStorage_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "books.json")
+
+	s, err := NewJsonStorage(path, testLogger)
+	if err != nil {
+		t.Fatalf("Unexpected error opening storage: %v", err)
+	}
+	s.Save(t.Context(), newTestBook("Clean Code"))
+	s.Save(t.Context(), newTestBook("Clean Architecture"))
+	s.Delete(t.Context(), 2)
+	s.Close()
+
+	// data must survive a restart and the sequence must not be reused
+	s, err = NewJsonStorage(path, testLogger)
+	if err != nil {
+		t.Fatalf("Unexpected error reopening storage: %v", err)
+	}
+	defer s.Close()
+
+	books, err := s.GetAll(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpected error getting all books: %v", err)
+	}
+	if len(books) != 1 || books[0].General.Title != "Clean Code" {
+		t.Errorf("Expected only Clean Code after reopen, got: %+v", books)
+	}
+
+	s.Save(t.Context(), newTestBook("Refactoring"))
+	if _, err := s.GetById(t.Context(), 3); err != nil {
+		t.Errorf("Expected a new id 3 from the sequence, got error: %v", err)
+	}
+}
+
+func TestJsonStorage_NotFound(t *testing.T) {
+	s, err := NewJsonStorage(filepath.Join(t.TempDir(), "books.json"), testLogger)
+	if err != nil {
+		t.Fatalf("Unexpected error opening storage: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.GetById(t.Context(), 42); err == nil {
+		t.Error("Expected error for missing book, got nil")
+	}
+	if err := s.Delete(t.Context(), 42); err == nil {
+		t.Error("Expected error deleting missing book, got nil")
+	}
+}
+
+func TestJsonStorage_RemovesTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "books.json")
+
+	// it is what a crash in the middle of writing leaves behind
+	leftover := path + ".tmp-12345"
+	if err := os.WriteFile(leftover, []byte(`{"nextId": 1, "bo`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewJsonStorage(path, testLogger)
+	if err != nil {
+		t.Fatalf("Unexpected error opening storage: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Errorf("Expected temporary file to be removed, got: %v", err)
+	}
+}
+
+func TestJsonStorage_CorruptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "books.json")
+	if err := os.WriteFile(path, []byte("not a json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewJsonStorage(path, testLogger); err == nil {
+		t.Error("Expected error for corrupted file, got nil")
+	}
+}
+
+func TestJsonStorage_WithTx(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "books.json")
+	s, err := NewJsonStorage(path, testLogger)
+	if err != nil {
+		t.Fatalf("Unexpected error opening storage: %v", err)
+	}
+	defer s.Close()
+	s.Save(t.Context(), newTestBook("Clean Code"))
+
+	err = s.WithTx(t.Context(), func(tx abstraction.Storage) error {
+		tx.Delete(t.Context(), 1)
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("Expected error of fn, got nil")
+	}
+
+	// the file is not changed by a failed transaction
+	reopened, err := NewJsonStorage(path, testLogger)
+	if err != nil {
+		t.Fatalf("Unexpected error reopening storage: %v", err)
+	}
+	if _, err := reopened.GetById(t.Context(), 1); err != nil {
+		t.Errorf("Expected book to survive rolled back transaction, got error: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// FindAuthors return authors ordered by sort name and id
+func (m *MemoryStorage) FindAuthors(ctx context.Context, q models.AuthorQuery) ([]models.Author, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	authors := make([]models.Author, 0, q.Limit)
+	for _, author := range m.authors {
+		if q.After == nil || compareAuthors(author, *q.After) > 0 {
+			authors = append(authors, cloneAuthor(author))
+		}
+	}
+	slices.SortFunc(authors, compareAuthors)
+
+	if q.Limit > 0 && len(authors) > q.Limit {
+		authors = authors[:q.Limit]
+	}
+	return authors, nil
+}
+
+// GetAuthor return an author by id
+func (m *MemoryStorage) GetAuthor(ctx context.Context, id uint64) (models.Author, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return models.Author{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Author{}, err
+	}
+
+	author, ok := m.authors[id]
+	if !ok {
+		return models.Author{}, fmt.Errorf("author with id %d %w", id, apperrors.ErrAuthorNotFound)
+	}
+	return cloneAuthor(author), nil
+}
+
+// SaveAuthor add an author and returns it with a new id
+func (m *MemoryStorage) SaveAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return models.Author{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Author{}, err
+	}
+
+	return m.saveAuthor(author), nil
+}
+
+// UpdateAuthor update an author, created_at is never changed
+func (m *MemoryStorage) UpdateAuthor(ctx context.Context, author models.Author) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	old, ok := m.authors[author.ID]
+	if !ok {
+		return fmt.Errorf("author with id: %d %w", author.ID, apperrors.ErrAuthorNotFound)
+	}
+	author = cloneAuthor(author)
+	author.CreatedAt = old.CreatedAt
+	m.authors[author.ID] = author
+
+	return nil
+}
+
+// DeleteAuthor delete an author who has no books and works
+func (m *MemoryStorage) DeleteAuthor(ctx context.Context, id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := m.authors[id]; !ok {
+		return fmt.Errorf("author with id: %d %w", id, apperrors.ErrAuthorNotFound)
+	}
+	for _, authorIDs := range m.bookAuthors {
+		if slices.Contains(authorIDs, id) {
+			return fmt.Errorf("author with id: %d %w", id, apperrors.ErrAuthorHasBooks)
+		}
+	}
+	for _, authorIDs := range m.workAuthors {
+		if slices.Contains(authorIDs, id) {
+			return fmt.Errorf("author with id: %d %w", id, apperrors.ErrAuthorHasBooks)
+		}
+	}
+	delete(m.authors, id)
+
+	return nil
+}
+
+// SetBookAuthors replaces authors of a book, a repeated author is kept once
+func (m *MemoryStorage) SetBookAuthors(ctx context.Context, bookID uint64, authorIDs []uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := m.books[bookID]; !ok {
+		return fmt.Errorf("book with id: %d %w", bookID, apperrors.ErrBookNotFound)
+	}
+	for _, id := range authorIDs {
+		if _, ok := m.authors[id]; !ok {
+			return fmt.Errorf("author with id: %d %w", id, apperrors.ErrAuthorNotFound)
+		}
+	}
+	m.setBookAuthors(bookID, authorIDs)
+
+	return nil
+}
+
+// BookAuthors return authors of books in order of credits
+func (m *MemoryStorage) BookAuthors(ctx context.Context, bookIDs []uint64) (map[uint64][]models.BookAuthor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint64][]models.BookAuthor, len(bookIDs))
+	for _, bookID := range bookIDs {
+		for _, id := range m.bookAuthors[bookID] {
+			result[bookID] = append(result[bookID], models.BookAuthor{ID: id, Name: m.authors[id].Name})
+		}
+	}
+	return result, nil
+}
+
+// LinkAuthors links books to authors by their author strings
+func (m *MemoryStorage) LinkAuthors(ctx context.Context, bookIDs []uint64, createdAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if bookIDs == nil {
+		for id := range m.books {
+			if len(m.bookAuthors[id]) == 0 {
+				bookIDs = append(bookIDs, id)
+			}
+		}
+		// authors are created in the same order every time
+		slices.Sort(bookIDs)
+	}
+
+	for _, bookID := range bookIDs {
+		book, ok := m.books[bookID]
+		if !ok {
+			continue
+		}
+		m.setBookAuthors(bookID, m.authorIDs(book.General.Author, createdAt))
+	}
+
+	return nil
+}
+
+// there are helpers, a caller holds the lock
+
+func (m *MemoryStorage) saveAuthor(author models.Author) models.Author {
+	author = cloneAuthor(author)
+	author.ID = m.nextAuthorID
+	m.nextAuthorID++
+	m.authors[author.ID] = author
+	return cloneAuthor(author)
+}
+
+// authorIDs returns ids of authors of an author string in order,
+// a missing author is created with createdAt
+func (m *MemoryStorage) authorIDs(author string, createdAt time.Time) []uint64 {
+	var ids []uint64
+	for _, name := range models.SplitAuthors(author) {
+		found, ok := m.authorByName(name)
+		if !ok {
+			found = m.saveAuthor(models.Author{Name: name, SortName: models.SortName(name), CreatedAt: createdAt, UpdatedAt: createdAt})
+		}
+		ids = append(ids, found.ID)
+	}
+	return ids
+}
+
+// authorByName returns the first author with a name in any case
+func (m *MemoryStorage) authorByName(name string) (models.Author, bool) {
+	var found models.Author
+	for _, author := range m.authors {
+		if strings.EqualFold(author.Name, name) && (found.ID == 0 || author.ID < found.ID) {
+			found = author
+		}
+	}
+	return found, found.ID != 0
+}
+
+// setBookAuthors stores a new slice without repeated authors
+func (m *MemoryStorage) setBookAuthors(bookID uint64, authorIDs []uint64) {
+	ids := uniqueIDs(authorIDs)
+	if len(ids) == 0 {
+		delete(m.bookAuthors, bookID)
+		return
+	}
+	m.bookAuthors[bookID] = ids
+}
+
+// uniqueIDs returns a new slice of ids, a repeated id is kept at its first position
+func uniqueIDs(authorIDs []uint64) []uint64 {
+	ids := make([]uint64, 0, len(authorIDs))
+	for _, id := range authorIDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// compareAuthors orders authors by sort name and id
+func compareAuthors(a, b models.Author) int {
+	if c := strings.Compare(a.SortName, b.SortName); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// cloneAuthor returns a copy of an author that doesn't share years with the storage
+func cloneAuthor(author models.Author) models.Author {
+	if author.BirthYear != nil {
+		year := *author.BirthYear
+		author.BirthYear = &year
+	}
+	if author.DeathYear != nil {
+		year := *author.DeathYear
+		author.DeathYear = &year
+	}
+	return author
+}
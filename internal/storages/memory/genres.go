@@ -0,0 +1,319 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// Genres return all genres ordered by name and id
+func (m *MemoryStorage) Genres(ctx context.Context) ([]models.Genre, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	genres := make([]models.Genre, 0, len(m.genres))
+	for _, genre := range m.genres {
+		genres = append(genres, cloneGenre(genre))
+	}
+	slices.SortFunc(genres, compareGenres)
+	return genres, nil
+}
+
+// GetGenre return a genre by id
+func (m *MemoryStorage) GetGenre(ctx context.Context, id uint64) (models.Genre, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return models.Genre{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Genre{}, err
+	}
+
+	genre, ok := m.genres[id]
+	if !ok {
+		return models.Genre{}, fmt.Errorf("genre with id %d %w", id, apperrors.ErrGenreNotFound)
+	}
+	return cloneGenre(genre), nil
+}
+
+// SaveGenre add a genre and returns it with a new id
+func (m *MemoryStorage) SaveGenre(ctx context.Context, genre models.Genre) (models.Genre, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return models.Genre{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Genre{}, err
+	}
+
+	genre.ID = 0
+	if err := m.checkGenre(genre); err != nil {
+		return models.Genre{}, err
+	}
+	return m.saveGenre(genre), nil
+}
+
+// UpdateGenre update a genre, created_at is never changed
+func (m *MemoryStorage) UpdateGenre(ctx context.Context, genre models.Genre) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	old, ok := m.genres[genre.ID]
+	if !ok {
+		return fmt.Errorf("genre with id: %d %w", genre.ID, apperrors.ErrGenreNotFound)
+	}
+	if err := m.checkGenre(genre); err != nil {
+		return err
+	}
+	genre = cloneGenre(genre)
+	genre.CreatedAt = old.CreatedAt
+	m.genres[genre.ID] = genre
+
+	return nil
+}
+
+// DeleteGenre delete a genre without books and subgenres
+func (m *MemoryStorage) DeleteGenre(ctx context.Context, id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := m.genres[id]; !ok {
+		return fmt.Errorf("genre with id: %d %w", id, apperrors.ErrGenreNotFound)
+	}
+	for _, genre := range m.genres {
+		if genre.ParentID != nil && *genre.ParentID == id {
+			return fmt.Errorf("genre with id: %d %w", id, apperrors.ErrGenreInUse)
+		}
+	}
+	for _, genreIDs := range m.bookGenres {
+		if slices.Contains(genreIDs, id) {
+			return fmt.Errorf("genre with id: %d %w", id, apperrors.ErrGenreInUse)
+		}
+	}
+	delete(m.genres, id)
+
+	return nil
+}
+
+// SetBookGenres replaces genres of a book, a repeated genre is kept once
+func (m *MemoryStorage) SetBookGenres(ctx context.Context, bookID uint64, genreIDs []uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := m.books[bookID]; !ok {
+		return fmt.Errorf("book with id: %d %w", bookID, apperrors.ErrBookNotFound)
+	}
+	for _, id := range genreIDs {
+		if _, ok := m.genres[id]; !ok {
+			return fmt.Errorf("genre with id: %d %w", id, apperrors.ErrGenreNotFound)
+		}
+	}
+	m.setBookGenres(bookID, genreIDs)
+
+	return nil
+}
+
+// BookGenres return genres of books ordered by name
+func (m *MemoryStorage) BookGenres(ctx context.Context, bookIDs []uint64) (map[uint64][]models.BookGenre, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint64][]models.BookGenre, len(bookIDs))
+	for _, bookID := range bookIDs {
+		genres := make([]models.Genre, 0, len(m.bookGenres[bookID]))
+		for _, id := range m.bookGenres[bookID] {
+			genres = append(genres, m.genres[id])
+		}
+		slices.SortFunc(genres, compareGenres)
+		for _, genre := range genres {
+			result[bookID] = append(result[bookID], models.BookGenre{ID: genre.ID, Slug: genre.Slug, Name: genre.Name})
+		}
+	}
+	return result, nil
+}
+
+// LinkGenres tags books with a genre by their genre strings
+func (m *MemoryStorage) LinkGenres(ctx context.Context, bookIDs []uint64, createdAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if bookIDs == nil {
+		for id := range m.books {
+			if len(m.bookGenres[id]) == 0 {
+				bookIDs = append(bookIDs, id)
+			}
+		}
+		// genres are created in the same order every time
+		slices.Sort(bookIDs)
+	}
+
+	for _, bookID := range bookIDs {
+		book, ok := m.books[bookID]
+		if !ok {
+			continue
+		}
+		var genreIDs []uint64
+		name := strings.TrimSpace(book.General.Genre)
+		if slug := models.Slugify(name); slug != "" {
+			genre, ok := m.genreBySlug(slug)
+			if !ok {
+				genre = m.saveGenre(models.Genre{Slug: slug, Name: name, CreatedAt: createdAt, UpdatedAt: createdAt})
+			}
+			genreIDs = append(genreIDs, genre.ID)
+		}
+		m.setBookGenres(bookID, genreIDs)
+	}
+
+	return nil
+}
+
+// there are helpers, a caller holds the lock
+
+func (m *MemoryStorage) saveGenre(genre models.Genre) models.Genre {
+	genre = cloneGenre(genre)
+	genre.ID = m.nextGenreID
+	m.nextGenreID++
+	m.genres[genre.ID] = genre
+	return cloneGenre(genre)
+}
+
+// checkGenre returns ErrSlugConflict when another genre has the same slug
+// and ErrGenreNotFound when a parent doesn't exist, like constraints of databases
+func (m *MemoryStorage) checkGenre(genre models.Genre) error {
+	if other, ok := m.genreBySlug(genre.Slug); ok && other.ID != genre.ID {
+		return fmt.Errorf("genre with slug %s %w", genre.Slug, apperrors.ErrSlugConflict)
+	}
+	if genre.ParentID != nil {
+		if _, ok := m.genres[*genre.ParentID]; !ok {
+			return fmt.Errorf("parent genre with id %d %w", *genre.ParentID, apperrors.ErrGenreNotFound)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStorage) genreBySlug(slug string) (models.Genre, bool) {
+	for _, genre := range m.genres {
+		if genre.Slug == slug {
+			return genre, true
+		}
+	}
+	return models.Genre{}, false
+}
+
+// subgenres returns ids of a genre and all its descendants
+func (m *MemoryStorage) subgenres(id uint64) map[uint64]bool {
+	ids := map[uint64]bool{id: true}
+	for found := true; found; {
+		found = false
+		for _, genre := range m.genres {
+			if genre.ParentID != nil && ids[*genre.ParentID] && !ids[genre.ID] {
+				ids[genre.ID] = true
+				found = true
+			}
+		}
+	}
+	return ids
+}
+
+// namedSubgenres returns ids of genres with a slug or a name and of all their descendants,
+// a slug is matched like LinkGenres does it
+func (m *MemoryStorage) namedSubgenres(name string) map[uint64]bool {
+	slug := models.Slugify(name)
+	ids := make(map[uint64]bool)
+	for _, genre := range m.genres {
+		if genre.Slug == slug || strings.EqualFold(genre.Name, name) {
+			maps.Copy(ids, m.subgenres(genre.ID))
+		}
+	}
+	return ids
+}
+
+// tagged reports whether a book is tagged with any of genres
+func (m *MemoryStorage) tagged(bookID uint64, genreIDs map[uint64]bool) bool {
+	return slices.ContainsFunc(m.bookGenres[bookID], func(id uint64) bool { return genreIDs[id] })
+}
+
+// setBookGenres stores a new slice without repeated genres
+func (m *MemoryStorage) setBookGenres(bookID uint64, genreIDs []uint64) {
+	ids := make([]uint64, 0, len(genreIDs))
+	for _, id := range genreIDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		delete(m.bookGenres, bookID)
+		return
+	}
+	m.bookGenres[bookID] = ids
+}
+
+// compareGenres orders genres by name and id
+func compareGenres(a, b models.Genre) int {
+	if c := strings.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// cloneGenre returns a copy of a genre that doesn't share a parent id with the storage
+func cloneGenre(genre models.Genre) models.Genre {
+	if genre.ParentID != nil {
+		id := *genre.ParentID
+		genre.ParentID = &id
+	}
+	return genre
+}
@@ -0,0 +1,455 @@
+// memory contains MemoryStorage, a storage that keeps books in RAM.
+// It doesn't need any database, so it is handy for development mode and tests.
+// All data is lost when the process is stopped.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// ErrClosed is returned when someone uses a storage after Close
+var ErrClosed = errors.New("storage is closed")
+
+// MemoryStorage implemented Storage interface.
+// It is safe for concurrent use.
+type MemoryStorage struct {
+	mu     sync.RWMutex
+	books  map[uint64]models.Book
+	nextID uint64 // works like SERIAL in PostgreSQL
+	closed bool
+	logger abstraction.Logger
+
+	authors      map[uint64]models.Author
+	nextAuthorID uint64
+	bookAuthors  map[uint64][]uint64 // ids of authors of a book in order, a slice is never changed in place
+
+	genres      map[uint64]models.Genre
+	nextGenreID uint64
+	bookGenres  map[uint64][]uint64 // ids of genres of a book, a slice is never changed in place
+
+	works       map[uint64]models.Work // editions of a work are books with its WorkID
+	nextWorkID  uint64
+	workAuthors map[uint64][]uint64 // ids of authors of a work in order, a slice is never changed in place
+
+	series       map[uint64]models.Series
+	nextSeriesID uint64
+	bookSeries   map[uint64]seriesPlace // a place of a book in its series
+}
+
+// NewMemoryStorage create new empty MemoryStorage
+func NewMemoryStorage(logger abstraction.Logger) *MemoryStorage {
+	return &MemoryStorage{
+		books:        make(map[uint64]models.Book),
+		nextID:       1,
+		logger:       logger,
+		authors:      make(map[uint64]models.Author),
+		nextAuthorID: 1,
+		bookAuthors:  make(map[uint64][]uint64),
+		genres:       make(map[uint64]models.Genre),
+		nextGenreID:  1,
+		bookGenres:   make(map[uint64][]uint64),
+		works:        make(map[uint64]models.Work),
+		nextWorkID:   1,
+		workAuthors:  make(map[uint64][]uint64),
+		series:       make(map[uint64]models.Series),
+		nextSeriesID: 1,
+		bookSeries:   make(map[uint64]seriesPlace),
+	}
+}
+
+// GetAll return all books from storage ordered by id
+func (m *MemoryStorage) GetAll(ctx context.Context) ([]models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	books := make([]models.Book, 0, len(m.books))
+	for _, book := range m.books {
+		books = append(books, cloneBook(book))
+	}
+	slices.SortFunc(books, func(a, b models.Book) int {
+		return cmp.Compare(a.General.ID, b.General.ID)
+	})
+
+	return books, nil
+}
+
+// Find return books that match a query in its order
+func (m *MemoryStorage) Find(ctx context.Context, q models.BookQuery) ([]models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var genreIDs, namedIDs map[uint64]bool
+	if q.GenreID != 0 {
+		genreIDs = m.subgenres(q.GenreID)
+	}
+	if q.Genre != "" {
+		// books are matched by tagged genres, not by the genre string
+		namedIDs = m.namedSubgenres(q.Genre)
+		q.Genre = ""
+	}
+
+	books := make([]models.Book, 0, q.Limit)
+	for _, book := range m.books {
+		if q.AuthorID != 0 && !slices.Contains(m.bookAuthors[book.General.ID], q.AuthorID) {
+			continue
+		}
+		if q.WorkID != 0 && book.General.WorkID != q.WorkID {
+			continue
+		}
+		if genreIDs != nil && !m.tagged(book.General.ID, genreIDs) {
+			continue
+		}
+		if namedIDs != nil && !m.tagged(book.General.ID, namedIDs) {
+			continue
+		}
+		if q.Match(book) {
+			books = append(books, cloneBook(book))
+		}
+	}
+	slices.SortFunc(books, q.Compare)
+
+	if q.Limit > 0 && len(books) > q.Limit {
+		books = books[:q.Limit]
+	}
+	return books, nil
+}
+
+// Stream returns books that match a query one by one.
+// Books are already in memory, so they are taken by Find and the lock
+// is not held while a caller handles them
+func (m *MemoryStorage) Stream(ctx context.Context, q models.BookQuery) iter.Seq2[models.Book, error] {
+	return func(yield func(models.Book, error) bool) {
+		books, err := m.Find(ctx, q)
+		if err != nil {
+			yield(models.Book{}, err)
+			return
+		}
+		for _, book := range books {
+			if err := ctx.Err(); err != nil {
+				yield(models.Book{}, err)
+				return
+			}
+			if !yield(book, nil) {
+				return
+			}
+		}
+	}
+}
+
+// GetById return a book by id
+func (m *MemoryStorage) GetById(ctx context.Context, id uint64) (models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return models.Book{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Book{}, err
+	}
+
+	book, ok := m.books[id]
+	if !ok {
+		return models.Book{}, fmt.Errorf("book with id %d %w", id, apperrors.ErrBookNotFound)
+	}
+	return cloneBook(book), nil
+}
+
+// GetByISBN return a book by its normalized ISBN
+func (m *MemoryStorage) GetByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return models.Book{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Book{}, err
+	}
+
+	for _, book := range m.books {
+		if isbn != "" && book.General.ISBN == isbn {
+			return cloneBook(book), nil
+		}
+	}
+	return models.Book{}, fmt.Errorf("book with isbn %s %w", isbn, apperrors.ErrBookNotFound)
+}
+
+// Save add a book to storage and returns it with a new id,
+// an id of the given book is ignored and a new one is taken from the sequence
+func (m *MemoryStorage) Save(ctx context.Context, book models.Book) (models.Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return models.Book{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Book{}, err
+	}
+
+	if err := m.checkISBN(book); err != nil {
+		return models.Book{}, err
+	}
+
+	book = cloneBook(book)
+	book.General.ID = m.nextID
+	book.Version = 1
+	m.nextID++
+	m.books[book.General.ID] = book
+
+	return cloneBook(book), nil
+}
+
+// SaveWithID add a book with its own id, the sequence
+// is moved forward, so Save never generates the same id
+func (m *MemoryStorage) SaveWithID(ctx context.Context, book models.Book) (models.Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return models.Book{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Book{}, err
+	}
+
+	if _, ok := m.books[book.General.ID]; ok {
+		return models.Book{}, fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrConflict)
+	}
+	if err := m.checkISBN(book); err != nil {
+		return models.Book{}, err
+	}
+
+	book = cloneBook(book)
+	book.Version = 1
+	m.books[book.General.ID] = book
+	m.nextID = max(m.nextID, book.General.ID+1)
+
+	return cloneBook(book), nil
+}
+
+// Update update a book in storage and increases its version
+func (m *MemoryStorage) Update(ctx context.Context, book models.Book) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	old, ok := m.books[book.General.ID]
+	if !ok {
+		return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrBookNotFound)
+	}
+	if err := m.checkISBN(book); err != nil {
+		return err
+	}
+
+	// created_at is never changed by update, the same as in PostgresStorage
+	book = cloneBook(book)
+	book.CreatedAt = old.CreatedAt
+	book.Version = old.Version + 1
+	m.books[book.General.ID] = book
+
+	return nil
+}
+
+// UpdateFields update only given fields and updated_at of a book
+func (m *MemoryStorage) UpdateFields(ctx context.Context, book models.Book, fields []models.BookField) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	old, ok := m.books[book.General.ID]
+	if !ok {
+		return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrBookNotFound)
+	}
+
+	for _, field := range fields {
+		switch field {
+		case models.FieldTitle:
+			old.General.Title = book.General.Title
+		case models.FieldAuthor:
+			old.General.Author = book.General.Author
+		case models.FieldGenre:
+			old.General.Genre = book.General.Genre
+		case models.FieldPublicationDate:
+			old.General.PublicationDate = book.General.PublicationDate
+		case models.FieldISBN:
+			if err := m.checkISBN(book); err != nil {
+				return err
+			}
+			old.General.ISBN = book.General.ISBN
+		case models.FieldWorkID:
+			old.General.WorkID = book.General.WorkID
+		case models.FieldPublisher:
+			old.General.Publisher = book.General.Publisher
+		case models.FieldEdition:
+			old.General.Edition = book.General.Edition
+		case models.FieldFormat:
+			old.General.Format = book.General.Format
+		case models.FieldPageCount:
+			old.General.PageCount = book.General.PageCount
+		case models.FieldLanguage:
+			old.General.Language = book.General.Language
+		default:
+			return fmt.Errorf("cannot update field %q", field)
+		}
+	}
+	old.UpdatedAt = book.UpdatedAt
+	old.Version++
+	m.books[book.General.ID] = old
+
+	return nil
+}
+
+// Delete delete a book by id
+func (m *MemoryStorage) Delete(ctx context.Context, id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := m.books[id]; !ok {
+		return fmt.Errorf("book with id: %d %w", id, apperrors.ErrBookNotFound)
+	}
+	delete(m.books, id)
+	delete(m.bookAuthors, id)
+	delete(m.bookGenres, id)
+	delete(m.bookSeries, id)
+
+	return nil
+}
+
+// WithTx runs fn in a transaction. The storage is locked while fn works
+// and fn changes a copy of data, the copy replaces data only if fn succeeded.
+// fn must use only tx, the storage itself is locked and would wait forever
+func (m *MemoryStorage) WithTx(ctx context.Context, fn func(tx abstraction.Storage) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx := &MemoryStorage{
+		books:        maps.Clone(m.books),
+		nextID:       m.nextID,
+		logger:       m.logger,
+		authors:      maps.Clone(m.authors),
+		nextAuthorID: m.nextAuthorID,
+		bookAuthors:  maps.Clone(m.bookAuthors),
+		genres:       maps.Clone(m.genres),
+		nextGenreID:  m.nextGenreID,
+		bookGenres:   maps.Clone(m.bookGenres),
+		works:        maps.Clone(m.works),
+		nextWorkID:   m.nextWorkID,
+		workAuthors:  maps.Clone(m.workAuthors),
+		series:       maps.Clone(m.series),
+		nextSeriesID: m.nextSeriesID,
+		bookSeries:   maps.Clone(m.bookSeries),
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	m.books = tx.books
+	m.nextID = tx.nextID
+	m.authors = tx.authors
+	m.nextAuthorID = tx.nextAuthorID
+	m.bookAuthors = tx.bookAuthors
+	m.genres = tx.genres
+	m.nextGenreID = tx.nextGenreID
+	m.bookGenres = tx.bookGenres
+	m.works = tx.works
+	m.nextWorkID = tx.nextWorkID
+	m.workAuthors = tx.workAuthors
+	m.series = tx.series
+	m.nextSeriesID = tx.nextSeriesID
+	m.bookSeries = tx.bookSeries
+	return nil
+}
+
+// Close close a storage and drop all data
+func (m *MemoryStorage) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	m.books = nil
+	m.authors = nil
+	m.bookAuthors = nil
+	m.genres = nil
+	m.bookGenres = nil
+	m.works = nil
+	m.workAuthors = nil
+	m.series = nil
+	m.bookSeries = nil
+	return nil
+}
+
+// there are helpers
+
+// checkISBN returns ErrISBNConflict when another book has the same ISBN,
+// like the unique index of databases. A caller holds the lock
+func (m *MemoryStorage) checkISBN(book models.Book) error {
+	if book.General.ISBN == "" {
+		return nil
+	}
+	for id, other := range m.books {
+		if id != book.General.ID && other.General.ISBN == book.General.ISBN {
+			return fmt.Errorf("book with isbn: %s %w", book.General.ISBN, apperrors.ErrISBNConflict)
+		}
+	}
+	return nil
+}
+
+// cloneBook returns a deep copy of a book, so callers
+// never share memory with the storage
+func cloneBook(book models.Book) models.Book {
+	book.Authors = slices.Clone(book.Authors)
+	return book
+}
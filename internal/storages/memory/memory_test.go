@@ -0,0 +1,493 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// Test data
+var (
+	testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	testTime   = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newTestBook(title string) models.Book {
+	return models.Book{
+		General: models.GeneralBook{
+			Title:           title,
+			Genre:           "Programming",
+			Author:          "Robert C. Martin",
+			PublicationDate: testTime,
+		},
+		CreatedAt: testTime,
+		UpdatedAt: testTime,
+	}
+}
+
+func TestMemoryStorage_SaveAndGet(t *testing.T) {
+	s := NewMemoryStorage(testLogger)
+
+	if _, err := s.Save(t.Context(), newTestBook("Clean Code")); err != nil {
+		t.Fatalf("Unexpected error saving a book: %v", err)
+	}
+	saved, err := s.Save(t.Context(), newTestBook("Clean Architecture"))
+	if err != nil {
+		t.Fatalf("Unexpected error saving a book: %v", err)
+	}
+	if saved.General.ID != 2 {
+		t.Errorf("Expected saved book to have id 2, got: %d", saved.General.ID)
+	}
+
+	book, err := s.GetById(t.Context(), 2)
+	if err != nil {
+		t.Fatalf("Expected book with id 2, got error: %v", err)
+	}
+	if book.General.Title != "Clean Architecture" {
+		t.Errorf("Expected title Clean Architecture, got: %s", book.General.Title)
+	}
+
+	books, err := s.GetAll(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpected error getting all books: %v", err)
+	}
+	if len(books) != 2 || books[0].General.ID != 1 || books[1].General.ID != 2 {
+		t.Errorf("Expected books ordered by id, got: %+v", books)
+	}
+}
+
+func TestMemoryStorage_NotFound(t *testing.T) {
+	s := NewMemoryStorage(testLogger)
+
+	if _, err := s.GetById(t.Context(), 42); err == nil {
+		t.Error("Expected error for missing book, got nil")
+	}
+	if err := s.Update(t.Context(), newTestBook("Missing")); err == nil {
+		t.Error("Expected error updating missing book, got nil")
+	}
+	if err := s.Delete(t.Context(), 42); err == nil {
+		t.Error("Expected error deleting missing book, got nil")
+	}
+}
+
+func TestMemoryStorage_UpdateKeepsCreatedAt(t *testing.T) {
+	s := NewMemoryStorage(testLogger)
+	s.Save(t.Context(), newTestBook("Clean Code"))
+
+	update := newTestBook("Clean Code 2")
+	update.General.ID = 1
+	update.CreatedAt = time.Now()
+	if err := s.Update(t.Context(), update); err != nil {
+		t.Fatalf("Unexpected error updating a book: %v", err)
+	}
+
+	book, _ := s.GetById(t.Context(), 1)
+	if book.General.Title != "Clean Code 2" {
+		t.Errorf("Expected updated title, got: %s", book.General.Title)
+	}
+	if !book.CreatedAt.Equal(testTime) {
+		t.Errorf("Expected created_at to be unchanged, got: %v", book.CreatedAt)
+	}
+	if book.Version != 2 {
+		t.Errorf("Expected version 2 after update, got: %d", book.Version)
+	}
+}
+
+func TestMemoryStorage_ISBN(t *testing.T) {
+	m := NewMemoryStorage(testLogger)
+
+	book := newTestBook("Clean Code")
+	book.General.ISBN = "9780132350884"
+	saved, err := m.Save(t.Context(), book)
+	if err != nil {
+		t.Fatalf("Unexpected error saving a book: %v", err)
+	}
+	if _, err := m.Save(t.Context(), newTestBook("Refactoring")); err != nil {
+		t.Fatalf("Unexpected error saving a book without ISBN: %v", err)
+	}
+
+	if found, err := m.GetByISBN(t.Context(), "9780132350884"); err != nil || found.General.ID != saved.General.ID {
+		t.Errorf("Expected the book by ISBN, got: %+v %v", found, err)
+	}
+	if _, err := m.GetByISBN(t.Context(), ""); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an empty ISBN, got: %v", err)
+	}
+	if _, err := m.Save(t.Context(), book); !errors.Is(err, apperrors.ErrISBNConflict) {
+		t.Errorf("Expected ErrISBNConflict, got: %v", err)
+	}
+
+	// a book keeps its own ISBN
+	saved.General.Title = "Clean Code 2"
+	if err := m.Update(t.Context(), saved); err != nil {
+		t.Errorf("Unexpected error updating a book with its ISBN: %v", err)
+	}
+}
+
+func TestMemoryStorage_Authors(t *testing.T) {
+	m := NewMemoryStorage(testLogger)
+
+	book := newTestBook("Refactoring")
+	book.General.Author = "Martin Fowler and Kent Beck"
+	book, _ = m.Save(t.Context(), book)
+	if err := m.LinkAuthors(t.Context(), nil, testTime); err != nil {
+		t.Fatalf("Unexpected error linking authors: %v", err)
+	}
+
+	authors, err := m.FindAuthors(t.Context(), models.AuthorQuery{})
+	if err != nil || len(authors) != 2 || authors[0].Name != "Kent Beck" || authors[1].SortName != "Fowler, Martin" {
+		t.Fatalf("Expected two authors ordered by sort name, got: %+v %v", authors, err)
+	}
+	beck := authors[0]
+
+	// an author is kept once
+	if err := m.SetBookAuthors(t.Context(), book.General.ID, []uint64{beck.ID, beck.ID}); err != nil {
+		t.Fatalf("Unexpected error setting authors: %v", err)
+	}
+	bookAuthors, _ := m.BookAuthors(t.Context(), []uint64{book.General.ID})
+	if names := bookAuthors[book.General.ID]; len(names) != 1 || names[0].ID != beck.ID {
+		t.Errorf("Expected only Kent Beck, got: %+v", names)
+	}
+
+	if err := m.DeleteAuthor(t.Context(), beck.ID); !errors.Is(err, apperrors.ErrAuthorHasBooks) {
+		t.Errorf("Expected ErrAuthorHasBooks, got: %v", err)
+	}
+	if err := m.Delete(t.Context(), book.General.ID); err != nil {
+		t.Fatalf("Unexpected error deleting a book: %v", err)
+	}
+	if err := m.DeleteAuthor(t.Context(), beck.ID); err != nil {
+		t.Errorf("Unexpected error deleting an author without books: %v", err)
+	}
+	if _, err := m.GetAuthor(t.Context(), beck.ID); !errors.Is(err, apperrors.ErrAuthorNotFound) {
+		t.Errorf("Expected ErrAuthorNotFound, got: %v", err)
+	}
+}
+
+func TestMemoryStorage_Genres(t *testing.T) {
+	m := NewMemoryStorage(testLogger)
+
+	book := newTestBook("Dune")
+	book.General.Genre = "Science Fiction"
+	book, _ = m.Save(t.Context(), book)
+	if err := m.LinkGenres(t.Context(), nil, testTime); err != nil {
+		t.Fatalf("Unexpected error linking genres: %v", err)
+	}
+
+	genres, err := m.Genres(t.Context())
+	if err != nil || len(genres) != 1 || genres[0].Slug != "science-fiction" {
+		t.Fatalf("Expected a genre from the book, got: %+v %v", genres, err)
+	}
+	scifi := genres[0]
+
+	fiction, err := m.SaveGenre(t.Context(), models.Genre{Slug: "fiction", Name: "Fiction", CreatedAt: testTime})
+	if err != nil {
+		t.Fatalf("Unexpected error saving a genre: %v", err)
+	}
+	scifi.ParentID = &fiction.ID
+	if err := m.UpdateGenre(t.Context(), scifi); err != nil {
+		t.Fatalf("Unexpected error updating a genre: %v", err)
+	}
+	scifi.Slug = "fiction"
+	if err := m.UpdateGenre(t.Context(), scifi); !errors.Is(err, apperrors.ErrSlugConflict) {
+		t.Errorf("Expected ErrSlugConflict, got: %v", err)
+	}
+
+	// a book of a subgenre is a book of a parent
+	books, err := m.Find(t.Context(), models.BookQuery{GenreID: fiction.ID})
+	if err != nil || len(books) != 1 {
+		t.Errorf("Expected the book of the subgenre, got: %+v %v", books, err)
+	}
+	// the genre filter is a slug or a name with subgenres, not the genre string
+	books, err = m.Find(t.Context(), models.BookQuery{Genre: "FICTION"})
+	if err != nil || len(books) != 1 {
+		t.Errorf("Expected the book of the subgenre by a name, got: %+v %v", books, err)
+	}
+	books, err = m.Find(t.Context(), models.BookQuery{Genre: "fiction", GenreID: scifi.ID})
+	if err != nil || len(books) != 1 {
+		t.Errorf("Expected the book by a slug and an id, got: %+v %v", books, err)
+	}
+	if books, _ := m.Find(t.Context(), models.BookQuery{Genre: "poetry"}); len(books) != 0 {
+		t.Errorf("Expected no books of a missing genre, got: %+v", books)
+	}
+
+	if err := m.DeleteGenre(t.Context(), fiction.ID); !errors.Is(err, apperrors.ErrGenreInUse) {
+		t.Errorf("Expected ErrGenreInUse, got: %v", err)
+	}
+	if err := m.Delete(t.Context(), book.General.ID); err != nil {
+		t.Fatalf("Unexpected error deleting a book: %v", err)
+	}
+	if err := m.DeleteGenre(t.Context(), scifi.ID); err != nil {
+		t.Errorf("Unexpected error deleting a genre without books: %v", err)
+	}
+}
+
+func TestMemoryStorage_Works(t *testing.T) {
+	m := NewMemoryStorage(testLogger)
+
+	hobbit := newTestBook("The Hobbit")
+	hobbit.General.Author = "J.R.R. Tolkien"
+	hobbit, _ = m.Save(t.Context(), hobbit)
+	other := newTestBook("the hobbit")
+	other.General.Author = "j.r.r. tolkien"
+	other, _ = m.Save(t.Context(), other)
+	// works are matched by authors of books
+	if err := m.LinkAuthors(t.Context(), nil, testTime); err != nil {
+		t.Fatalf("Unexpected error linking authors: %v", err)
+	}
+	if err := m.LinkWorks(t.Context(), testTime); err != nil {
+		t.Fatalf("Unexpected error linking works: %v", err)
+	}
+
+	// the same title and author in another case is one work
+	works, err := m.FindWorks(t.Context(), models.WorkQuery{})
+	if err != nil || len(works) != 1 || works[0].Title != "The Hobbit" || len(works[0].Authors) != 1 {
+		t.Fatalf("Expected one work of both books, got: %+v %v", works, err)
+	}
+	work := works[0]
+	books, err := m.Find(t.Context(), models.BookQuery{WorkID: work.ID})
+	if err != nil || len(books) != 2 {
+		t.Errorf("Expected two editions of the work, got: %+v %v", books, err)
+	}
+
+	// "Last, First" is the same author
+	found, err := m.EnsureWork(t.Context(), models.Work{Title: "THE HOBBIT", Author: "Tolkien, J.R.R.", CreatedAt: testTime})
+	if err != nil || found.ID != work.ID {
+		t.Errorf("Expected the existing work, got: %+v %v", found, err)
+	}
+	// another author is another work
+	found, err = m.EnsureWork(t.Context(), models.Work{Title: "The Hobbit", Author: "Christopher Tolkien", CreatedAt: testTime})
+	if err != nil || found.ID == work.ID || len(found.Authors) != 1 || found.Authors[0].Name != "Christopher Tolkien" {
+		t.Errorf("Expected a new work of another author, got: %+v %v", found, err)
+	}
+	if err := m.DeleteWork(t.Context(), found.ID); err != nil {
+		t.Fatalf("Unexpected error deleting a work: %v", err)
+	}
+	silmarillion, err := m.SaveWork(t.Context(), models.Work{Title: "Silmarillion", Author: "J.R.R. Tolkien", CreatedAt: testTime})
+	if err != nil {
+		t.Fatalf("Unexpected error saving a work: %v", err)
+	}
+	works, err = m.FindWorks(t.Context(), models.WorkQuery{After: &work, Limit: 1})
+	if err != nil || len(works) != 0 {
+		t.Errorf("Expected no works after The Hobbit, got: %+v %v", works, err)
+	}
+	works, err = m.FindWorks(t.Context(), models.WorkQuery{After: &silmarillion})
+	if err != nil || len(works) != 1 || works[0].ID != work.ID {
+		t.Errorf("Expected The Hobbit after Silmarillion, got: %+v %v", works, err)
+	}
+
+	other.General.WorkID = silmarillion.ID
+	other.General.Publisher = "Allen & Unwin"
+	if err := m.UpdateFields(t.Context(), other, []models.BookField{models.FieldWorkID, models.FieldPublisher}); err != nil {
+		t.Fatalf("Unexpected error updating fields: %v", err)
+	}
+	got, err := m.GetById(t.Context(), other.General.ID)
+	if err != nil || got.General.WorkID != silmarillion.ID || got.General.Publisher != "Allen & Unwin" {
+		t.Errorf("Expected updated fields, got: %+v %v", got.General, err)
+	}
+
+	if _, err := m.GetWork(t.Context(), 99); !errors.Is(err, apperrors.ErrWorkNotFound) {
+		t.Errorf("Expected ErrWorkNotFound, got: %v", err)
+	}
+	if err := m.DeleteWork(t.Context(), work.ID); !errors.Is(err, apperrors.ErrWorkHasEditions) {
+		t.Errorf("Expected ErrWorkHasEditions, got: %v", err)
+	}
+	if err := m.Delete(t.Context(), hobbit.General.ID); err != nil {
+		t.Fatalf("Unexpected error deleting a book: %v", err)
+	}
+	if err := m.DeleteWork(t.Context(), work.ID); err != nil {
+		t.Errorf("Unexpected error deleting a work without editions: %v", err)
+	}
+}
+
+func TestMemoryStorage_Series(t *testing.T) {
+	m := NewMemoryStorage(testLogger)
+
+	series, err := m.SaveSeries(t.Context(), models.Series{Name: "The Lord of the Rings", CreatedAt: testTime, UpdatedAt: testTime})
+	if err != nil {
+		t.Fatalf("Unexpected error saving a series: %v", err)
+	}
+	var books []models.Book
+	for _, title := range []string{"The Two Towers", "The Fellowship of the Ring", "The Return of the King"} {
+		book, _ := m.Save(t.Context(), newTestBook(title))
+		books = append(books, book)
+	}
+	// positions are not in order of ids, one is fractional
+	for i, position := range []float64{2, 1, 2.5} {
+		if err := m.SetBookSeries(t.Context(), books[i].General.ID, series.ID, position); err != nil {
+			t.Fatalf("Unexpected error setting book series: %v", err)
+		}
+	}
+
+	volumes, err := m.SeriesVolumes(t.Context(), series.ID)
+	if err != nil || len(volumes) != 3 || volumes[0].Title != "The Fellowship of the Ring" || volumes[2].Position != 2.5 {
+		t.Fatalf("Expected volumes ordered by position, got: %+v %v", volumes, err)
+	}
+
+	found, err := m.BookSeries(t.Context(), []uint64{books[0].General.ID, books[1].General.ID, 99})
+	if err != nil || len(found) != 2 {
+		t.Fatalf("Expected series of two books, got: %+v %v", found, err)
+	}
+	middle := found[books[0].General.ID]
+	if middle.Name != series.Name || middle.Position != 2 || middle.Previous == nil || middle.Previous.ID != books[1].General.ID ||
+		middle.Next == nil || middle.Next.Position != 2.5 {
+		t.Errorf("Expected both neighbours of the second volume, got: %+v", middle)
+	}
+	if first := found[books[1].General.ID]; first.Previous != nil || first.Next == nil || first.Next.ID != books[0].General.ID {
+		t.Errorf("Expected only the next volume of the first one, got: %+v", first)
+	}
+
+	if err := m.SetBookSeries(t.Context(), books[2].General.ID, series.ID, 1); !errors.Is(err, apperrors.ErrPositionConflict) {
+		t.Errorf("Expected ErrPositionConflict, got: %v", err)
+	}
+	if err := m.SetBookSeries(t.Context(), books[2].General.ID, 99, 1); !errors.Is(err, apperrors.ErrSeriesNotFound) {
+		t.Errorf("Expected ErrSeriesNotFound, got: %v", err)
+	}
+	if err := m.SetBookSeries(t.Context(), 99, series.ID, 3); !errors.Is(err, apperrors.ErrNotFound) || errors.Is(err, apperrors.ErrSeriesNotFound) {
+		t.Errorf("Expected ErrNotFound of a book, got: %v", err)
+	}
+	if err := m.DeleteSeries(t.Context(), series.ID); !errors.Is(err, apperrors.ErrSeriesHasVolumes) {
+		t.Errorf("Expected ErrSeriesHasVolumes, got: %v", err)
+	}
+
+	// a deleted book and a book taken out leave the series
+	if err := m.Delete(t.Context(), books[0].General.ID); err != nil {
+		t.Fatalf("Unexpected error deleting a book: %v", err)
+	}
+	if err := m.SetBookSeries(t.Context(), books[1].General.ID, 0, 0); err != nil {
+		t.Fatalf("Unexpected error removing book series: %v", err)
+	}
+	volumes, err = m.SeriesVolumes(t.Context(), series.ID)
+	if err != nil || len(volumes) != 1 || volumes[0].ID != books[2].General.ID {
+		t.Errorf("Expected one volume left, got: %+v %v", volumes, err)
+	}
+	if err := m.SetBookSeries(t.Context(), books[2].General.ID, 0, 0); err != nil {
+		t.Fatalf("Unexpected error removing book series: %v", err)
+	}
+	if err := m.DeleteSeries(t.Context(), series.ID); err != nil {
+		t.Errorf("Unexpected error deleting a series without volumes: %v", err)
+	}
+	if _, err := m.GetSeries(t.Context(), series.ID); !errors.Is(err, apperrors.ErrSeriesNotFound) {
+		t.Errorf("Expected ErrSeriesNotFound, got: %v", err)
+	}
+}
+
+func TestMemoryStorage_DeleteDoesNotReuseID(t *testing.T) {
+	s := NewMemoryStorage(testLogger)
+	s.Save(t.Context(), newTestBook("Clean Code"))
+
+	if err := s.Delete(t.Context(), 1); err != nil {
+		t.Fatalf("Unexpected error deleting a book: %v", err)
+	}
+	s.Save(t.Context(), newTestBook("Clean Architecture"))
+
+	if _, err := s.GetById(t.Context(), 2); err != nil {
+		t.Errorf("Expected a new id from the sequence, got error: %v", err)
+	}
+}
+
+func TestMemoryStorage_Closed(t *testing.T) {
+	s := NewMemoryStorage(testLogger)
+	s.Close()
+
+	if _, err := s.GetAll(t.Context()); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got: %v", err)
+	}
+}
+
+func TestMemoryStorage_Concurrent(t *testing.T) {
+	s := NewMemoryStorage(testLogger)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Save(t.Context(), newTestBook("Clean Code"))
+			s.GetAll(t.Context())
+		}()
+	}
+	wg.Wait()
+
+	books, _ := s.GetAll(t.Context())
+	if len(books) != 100 {
+		t.Errorf("Expected 100 books, got: %d", len(books))
+	}
+}
+
+func TestMemoryStorage_CanceledContext(t *testing.T) {
+	s := NewMemoryStorage(testLogger)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := s.Save(ctx, newTestBook("Clean Code")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if books, _ := s.GetAll(t.Context()); len(books) != 0 {
+		t.Errorf("Expected nothing to be saved, got: %+v", books)
+	}
+}
+
+func TestMemoryStorage_WithTx(t *testing.T) {
+	s := NewMemoryStorage(testLogger)
+	s.Save(t.Context(), newTestBook("Clean Code"))
+
+	// a failed transaction leaves nothing behind
+	errFailed := errors.New("failed")
+	err := s.WithTx(t.Context(), func(tx abstraction.Storage) error {
+		tx.Save(t.Context(), newTestBook("Clean Architecture"))
+		tx.Delete(t.Context(), 1)
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("Expected error of fn, got: %v", err)
+	}
+	books, _ := s.GetAll(t.Context())
+	if len(books) != 1 || books[0].General.ID != 1 {
+		t.Fatalf("Expected rolled back changes, got: %+v", books)
+	}
+
+	err = s.WithTx(t.Context(), func(tx abstraction.Storage) error {
+		_, err := tx.Save(t.Context(), newTestBook("Clean Architecture"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error committing transaction: %v", err)
+	}
+	if _, err := s.GetById(t.Context(), 2); err != nil {
+		t.Errorf("Expected committed book, got error: %v", err)
+	}
+}
+
+func TestMemoryStorage_Stream(t *testing.T) {
+	s := NewMemoryStorage(testLogger)
+	for _, title := range []string{"Clean Code", "Refactoring", "Clean Architecture"} {
+		s.Save(t.Context(), newTestBook(title))
+	}
+
+	var ids []uint64
+	for book, err := range s.Stream(t.Context(), models.BookQuery{Title: "clean", Desc: true}) {
+		if err != nil {
+			t.Fatalf("Unexpected error streaming books: %v", err)
+		}
+		ids = append(ids, book.General.ID)
+	}
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 1 {
+		t.Errorf("Expected ids [3 1], got: %v", ids)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	for _, err := range s.Stream(ctx, models.BookQuery{}) {
+		if err == nil {
+			t.Error("Expected error for canceled context")
+		}
+	}
+}
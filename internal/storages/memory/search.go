@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"html"
+	"slices"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// weights of fields, they are the same as default weights
+// of ts_rank in PostgreSQL for A, B and C
+const (
+	titleWeight  = 1.0
+	authorWeight = 0.4
+	genreWeight  = 0.2
+)
+
+// Search return books that contain all words of a text query
+// ordered by relevance. It is a simple replacement of PostgreSQL
+// full-text search: there is no stemming, words are matched entirely
+func (m *MemoryStorage) Search(ctx context.Context, text string, limit int) ([]models.SearchResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	terms := strings.FieldsFunc(strings.ToLower(text), isSeparator)
+	if len(terms) == 0 {
+		return []models.SearchResult{}, nil
+	}
+	results := make([]models.SearchResult, 0)
+	for _, book := range m.books {
+		g := book.General
+		score, ok := 0.0, true
+		for _, term := range terms {
+			found := false
+			for _, f := range []struct {
+				value  string
+				weight float64
+			}{{g.Title, titleWeight}, {g.Author, authorWeight}, {g.Genre, genreWeight}} {
+				if hasWord(f.value, term) {
+					score += f.weight
+					found = true
+				}
+			}
+			if !found {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		results = append(results, models.SearchResult{
+			Book:  cloneBook(book),
+			Score: score,
+			Highlight: models.BookHighlight{
+				Title:  highlight(g.Title, terms),
+				Author: highlight(g.Author, terms),
+				Genre:  highlight(g.Genre, terms),
+			},
+		})
+	}
+
+	slices.SortFunc(results, func(a, b models.SearchResult) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Book.General.ID, b.Book.General.ID)
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// hasWord reports whether s contains a word, case-insensitive
+func hasWord(s, word string) bool {
+	return slices.ContainsFunc(strings.FieldsFunc(s, isSeparator), func(w string) bool {
+		return strings.EqualFold(w, word)
+	})
+}
+
+// highlight wraps every word of s that is one of terms in <mark></mark>,
+// the text is escaped as HTML, so only <mark></mark> are tags in it
+func highlight(s string, terms []string) string {
+	var sb strings.Builder
+	for len(s) > 0 {
+		// copy separators as is
+		i := strings.IndexFunc(s, func(r rune) bool { return !isSeparator(r) })
+		if i < 0 {
+			sb.WriteString(html.EscapeString(s))
+			break
+		}
+		sb.WriteString(html.EscapeString(s[:i]))
+		s = s[i:]
+
+		// a word goes until the next separator
+		j := strings.IndexFunc(s, isSeparator)
+		if j < 0 {
+			j = len(s)
+		}
+		word := s[:j]
+		s = s[j:]
+
+		if slices.ContainsFunc(terms, func(t string) bool { return strings.EqualFold(t, word) }) {
+			sb.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			sb.WriteString(html.EscapeString(word))
+		}
+	}
+	return sb.String()
+}
+
+// isSeparator reports whether r separates words
+func isSeparator(r rune) bool {
+	return !(r == '\'' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127)
+}
@@ -0,0 +1,52 @@
+package memory
+
+import "testing"
+
+func TestMemoryStorage_Search(t *testing.T) {
+	s := NewMemoryStorage(testLogger)
+
+	hobbit := newTestBook("The Hobbit")
+	hobbit.General.Author = "J.R.R. Tolkien"
+	hobbit.General.Genre = "Fantasy"
+	s.Save(t.Context(), hobbit)
+
+	// Tolkien is only an author here, so the book is less relevant
+	letters := newTestBook("Letters")
+	letters.General.Author = "Tolkien"
+	s.Save(t.Context(), letters)
+
+	s.Save(t.Context(), newTestBook("Clean Code"))
+
+	results, err := s.Search(t.Context(), "tolkien", 10)
+	if err != nil {
+		t.Fatalf("Unexpected error searching books: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got: %+v", results)
+	}
+	if results[0].Highlight.Author != "J.R.R. <mark>Tolkien</mark>" {
+		t.Errorf("Unexpected highlight: %s", results[0].Highlight.Author)
+	}
+
+	results, err = s.Search(t.Context(), "hobbit fantasy", 10)
+	if err != nil {
+		t.Fatalf("Unexpected error searching books: %v", err)
+	}
+	if len(results) != 1 || results[0].Highlight.Title != "The <mark>Hobbit</mark>" || results[0].Score <= titleWeight {
+		t.Errorf("Expected The Hobbit matched by title and genre, got: %+v", results)
+	}
+
+	// stored text is escaped, only <mark></mark> are tags
+	xss := newTestBook("<b>Tom</b> & Jerry")
+	s.Save(t.Context(), xss)
+	results, _ = s.Search(t.Context(), "jerry", 10)
+	if len(results) != 1 || results[0].Highlight.Title != "&lt;b&gt;Tom&lt;/b&gt; &amp; <mark>Jerry</mark>" {
+		t.Errorf("Expected an escaped highlight, got: %+v", results)
+	}
+
+	// every word must be found
+	results, _ = s.Search(t.Context(), "hobbit code", 10)
+	if len(results) != 0 {
+		t.Errorf("Expected no results, got: %+v", results)
+	}
+}
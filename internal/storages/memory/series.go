@@ -0,0 +1,233 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// seriesPlace is a place of a book in a series, like a row of series_volumes
+type seriesPlace struct {
+	seriesID uint64
+	position float64
+}
+
+// FindSeries return series ordered by name and id
+func (m *MemoryStorage) FindSeries(ctx context.Context, q models.SeriesQuery) ([]models.Series, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	found := make([]models.Series, 0, q.Limit)
+	for _, series := range m.series {
+		if q.After == nil || compareSeries(series, *q.After) > 0 {
+			found = append(found, series)
+		}
+	}
+	slices.SortFunc(found, compareSeries)
+
+	if q.Limit > 0 && len(found) > q.Limit {
+		found = found[:q.Limit]
+	}
+	return found, nil
+}
+
+// GetSeries return a series by id
+func (m *MemoryStorage) GetSeries(ctx context.Context, id uint64) (models.Series, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return models.Series{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Series{}, err
+	}
+
+	series, ok := m.series[id]
+	if !ok {
+		return models.Series{}, fmt.Errorf("series with id %d %w", id, apperrors.ErrSeriesNotFound)
+	}
+	return series, nil
+}
+
+// SaveSeries add a series and returns it with a new id
+func (m *MemoryStorage) SaveSeries(ctx context.Context, series models.Series) (models.Series, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return models.Series{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Series{}, err
+	}
+
+	series.ID = m.nextSeriesID
+	m.nextSeriesID++
+	m.series[series.ID] = series
+
+	return series, nil
+}
+
+// UpdateSeries update a series, created_at is never changed
+func (m *MemoryStorage) UpdateSeries(ctx context.Context, series models.Series) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	old, ok := m.series[series.ID]
+	if !ok {
+		return fmt.Errorf("series with id: %d %w", series.ID, apperrors.ErrSeriesNotFound)
+	}
+	series.CreatedAt = old.CreatedAt
+	m.series[series.ID] = series
+
+	return nil
+}
+
+// DeleteSeries delete a series without volumes
+func (m *MemoryStorage) DeleteSeries(ctx context.Context, id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := m.series[id]; !ok {
+		return fmt.Errorf("series with id: %d %w", id, apperrors.ErrSeriesNotFound)
+	}
+	for _, place := range m.bookSeries {
+		if place.seriesID == id {
+			return fmt.Errorf("series with id: %d %w", id, apperrors.ErrSeriesHasVolumes)
+		}
+	}
+	delete(m.series, id)
+
+	return nil
+}
+
+// SetBookSeries places a book at a position of a series, zero seriesID takes it out
+func (m *MemoryStorage) SetBookSeries(ctx context.Context, bookID, seriesID uint64, position float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := m.books[bookID]; !ok {
+		return fmt.Errorf("book with id: %d %w", bookID, apperrors.ErrBookNotFound)
+	}
+	if seriesID == 0 {
+		delete(m.bookSeries, bookID)
+		return nil
+	}
+	if _, ok := m.series[seriesID]; !ok {
+		return fmt.Errorf("series with id: %d %w", seriesID, apperrors.ErrSeriesNotFound)
+	}
+	place := seriesPlace{seriesID: seriesID, position: position}
+	for id, other := range m.bookSeries {
+		if id != bookID && other == place {
+			return fmt.Errorf("position %g of series %d %w", position, seriesID, apperrors.ErrPositionConflict)
+		}
+	}
+	m.bookSeries[bookID] = place
+
+	return nil
+}
+
+// SeriesVolumes return books of a series ordered by position
+func (m *MemoryStorage) SeriesVolumes(ctx context.Context, seriesID uint64) ([]models.SeriesVolume, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.volumes(seriesID), nil
+}
+
+// BookSeries return series of books with their neighbours
+func (m *MemoryStorage) BookSeries(ctx context.Context, bookIDs []uint64) (map[uint64]models.BookSeries, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint64]models.BookSeries, len(bookIDs))
+	for _, bookID := range bookIDs {
+		place, ok := m.bookSeries[bookID]
+		if !ok {
+			continue
+		}
+		found := models.BookSeries{ID: place.seriesID, Name: m.series[place.seriesID].Name, Position: place.position}
+		volumes := m.volumes(place.seriesID)
+		i := slices.IndexFunc(volumes, func(v models.SeriesVolume) bool { return v.ID == bookID })
+		if i > 0 {
+			found.Previous = &volumes[i-1]
+		}
+		if i < len(volumes)-1 {
+			found.Next = &volumes[i+1]
+		}
+		result[bookID] = found
+	}
+	return result, nil
+}
+
+// there are helpers, a caller holds the lock
+
+// volumes returns books of a series ordered by position
+func (m *MemoryStorage) volumes(seriesID uint64) []models.SeriesVolume {
+	volumes := []models.SeriesVolume{}
+	for bookID, place := range m.bookSeries {
+		if place.seriesID == seriesID {
+			volumes = append(volumes, models.SeriesVolume{ID: bookID, Title: m.books[bookID].General.Title, Position: place.position})
+		}
+	}
+	slices.SortFunc(volumes, func(a, b models.SeriesVolume) int {
+		return cmp.Compare(a.Position, b.Position)
+	})
+	return volumes
+}
+
+// compareSeries orders series by name and id
+func compareSeries(a, b models.Series) int {
+	if c := strings.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
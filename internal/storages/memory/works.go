@@ -0,0 +1,235 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// FindWorks return works ordered by title and id
+func (m *MemoryStorage) FindWorks(ctx context.Context, q models.WorkQuery) ([]models.Work, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	works := make([]models.Work, 0, q.Limit)
+	for _, work := range m.works {
+		if q.After == nil || compareWorks(work, *q.After) > 0 {
+			works = append(works, work)
+		}
+	}
+	slices.SortFunc(works, compareWorks)
+
+	if q.Limit > 0 && len(works) > q.Limit {
+		works = works[:q.Limit]
+	}
+	for i := range works {
+		works[i] = m.withAuthors(works[i])
+	}
+	return works, nil
+}
+
+// GetWork return a work by id
+func (m *MemoryStorage) GetWork(ctx context.Context, id uint64) (models.Work, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return models.Work{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Work{}, err
+	}
+
+	work, ok := m.works[id]
+	if !ok {
+		return models.Work{}, fmt.Errorf("work with id %d %w", id, apperrors.ErrWorkNotFound)
+	}
+	return m.withAuthors(work), nil
+}
+
+// SaveWork add a work and returns it with a new id
+func (m *MemoryStorage) SaveWork(ctx context.Context, work models.Work) (models.Work, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return models.Work{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Work{}, err
+	}
+
+	return m.saveWork(work, m.authorIDs(work.Author, work.CreatedAt)), nil
+}
+
+// UpdateWork update a work, created_at is never changed
+func (m *MemoryStorage) UpdateWork(ctx context.Context, work models.Work) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	old, ok := m.works[work.ID]
+	if !ok {
+		return fmt.Errorf("work with id: %d %w", work.ID, apperrors.ErrWorkNotFound)
+	}
+	work.CreatedAt = old.CreatedAt
+	work.Authors = nil
+	m.works[work.ID] = work
+	m.setWorkAuthors(work.ID, m.authorIDs(work.Author, work.UpdatedAt))
+
+	return nil
+}
+
+// DeleteWork delete a work without editions
+func (m *MemoryStorage) DeleteWork(ctx context.Context, id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := m.works[id]; !ok {
+		return fmt.Errorf("work with id: %d %w", id, apperrors.ErrWorkNotFound)
+	}
+	for _, book := range m.books {
+		if book.General.WorkID == id {
+			return fmt.Errorf("work with id: %d %w", id, apperrors.ErrWorkHasEditions)
+		}
+	}
+	delete(m.works, id)
+	delete(m.workAuthors, id)
+
+	return nil
+}
+
+// EnsureWork returns a work with the same title and authors or saves a new one
+func (m *MemoryStorage) EnsureWork(ctx context.Context, work models.Work) (models.Work, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return models.Work{}, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Work{}, err
+	}
+
+	authorIDs := m.authorIDs(work.Author, work.CreatedAt)
+	if found, ok := m.workByTitle(work.Title, authorIDs); ok {
+		return m.withAuthors(found), nil
+	}
+	return m.saveWork(work, authorIDs), nil
+}
+
+// LinkWorks makes every book without a work an edition of a work
+func (m *MemoryStorage) LinkWorks(ctx context.Context, createdAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var ids []uint64
+	for id, book := range m.books {
+		if book.General.WorkID == 0 {
+			ids = append(ids, id)
+		}
+	}
+	// works are created in the same order every time
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		book := m.books[id]
+		work, ok := m.workByTitle(book.General.Title, m.bookAuthors[id])
+		if !ok {
+			work = m.saveWork(models.Work{Title: book.General.Title, Author: book.General.Author, CreatedAt: createdAt, UpdatedAt: createdAt}, m.bookAuthors[id])
+		}
+		book.General.WorkID = work.ID
+		m.books[id] = book
+	}
+
+	return nil
+}
+
+// there are helpers, a caller holds the lock
+
+// saveWork saves a work with authors in order of credits
+func (m *MemoryStorage) saveWork(work models.Work, authorIDs []uint64) models.Work {
+	work.ID = m.nextWorkID
+	work.Authors = nil
+	m.nextWorkID++
+	m.works[work.ID] = work
+	m.setWorkAuthors(work.ID, authorIDs)
+	return m.withAuthors(work)
+}
+
+// setWorkAuthors stores a new slice without repeated authors
+func (m *MemoryStorage) setWorkAuthors(workID uint64, authorIDs []uint64) {
+	ids := uniqueIDs(authorIDs)
+	if len(ids) == 0 {
+		delete(m.workAuthors, workID)
+		return
+	}
+	m.workAuthors[workID] = ids
+}
+
+// withAuthors returns a work with its authors in order of credits
+func (m *MemoryStorage) withAuthors(work models.Work) models.Work {
+	work.Authors = nil
+	for _, id := range m.workAuthors[work.ID] {
+		work.Authors = append(work.Authors, models.BookAuthor{ID: id, Name: m.authors[id].Name})
+	}
+	return work
+}
+
+// workByTitle returns the first work with a title in any case and the same authors in any order
+func (m *MemoryStorage) workByTitle(title string, authorIDs []uint64) (models.Work, bool) {
+	ids := uniqueIDs(authorIDs)
+	slices.Sort(ids)
+
+	var found models.Work
+	for _, work := range m.works {
+		if !strings.EqualFold(work.Title, title) || (found.ID != 0 && work.ID > found.ID) {
+			continue
+		}
+		if workIDs := slices.Sorted(slices.Values(m.workAuthors[work.ID])); slices.Equal(workIDs, ids) {
+			found = work
+		}
+	}
+	return found, found.ID != 0
+}
+
+// compareWorks orders works by title and id
+func compareWorks(a, b models.Work) int {
+	if c := strings.Compare(a.Title, b.Title); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
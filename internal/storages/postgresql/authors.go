@@ -0,0 +1,382 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// foreignKeyViolation is a PostgreSQL error code
+const foreignKeyViolation = "23503"
+
+// expressions of models.SplitAuthors and models.SortName in PostgreSQL syntax,
+// migrations have the same ones
+const (
+	authorSeparator     = `\s*([;&]|\mand\M)\s*`
+	commaSeparator      = `\s*,\s*`
+	invertedNamePattern = `^([^[:space:],]+)\s*,\s*([^,]+)$`
+	sortNamePattern     = `^(.+)\s+(\S+)$`
+)
+
+// authorNames is a query of names of authors of books in order, like models.SplitAuthors:
+// an author string is split by ; & and "and", then a part is a "Last, First" name or is split by commas
+const authorNames = `
+	SELECT books.id AS book_id, TRIM(names.name) AS name,
+		ROW_NUMBER() OVER (PARTITION BY books.id ORDER BY parts.position, names.position) AS position
+	FROM books
+	CROSS JOIN LATERAL regexp_split_to_table(books.author, '` + authorSeparator + `') WITH ORDINALITY AS parts(part, position)
+	CROSS JOIN LATERAL (
+		SELECT regexp_replace(TRIM(parts.part), '` + invertedNamePattern + `', '\2 \1') AS name, 1::bigint AS position
+		WHERE TRIM(parts.part) ~ '` + invertedNamePattern + `'
+		UNION ALL
+		SELECT split.name, split.position
+		FROM regexp_split_to_table(parts.part, '` + commaSeparator + `') WITH ORDINALITY AS split(name, position)
+		WHERE TRIM(parts.part) !~ '` + invertedNamePattern + `'
+	) names
+	WHERE TRIM(names.name) <> ''
+`
+
+// FindAuthors return authors ordered by sort name and id
+func (p *PostgresStorage) FindAuthors(ctx context.Context, q models.AuthorQuery) ([]models.Author, error) {
+	var args []any
+	// arg adds a value and returns its placeholder
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`
+	SELECT id, name, sort_name, birth_year, death_year, bio, created_at, updated_at
+	FROM authors
+	`)
+	if q.After != nil {
+		sb.WriteString(fmt.Sprintf("WHERE (sort_name, id) > (%s, %s)\n", arg(q.After.SortName), arg(q.After.ID)))
+	}
+	sb.WriteString("ORDER BY sort_name, id\n")
+	if q.Limit > 0 {
+		sb.WriteString("LIMIT " + arg(q.Limit) + "\n")
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, sb.String(), args...)
+	if err != nil {
+		p.logger.Error("Faild to query authors", "error", err)
+		return nil, fmt.Errorf("faild to query authors: %w", err)
+	}
+	defer rows.Close()
+
+	authors := make([]models.Author, 0, q.Limit)
+	for rows.Next() {
+		var author models.Author
+		err := rows.Scan(
+			&author.ID,
+			&author.Name,
+			&author.SortName,
+			&author.BirthYear,
+			&author.DeathYear,
+			&author.Bio,
+			&author.CreatedAt,
+			&author.UpdatedAt,
+		)
+		if err != nil {
+			p.logger.Error("Faild to scan authors", "error", err)
+			return nil, fmt.Errorf("faild to scan authors: %w", err)
+		}
+		authors = append(authors, author)
+	}
+
+	if err := rows.Err(); err != nil {
+		p.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return authors, nil
+}
+
+// GetAuthor return an author by id
+func (p *PostgresStorage) GetAuthor(ctx context.Context, id uint64) (models.Author, error) {
+	query := `
+	SELECT id, name, sort_name, birth_year, death_year, bio, created_at, updated_at
+	FROM authors
+	WHERE id = $1
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var author models.Author
+	err := p.db.QueryRow(ctx, query, id).Scan(
+		&author.ID,
+		&author.Name,
+		&author.SortName,
+		&author.BirthYear,
+		&author.DeathYear,
+		&author.Bio,
+		&author.CreatedAt,
+		&author.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Author{}, fmt.Errorf("author with id %d %w", id, apperrors.ErrAuthorNotFound)
+		}
+		p.logger.Error("Faild to get author", "error", err)
+		return models.Author{}, fmt.Errorf("failed to get author: %w", err)
+	}
+	return author, nil
+}
+
+// SaveAuthor add an author and returns it with id from database
+func (p *PostgresStorage) SaveAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	query := `
+	INSERT INTO authors (name, sort_name, birth_year, death_year, bio, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := p.db.QueryRow(ctx, query,
+		author.Name,
+		author.SortName,
+		author.BirthYear,
+		author.DeathYear,
+		author.Bio,
+		author.CreatedAt,
+		author.UpdatedAt,
+	).Scan(&author.ID)
+	if err != nil {
+		p.logger.Error("Failed to save author", "error", err)
+		return models.Author{}, fmt.Errorf("failed to save author: %w", err)
+	}
+	return author, nil
+}
+
+// UpdateAuthor update an author, created_at is never changed
+func (p *PostgresStorage) UpdateAuthor(ctx context.Context, author models.Author) error {
+	query := `
+	UPDATE authors
+	SET
+		name = $1,
+		sort_name = $2,
+		birth_year = $3,
+		death_year = $4,
+		bio = $5,
+		updated_at = $6
+	WHERE id = $7
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.Exec(ctx, query,
+		author.Name,
+		author.SortName,
+		author.BirthYear,
+		author.DeathYear,
+		author.Bio,
+		author.UpdatedAt,
+		author.ID,
+	)
+	if err != nil {
+		p.logger.Error("Failed to update author", "error", err)
+		return fmt.Errorf("failed to update author: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("author with id: %d %w", author.ID, apperrors.ErrAuthorNotFound)
+	}
+	return nil
+}
+
+// DeleteAuthor delete an author, foreign keys of book_authors and work_authors
+// don't allow to delete an author of books or works
+func (p *PostgresStorage) DeleteAuthor(ctx context.Context, id uint64) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.Exec(ctx, `DELETE FROM authors WHERE id = $1`, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return fmt.Errorf("author with id: %d %w", id, apperrors.ErrAuthorHasBooks)
+		}
+		p.logger.Error("Failed to delete author", "error", err)
+		return fmt.Errorf("failed to delete author: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("author with id: %d %w", id, apperrors.ErrAuthorNotFound)
+	}
+	return nil
+}
+
+// SetBookAuthors replaces authors of a book, a repeated author is kept
+// at its first position
+func (p *PostgresStorage) SetBookAuthors(ctx context.Context, bookID uint64, authorIDs []uint64) error {
+	insertQuery := `
+	INSERT INTO book_authors (book_id, author_id, position)
+	SELECT $1, ids.id, MIN(ids.position)
+	FROM unnest($2::bigint[]) WITH ORDINALITY AS ids(id, position)
+	GROUP BY ids.id
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM book_authors WHERE book_id = $1`, bookID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, insertQuery, bookID, int64IDs(authorIDs))
+		return err
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			if strings.Contains(pgErr.ConstraintName, "book_id") {
+				return fmt.Errorf("book with id: %d %w", bookID, apperrors.ErrBookNotFound)
+			}
+			return fmt.Errorf("author of book %d %w", bookID, apperrors.ErrAuthorNotFound)
+		}
+		p.logger.Error("Failed to set book authors", "error", err)
+		return fmt.Errorf("failed to set book authors: %w", err)
+	}
+	return nil
+}
+
+// BookAuthors return authors of books in order of credits
+func (p *PostgresStorage) BookAuthors(ctx context.Context, bookIDs []uint64) (map[uint64][]models.BookAuthor, error) {
+	query := `
+	SELECT book_authors.book_id, authors.id, authors.name
+	FROM book_authors
+	JOIN authors ON authors.id = book_authors.author_id
+	WHERE book_authors.book_id = ANY($1)
+	ORDER BY book_authors.book_id, book_authors.position
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, query, int64IDs(bookIDs))
+	if err != nil {
+		p.logger.Error("Faild to query book authors", "error", err)
+		return nil, fmt.Errorf("faild to query book authors: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[uint64][]models.BookAuthor, len(bookIDs))
+	for rows.Next() {
+		var (
+			bookID uint64
+			author models.BookAuthor
+		)
+		if err := rows.Scan(&bookID, &author.ID, &author.Name); err != nil {
+			p.logger.Error("Faild to scan book authors", "error", err)
+			return nil, fmt.Errorf("faild to scan book authors: %w", err)
+		}
+		result[bookID] = append(result[bookID], author)
+	}
+
+	if err := rows.Err(); err != nil {
+		p.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return result, nil
+}
+
+// LinkAuthors links books to authors by their author strings in SQL,
+// the same way as the migration of authors does
+func (p *PostgresStorage) LinkAuthors(ctx context.Context, bookIDs []uint64, createdAt time.Time) error {
+	// names of authors of books in order, nil ids are books without authors
+	names := authorNames + `
+	AND CASE WHEN $1::bigint[] IS NULL
+		THEN NOT EXISTS (SELECT 1 FROM book_authors WHERE book_authors.book_id = books.id)
+		ELSE books.id = ANY($1)
+	END
+	`
+	authorsQuery := `
+	INSERT INTO authors (name, sort_name, created_at, updated_at)
+	SELECT DISTINCT ON (LOWER(names.name)) names.name, regexp_replace(names.name, $2, '\2, \1'), $3, $3
+	FROM (` + names + `) names
+	WHERE NOT EXISTS (SELECT 1 FROM authors WHERE LOWER(authors.name) = LOWER(names.name))
+	ORDER BY LOWER(names.name), names.name
+	`
+	linksQuery := `
+	INSERT INTO book_authors (book_id, author_id, position)
+	SELECT DISTINCT ON (names.book_id, author.id) names.book_id, author.id, names.position
+	FROM (` + names + `) names
+	CROSS JOIN LATERAL (
+		SELECT id FROM authors WHERE LOWER(authors.name) = LOWER(names.name) ORDER BY id LIMIT 1
+	) author
+	ORDER BY names.book_id, author.id, names.position
+	`
+
+	ids := int64IDs(bookIDs)
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		if ids != nil {
+			if _, err := tx.Exec(ctx, `DELETE FROM book_authors WHERE book_id = ANY($1)`, ids); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(ctx, authorsQuery, ids, sortNamePattern, createdAt); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, linksQuery, ids)
+		return err
+	})
+	if err != nil {
+		p.logger.Error("Failed to link authors", "error", err)
+		return fmt.Errorf("failed to link authors: %w", err)
+	}
+	return nil
+}
+
+// int64IDs converts ids for bigint[], nil stays nil (NULL)
+func int64IDs(ids []uint64) []int64 {
+	if ids == nil {
+		return nil
+	}
+	result := make([]int64, len(ids))
+	for i, id := range ids {
+		result[i] = int64(id)
+	}
+	return result
+}
+
+// authorIDs returns ids of authors of an author string in order of credits,
+// missing authors are saved. Names are split and matched like LinkAuthors does
+func authorIDs(ctx context.Context, db querier, author string, createdAt time.Time) ([]uint64, error) {
+	insertQuery := `
+	INSERT INTO authors (name, sort_name, created_at, updated_at)
+	VALUES ($1, $2, $3, $3)
+	RETURNING id
+	`
+
+	var ids []uint64
+	for _, name := range models.SplitAuthors(author) {
+		var id uint64
+		err := db.QueryRow(ctx,
+			`SELECT id FROM authors WHERE LOWER(name) = LOWER($1) ORDER BY id LIMIT 1`, name,
+		).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = db.QueryRow(ctx, insertQuery, name, models.SortName(name), createdAt).Scan(&id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
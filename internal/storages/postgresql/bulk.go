@@ -0,0 +1,86 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SaveMany adds books by COPY, it is much faster than INSERT for many books.
+// Ids and versions are set by database
+func (p *PostgresStorage) SaveMany(ctx context.Context, books []models.Book) (int64, error) {
+	columns := []string{"title", "author", "genre", "publication_date", "created_at", "updated_at", "isbn",
+		"work_id", "publisher", "edition", "format", "page_count", "language"}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	count, err := p.db.CopyFrom(ctx, pgx.Identifier{"books"}, columns,
+		pgx.CopyFromSlice(len(books), func(i int) ([]any, error) {
+			book := books[i]
+			return []any{
+				book.General.Title,
+				book.General.Author,
+				book.General.Genre,
+				book.General.PublicationDate,
+				book.CreatedAt,
+				book.UpdatedAt,
+				nullISBN(book.General.ISBN),
+				nullWorkID(book.General.WorkID),
+				book.General.Publisher,
+				book.General.Edition,
+				book.General.Format,
+				book.General.PageCount,
+				book.General.Language,
+			}, nil
+		}))
+	if err != nil {
+		// an ISBN might be stored by another request after ExistingISBNs
+		if conflict := conflictError(err, copiedBook(err, books)); conflict != nil {
+			return 0, conflict
+		}
+		p.logger.Error("Failed to copy books", "error", err, "count", len(books))
+		return 0, fmt.Errorf("failed to save books: %w", err)
+	}
+	return count, nil
+}
+
+// copiedBook returns a book whose ISBN is in the detail of a unique violation of COPY,
+// like "Key (isbn)=(9780132350884) already exists."
+func copiedBook(err error, books []models.Book) models.Book {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return models.Book{}
+	}
+	for _, book := range books {
+		if book.General.ISBN != "" && strings.Contains(pgErr.Detail, "("+book.General.ISBN+")") {
+			return book
+		}
+	}
+	return models.Book{}
+}
+
+// ExistingISBNs returns which of isbns are already stored by one query
+func (p *PostgresStorage) ExistingISBNs(ctx context.Context, isbns []string) ([]string, error) {
+	query := `SELECT isbn FROM books WHERE isbn = ANY($1)`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, query, isbns)
+	if err != nil {
+		p.logger.Error("Faild to query isbns", "error", err)
+		return nil, fmt.Errorf("faild to query isbns: %w", err)
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		p.logger.Error("Faild to scan isbns", "error", err)
+		return nil, fmt.Errorf("faild to scan isbns: %w", err)
+	}
+	return existing, nil
+}
@@ -0,0 +1,320 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// slugExpression is models.Slugify of a column in PostgreSQL syntax,
+// migrations have the same one. A name without latin letters or digits
+// gets genre- and 12 hex digits of md5 of the trimmed name
+const slugExpression = `CASE
+		WHEN regexp_replace(%[1]s, '^\s+|\s+$', '', 'g') = '' THEN ''
+		ELSE COALESCE(
+			NULLIF(TRIM(BOTH '-' FROM regexp_replace(LOWER(%[1]s), '[^a-z0-9]+', '-', 'g')), ''),
+			'genre-' || LEFT(md5(regexp_replace(%[1]s, '^\s+|\s+$', '', 'g')), 12)
+		)
+	END`
+
+// Genres return all genres ordered by name and id
+func (p *PostgresStorage) Genres(ctx context.Context) ([]models.Genre, error) {
+	query := `
+	SELECT id, parent_id, slug, name, created_at, updated_at
+	FROM genres
+	ORDER BY name, id
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, query)
+	if err != nil {
+		p.logger.Error("Faild to query genres", "error", err)
+		return nil, fmt.Errorf("faild to query genres: %w", err)
+	}
+	defer rows.Close()
+
+	var genres []models.Genre
+	for rows.Next() {
+		var genre models.Genre
+		err := rows.Scan(
+			&genre.ID,
+			&genre.ParentID,
+			&genre.Slug,
+			&genre.Name,
+			&genre.CreatedAt,
+			&genre.UpdatedAt,
+		)
+		if err != nil {
+			p.logger.Error("Faild to scan genres", "error", err)
+			return nil, fmt.Errorf("faild to scan genres: %w", err)
+		}
+		genres = append(genres, genre)
+	}
+
+	if err := rows.Err(); err != nil {
+		p.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return genres, nil
+}
+
+// GetGenre return a genre by id
+func (p *PostgresStorage) GetGenre(ctx context.Context, id uint64) (models.Genre, error) {
+	query := `
+	SELECT id, parent_id, slug, name, created_at, updated_at
+	FROM genres
+	WHERE id = $1
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var genre models.Genre
+	err := p.db.QueryRow(ctx, query, id).Scan(
+		&genre.ID,
+		&genre.ParentID,
+		&genre.Slug,
+		&genre.Name,
+		&genre.CreatedAt,
+		&genre.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Genre{}, fmt.Errorf("genre with id %d %w", id, apperrors.ErrGenreNotFound)
+		}
+		p.logger.Error("Faild to get genre", "error", err)
+		return models.Genre{}, fmt.Errorf("failed to get genre: %w", err)
+	}
+	return genre, nil
+}
+
+// SaveGenre add a genre and returns it with id from database
+func (p *PostgresStorage) SaveGenre(ctx context.Context, genre models.Genre) (models.Genre, error) {
+	query := `
+	INSERT INTO genres (parent_id, slug, name, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := p.db.QueryRow(ctx, query,
+		genre.ParentID,
+		genre.Slug,
+		genre.Name,
+		genre.CreatedAt,
+		genre.UpdatedAt,
+	).Scan(&genre.ID)
+	if err != nil {
+		if genreErr := genreConstraintError(err, genre); genreErr != nil {
+			return models.Genre{}, genreErr
+		}
+		p.logger.Error("Failed to save genre", "error", err)
+		return models.Genre{}, fmt.Errorf("failed to save genre: %w", err)
+	}
+	return genre, nil
+}
+
+// UpdateGenre update a genre, created_at is never changed
+func (p *PostgresStorage) UpdateGenre(ctx context.Context, genre models.Genre) error {
+	query := `
+	UPDATE genres
+	SET
+		parent_id = $1,
+		slug = $2,
+		name = $3,
+		updated_at = $4
+	WHERE id = $5
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.Exec(ctx, query,
+		genre.ParentID,
+		genre.Slug,
+		genre.Name,
+		genre.UpdatedAt,
+		genre.ID,
+	)
+	if err != nil {
+		if genreErr := genreConstraintError(err, genre); genreErr != nil {
+			return genreErr
+		}
+		p.logger.Error("Failed to update genre", "error", err)
+		return fmt.Errorf("failed to update genre: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("genre with id: %d %w", genre.ID, apperrors.ErrGenreNotFound)
+	}
+	return nil
+}
+
+// DeleteGenre delete a genre, foreign keys of subgenres and book_genres
+// don't allow to delete a genre in use
+func (p *PostgresStorage) DeleteGenre(ctx context.Context, id uint64) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.Exec(ctx, `DELETE FROM genres WHERE id = $1`, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return fmt.Errorf("genre with id: %d %w", id, apperrors.ErrGenreInUse)
+		}
+		p.logger.Error("Failed to delete genre", "error", err)
+		return fmt.Errorf("failed to delete genre: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("genre with id: %d %w", id, apperrors.ErrGenreNotFound)
+	}
+	return nil
+}
+
+// SetBookGenres replaces genres of a book
+func (p *PostgresStorage) SetBookGenres(ctx context.Context, bookID uint64, genreIDs []uint64) error {
+	insertQuery := `
+	INSERT INTO book_genres (book_id, genre_id)
+	SELECT DISTINCT $1::integer, id
+	FROM unnest($2::bigint[]) AS id
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM book_genres WHERE book_id = $1`, bookID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, insertQuery, bookID, int64IDs(genreIDs))
+		return err
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			if strings.Contains(pgErr.ConstraintName, "book_id") {
+				return fmt.Errorf("book with id: %d %w", bookID, apperrors.ErrBookNotFound)
+			}
+			return fmt.Errorf("genre of book %d %w", bookID, apperrors.ErrGenreNotFound)
+		}
+		p.logger.Error("Failed to set book genres", "error", err)
+		return fmt.Errorf("failed to set book genres: %w", err)
+	}
+	return nil
+}
+
+// BookGenres return genres of books ordered by name
+func (p *PostgresStorage) BookGenres(ctx context.Context, bookIDs []uint64) (map[uint64][]models.BookGenre, error) {
+	query := `
+	SELECT book_genres.book_id, genres.id, genres.slug, genres.name
+	FROM book_genres
+	JOIN genres ON genres.id = book_genres.genre_id
+	WHERE book_genres.book_id = ANY($1)
+	ORDER BY book_genres.book_id, genres.name, genres.id
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, query, int64IDs(bookIDs))
+	if err != nil {
+		p.logger.Error("Faild to query book genres", "error", err)
+		return nil, fmt.Errorf("faild to query book genres: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[uint64][]models.BookGenre, len(bookIDs))
+	for rows.Next() {
+		var (
+			bookID uint64
+			genre  models.BookGenre
+		)
+		if err := rows.Scan(&bookID, &genre.ID, &genre.Slug, &genre.Name); err != nil {
+			p.logger.Error("Faild to scan book genres", "error", err)
+			return nil, fmt.Errorf("faild to scan book genres: %w", err)
+		}
+		result[bookID] = append(result[bookID], genre)
+	}
+
+	if err := rows.Err(); err != nil {
+		p.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return result, nil
+}
+
+// LinkGenres tags books with a genre by their genre strings in SQL,
+// the same way as the migration of genres does
+func (p *PostgresStorage) LinkGenres(ctx context.Context, bookIDs []uint64, createdAt time.Time) error {
+	// slugs of genres of books, nil ids are books without genres
+	names := `
+	SELECT books.id AS book_id, TRIM(books.genre) AS name, ` + fmt.Sprintf(slugExpression, "books.genre") + ` AS slug
+	FROM books
+	WHERE CASE WHEN $1::bigint[] IS NULL
+		THEN NOT EXISTS (SELECT 1 FROM book_genres WHERE book_genres.book_id = books.id)
+		ELSE books.id = ANY($1)
+	END
+	`
+	genresQuery := `
+	INSERT INTO genres (slug, name, created_at, updated_at)
+	SELECT DISTINCT ON (names.slug) names.slug, names.name, $2, $2
+	FROM (` + names + `) names
+	WHERE names.slug <> ''
+	ORDER BY names.slug, names.name
+	ON CONFLICT (slug) DO NOTHING
+	`
+	linksQuery := `
+	INSERT INTO book_genres (book_id, genre_id)
+	SELECT names.book_id, genres.id
+	FROM (` + names + `) names
+	JOIN genres ON genres.slug = names.slug
+	`
+
+	ids := int64IDs(bookIDs)
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		if ids != nil {
+			if _, err := tx.Exec(ctx, `DELETE FROM book_genres WHERE book_id = ANY($1)`, ids); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(ctx, genresQuery, ids, createdAt); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, linksQuery, ids)
+		return err
+	})
+	if err != nil {
+		p.logger.Error("Failed to link genres", "error", err)
+		return fmt.Errorf("failed to link genres: %w", err)
+	}
+	return nil
+}
+
+// genreConstraintError tells a used slug and a missing parent from other errors,
+// it returns nil for other errors
+func genreConstraintError(err error, genre models.Genre) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+	switch pgErr.Code {
+	case uniqueViolation:
+		return fmt.Errorf("genre with slug %s %w", genre.Slug, apperrors.ErrSlugConflict)
+	case foreignKeyViolation:
+		return fmt.Errorf("parent genre of %s %w", genre.Slug, apperrors.ErrGenreNotFound)
+	}
+	return nil
+}
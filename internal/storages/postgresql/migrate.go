@@ -0,0 +1,309 @@
+package postgresql
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationFiles contains schema migrations, they are built into the binary.
+// A file name is NNNN_name.up.sql or NNNN_name.down.sql,
+// NNNN is a version, migrations are applied in order of versions.
+// A migration must never be changed after it was released,
+// add a new one instead, checksums are verified on every run
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is a key of the advisory lock,
+// only one instance can migrate a database at the same time
+const migrationLockID = 0x626f6f6b73 // "books"
+
+// migration is one versioned change of a schema
+type migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // sha256 of Up
+}
+
+// MigrationStatus describes a migration and whether it was applied
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time // zero if it is not applied
+}
+
+// Migrator applies and rolls back schema migrations
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []migration
+	logger     abstraction.Logger
+}
+
+// NewMigrator creates a Migrator that uses a pool
+func NewMigrator(pool *pgxpool.Pool, logger abstraction.Logger) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+		logger:     logger,
+	}, nil
+}
+
+// OpenMigrator connects to a database and returns a Migrator,
+// the caller must close it
+func OpenMigrator(config *config.DatabaseConfig, logger abstraction.Logger) (*Migrator, error) {
+	pool, err := newPool(config)
+	if err != nil {
+		return nil, err
+	}
+	m, err := NewMigrator(pool, logger)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Close closes a connection pool of a Migrator
+func (m *Migrator) Close() {
+	m.pool.Close()
+}
+
+// Up applies all migrations that are not applied yet
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, mg.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx,
+					`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+					mg.Version, mg.Name, mg.Checksum, time.Now())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			m.logger.Info("Migration applied", "version", mg.Version, "name", mg.Name)
+		}
+		return nil
+	})
+}
+
+// Down rolls back the last steps applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, mg.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mg.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to roll back migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			m.logger.Info("Migration rolled back", "version", mg.Version, "name", mg.Name)
+			steps--
+		}
+		return nil
+	})
+}
+
+// Status returns all known migrations and when they were applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		statuses = make([]MigrationStatus, 0, len(m.migrations))
+		for _, mg := range m.migrations {
+			statuses = append(statuses, MigrationStatus{
+				Version:   mg.Version,
+				Name:      mg.Name,
+				AppliedAt: applied[mg.Version].AppliedAt,
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// withLock takes a connection, holds the advisory lock on it,
+// verifies applied migrations and calls fn.
+// The lock belongs to a session, so everything must be done on the same connection
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	// it waits while another instance is migrating
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		// ctx might be already canceled, but the lock must be released anyway
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			m.logger.Error("Failed to release migration lock", "error", err)
+			// the session still holds the lock, so the connection must not be reused
+			conn.Conn().Close(unlockCtx)
+		}
+	}()
+
+	_, err = conn.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+// applied reads applied migrations and checks that
+// they are the same as migrations of this binary
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.Query(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var version int64
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	for version, a := range applied {
+		i := slices.IndexFunc(m.migrations, func(mg migration) bool { return mg.Version == version })
+		if i < 0 {
+			return nil, fmt.Errorf("database has migration %d that is unknown to this version of the application", version)
+		}
+		if m.migrations[i].Checksum != a.Checksum {
+			return nil, fmt.Errorf("migration %d_%s was changed after it was applied", version, m.migrations[i].Name)
+		}
+	}
+
+	return applied, nil
+}
+
+// loadMigrations reads migrations from a directory, every version
+// must have both up and down files. Migrations are sorted by version
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		fileName := entry.Name()
+
+		base, direction, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), ".")
+		strVersion, name, found := strings.Cut(base, "_")
+		if !ok || !found || !strings.HasSuffix(fileName, ".sql") || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name: %s", fileName)
+		}
+		version, err := strconv.ParseInt(strVersion, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", fileName)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", fileName, err)
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &migration{Version: version, Name: name}
+			byVersion[version] = mg
+		}
+		if mg.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mg.Name, name)
+		}
+		if direction == "up" {
+			mg.Up = string(content)
+			sum := sha256.Sum256(content)
+			mg.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mg.Down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == "" || mg.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", mg.Version, mg.Name)
+		}
+		migrations = append(migrations, *mg)
+	}
+	slices.SortFunc(migrations, func(a, b migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	if len(migrations) == 0 {
+		return nil, errors.New("there are no migrations")
+	}
+	return migrations, nil
+}
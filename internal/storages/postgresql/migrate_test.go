@@ -0,0 +1,67 @@
+package postgresql
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatalf("Embedded migrations are invalid: %v", err)
+	}
+
+	for i, mg := range migrations {
+		if mg.Version != int64(i+1) {
+			t.Errorf("Expected version %d, got: %d (versions must have no gaps)", i+1, mg.Version)
+		}
+		if len(mg.Checksum) != 64 {
+			t.Errorf("Expected sha256 checksum for migration %d, got: %s", mg.Version, mg.Checksum)
+		}
+	}
+}
+
+func TestLoadMigrations_Sorted(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0010_b.up.sql":   {Data: []byte("SELECT 10")},
+		"m/0010_b.down.sql": {Data: []byte("SELECT -10")},
+		"m/0002_a.up.sql":   {Data: []byte("SELECT 2")},
+		"m/0002_a.down.sql": {Data: []byte("SELECT -2")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 2 || migrations[1].Version != 10 {
+		t.Errorf("Expected migrations sorted by version, got: %+v", migrations)
+	}
+	if migrations[0].Name != "a" || migrations[0].Down != "SELECT -2" {
+		t.Errorf("Unexpected migration: %+v", migrations[0])
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no down": {
+			"m/0001_a.up.sql": {Data: []byte("SELECT 1")},
+		},
+		"bad name": {
+			"m/first.up.sql":   {Data: []byte("SELECT 1")},
+			"m/first.down.sql": {Data: []byte("SELECT 1")},
+		},
+		"bad direction": {
+			"m/0001_a.sideways.sql": {Data: []byte("SELECT 1")},
+		},
+		"two names": {
+			"m/0001_a.up.sql":   {Data: []byte("SELECT 1")},
+			"m/0001_b.down.sql": {Data: []byte("SELECT 1")},
+		},
+	}
+
+	for name, fsys := range tests {
+		if _, err := loadMigrations(fsys, "m"); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
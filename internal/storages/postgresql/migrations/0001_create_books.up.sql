@@ -0,0 +1,10 @@
+-- IF NOT EXISTS keeps databases that were created before migrations working
+CREATE TABLE IF NOT EXISTS books (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(100) NOT NULL,
+	author VARCHAR(100) NOT NULL,
+	genre VARCHAR(100) NOT NULL,
+	publication_date TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
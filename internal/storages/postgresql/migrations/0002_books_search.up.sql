@@ -0,0 +1,10 @@
+-- full-text search, the column is computed by PostgreSQL itself,
+-- title is the most important (A) then author (B) and genre (C)
+ALTER TABLE books ADD COLUMN IF NOT EXISTS search tsvector
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', title), 'A') ||
+		setweight(to_tsvector('english', author), 'B') ||
+		setweight(to_tsvector('english', genre), 'C')
+	) STORED;
+
+CREATE INDEX IF NOT EXISTS books_search_idx ON books USING GIN (search);
@@ -0,0 +1,3 @@
+-- version of a row for optimistic concurrency (ETag),
+-- it is increased by every update
+ALTER TABLE books ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+-- ISBN is optional, books without it have NULL,
+-- so the unique index doesn't count them
+ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn VARCHAR(13);
+
+CREATE UNIQUE INDEX IF NOT EXISTS books_isbn_key ON books (isbn) WHERE isbn IS NOT NULL;
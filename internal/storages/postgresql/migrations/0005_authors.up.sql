@@ -0,0 +1,59 @@
+CREATE TABLE IF NOT EXISTS authors (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	sort_name VARCHAR(100) NOT NULL,
+	birth_year INTEGER,
+	death_year INTEGER,
+	bio TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+-- authors are matched by name in any case
+CREATE INDEX IF NOT EXISTS authors_name_idx ON authors (LOWER(name));
+CREATE INDEX IF NOT EXISTS authors_sort_name_idx ON authors (sort_name, id);
+
+-- position is the order of credits of a book
+CREATE TABLE IF NOT EXISTS book_authors (
+	book_id INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+	author_id INTEGER NOT NULL REFERENCES authors (id),
+	position INTEGER NOT NULL,
+	PRIMARY KEY (book_id, author_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_authors_author_idx ON book_authors (author_id);
+
+-- existing author strings are split like models.SplitAuthors: by ; & and "and",
+-- then a part with one word before its only comma is a "Last, First" name
+-- ("Tolkien, J.R.R." is "J.R.R. Tolkien"), other parts are split by commas.
+-- A surname of two words ("Le Guin, Ursula K.") becomes two authors, they can be fixed
+-- by PUT /books/{id}/authors. The same name in another case is one author
+CREATE TEMPORARY TABLE author_names ON COMMIT DROP AS
+SELECT books.id AS book_id, TRIM(names.name) AS name,
+	ROW_NUMBER() OVER (PARTITION BY books.id ORDER BY parts.position, names.position) AS position
+FROM books
+CROSS JOIN LATERAL regexp_split_to_table(books.author, '\s*([;&]|\mand\M)\s*') WITH ORDINALITY AS parts(part, position)
+CROSS JOIN LATERAL (
+	SELECT regexp_replace(TRIM(parts.part), '^([^[:space:],]+)\s*,\s*([^,]+)$', '\2 \1') AS name, 1::bigint AS position
+	WHERE TRIM(parts.part) ~ '^([^[:space:],]+)\s*,\s*([^,]+)$'
+	UNION ALL
+	SELECT split.name, split.position
+	FROM regexp_split_to_table(parts.part, '\s*,\s*') WITH ORDINALITY AS split(name, position)
+	WHERE TRIM(parts.part) !~ '^([^[:space:],]+)\s*,\s*([^,]+)$'
+) names
+WHERE TRIM(names.name) <> '';
+
+INSERT INTO authors (name, sort_name, created_at, updated_at)
+SELECT DISTINCT ON (LOWER(name))
+	name,
+	regexp_replace(name, '^(.+)\s+(\S+)$', '\2, \1'),
+	NOW() AT TIME ZONE 'UTC',
+	NOW() AT TIME ZONE 'UTC'
+FROM author_names
+ORDER BY LOWER(name), name;
+
+INSERT INTO book_authors (book_id, author_id, position)
+SELECT DISTINCT ON (author_names.book_id, authors.id) author_names.book_id, authors.id, author_names.position
+FROM author_names
+JOIN authors ON LOWER(authors.name) = LOWER(author_names.name)
+ORDER BY author_names.book_id, authors.id, author_names.position;
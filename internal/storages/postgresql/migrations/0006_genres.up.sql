@@ -0,0 +1,65 @@
+CREATE TABLE IF NOT EXISTS genres (
+	id SERIAL PRIMARY KEY,
+	parent_id INTEGER REFERENCES genres (id),
+	slug VARCHAR(100) NOT NULL UNIQUE,
+	name VARCHAR(100) NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS genres_parent_idx ON genres (parent_id);
+
+CREATE TABLE IF NOT EXISTS book_genres (
+	book_id INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+	genre_id INTEGER NOT NULL REFERENCES genres (id),
+	PRIMARY KEY (book_id, genre_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_genres_genre_idx ON book_genres (genre_id);
+
+-- existing genre strings become root genres, they are matched by slug (models.Slugify),
+-- so "Science Fiction" and "science fiction" are one genre. A name without latin letters
+-- or digits ("Фантастика") gets genre- and 12 hex digits of md5 of the trimmed name
+CREATE TEMPORARY TABLE genre_names ON COMMIT DROP AS
+SELECT
+	books.id AS book_id,
+	TRIM(books.genre) AS name,
+	CASE
+		WHEN regexp_replace(books.genre, '^\s+|\s+$', '', 'g') = '' THEN ''
+		ELSE COALESCE(
+			NULLIF(TRIM(BOTH '-' FROM regexp_replace(LOWER(books.genre), '[^a-z0-9]+', '-', 'g')), ''),
+			'genre-' || LEFT(md5(regexp_replace(books.genre, '^\s+|\s+$', '', 'g')), 12)
+		)
+	END AS slug
+FROM books;
+
+INSERT INTO genres (slug, name, created_at, updated_at)
+SELECT DISTINCT ON (slug)
+	slug,
+	name,
+	NOW() AT TIME ZONE 'UTC',
+	NOW() AT TIME ZONE 'UTC'
+FROM genre_names
+WHERE slug <> ''
+ORDER BY slug, name;
+
+INSERT INTO book_genres (book_id, genre_id)
+SELECT genre_names.book_id, genres.id
+FROM genre_names
+JOIN genres ON genres.slug = genre_names.slug;
+
+-- a book with a genre that has no genre now would lose it, so the migration stops
+DO $$
+DECLARE
+	lost INTEGER;
+BEGIN
+	SELECT COUNT(*) INTO lost
+	FROM genre_names
+	WHERE slug <> '' AND NOT EXISTS (
+		SELECT 1 FROM book_genres WHERE book_genres.book_id = genre_names.book_id
+	);
+	IF lost > 0 THEN
+		RAISE EXCEPTION 'genres migration: % books would lose their genres', lost;
+	END IF;
+END
+$$;
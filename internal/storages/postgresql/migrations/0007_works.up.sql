@@ -0,0 +1,39 @@
+-- a work is an abstract book, a row of books is one of its editions
+CREATE TABLE IF NOT EXISTS works (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(100) NOT NULL,
+	author VARCHAR(100) NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+-- works are matched by title and author in any case
+CREATE INDEX IF NOT EXISTS works_title_idx ON works (LOWER(title), LOWER(author));
+CREATE INDEX IF NOT EXISTS works_sort_idx ON works (title, id);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS work_id INTEGER REFERENCES works (id);
+ALTER TABLE books ADD COLUMN IF NOT EXISTS publisher VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS edition VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS format VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS page_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS language VARCHAR(2) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS books_work_idx ON books (work_id);
+
+-- every existing book becomes the only edition of a work,
+-- books with the same title and author in any case are editions of one work
+INSERT INTO works (title, author, created_at, updated_at)
+SELECT DISTINCT ON (LOWER(title), LOWER(author))
+	title,
+	author,
+	NOW() AT TIME ZONE 'UTC',
+	NOW() AT TIME ZONE 'UTC'
+FROM books
+ORDER BY LOWER(title), LOWER(author), id;
+
+UPDATE books
+SET work_id = works.id
+FROM works
+WHERE books.work_id IS NULL
+	AND LOWER(works.title) = LOWER(books.title)
+	AND LOWER(works.author) = LOWER(books.author);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS series (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS series_sort_idx ON series (name, id);
+
+-- a book is a volume of one series at most, a position might be fractional like 2.5
+CREATE TABLE IF NOT EXISTS series_volumes (
+	book_id INTEGER PRIMARY KEY REFERENCES books (id) ON DELETE CASCADE,
+	series_id INTEGER NOT NULL REFERENCES series (id),
+	position DOUBLE PRECISION NOT NULL,
+	UNIQUE (series_id, position)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS work_authors;
+DROP INDEX IF EXISTS works_title_idx;
+CREATE INDEX IF NOT EXISTS works_title_idx ON works (LOWER(title), LOWER(author));
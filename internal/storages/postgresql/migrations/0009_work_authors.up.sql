@@ -0,0 +1,47 @@
+-- position is the order of credits of a work
+CREATE TABLE IF NOT EXISTS work_authors (
+	work_id INTEGER NOT NULL REFERENCES works (id) ON DELETE CASCADE,
+	author_id INTEGER NOT NULL REFERENCES authors (id),
+	position INTEGER NOT NULL,
+	PRIMARY KEY (work_id, author_id)
+);
+
+CREATE INDEX IF NOT EXISTS work_authors_author_idx ON work_authors (author_id);
+
+-- works are matched by title in any case and the same authors
+DROP INDEX IF EXISTS works_title_idx;
+CREATE INDEX IF NOT EXISTS works_title_idx ON works (LOWER(title));
+
+-- existing author strings of works are split the same way as the migration of authors does
+CREATE TEMPORARY TABLE work_author_names ON COMMIT DROP AS
+SELECT works.id AS work_id, TRIM(names.name) AS name,
+	ROW_NUMBER() OVER (PARTITION BY works.id ORDER BY parts.position, names.position) AS position
+FROM works
+CROSS JOIN LATERAL regexp_split_to_table(works.author, '\s*([;&]|\mand\M)\s*') WITH ORDINALITY AS parts(part, position)
+CROSS JOIN LATERAL (
+	SELECT regexp_replace(TRIM(parts.part), '^([^[:space:],]+)\s*,\s*([^,]+)$', '\2 \1') AS name, 1::bigint AS position
+	WHERE TRIM(parts.part) ~ '^([^[:space:],]+)\s*,\s*([^,]+)$'
+	UNION ALL
+	SELECT split.name, split.position
+	FROM regexp_split_to_table(parts.part, '\s*,\s*') WITH ORDINALITY AS split(name, position)
+	WHERE TRIM(parts.part) !~ '^([^[:space:],]+)\s*,\s*([^,]+)$'
+) names
+WHERE TRIM(names.name) <> '';
+
+INSERT INTO authors (name, sort_name, created_at, updated_at)
+SELECT DISTINCT ON (LOWER(name))
+	name,
+	regexp_replace(name, '^(.+)\s+(\S+)$', '\2, \1'),
+	NOW() AT TIME ZONE 'UTC',
+	NOW() AT TIME ZONE 'UTC'
+FROM work_author_names
+WHERE NOT EXISTS (SELECT 1 FROM authors WHERE LOWER(authors.name) = LOWER(work_author_names.name))
+ORDER BY LOWER(name), name;
+
+INSERT INTO work_authors (work_id, author_id, position)
+SELECT DISTINCT ON (work_author_names.work_id, author.id) work_author_names.work_id, author.id, work_author_names.position
+FROM work_author_names
+CROSS JOIN LATERAL (
+	SELECT id FROM authors WHERE LOWER(authors.name) = LOWER(work_author_names.name) ORDER BY id LIMIT 1
+) author
+ORDER BY work_author_names.work_id, author.id, work_author_names.position;
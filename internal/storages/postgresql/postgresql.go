@@ -4,22 +4,70 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
+	"time"
 
 	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
 	"github.com/Talos-hub/BooksRestApi/internal/models"
 	"github.com/Talos-hub/BooksRestApi/internal/storages/config"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// uniqueViolation is a PostgreSQL error code
+const uniqueViolation = "23505"
+
+// querier is what a pool and a transaction have in common,
+// so the same methods work inside and outside of a transaction
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
 type PostgresStorage struct {
-	pool   *pgxpool.Pool
-	config *config.DatabaseConfig
-	logger abstraction.Logger
+	pool       *pgxpool.Pool
+	db         querier // the pool or a transaction
+	inTx       bool
+	txDeadline time.Time // zero if a transaction has no deadline
+	config     *config.DatabaseConfig
+	logger     abstraction.Logger
 }
 
-// NewPosgresStorage create new PostgresStorage that implemented Storage interface
+// NewPosgresStorage create new PostgresStorage that implemented Storage interface.
+// It applies all schema migrations that are not applied yet
 func NewPostgresStorage(config *config.DatabaseConfig, logger abstraction.Logger) (*PostgresStorage, error) {
+	pool, err := newPool(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// create or upgrade tables, migrations might be long
+	// on a big table, so there is no timeout
+	migrator, err := NewMigrator(pool, logger)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	if err = migrator.Up(context.Background()); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &PostgresStorage{
+		pool:   pool,
+		db:     pool,
+		config: config,
+		logger: logger,
+	}, nil
+}
+
+// newPool creates a connection pool and checks that database is available
+func newPool(config *config.DatabaseConfig) (*pgxpool.Pool, error) {
 	pgxconf, err := pgxpool.ParseConfig(config.ConnectionString())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse connection string: %w", err)
@@ -41,63 +89,64 @@ func NewPostgresStorage(config *config.DatabaseConfig, logger abstraction.Logger
 	defer cancel()
 	// ping to database
 	if err = pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("faild to ping database: %w", err)
 	}
 
-	// create a table book if it not exist
-	err = initTable(config, pool)
-	if err != nil {
-		return nil, err
-	}
-
-	return &PostgresStorage{
-		pool:   pool,
-		config: config,
-		logger: logger,
-	}, nil
+	return pool, nil
 }
 
-// initTable create a table if it not exist
-func initTable(config *config.DatabaseConfig, pool *pgxpool.Pool) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS books (
-		id SERIAL PRIMARY KEY,
-		title VARCHAR(100) NOT NULL,
-		author VARCHAR(100) NOT NULL,
-		genre VARCHAR(100) NOT NULL,
-		publication_date TIMESTAMP NOT NULL,
-		created_at TIMESTAMP NOT NULL,
-		updated_at TIMESTAMP NOT NULL
-	);
-	`
-	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
-	defer cancel()
-
-	_, err := pool.Exec(ctx, query)
-	if err != nil {
-		return fmt.Errorf("faild to init database table: %w", err)
-	}
-
-	return nil
-
-}
-
-// GetAll return all books from storage
-func (p *PostgresStorage) GetAll() ([]models.Book, error) {
-	query := `
-	SELECT
+// bookColumns are columns of a book in the order of scanBook
+const bookColumns = `
 		id,
 		title,
 		author,
 		genre,
 		publication_date,
 		created_at,
-		updated_at
+		updated_at,
+		version,
+		COALESCE(isbn, ''),
+		COALESCE(work_id, 0),
+		publisher,
+		edition,
+		format,
+		page_count,
+		language`
+
+// scanBook scans a row of bookColumns, extra are columns after them
+func scanBook(row pgx.Row, extra ...any) (models.Book, error) {
+	var book models.Book
+	dest := []any{
+		&book.General.ID,
+		&book.General.Title,
+		&book.General.Author,
+		&book.General.Genre,
+		&book.General.PublicationDate,
+		&book.CreatedAt,
+		&book.UpdatedAt,
+		&book.Version,
+		&book.General.ISBN,
+		&book.General.WorkID,
+		&book.General.Publisher,
+		&book.General.Edition,
+		&book.General.Format,
+		&book.General.PageCount,
+		&book.General.Language,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return book, err
+}
+
+// GetAll return all books from storage
+func (p *PostgresStorage) GetAll(ctx context.Context) ([]models.Book, error) {
+	query := `
+	SELECT ` + bookColumns + `
 	FROM books
 	ORDER BY id
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	ctx, cancel := p.withTimeout(ctx)
 	defer cancel()
 
 	//get amount of books
@@ -106,7 +155,7 @@ func (p *PostgresStorage) GetAll() ([]models.Book, error) {
 		return nil, err
 	}
 
-	rows, err := p.pool.Query(ctx, query)
+	rows, err := p.db.Query(ctx, query)
 	if err != nil {
 		p.logger.Error("Faild to query books", "error", err)
 		return nil, fmt.Errorf("faild to query books: %w", err)
@@ -116,7 +165,6 @@ func (p *PostgresStorage) GetAll() ([]models.Book, error) {
 
 	// created books avoid allocations
 	var books []models.Book = make([]models.Book, count)
-	var book models.Book
 
 	// it uses books[i] = book insted append because it more performance
 	// Direct assignment - very fast :
@@ -124,15 +172,7 @@ func (p *PostgresStorage) GetAll() ([]models.Book, error) {
 	// Direct memory access - O(1) time complexity
 	i := 0
 	for rows.Next() {
-		err := rows.Scan(
-			&book.General.ID,
-			&book.General.Title,
-			&book.General.Author,
-			&book.General.Genre,
-			&book.General.PublicationDate,
-			&book.CreatedAt,
-			&book.UpdatedAt,
-		)
+		book, err := scanBook(rows)
 		if err != nil {
 			p.logger.Error("Faild to scan books", "error", err)
 			return nil, fmt.Errorf("faild to scan books: %w", err)
@@ -152,37 +192,125 @@ func (p *PostgresStorage) GetAll() ([]models.Book, error) {
 	return books, nil
 }
 
+// Find return books that match a query.
+// Pagination is keyset (WHERE (column, id) > (...)) instead of OFFSET,
+// so any page is as fast as the first one
+func (p *PostgresStorage) Find(ctx context.Context, q models.BookQuery) ([]models.Book, error) {
+	query, args, err := buildFindQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, query, args...)
+	if err != nil {
+		p.logger.Error("Faild to query books", "error", err)
+		return nil, fmt.Errorf("faild to query books: %w", err)
+	}
+	defer rows.Close()
+
+	books := make([]models.Book, 0, q.Limit)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			p.logger.Error("Faild to scan books", "error", err)
+			return nil, fmt.Errorf("faild to scan books: %w", err)
+		}
+		books = append(books, book)
+	}
+
+	// Check for any errors during iteration
+	if err := rows.Err(); err != nil {
+		p.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return books, nil
+}
+
+// Stream returns books that match a query one by one as they come from database,
+// so memory doesn't depend on amount of books. There is no timeout of the storage,
+// a query lasts as long as a caller reads, it is stopped by ctx
+func (p *PostgresStorage) Stream(ctx context.Context, q models.BookQuery) iter.Seq2[models.Book, error] {
+	return func(yield func(models.Book, error) bool) {
+		query, args, err := buildFindQuery(q)
+		if err != nil {
+			yield(models.Book{}, err)
+			return
+		}
+
+		rows, err := p.db.Query(ctx, query, args...)
+		if err != nil {
+			p.logger.Error("Faild to query books", "error", err)
+			yield(models.Book{}, fmt.Errorf("faild to query books: %w", err))
+			return
+		}
+		// closing rows before the end cancels the rest of the query
+		defer rows.Close()
+
+		for rows.Next() {
+			book, err := scanBook(rows)
+			if err != nil {
+				p.logger.Error("Faild to scan books", "error", err)
+				yield(models.Book{}, fmt.Errorf("faild to scan books: %w", err))
+				return
+			}
+			if !yield(book, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			p.logger.Error("Error iterating rows", "error", err)
+			yield(models.Book{}, fmt.Errorf("error iterating rows: %w", err))
+		}
+	}
+}
+
 // GetById return a book by id
-func (p *PostgresStorage) GetById(id uint64) (models.Book, error) {
+func (p *PostgresStorage) GetById(ctx context.Context, id uint64) (models.Book, error) {
 	query := `
-	SELECT
-		id,
-		title,
-		author,
-		genre,
-		publication_date,
-		created_at,
-		updated_at
+	SELECT ` + bookColumns + `
 	FROM books
 	WHERE id = $1
 	`
+	// inside a transaction the row is locked until commit,
+	// so nobody changes it between a check and an update
+	if p.inTx {
+		query += "FOR UPDATE"
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	ctx, cancel := p.withTimeout(ctx)
 	defer cancel()
 
-	var book models.Book
-	err := p.pool.QueryRow(ctx, query, id).Scan(
-		&book.General.ID,
-		&book.General.Title,
-		&book.General.Author,
-		&book.General.Genre,
-		&book.General.PublicationDate,
-		&book.CreatedAt,
-		&book.UpdatedAt,
-	)
+	book, err := scanBook(p.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Book{}, fmt.Errorf("book with id %d %w", id, apperrors.ErrBookNotFound)
+		}
+		p.logger.Error("Faild to get book", "error", err)
+		return models.Book{}, fmt.Errorf("failed to get book: %w", err)
+	}
+	return book, nil
+}
+
+// GetByISBN return a book by its normalized ISBN
+func (p *PostgresStorage) GetByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	query := `
+	SELECT ` + bookColumns + `
+	FROM books
+	WHERE isbn = $1
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	book, err := scanBook(p.db.QueryRow(ctx, query, isbn))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return models.Book{}, fmt.Errorf("book with id %d not found", id)
+			return models.Book{}, fmt.Errorf("book with isbn %s %w", isbn, apperrors.ErrBookNotFound)
 		}
 		p.logger.Error("Faild to get book", "error", err)
 		return models.Book{}, fmt.Errorf("failed to get book: %w", err)
@@ -190,37 +318,100 @@ func (p *PostgresStorage) GetById(id uint64) (models.Book, error) {
 	return book, nil
 }
 
-// Save add a book to database
-func (p *PostgresStorage) Save(book models.Book) error {
+// Save add a book to database and returns it with id from database
+func (p *PostgresStorage) Save(ctx context.Context, book models.Book) (models.Book, error) {
 	query := `
-	INSERT INTO books (title, author, genre, publication_date, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, $6)
-	RETURNING id
+	INSERT INTO books (title, author, genre, publication_date, created_at, updated_at, isbn,
+		work_id, publisher, edition, format, page_count, language)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	RETURNING id, version
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	ctx, cancel := p.withTimeout(ctx)
 	defer cancel()
 
-	err := p.pool.QueryRow(ctx, query,
+	err := p.db.QueryRow(ctx, query,
 		book.General.Title,
 		book.General.Author,
 		book.General.Genre,
 		book.General.PublicationDate,
 		book.CreatedAt,
 		book.UpdatedAt,
-	).Scan(&book.General.ID)
+		nullISBN(book.General.ISBN),
+		nullWorkID(book.General.WorkID),
+		book.General.Publisher,
+		book.General.Edition,
+		book.General.Format,
+		book.General.PageCount,
+		book.General.Language,
+	).Scan(&book.General.ID, &book.Version)
 
 	if err != nil {
+		if conflict := conflictError(err, book); conflict != nil {
+			return models.Book{}, conflict
+		}
 		p.logger.Error("Failed to save book", "error", err)
-		return fmt.Errorf("failed to save book: %w", err)
+		return models.Book{}, fmt.Errorf("failed to save book: %w", err)
 	}
 
-	return nil
+	return book, nil
+
+}
+
+// SaveWithID add a book with its own id to database.
+// The id sequence is moved forward, so Save never generates the same id
+func (p *PostgresStorage) SaveWithID(ctx context.Context, book models.Book) (models.Book, error) {
+	query := `
+	INSERT INTO books (id, title, author, genre, publication_date, created_at, updated_at, isbn,
+		work_id, publisher, edition, format, page_count, language)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	RETURNING version
+	`
+	sequenceQuery := `
+	SELECT setval(pg_get_serial_sequence('books', 'id'), $1)
+	WHERE $1 > COALESCE(pg_sequence_last_value(pg_get_serial_sequence('books', 'id')::regclass), 0)
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	// both statements are one transaction (or a savepoint inside a transaction)
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query,
+			book.General.ID,
+			book.General.Title,
+			book.General.Author,
+			book.General.Genre,
+			book.General.PublicationDate,
+			book.CreatedAt,
+			book.UpdatedAt,
+			nullISBN(book.General.ISBN),
+			nullWorkID(book.General.WorkID),
+			book.General.Publisher,
+			book.General.Edition,
+			book.General.Format,
+			book.General.PageCount,
+			book.General.Language,
+		).Scan(&book.Version)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, sequenceQuery, int64(book.General.ID))
+		return err
+	})
+	if err != nil {
+		if conflict := conflictError(err, book); conflict != nil {
+			return models.Book{}, conflict
+		}
+		p.logger.Error("Failed to save book", "error", err)
+		return models.Book{}, fmt.Errorf("failed to save book: %w", err)
+	}
 
+	return book, nil
 }
 
-// Update update a book into database
-func (p *PostgresStorage) Update(book models.Book) error {
+// Update update a book into database and increases its version
+func (p *PostgresStorage) Update(ctx context.Context, book models.Book) error {
 	query := `
 	UPDATE books 
 	SET 
@@ -228,67 +419,188 @@ func (p *PostgresStorage) Update(book models.Book) error {
 		author = $2, 
 		genre = $3, 
 		publication_date = $4, 
-		updated_at = $5
-	WHERE id = $6
+		updated_at = $5,
+		isbn = $6,
+		work_id = $7,
+		publisher = $8,
+		edition = $9,
+		format = $10,
+		page_count = $11,
+		language = $12,
+		version = version + 1
+	WHERE id = $13
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	ctx, cancel := p.withTimeout(ctx)
 	defer cancel()
 
-	result, err := p.pool.Exec(ctx, query, book.General.Title,
+	result, err := p.db.Exec(ctx, query, book.General.Title,
 		book.General.Author,
 		book.General.Genre,
 		book.General.PublicationDate,
 		book.UpdatedAt,
+		nullISBN(book.General.ISBN),
+		nullWorkID(book.General.WorkID),
+		book.General.Publisher,
+		book.General.Edition,
+		book.General.Format,
+		book.General.PageCount,
+		book.General.Language,
 		book.General.ID,
 	)
 
 	if err != nil {
+		if conflict := conflictError(err, book); conflict != nil {
+			return conflict
+		}
 		p.logger.Error("Failed to update book", "error", err)
 		return fmt.Errorf("failed to update book: %w", err)
 	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("book with id: %d not found", book.General.ID)
+		return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrBookNotFound)
 	}
 
 	return nil
 
 }
 
+// UpdateFields update only given fields and updated_at of a book,
+// so other fields changed by somebody else are not overwritten
+func (p *PostgresStorage) UpdateFields(ctx context.Context, book models.Book, fields []models.BookField) error {
+	query, args, err := buildUpdateFieldsQuery(book, fields)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.Exec(ctx, query, args...)
+	if err != nil {
+		if conflict := conflictError(err, book); conflict != nil {
+			return conflict
+		}
+		p.logger.Error("Failed to update book", "error", err)
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrBookNotFound)
+	}
+
+	return nil
+}
+
 // Delete delete a book by id
-func (p *PostgresStorage) Delete(id uint64) error {
+func (p *PostgresStorage) Delete(ctx context.Context, id uint64) error {
 	query := `
 	DELETE FROM books
 	WHERE id = $1
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	ctx, cancel := p.withTimeout(ctx)
 	defer cancel()
 
-	result, err := p.pool.Exec(ctx, query, id)
+	result, err := p.db.Exec(ctx, query, id)
 	if err != nil {
 		p.logger.Error("Failed to delete a book", "error", err)
 		return fmt.Errorf("failed to delete a book: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("book with id: %d not found", id)
+		return fmt.Errorf("book with id: %d %w", id, apperrors.ErrBookNotFound)
 	}
 	return nil
 }
 
-// Close close a storage
+// WithTx runs fn in a transaction. A transaction inside
+// another one is a savepoint, so it can be rolled back alone.
+// Timeout limits every statement of fn, the whole transaction is limited
+// by TxTimeout, a savepoint has the deadline of its transaction
+func (p *PostgresStorage) WithTx(ctx context.Context, fn func(tx abstraction.Storage) error) error {
+	deadline := p.txDeadline
+	if !p.inTx && p.config.TxTimeout > 0 {
+		deadline = time.Now().Add(p.config.TxTimeout)
+	}
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		return fn(&PostgresStorage{
+			pool:       p.pool,
+			db:         tx,
+			inTx:       true,
+			txDeadline: deadline,
+			config:     p.config,
+			logger:     p.logger,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+	return nil
+}
+
+// Close close a storage, a storage of a transaction
+// doesn't own the pool, so it does nothing
 func (p *PostgresStorage) Close() error {
+	if p.inTx {
+		return nil
+	}
 	p.pool.Close()
 	return nil
 }
 
+// withTimeout limits one statement by Timeout,
+// but not longer than the deadline of its transaction
+func (p *PostgresStorage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(p.config.Timeout)
+	if !p.txDeadline.IsZero() && p.txDeadline.Before(deadline) {
+		deadline = p.txDeadline
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// isbnIndex is a name of the unique index of ISBN from migrations
+const isbnIndex = "books_isbn_key"
+
+// nullISBN stores a book without ISBN as NULL, so the unique index skips it
+func nullISBN(isbn string) any {
+	if isbn == "" {
+		return nil
+	}
+	return isbn
+}
+
+// nullWorkID stores a book without a work as NULL, so the foreign key skips it
+func nullWorkID(id uint64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// conflictError converts a unique violation to ErrConflict or ErrISBNConflict,
+// it returns nil for other errors
+func conflictError(err error, book models.Book) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return nil
+	}
+	if pgErr.ConstraintName == isbnIndex {
+		return fmt.Errorf("book with isbn: %s %w", book.General.ISBN, apperrors.ErrISBNConflict)
+	}
+	return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrConflict)
+}
+
 // Helper function to get total book count
 func (p *PostgresStorage) getCount(ctx context.Context) (int, error) {
 	query := `SELECT COUNT(*) FROM books`
 
 	var count int
-	err := p.pool.QueryRow(ctx, query).Scan(&count)
+	err := p.db.QueryRow(ctx, query).Scan(&count)
 	if err != nil {
 		p.logger.Error("Failed to get book id", "error", err)
 		return 0, fmt.Errorf("failed to get books count: %w", err)
@@ -0,0 +1,165 @@
+package postgresql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// sortColumns is a whitelist of columns that books might be sorted by.
+// A column name cannot be a query parameter, so it is put
+// into SQL text and must never come from a client directly
+var sortColumns = map[models.SortField]string{
+	models.SortByID:              "id",
+	models.SortByTitle:           "title",
+	models.SortByAuthor:          "author",
+	models.SortByGenre:           "genre",
+	models.SortByPublicationDate: "publication_date",
+}
+
+// likeEscaper escapes wildcards of LIKE, so they are matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// subgenresFilter matches books tagged with genres or any of their descendants,
+// %s is a condition of the genres, like "id = $1"
+const subgenresFilter = `id IN (
+	SELECT book_id FROM book_genres WHERE genre_id IN (
+		WITH RECURSIVE tree (id) AS (
+			SELECT id FROM genres WHERE %s
+			UNION
+			SELECT genres.id FROM genres JOIN tree ON genres.parent_id = tree.id
+		)
+		SELECT id FROM tree
+	)
+)`
+
+// buildFindQuery builds a parameterised SELECT from a BookQuery.
+// All values are passed as arguments, only whitelisted columns get into the text
+func buildFindQuery(q models.BookQuery) (string, []any, error) {
+	sortBy := q.SortBy
+	if sortBy == "" {
+		sortBy = models.SortByID
+	}
+	column, ok := sortColumns[sortBy]
+	if !ok {
+		return "", nil, fmt.Errorf("cannot sort books by %q", sortBy)
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	// arg adds a value and returns its placeholder
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if q.Author != "" {
+		where = append(where, "LOWER(author) = LOWER("+arg(q.Author)+")")
+	}
+	if q.Genre != "" {
+		// a genre is matched like LinkGenres does it or by its name
+		where = append(where, fmt.Sprintf(subgenresFilter, "slug = "+arg(models.Slugify(q.Genre))+" OR LOWER(name) = LOWER("+arg(q.Genre)+")"))
+	}
+	if q.Title != "" {
+		where = append(where, "title ILIKE "+arg("%"+likeEscaper.Replace(q.Title)+"%"))
+	}
+	if q.AuthorID != 0 {
+		where = append(where, "id IN (SELECT book_id FROM book_authors WHERE author_id = "+arg(q.AuthorID)+")")
+	}
+	if q.WorkID != 0 {
+		where = append(where, "work_id = "+arg(q.WorkID))
+	}
+	if q.GenreID != 0 {
+		where = append(where, fmt.Sprintf(subgenresFilter, "id = "+arg(q.GenreID)))
+	}
+	if !q.PublishedAfter.IsZero() {
+		where = append(where, "publication_date >= "+arg(q.PublishedAfter))
+	}
+	if !q.PublishedBefore.IsZero() {
+		where = append(where, "publication_date < "+arg(q.PublishedBefore))
+	}
+
+	direction, compare := "ASC", ">"
+	if q.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	// keyset pagination, id makes the order unique
+	if q.After != nil {
+		if sortBy == models.SortByID {
+			where = append(where, "id "+compare+" "+arg(q.After.General.ID))
+		} else {
+			where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)",
+				column, compare, arg(sortBy.Value(*q.After)), arg(q.After.General.ID)))
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`
+	SELECT ` + bookColumns + `
+	FROM books
+	`)
+	if len(where) > 0 {
+		sb.WriteString("WHERE " + strings.Join(where, " AND ") + "\n")
+	}
+	if sortBy == models.SortByID {
+		sb.WriteString(fmt.Sprintf("ORDER BY id %s\n", direction))
+	} else {
+		sb.WriteString(fmt.Sprintf("ORDER BY %s %s, id %s\n", column, direction, direction))
+	}
+	if q.Limit > 0 {
+		sb.WriteString("LIMIT " + arg(q.Limit) + "\n")
+	}
+
+	return sb.String(), args, nil
+}
+
+// fieldColumns is a whitelist of columns that might be updated one by one
+var fieldColumns = map[models.BookField]string{
+	models.FieldTitle:           "title",
+	models.FieldAuthor:          "author",
+	models.FieldGenre:           "genre",
+	models.FieldPublicationDate: "publication_date",
+	models.FieldISBN:            "isbn",
+	models.FieldWorkID:          "work_id",
+	models.FieldPublisher:       "publisher",
+	models.FieldEdition:         "edition",
+	models.FieldFormat:          "format",
+	models.FieldPageCount:       "page_count",
+	models.FieldLanguage:        "language",
+}
+
+// buildUpdateFieldsQuery builds a parameterised UPDATE that sets
+// only given fields and updated_at of a book, the version is increased
+func buildUpdateFieldsQuery(book models.Book, fields []models.BookField) (string, []any, error) {
+	sets := make([]string, 0, len(fields)+1)
+	args := make([]any, 0, len(fields)+2)
+	// arg adds a value and returns its placeholder
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	for _, field := range fields {
+		column, ok := fieldColumns[field]
+		if !ok {
+			return "", nil, fmt.Errorf("cannot update field %q", field)
+		}
+		value := field.Value(book)
+		switch field {
+		case models.FieldISBN:
+			value = nullISBN(book.General.ISBN)
+		case models.FieldWorkID:
+			value = nullWorkID(book.General.WorkID)
+		}
+		sets = append(sets, column+" = "+arg(value))
+	}
+	sets = append(sets, "updated_at = "+arg(book.UpdatedAt), "version = version + 1")
+
+	query := fmt.Sprintf("UPDATE books SET %s WHERE id = %s", strings.Join(sets, ", "), arg(book.General.ID))
+	return query, args, nil
+}
@@ -0,0 +1,80 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// headlineOptions wraps matched words in control characters, they are replaced by <mark></mark>
+// after the stored text is escaped (markHighlight).
+// Fields are short, so they are highlighted entirely instead of fragments
+const headlineOptions = "StartSel=" + startSel + ", StopSel=" + stopSel + ", HighlightAll=true"
+
+// selectors of matched words, they aren't changed by html.EscapeString
+// and are removed from stored text before highlighting
+const (
+	startSel = "\x02"
+	stopSel  = "\x03"
+)
+
+// markReplacer turns selectors of ts_headline into <mark></mark>
+var markReplacer = strings.NewReplacer(startSel, "<mark>", stopSel, "</mark>")
+
+// markHighlight escapes a field highlighted by ts_headline as HTML,
+// so only <mark></mark> are tags in it
+func markHighlight(s string) string {
+	return markReplacer.Replace(html.EscapeString(s))
+}
+
+// Search return books that match a text query ordered by relevance.
+// The query has web search syntax: "quoted phrase", -excluded, or
+func (p *PostgresStorage) Search(ctx context.Context, text string, limit int) ([]models.SearchResult, error) {
+	query := `
+	SELECT ` + bookColumns + `,
+		ts_rank(search, q)::float8 AS score,
+		ts_headline('english', translate(title, chr(2) || chr(3), ''), q, $2),
+		ts_headline('english', translate(author, chr(2) || chr(3), ''), q, $2),
+		ts_headline('english', translate(genre, chr(2) || chr(3), ''), q, $2)
+	FROM books, websearch_to_tsquery('english', $1) AS q
+	WHERE search @@ q
+	ORDER BY score DESC, id
+	LIMIT $3
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, query, text, headlineOptions, limit)
+	if err != nil {
+		p.logger.Error("Faild to search books", "error", err)
+		return nil, fmt.Errorf("faild to search books: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]models.SearchResult, 0, limit)
+	for rows.Next() {
+		var r models.SearchResult
+		book, err := scanBook(rows, &r.Score, &r.Highlight.Title, &r.Highlight.Author, &r.Highlight.Genre)
+		if err != nil {
+			p.logger.Error("Faild to scan search results", "error", err)
+			return nil, fmt.Errorf("faild to scan search results: %w", err)
+		}
+		r.Book = book
+		r.Highlight.Title = markHighlight(r.Highlight.Title)
+		r.Highlight.Author = markHighlight(r.Highlight.Author)
+		r.Highlight.Genre = markHighlight(r.Highlight.Genre)
+		results = append(results, r)
+	}
+
+	// Check for any errors during iteration
+	if err := rows.Err(); err != nil {
+		p.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return results, nil
+}
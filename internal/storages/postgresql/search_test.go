@@ -0,0 +1,10 @@
+package postgresql
+
+import "testing"
+
+func TestMarkHighlight(t *testing.T) {
+	got := markHighlight("<b>Tom</b> & \x02Jerry\x03")
+	if got != "&lt;b&gt;Tom&lt;/b&gt; &amp; <mark>Jerry</mark>" {
+		t.Errorf("Expected an escaped highlight, got: %s", got)
+	}
+}
@@ -0,0 +1,301 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// FindSeries return series ordered by name and id
+func (p *PostgresStorage) FindSeries(ctx context.Context, q models.SeriesQuery) ([]models.Series, error) {
+	var args []any
+	// arg adds a value and returns its placeholder
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`
+	SELECT id, name, description, created_at, updated_at
+	FROM series
+	`)
+	if q.After != nil {
+		sb.WriteString(fmt.Sprintf("WHERE (name, id) > (%s, %s)\n", arg(q.After.Name), arg(q.After.ID)))
+	}
+	sb.WriteString("ORDER BY name, id\n")
+	if q.Limit > 0 {
+		sb.WriteString("LIMIT " + arg(q.Limit) + "\n")
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, sb.String(), args...)
+	if err != nil {
+		p.logger.Error("Faild to query series", "error", err)
+		return nil, fmt.Errorf("faild to query series: %w", err)
+	}
+	defer rows.Close()
+
+	found := make([]models.Series, 0, q.Limit)
+	for rows.Next() {
+		var series models.Series
+		err := rows.Scan(&series.ID, &series.Name, &series.Description, &series.CreatedAt, &series.UpdatedAt)
+		if err != nil {
+			p.logger.Error("Faild to scan series", "error", err)
+			return nil, fmt.Errorf("faild to scan series: %w", err)
+		}
+		found = append(found, series)
+	}
+
+	if err := rows.Err(); err != nil {
+		p.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return found, nil
+}
+
+// GetSeries return a series by id
+func (p *PostgresStorage) GetSeries(ctx context.Context, id uint64) (models.Series, error) {
+	query := `
+	SELECT id, name, description, created_at, updated_at
+	FROM series
+	WHERE id = $1
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var series models.Series
+	err := p.db.QueryRow(ctx, query, id).Scan(&series.ID, &series.Name, &series.Description, &series.CreatedAt, &series.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Series{}, fmt.Errorf("series with id %d %w", id, apperrors.ErrSeriesNotFound)
+		}
+		p.logger.Error("Faild to get series", "error", err)
+		return models.Series{}, fmt.Errorf("failed to get series: %w", err)
+	}
+	return series, nil
+}
+
+// SaveSeries add a series and returns it with id from database
+func (p *PostgresStorage) SaveSeries(ctx context.Context, series models.Series) (models.Series, error) {
+	query := `
+	INSERT INTO series (name, description, created_at, updated_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := p.db.QueryRow(ctx, query, series.Name, series.Description, series.CreatedAt, series.UpdatedAt).Scan(&series.ID)
+	if err != nil {
+		p.logger.Error("Failed to save series", "error", err)
+		return models.Series{}, fmt.Errorf("failed to save series: %w", err)
+	}
+	return series, nil
+}
+
+// UpdateSeries update a series, created_at is never changed
+func (p *PostgresStorage) UpdateSeries(ctx context.Context, series models.Series) error {
+	query := `
+	UPDATE series
+	SET
+		name = $1,
+		description = $2,
+		updated_at = $3
+	WHERE id = $4
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.Exec(ctx, query, series.Name, series.Description, series.UpdatedAt, series.ID)
+	if err != nil {
+		p.logger.Error("Failed to update series", "error", err)
+		return fmt.Errorf("failed to update series: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("series with id: %d %w", series.ID, apperrors.ErrSeriesNotFound)
+	}
+	return nil
+}
+
+// DeleteSeries delete a series, the foreign key of series_volumes
+// doesn't allow to delete a series with volumes
+func (p *PostgresStorage) DeleteSeries(ctx context.Context, id uint64) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.Exec(ctx, `DELETE FROM series WHERE id = $1`, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return fmt.Errorf("series with id: %d %w", id, apperrors.ErrSeriesHasVolumes)
+		}
+		p.logger.Error("Failed to delete series", "error", err)
+		return fmt.Errorf("failed to delete series: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("series with id: %d %w", id, apperrors.ErrSeriesNotFound)
+	}
+	return nil
+}
+
+// SetBookSeries places a book at a position of a series, zero seriesID takes it out
+func (p *PostgresStorage) SetBookSeries(ctx context.Context, bookID, seriesID uint64, position float64) error {
+	upsertQuery := `
+	INSERT INTO series_volumes (book_id, series_id, position)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (book_id) DO UPDATE SET series_id = EXCLUDED.series_id, position = EXCLUDED.position
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE id = $1)`, bookID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("book with id: %d %w", bookID, apperrors.ErrBookNotFound)
+		}
+		if seriesID == 0 {
+			_, err := tx.Exec(ctx, `DELETE FROM series_volumes WHERE book_id = $1`, bookID)
+			return err
+		}
+		_, err := tx.Exec(ctx, upsertQuery, bookID, seriesID, position)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, apperrors.ErrBookNotFound) {
+			return err
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case uniqueViolation:
+				return fmt.Errorf("position %g of series %d %w", position, seriesID, apperrors.ErrPositionConflict)
+			case foreignKeyViolation:
+				return fmt.Errorf("series with id: %d %w", seriesID, apperrors.ErrSeriesNotFound)
+			}
+		}
+		p.logger.Error("Failed to set book series", "error", err)
+		return fmt.Errorf("failed to set book series: %w", err)
+	}
+	return nil
+}
+
+// SeriesVolumes return books of a series ordered by position
+func (p *PostgresStorage) SeriesVolumes(ctx context.Context, seriesID uint64) ([]models.SeriesVolume, error) {
+	query := `
+	SELECT books.id, books.title, series_volumes.position
+	FROM series_volumes
+	JOIN books ON books.id = series_volumes.book_id
+	WHERE series_volumes.series_id = $1
+	ORDER BY series_volumes.position
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, query, seriesID)
+	if err != nil {
+		p.logger.Error("Faild to query series volumes", "error", err)
+		return nil, fmt.Errorf("faild to query series volumes: %w", err)
+	}
+	defer rows.Close()
+
+	volumes := []models.SeriesVolume{}
+	for rows.Next() {
+		var volume models.SeriesVolume
+		if err := rows.Scan(&volume.ID, &volume.Title, &volume.Position); err != nil {
+			p.logger.Error("Faild to scan series volumes", "error", err)
+			return nil, fmt.Errorf("faild to scan series volumes: %w", err)
+		}
+		volumes = append(volumes, volume)
+	}
+
+	if err := rows.Err(); err != nil {
+		p.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return volumes, nil
+}
+
+// BookSeries return series of books with their neighbours,
+// neighbours are found by window functions over volumes of the same series
+func (p *PostgresStorage) BookSeries(ctx context.Context, bookIDs []uint64) (map[uint64]models.BookSeries, error) {
+	query := `
+	SELECT volumes.book_id, series.id, series.name, volumes.position,
+		volumes.prev_id, volumes.prev_title, volumes.prev_position,
+		volumes.next_id, volumes.next_title, volumes.next_position
+	FROM (
+		SELECT series_volumes.book_id, series_volumes.series_id, series_volumes.position,
+			LAG(books.id) OVER w AS prev_id,
+			LAG(books.title) OVER w AS prev_title,
+			LAG(series_volumes.position) OVER w AS prev_position,
+			LEAD(books.id) OVER w AS next_id,
+			LEAD(books.title) OVER w AS next_title,
+			LEAD(series_volumes.position) OVER w AS next_position
+		FROM series_volumes
+		JOIN books ON books.id = series_volumes.book_id
+		WHERE series_volumes.series_id IN (SELECT series_id FROM series_volumes WHERE book_id = ANY($1))
+		WINDOW w AS (PARTITION BY series_volumes.series_id ORDER BY series_volumes.position)
+	) volumes
+	JOIN series ON series.id = volumes.series_id
+	WHERE volumes.book_id = ANY($1)
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, query, int64IDs(bookIDs))
+	if err != nil {
+		p.logger.Error("Faild to query book series", "error", err)
+		return nil, fmt.Errorf("faild to query book series: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[uint64]models.BookSeries, len(bookIDs))
+	for rows.Next() {
+		var (
+			bookID         uint64
+			series         models.BookSeries
+			prevID, nextID *uint64
+			prevTitle      *string
+			nextTitle      *string
+			prevPosition   *float64
+			nextPosition   *float64
+		)
+		err := rows.Scan(&bookID, &series.ID, &series.Name, &series.Position,
+			&prevID, &prevTitle, &prevPosition, &nextID, &nextTitle, &nextPosition)
+		if err != nil {
+			p.logger.Error("Faild to scan book series", "error", err)
+			return nil, fmt.Errorf("faild to scan book series: %w", err)
+		}
+		if prevID != nil {
+			series.Previous = &models.SeriesVolume{ID: *prevID, Title: *prevTitle, Position: *prevPosition}
+		}
+		if nextID != nil {
+			series.Next = &models.SeriesVolume{ID: *nextID, Title: *nextTitle, Position: *nextPosition}
+		}
+		result[bookID] = series
+	}
+
+	if err := rows.Err(); err != nil {
+		p.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return result, nil
+}
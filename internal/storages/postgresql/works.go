@@ -0,0 +1,381 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// workAuthorIDs and bookAuthorIDs are sorted ids of authors of works.id and books.id,
+// works are matched by them, so the order of credits doesn't matter
+const (
+	workAuthorIDs = `ARRAY(SELECT author_id::bigint FROM work_authors WHERE work_authors.work_id = works.id ORDER BY author_id)`
+	bookAuthorIDs = `ARRAY(SELECT author_id::bigint FROM book_authors WHERE book_authors.book_id = books.id ORDER BY author_id)`
+)
+
+// FindWorks return works ordered by title and id
+func (p *PostgresStorage) FindWorks(ctx context.Context, q models.WorkQuery) ([]models.Work, error) {
+	var args []any
+	// arg adds a value and returns its placeholder
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`
+	SELECT id, title, author, created_at, updated_at
+	FROM works
+	`)
+	if q.After != nil {
+		sb.WriteString(fmt.Sprintf("WHERE (title, id) > (%s, %s)\n", arg(q.After.Title), arg(q.After.ID)))
+	}
+	sb.WriteString("ORDER BY title, id\n")
+	if q.Limit > 0 {
+		sb.WriteString("LIMIT " + arg(q.Limit) + "\n")
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, sb.String(), args...)
+	if err != nil {
+		p.logger.Error("Faild to query works", "error", err)
+		return nil, fmt.Errorf("faild to query works: %w", err)
+	}
+	defer rows.Close()
+
+	works := make([]models.Work, 0, q.Limit)
+	for rows.Next() {
+		var work models.Work
+		err := rows.Scan(&work.ID, &work.Title, &work.Author, &work.CreatedAt, &work.UpdatedAt)
+		if err != nil {
+			p.logger.Error("Faild to scan works", "error", err)
+			return nil, fmt.Errorf("faild to scan works: %w", err)
+		}
+		works = append(works, work)
+	}
+
+	if err := rows.Err(); err != nil {
+		p.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	if err := withWorkAuthors(ctx, p.db, works); err != nil {
+		p.logger.Error("Faild to query work authors", "error", err)
+		return nil, fmt.Errorf("faild to query work authors: %w", err)
+	}
+	return works, nil
+}
+
+// GetWork return a work by id
+func (p *PostgresStorage) GetWork(ctx context.Context, id uint64) (models.Work, error) {
+	query := `
+	SELECT id, title, author, created_at, updated_at
+	FROM works
+	WHERE id = $1
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var work models.Work
+	err := p.db.QueryRow(ctx, query, id).Scan(&work.ID, &work.Title, &work.Author, &work.CreatedAt, &work.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Work{}, fmt.Errorf("work with id %d %w", id, apperrors.ErrWorkNotFound)
+		}
+		p.logger.Error("Faild to get work", "error", err)
+		return models.Work{}, fmt.Errorf("failed to get work: %w", err)
+	}
+
+	works := []models.Work{work}
+	if err := withWorkAuthors(ctx, p.db, works); err != nil {
+		p.logger.Error("Faild to get work authors", "error", err)
+		return models.Work{}, fmt.Errorf("failed to get work authors: %w", err)
+	}
+	return works[0], nil
+}
+
+// SaveWork add a work and returns it with id from database,
+// it is linked to authors of its author string
+func (p *PostgresStorage) SaveWork(ctx context.Context, work models.Work) (models.Work, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		authorIDs, err := authorIDs(ctx, tx, work.Author, work.CreatedAt)
+		if err != nil {
+			return err
+		}
+		work, err = saveWork(ctx, tx, work, authorIDs)
+		return err
+	})
+	if err != nil {
+		p.logger.Error("Failed to save work", "error", err)
+		return models.Work{}, fmt.Errorf("failed to save work: %w", err)
+	}
+	return work, nil
+}
+
+// UpdateWork update a work, created_at is never changed.
+// Authors are linked again from the author string
+func (p *PostgresStorage) UpdateWork(ctx context.Context, work models.Work) error {
+	query := `
+	UPDATE works
+	SET
+		title = $1,
+		author = $2,
+		updated_at = $3
+	WHERE id = $4
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var affected int64
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, work.Title, work.Author, work.UpdatedAt, work.ID)
+		if err != nil {
+			return err
+		}
+		if affected = result.RowsAffected(); affected == 0 {
+			return nil
+		}
+
+		authorIDs, err := authorIDs(ctx, tx, work.Author, work.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return setWorkAuthors(ctx, tx, work.ID, authorIDs)
+	})
+	if err != nil {
+		p.logger.Error("Failed to update work", "error", err)
+		return fmt.Errorf("failed to update work: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("work with id: %d %w", work.ID, apperrors.ErrWorkNotFound)
+	}
+	return nil
+}
+
+// DeleteWork delete a work, the foreign key of books
+// doesn't allow to delete a work with editions
+func (p *PostgresStorage) DeleteWork(ctx context.Context, id uint64) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.Exec(ctx, `DELETE FROM works WHERE id = $1`, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return fmt.Errorf("work with id: %d %w", id, apperrors.ErrWorkHasEditions)
+		}
+		p.logger.Error("Failed to delete work", "error", err)
+		return fmt.Errorf("failed to delete work: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("work with id: %d %w", id, apperrors.ErrWorkNotFound)
+	}
+	return nil
+}
+
+// EnsureWork returns a work with the same title in any case and the same authors
+// or saves a new one
+func (p *PostgresStorage) EnsureWork(ctx context.Context, work models.Work) (models.Work, error) {
+	query := `
+	SELECT id, title, author, created_at, updated_at
+	FROM works
+	WHERE LOWER(title) = LOWER($1) AND ` + workAuthorIDs + ` = $2::bigint[]
+	ORDER BY id
+	LIMIT 1
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var found models.Work
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		authorIDs, err := authorIDs(ctx, tx, work.Author, work.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		// an empty array and not NULL matches works without authors
+		sorted := make([]int64, 0, len(authorIDs))
+		sorted = append(sorted, int64IDs(authorIDs)...)
+		slices.Sort(sorted)
+		sorted = slices.Compact(sorted)
+
+		err = tx.QueryRow(ctx, query, work.Title, sorted).Scan(
+			&found.ID, &found.Title, &found.Author, &found.CreatedAt, &found.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			found, err = saveWork(ctx, tx, work, authorIDs)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		works := []models.Work{found}
+		err = withWorkAuthors(ctx, tx, works)
+		found = works[0]
+		return err
+	})
+	if err != nil {
+		p.logger.Error("Failed to ensure work", "error", err)
+		return models.Work{}, fmt.Errorf("failed to ensure work: %w", err)
+	}
+	return found, nil
+}
+
+// LinkWorks makes every book without a work an edition of a work in SQL,
+// books with the same title in any case and the same authors are editions of one work
+func (p *PostgresStorage) LinkWorks(ctx context.Context, createdAt time.Time) error {
+	// one new work of every title and authors of books without a work,
+	// ids are taken before inserting, so authors are linked in the same statement
+	worksQuery := `
+	WITH missing AS (
+		SELECT nextval(pg_get_serial_sequence('works', 'id')) AS id, keys.title, keys.author, keys.credits
+		FROM (
+			SELECT DISTINCT ON (LOWER(keys.title), keys.author_ids) keys.title, keys.author, keys.credits
+			FROM (
+				SELECT
+					books.id,
+					books.title,
+					books.author,
+					` + bookAuthorIDs + ` AS author_ids,
+					ARRAY(SELECT author_id::bigint FROM book_authors WHERE book_authors.book_id = books.id ORDER BY position) AS credits
+				FROM books
+				WHERE books.work_id IS NULL
+			) keys
+			WHERE NOT EXISTS (
+				SELECT 1 FROM works
+				WHERE LOWER(works.title) = LOWER(keys.title) AND ` + workAuthorIDs + ` = keys.author_ids
+			)
+			ORDER BY LOWER(keys.title), keys.author_ids, keys.id
+		) keys
+	), saved AS (
+		INSERT INTO works (id, title, author, created_at, updated_at)
+		SELECT id, title, author, $1, $1 FROM missing
+	)
+	INSERT INTO work_authors (work_id, author_id, position)
+	SELECT missing.id, credits.id, MIN(credits.position)
+	FROM missing
+	CROSS JOIN LATERAL unnest(missing.credits) WITH ORDINALITY AS credits(id, position)
+	GROUP BY missing.id, credits.id
+	`
+	linksQuery := `
+	UPDATE books
+	SET work_id = (
+		SELECT id FROM works
+		WHERE LOWER(works.title) = LOWER(books.title) AND ` + workAuthorIDs + ` = ` + bookAuthorIDs + `
+		ORDER BY id LIMIT 1
+	)
+	WHERE work_id IS NULL
+	`
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, worksQuery, createdAt); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, linksQuery)
+		return err
+	})
+	if err != nil {
+		p.logger.Error("Failed to link works", "error", err)
+		return fmt.Errorf("failed to link works: %w", err)
+	}
+	return nil
+}
+
+// saveWork inserts a work with authors in order of credits,
+// the caller runs it in a transaction
+func saveWork(ctx context.Context, db querier, work models.Work, authorIDs []uint64) (models.Work, error) {
+	query := `
+	INSERT INTO works (title, author, created_at, updated_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id
+	`
+	err := db.QueryRow(ctx, query, work.Title, work.Author, work.CreatedAt, work.UpdatedAt).Scan(&work.ID)
+	if err != nil {
+		return models.Work{}, err
+	}
+	if err := setWorkAuthors(ctx, db, work.ID, authorIDs); err != nil {
+		return models.Work{}, err
+	}
+
+	works := []models.Work{work}
+	if err := withWorkAuthors(ctx, db, works); err != nil {
+		return models.Work{}, err
+	}
+	return works[0], nil
+}
+
+// setWorkAuthors replaces links of a work like SetBookAuthors does
+func setWorkAuthors(ctx context.Context, db querier, workID uint64, authorIDs []uint64) error {
+	insertQuery := `
+	INSERT INTO work_authors (work_id, author_id, position)
+	SELECT $1, ids.id, MIN(ids.position)
+	FROM unnest($2::bigint[]) WITH ORDINALITY AS ids(id, position)
+	GROUP BY ids.id
+	`
+	if _, err := db.Exec(ctx, `DELETE FROM work_authors WHERE work_id = $1`, workID); err != nil {
+		return err
+	}
+	_, err := db.Exec(ctx, insertQuery, workID, int64IDs(authorIDs))
+	return err
+}
+
+// withWorkAuthors sets authors of works in order of credits
+func withWorkAuthors(ctx context.Context, db querier, works []models.Work) error {
+	if len(works) == 0 {
+		return nil
+	}
+	query := `
+	SELECT work_authors.work_id, authors.id, authors.name
+	FROM work_authors
+	JOIN authors ON authors.id = work_authors.author_id
+	WHERE work_authors.work_id = ANY($1)
+	ORDER BY work_authors.work_id, work_authors.position
+	`
+
+	ids := make([]int64, len(works))
+	index := make(map[uint64]int, len(works))
+	for i, work := range works {
+		ids[i] = int64(work.ID)
+		index[work.ID] = i
+		works[i].Authors = nil
+	}
+
+	rows, err := db.Query(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			workID uint64
+			author models.BookAuthor
+		)
+		if err := rows.Scan(&workID, &author.ID, &author.Name); err != nil {
+			return err
+		}
+		works[index[workID]].Authors = append(works[index[workID]].Authors, author)
+	}
+	return rows.Err()
+}
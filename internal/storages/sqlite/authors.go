@@ -0,0 +1,392 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// FindAuthors return authors ordered by sort name and id
+func (s *SqliteStorage) FindAuthors(ctx context.Context, q models.AuthorQuery) ([]models.Author, error) {
+	var args []any
+
+	var sb strings.Builder
+	sb.WriteString(`
+	SELECT id, name, sort_name, birth_year, death_year, bio, created_at, updated_at
+	FROM authors
+	`)
+	if q.After != nil {
+		sb.WriteString("WHERE (sort_name, id) > (?, ?)\n")
+		args = append(args, q.After.SortName, q.After.ID)
+	}
+	sb.WriteString("ORDER BY sort_name, id\n")
+	if q.Limit > 0 {
+		sb.WriteString("LIMIT ?\n")
+		args = append(args, q.Limit)
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.conn.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		s.logger.Error("Faild to query authors", "error", err)
+		return nil, fmt.Errorf("faild to query authors: %w", err)
+	}
+	defer rows.Close()
+
+	authors := make([]models.Author, 0, q.Limit)
+	for rows.Next() {
+		author, err := scanAuthor(rows)
+		if err != nil {
+			s.logger.Error("Faild to scan authors", "error", err)
+			return nil, fmt.Errorf("faild to scan authors: %w", err)
+		}
+		authors = append(authors, author)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return authors, nil
+}
+
+// GetAuthor return an author by id
+func (s *SqliteStorage) GetAuthor(ctx context.Context, id uint64) (models.Author, error) {
+	query := `
+	SELECT id, name, sort_name, birth_year, death_year, bio, created_at, updated_at
+	FROM authors
+	WHERE id = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	author, err := scanAuthor(s.conn.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Author{}, fmt.Errorf("author with id %d %w", id, apperrors.ErrAuthorNotFound)
+		}
+		s.logger.Error("Faild to get author", "error", err)
+		return models.Author{}, fmt.Errorf("failed to get author: %w", err)
+	}
+	return author, nil
+}
+
+// SaveAuthor add an author and returns it with id from database
+func (s *SqliteStorage) SaveAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	author, err := s.saveAuthor(ctx, author)
+	if err != nil {
+		s.logger.Error("Failed to save author", "error", err)
+		return models.Author{}, fmt.Errorf("failed to save author: %w", err)
+	}
+	return author, nil
+}
+
+// UpdateAuthor update an author, created_at is never changed
+func (s *SqliteStorage) UpdateAuthor(ctx context.Context, author models.Author) error {
+	query := `
+	UPDATE authors
+	SET
+		name = ?,
+		sort_name = ?,
+		birth_year = ?,
+		death_year = ?,
+		bio = ?,
+		updated_at = ?
+	WHERE id = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.conn.ExecContext(ctx, query,
+		author.Name,
+		author.SortName,
+		author.BirthYear,
+		author.DeathYear,
+		author.Bio,
+		author.UpdatedAt,
+		author.ID,
+	)
+	if err != nil {
+		s.logger.Error("Failed to update author", "error", err)
+		return fmt.Errorf("failed to update author: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update author: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("author with id: %d %w", author.ID, apperrors.ErrAuthorNotFound)
+	}
+	return nil
+}
+
+// DeleteAuthor delete an author, foreign keys of book_authors and work_authors
+// don't allow to delete an author of books or works
+func (s *SqliteStorage) DeleteAuthor(ctx context.Context, id uint64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.conn.ExecContext(ctx, `DELETE FROM authors WHERE id = ?`, id)
+	if err != nil {
+		if isForeignKeyError(err) {
+			return fmt.Errorf("author with id: %d %w", id, apperrors.ErrAuthorHasBooks)
+		}
+		s.logger.Error("Failed to delete author", "error", err)
+		return fmt.Errorf("failed to delete author: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete author: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("author with id: %d %w", id, apperrors.ErrAuthorNotFound)
+	}
+	return nil
+}
+
+// SetBookAuthors replaces authors of a book, a repeated author is kept
+// at its first position
+func (s *SqliteStorage) SetBookAuthors(ctx context.Context, bookID uint64, authorIDs []uint64) error {
+	return s.WithTx(ctx, func(tx abstraction.Storage) error {
+		conn := tx.(*SqliteStorage).conn
+
+		var exists bool
+		err := conn.QueryRowContext(ctx, `SELECT COUNT(*) > 0 FROM books WHERE id = ?`, bookID).Scan(&exists)
+		if err != nil {
+			s.logger.Error("Failed to set book authors", "error", err)
+			return fmt.Errorf("failed to set book authors: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("book with id: %d %w", bookID, apperrors.ErrBookNotFound)
+		}
+
+		if err := setBookAuthors(ctx, conn, bookID, authorIDs); err != nil {
+			if isForeignKeyError(err) {
+				return fmt.Errorf("author of book %d %w", bookID, apperrors.ErrAuthorNotFound)
+			}
+			s.logger.Error("Failed to set book authors", "error", err)
+			return fmt.Errorf("failed to set book authors: %w", err)
+		}
+		return nil
+	})
+}
+
+// BookAuthors return authors of books in order of credits
+func (s *SqliteStorage) BookAuthors(ctx context.Context, bookIDs []uint64) (map[uint64][]models.BookAuthor, error) {
+	result := make(map[uint64][]models.BookAuthor, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]any, len(bookIDs))
+	for i, id := range bookIDs {
+		args[i] = id
+	}
+	query := `
+	SELECT book_authors.book_id, authors.id, authors.name
+	FROM book_authors
+	JOIN authors ON authors.id = book_authors.author_id
+	WHERE book_authors.book_id IN (?` + strings.Repeat(", ?", len(bookIDs)-1) + `)
+	ORDER BY book_authors.book_id, book_authors.position
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Faild to query book authors", "error", err)
+		return nil, fmt.Errorf("faild to query book authors: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			bookID uint64
+			author models.BookAuthor
+		)
+		if err := rows.Scan(&bookID, &author.ID, &author.Name); err != nil {
+			s.logger.Error("Faild to scan book authors", "error", err)
+			return nil, fmt.Errorf("faild to scan book authors: %w", err)
+		}
+		result[bookID] = append(result[bookID], author)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return result, nil
+}
+
+// LinkAuthors links books to authors by their author strings.
+// SQLite has no regular expressions, so strings are split in Go
+func (s *SqliteStorage) LinkAuthors(ctx context.Context, bookIDs []uint64, createdAt time.Time) error {
+	err := s.WithTx(ctx, func(tx abstraction.Storage) error {
+		conn := tx.(*SqliteStorage).conn
+
+		// book ids and their author strings
+		query := `SELECT id, author FROM books WHERE id NOT IN (SELECT book_id FROM book_authors) ORDER BY id`
+		var args []any
+		if bookIDs != nil {
+			if len(bookIDs) == 0 {
+				return nil
+			}
+			query = `SELECT id, author FROM books WHERE id IN (?` + strings.Repeat(", ?", len(bookIDs)-1) + `) ORDER BY id`
+			for _, id := range bookIDs {
+				args = append(args, id)
+			}
+		}
+
+		rows, err := conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		books := make(map[uint64]string)
+		var ids []uint64
+		for rows.Next() {
+			var (
+				id     uint64
+				author string
+			)
+			if err := rows.Scan(&id, &author); err != nil {
+				rows.Close()
+				return err
+			}
+			books[id] = author
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			authorIDs, err := tx.(*SqliteStorage).authorIDs(ctx, books[id], createdAt)
+			if err != nil {
+				return err
+			}
+			if err := setBookAuthors(ctx, conn, id, authorIDs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to link authors", "error", err)
+		return fmt.Errorf("failed to link authors: %w", err)
+	}
+	return nil
+}
+
+// authorIDs returns ids of authors of an author string in order (models.SplitAuthors),
+// a name is matched in any case and a missing author is created with createdAt.
+// It has no timeout of its own, the caller runs it in a transaction
+func (s *SqliteStorage) authorIDs(ctx context.Context, author string, createdAt time.Time) ([]uint64, error) {
+	var ids []uint64
+	for _, name := range models.SplitAuthors(author) {
+		var id uint64
+		err := s.conn.QueryRowContext(ctx,
+			`SELECT id FROM authors WHERE LOWER(name) = LOWER(?) ORDER BY id LIMIT 1`, name,
+		).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			saved, err := s.saveAuthor(ctx, models.Author{
+				Name:      name,
+				SortName:  models.SortName(name),
+				CreatedAt: createdAt,
+				UpdatedAt: createdAt,
+			})
+			if err != nil {
+				return nil, err
+			}
+			id = saved.ID
+		} else if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// saveAuthor inserts an author without a timeout of its own
+func (s *SqliteStorage) saveAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	query := `
+	INSERT INTO authors (name, sort_name, birth_year, death_year, bio, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	RETURNING id
+	`
+	err := s.conn.QueryRowContext(ctx, query,
+		author.Name,
+		author.SortName,
+		author.BirthYear,
+		author.DeathYear,
+		author.Bio,
+		author.CreatedAt,
+		author.UpdatedAt,
+	).Scan(&author.ID)
+	if err != nil {
+		return models.Author{}, err
+	}
+	return author, nil
+}
+
+// setBookAuthors replaces links of a book, the caller runs it in a transaction
+func setBookAuthors(ctx context.Context, conn querier, bookID uint64, authorIDs []uint64) error {
+	if _, err := conn.ExecContext(ctx, `DELETE FROM book_authors WHERE book_id = ?`, bookID); err != nil {
+		return err
+	}
+	for position, id := range authorIDs {
+		_, err := conn.ExecContext(ctx,
+			`INSERT INTO book_authors (book_id, author_id, position) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`,
+			bookID, id, position+1,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanner is what sql.Row and sql.Rows have in common
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAuthor(row scanner) (models.Author, error) {
+	var author models.Author
+	err := row.Scan(
+		&author.ID,
+		&author.Name,
+		&author.SortName,
+		&author.BirthYear,
+		&author.DeathYear,
+		&author.Bio,
+		&author.CreatedAt,
+		&author.UpdatedAt,
+	)
+	return author, err
+}
+
+// isForeignKeyError reports whether err is a violation of a foreign key
+func isForeignKeyError(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+}
@@ -0,0 +1,370 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Genres return all genres ordered by name and id
+func (s *SqliteStorage) Genres(ctx context.Context) ([]models.Genre, error) {
+	query := `
+	SELECT id, parent_id, slug, name, created_at, updated_at
+	FROM genres
+	ORDER BY name, id
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.conn.QueryContext(ctx, query)
+	if err != nil {
+		s.logger.Error("Faild to query genres", "error", err)
+		return nil, fmt.Errorf("faild to query genres: %w", err)
+	}
+	defer rows.Close()
+
+	var genres []models.Genre
+	for rows.Next() {
+		genre, err := scanGenre(rows)
+		if err != nil {
+			s.logger.Error("Faild to scan genres", "error", err)
+			return nil, fmt.Errorf("faild to scan genres: %w", err)
+		}
+		genres = append(genres, genre)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return genres, nil
+}
+
+// GetGenre return a genre by id
+func (s *SqliteStorage) GetGenre(ctx context.Context, id uint64) (models.Genre, error) {
+	query := `
+	SELECT id, parent_id, slug, name, created_at, updated_at
+	FROM genres
+	WHERE id = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	genre, err := scanGenre(s.conn.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Genre{}, fmt.Errorf("genre with id %d %w", id, apperrors.ErrGenreNotFound)
+		}
+		s.logger.Error("Faild to get genre", "error", err)
+		return models.Genre{}, fmt.Errorf("failed to get genre: %w", err)
+	}
+	return genre, nil
+}
+
+// SaveGenre add a genre and returns it with id from database
+func (s *SqliteStorage) SaveGenre(ctx context.Context, genre models.Genre) (models.Genre, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	genre, err := s.saveGenre(ctx, genre)
+	if err != nil {
+		if genreErr := genreConstraintError(err, genre); genreErr != nil {
+			return models.Genre{}, genreErr
+		}
+		s.logger.Error("Failed to save genre", "error", err)
+		return models.Genre{}, fmt.Errorf("failed to save genre: %w", err)
+	}
+	return genre, nil
+}
+
+// UpdateGenre update a genre, created_at is never changed
+func (s *SqliteStorage) UpdateGenre(ctx context.Context, genre models.Genre) error {
+	query := `
+	UPDATE genres
+	SET
+		parent_id = ?,
+		slug = ?,
+		name = ?,
+		updated_at = ?
+	WHERE id = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.conn.ExecContext(ctx, query,
+		genre.ParentID,
+		genre.Slug,
+		genre.Name,
+		genre.UpdatedAt,
+		genre.ID,
+	)
+	if err != nil {
+		if genreErr := genreConstraintError(err, genre); genreErr != nil {
+			return genreErr
+		}
+		s.logger.Error("Failed to update genre", "error", err)
+		return fmt.Errorf("failed to update genre: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update genre: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("genre with id: %d %w", genre.ID, apperrors.ErrGenreNotFound)
+	}
+	return nil
+}
+
+// DeleteGenre delete a genre, foreign keys of subgenres and book_genres
+// don't allow to delete a genre in use
+func (s *SqliteStorage) DeleteGenre(ctx context.Context, id uint64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.conn.ExecContext(ctx, `DELETE FROM genres WHERE id = ?`, id)
+	if err != nil {
+		if isForeignKeyError(err) {
+			return fmt.Errorf("genre with id: %d %w", id, apperrors.ErrGenreInUse)
+		}
+		s.logger.Error("Failed to delete genre", "error", err)
+		return fmt.Errorf("failed to delete genre: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete genre: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("genre with id: %d %w", id, apperrors.ErrGenreNotFound)
+	}
+	return nil
+}
+
+// SetBookGenres replaces genres of a book
+func (s *SqliteStorage) SetBookGenres(ctx context.Context, bookID uint64, genreIDs []uint64) error {
+	return s.WithTx(ctx, func(tx abstraction.Storage) error {
+		conn := tx.(*SqliteStorage).conn
+
+		var exists bool
+		err := conn.QueryRowContext(ctx, `SELECT COUNT(*) > 0 FROM books WHERE id = ?`, bookID).Scan(&exists)
+		if err != nil {
+			s.logger.Error("Failed to set book genres", "error", err)
+			return fmt.Errorf("failed to set book genres: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("book with id: %d %w", bookID, apperrors.ErrBookNotFound)
+		}
+
+		if err := setBookGenres(ctx, conn, bookID, genreIDs); err != nil {
+			if isForeignKeyError(err) {
+				return fmt.Errorf("genre of book %d %w", bookID, apperrors.ErrGenreNotFound)
+			}
+			s.logger.Error("Failed to set book genres", "error", err)
+			return fmt.Errorf("failed to set book genres: %w", err)
+		}
+		return nil
+	})
+}
+
+// BookGenres return genres of books ordered by name
+func (s *SqliteStorage) BookGenres(ctx context.Context, bookIDs []uint64) (map[uint64][]models.BookGenre, error) {
+	result := make(map[uint64][]models.BookGenre, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]any, len(bookIDs))
+	for i, id := range bookIDs {
+		args[i] = id
+	}
+	query := `
+	SELECT book_genres.book_id, genres.id, genres.slug, genres.name
+	FROM book_genres
+	JOIN genres ON genres.id = book_genres.genre_id
+	WHERE book_genres.book_id IN (?` + strings.Repeat(", ?", len(bookIDs)-1) + `)
+	ORDER BY book_genres.book_id, genres.name, genres.id
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Faild to query book genres", "error", err)
+		return nil, fmt.Errorf("faild to query book genres: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			bookID uint64
+			genre  models.BookGenre
+		)
+		if err := rows.Scan(&bookID, &genre.ID, &genre.Slug, &genre.Name); err != nil {
+			s.logger.Error("Faild to scan book genres", "error", err)
+			return nil, fmt.Errorf("faild to scan book genres: %w", err)
+		}
+		result[bookID] = append(result[bookID], genre)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return result, nil
+}
+
+// LinkGenres tags books with a genre by their genre strings.
+// SQLite has no regular expressions, so slugs are made in Go
+func (s *SqliteStorage) LinkGenres(ctx context.Context, bookIDs []uint64, createdAt time.Time) error {
+	err := s.WithTx(ctx, func(tx abstraction.Storage) error {
+		conn := tx.(*SqliteStorage).conn
+
+		// book ids and their genre strings
+		query := `SELECT id, genre FROM books WHERE id NOT IN (SELECT book_id FROM book_genres) ORDER BY id`
+		var args []any
+		if bookIDs != nil {
+			if len(bookIDs) == 0 {
+				return nil
+			}
+			query = `SELECT id, genre FROM books WHERE id IN (?` + strings.Repeat(", ?", len(bookIDs)-1) + `) ORDER BY id`
+			for _, id := range bookIDs {
+				args = append(args, id)
+			}
+		}
+
+		rows, err := conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		books := make(map[uint64]string)
+		var ids []uint64
+		for rows.Next() {
+			var (
+				id    uint64
+				genre string
+			)
+			if err := rows.Scan(&id, &genre); err != nil {
+				rows.Close()
+				return err
+			}
+			books[id] = strings.TrimSpace(genre)
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			var genreIDs []uint64
+			if slug := models.Slugify(books[id]); slug != "" {
+				var genreID uint64
+				err := conn.QueryRowContext(ctx, `SELECT id FROM genres WHERE slug = ?`, slug).Scan(&genreID)
+				if errors.Is(err, sql.ErrNoRows) {
+					genre, err := tx.(*SqliteStorage).saveGenre(ctx, models.Genre{
+						Slug:      slug,
+						Name:      books[id],
+						CreatedAt: createdAt,
+						UpdatedAt: createdAt,
+					})
+					if err != nil {
+						return err
+					}
+					genreID = genre.ID
+				} else if err != nil {
+					return err
+				}
+				genreIDs = append(genreIDs, genreID)
+			}
+			if err := setBookGenres(ctx, conn, id, genreIDs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to link genres", "error", err)
+		return fmt.Errorf("failed to link genres: %w", err)
+	}
+	return nil
+}
+
+// saveGenre inserts a genre without a timeout of its own
+func (s *SqliteStorage) saveGenre(ctx context.Context, genre models.Genre) (models.Genre, error) {
+	query := `
+	INSERT INTO genres (parent_id, slug, name, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?)
+	RETURNING id
+	`
+	err := s.conn.QueryRowContext(ctx, query,
+		genre.ParentID,
+		genre.Slug,
+		genre.Name,
+		genre.CreatedAt,
+		genre.UpdatedAt,
+	).Scan(&genre.ID)
+	if err != nil {
+		return models.Genre{}, err
+	}
+	return genre, nil
+}
+
+// setBookGenres replaces genres of a book, the caller runs it in a transaction
+func setBookGenres(ctx context.Context, conn querier, bookID uint64, genreIDs []uint64) error {
+	if _, err := conn.ExecContext(ctx, `DELETE FROM book_genres WHERE book_id = ?`, bookID); err != nil {
+		return err
+	}
+	for _, id := range genreIDs {
+		_, err := conn.ExecContext(ctx,
+			`INSERT INTO book_genres (book_id, genre_id) VALUES (?, ?) ON CONFLICT DO NOTHING`,
+			bookID, id,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanGenre(row scanner) (models.Genre, error) {
+	var genre models.Genre
+	err := row.Scan(
+		&genre.ID,
+		&genre.ParentID,
+		&genre.Slug,
+		&genre.Name,
+		&genre.CreatedAt,
+		&genre.UpdatedAt,
+	)
+	return genre, err
+}
+
+// genreConstraintError tells a used slug and a missing parent from other errors,
+// it returns nil for other errors
+func genreConstraintError(err error, genre models.Genre) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return nil
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return fmt.Errorf("genre with slug %s %w", genre.Slug, apperrors.ErrSlugConflict)
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return fmt.Errorf("parent genre of %s %w", genre.Slug, apperrors.ErrGenreNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// FindSeries return series ordered by name and id
+func (s *SqliteStorage) FindSeries(ctx context.Context, q models.SeriesQuery) ([]models.Series, error) {
+	var args []any
+
+	var sb strings.Builder
+	sb.WriteString(`
+	SELECT id, name, description, created_at, updated_at
+	FROM series
+	`)
+	if q.After != nil {
+		sb.WriteString("WHERE (name, id) > (?, ?)\n")
+		args = append(args, q.After.Name, q.After.ID)
+	}
+	sb.WriteString("ORDER BY name, id\n")
+	if q.Limit > 0 {
+		sb.WriteString("LIMIT ?\n")
+		args = append(args, q.Limit)
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.conn.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		s.logger.Error("Faild to query series", "error", err)
+		return nil, fmt.Errorf("faild to query series: %w", err)
+	}
+	defer rows.Close()
+
+	found := make([]models.Series, 0, q.Limit)
+	for rows.Next() {
+		series, err := scanSeries(rows)
+		if err != nil {
+			s.logger.Error("Faild to scan series", "error", err)
+			return nil, fmt.Errorf("faild to scan series: %w", err)
+		}
+		found = append(found, series)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return found, nil
+}
+
+// GetSeries return a series by id
+func (s *SqliteStorage) GetSeries(ctx context.Context, id uint64) (models.Series, error) {
+	query := `
+	SELECT id, name, description, created_at, updated_at
+	FROM series
+	WHERE id = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	series, err := scanSeries(s.conn.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Series{}, fmt.Errorf("series with id %d %w", id, apperrors.ErrSeriesNotFound)
+		}
+		s.logger.Error("Faild to get series", "error", err)
+		return models.Series{}, fmt.Errorf("failed to get series: %w", err)
+	}
+	return series, nil
+}
+
+// SaveSeries add a series and returns it with id from database
+func (s *SqliteStorage) SaveSeries(ctx context.Context, series models.Series) (models.Series, error) {
+	query := `
+	INSERT INTO series (name, description, created_at, updated_at)
+	VALUES (?, ?, ?, ?)
+	RETURNING id
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := s.conn.QueryRowContext(ctx, query, series.Name, series.Description, series.CreatedAt, series.UpdatedAt).Scan(&series.ID)
+	if err != nil {
+		s.logger.Error("Failed to save series", "error", err)
+		return models.Series{}, fmt.Errorf("failed to save series: %w", err)
+	}
+	return series, nil
+}
+
+// UpdateSeries update a series, created_at is never changed
+func (s *SqliteStorage) UpdateSeries(ctx context.Context, series models.Series) error {
+	query := `
+	UPDATE series
+	SET
+		name = ?,
+		description = ?,
+		updated_at = ?
+	WHERE id = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.conn.ExecContext(ctx, query, series.Name, series.Description, series.UpdatedAt, series.ID)
+	if err != nil {
+		s.logger.Error("Failed to update series", "error", err)
+		return fmt.Errorf("failed to update series: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update series: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("series with id: %d %w", series.ID, apperrors.ErrSeriesNotFound)
+	}
+	return nil
+}
+
+// DeleteSeries delete a series, the foreign key of series_volumes
+// doesn't allow to delete a series with volumes
+func (s *SqliteStorage) DeleteSeries(ctx context.Context, id uint64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.conn.ExecContext(ctx, `DELETE FROM series WHERE id = ?`, id)
+	if err != nil {
+		if isForeignKeyError(err) {
+			return fmt.Errorf("series with id: %d %w", id, apperrors.ErrSeriesHasVolumes)
+		}
+		s.logger.Error("Failed to delete series", "error", err)
+		return fmt.Errorf("failed to delete series: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete series: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("series with id: %d %w", id, apperrors.ErrSeriesNotFound)
+	}
+	return nil
+}
+
+// SetBookSeries places a book at a position of a series, zero seriesID takes it out
+func (s *SqliteStorage) SetBookSeries(ctx context.Context, bookID, seriesID uint64, position float64) error {
+	upsertQuery := `
+	INSERT INTO series_volumes (book_id, series_id, position)
+	VALUES (?, ?, ?)
+	ON CONFLICT (book_id) DO UPDATE SET series_id = excluded.series_id, position = excluded.position
+	`
+
+	return s.WithTx(ctx, func(tx abstraction.Storage) error {
+		conn := tx.(*SqliteStorage).conn
+
+		var exists bool
+		err := conn.QueryRowContext(ctx, `SELECT COUNT(*) > 0 FROM books WHERE id = ?`, bookID).Scan(&exists)
+		if err != nil {
+			s.logger.Error("Failed to set book series", "error", err)
+			return fmt.Errorf("failed to set book series: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("book with id: %d %w", bookID, apperrors.ErrBookNotFound)
+		}
+
+		if seriesID == 0 {
+			_, err = conn.ExecContext(ctx, `DELETE FROM series_volumes WHERE book_id = ?`, bookID)
+		} else {
+			_, err = conn.ExecContext(ctx, upsertQuery, bookID, seriesID, position)
+		}
+		if err != nil {
+			var sqliteErr *sqlite.Error
+			if errors.As(err, &sqliteErr) {
+				switch sqliteErr.Code() {
+				case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+					return fmt.Errorf("position %g of series %d %w", position, seriesID, apperrors.ErrPositionConflict)
+				case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+					return fmt.Errorf("series with id: %d %w", seriesID, apperrors.ErrSeriesNotFound)
+				}
+			}
+			s.logger.Error("Failed to set book series", "error", err)
+			return fmt.Errorf("failed to set book series: %w", err)
+		}
+		return nil
+	})
+}
+
+// SeriesVolumes return books of a series ordered by position
+func (s *SqliteStorage) SeriesVolumes(ctx context.Context, seriesID uint64) ([]models.SeriesVolume, error) {
+	query := `
+	SELECT books.id, books.title, series_volumes.position
+	FROM series_volumes
+	JOIN books ON books.id = series_volumes.book_id
+	WHERE series_volumes.series_id = ?
+	ORDER BY series_volumes.position
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.conn.QueryContext(ctx, query, seriesID)
+	if err != nil {
+		s.logger.Error("Faild to query series volumes", "error", err)
+		return nil, fmt.Errorf("faild to query series volumes: %w", err)
+	}
+	defer rows.Close()
+
+	volumes := []models.SeriesVolume{}
+	for rows.Next() {
+		var volume models.SeriesVolume
+		if err := rows.Scan(&volume.ID, &volume.Title, &volume.Position); err != nil {
+			s.logger.Error("Faild to scan series volumes", "error", err)
+			return nil, fmt.Errorf("faild to scan series volumes: %w", err)
+		}
+		volumes = append(volumes, volume)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return volumes, nil
+}
+
+// BookSeries return series of books with their neighbours,
+// neighbours are found by window functions like in PostgreSQL
+func (s *SqliteStorage) BookSeries(ctx context.Context, bookIDs []uint64) (map[uint64]models.BookSeries, error) {
+	result := make(map[uint64]models.BookSeries, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]any, len(bookIDs))
+	for i, id := range bookIDs {
+		args[i] = id
+	}
+	placeholders := "?" + strings.Repeat(", ?", len(bookIDs)-1)
+	query := `
+	SELECT volumes.book_id, series.id, series.name, volumes.position,
+		volumes.prev_id, volumes.prev_title, volumes.prev_position,
+		volumes.next_id, volumes.next_title, volumes.next_position
+	FROM (
+		SELECT series_volumes.book_id, series_volumes.series_id, series_volumes.position,
+			LAG(books.id) OVER w AS prev_id,
+			LAG(books.title) OVER w AS prev_title,
+			LAG(series_volumes.position) OVER w AS prev_position,
+			LEAD(books.id) OVER w AS next_id,
+			LEAD(books.title) OVER w AS next_title,
+			LEAD(series_volumes.position) OVER w AS next_position
+		FROM series_volumes
+		JOIN books ON books.id = series_volumes.book_id
+		WHERE series_volumes.series_id IN (SELECT series_id FROM series_volumes WHERE book_id IN (` + placeholders + `))
+		WINDOW w AS (PARTITION BY series_volumes.series_id ORDER BY series_volumes.position)
+	) volumes
+	JOIN series ON series.id = volumes.series_id
+	WHERE volumes.book_id IN (` + placeholders + `)
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.conn.QueryContext(ctx, query, append(args, args...)...)
+	if err != nil {
+		s.logger.Error("Faild to query book series", "error", err)
+		return nil, fmt.Errorf("faild to query book series: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			bookID       uint64
+			series       models.BookSeries
+			prevID       sql.NullInt64
+			nextID       sql.NullInt64
+			prevTitle    sql.NullString
+			nextTitle    sql.NullString
+			prevPosition sql.NullFloat64
+			nextPosition sql.NullFloat64
+		)
+		err := rows.Scan(&bookID, &series.ID, &series.Name, &series.Position,
+			&prevID, &prevTitle, &prevPosition, &nextID, &nextTitle, &nextPosition)
+		if err != nil {
+			s.logger.Error("Faild to scan book series", "error", err)
+			return nil, fmt.Errorf("faild to scan book series: %w", err)
+		}
+		if prevID.Valid {
+			series.Previous = &models.SeriesVolume{ID: uint64(prevID.Int64), Title: prevTitle.String, Position: prevPosition.Float64}
+		}
+		if nextID.Valid {
+			series.Next = &models.SeriesVolume{ID: uint64(nextID.Int64), Title: nextTitle.String, Position: nextPosition.Float64}
+		}
+		result[bookID] = series
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return result, nil
+}
+
+func scanSeries(row scanner) (models.Series, error) {
+	var series models.Series
+	err := row.Scan(&series.ID, &series.Name, &series.Description, &series.CreatedAt, &series.UpdatedAt)
+	return series, err
+}
@@ -0,0 +1,795 @@
+// sqlite contains SqliteStorage, a storage that keeps books in an embedded
+// SQLite database. It uses a pure Go driver (modernc.org/sqlite),
+// so the application is still built as a single static binary without cgo.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/config"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// busyTimeout is how long (in milliseconds) a connection waits
+// for a lock that is held by another connection
+const busyTimeout = 5000
+
+// querier is what sql.DB and sql.Tx have in common,
+// so the same methods work inside and outside of a transaction
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type SqliteStorage struct {
+	db         *sql.DB
+	conn       querier // db or a transaction
+	inTx       bool
+	txDeadline time.Time // zero if a transaction has no deadline
+	config     *config.DatabaseConfig
+	logger     abstraction.Logger
+}
+
+// NewSqliteStorage create new SqliteStorage that implemented Storage interface
+func NewSqliteStorage(config *config.DatabaseConfig, logger abstraction.Logger) (*SqliteStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(config.FilePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", dataSourceName(config.FilePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	//set connection pool settings
+	db.SetMaxOpenConns(config.MaxConns)
+	db.SetMaxIdleConns(config.MinConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifeTime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	// ping to database
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("faild to ping database: %w", err)
+	}
+
+	// create a table book if it not exist
+	newAuthors, newGenres, newWorks, newWorkAuthors, err := initTable(config, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &SqliteStorage{
+		db:     db,
+		conn:   db,
+		config: config,
+		logger: logger,
+	}
+
+	// books of a file created before authors get them from their author strings
+	if newAuthors {
+		if err := s.LinkAuthors(context.Background(), nil, time.Now().UTC()); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	// and genres from their genre strings
+	if newGenres {
+		if err := s.LinkGenres(context.Background(), nil, time.Now().UTC()); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	// works of a file created before work_authors get authors from their author strings
+	if newWorkAuthors {
+		if err := s.linkWorkAuthors(context.Background(), time.Now().UTC()); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	// and every book becomes an edition of a work
+	if newWorks {
+		if err := s.LinkWorks(context.Background(), time.Now().UTC()); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// dataSourceName builds a DSN, pragmas are applied to every new connection.
+// WAL lets readers work while somebody writes,
+// immediate transactions avoid deadlocks between two writers
+func dataSourceName(path string) string {
+	params := url.Values{}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout))
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Add("_pragma", "foreign_keys(ON)")
+	params.Set("_txlock", "immediate")
+
+	return "file:" + path + "?" + params.Encode()
+}
+
+// initTable create tables if they not exist.
+// They are the same tables as in PostgreSQL,
+// AUTOINCREMENT makes ids never reused like SERIAL does.
+// It reports whether book_authors, book_genres, works and work_authors were created now,
+// so books and works have to be linked
+func initTable(config *config.DatabaseConfig, db *sql.DB) (newAuthors, newGenres, newWorks, newWorkAuthors bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	var hasAuthors, hasGenres, hasWorks, hasWorkAuthors bool
+	err = db.QueryRowContext(ctx,
+		`SELECT
+			COUNT(*) FILTER (WHERE name = 'book_authors') > 0,
+			COUNT(*) FILTER (WHERE name = 'book_genres') > 0,
+			COUNT(*) FILTER (WHERE name = 'works') > 0,
+			COUNT(*) FILTER (WHERE name = 'work_authors') > 0
+		FROM sqlite_master WHERE type = 'table'`,
+	).Scan(&hasAuthors, &hasGenres, &hasWorks, &hasWorkAuthors)
+	if err != nil {
+		return false, false, false, false, fmt.Errorf("faild to check database table: %w", err)
+	}
+
+	// a work is an abstract book, a row of books is one of its editions
+	query := `
+	CREATE TABLE IF NOT EXISTS works (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title VARCHAR(100) NOT NULL,
+		author VARCHAR(100) NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS works_title_idx ON works (LOWER(title));
+	CREATE INDEX IF NOT EXISTS works_sort_idx ON works (title, id);
+
+	CREATE TABLE IF NOT EXISTS books (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title VARCHAR(100) NOT NULL,
+		author VARCHAR(100) NOT NULL,
+		genre VARCHAR(100) NOT NULL,
+		publication_date TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		version INTEGER NOT NULL DEFAULT 1,
+		isbn VARCHAR(13),
+		work_id INTEGER REFERENCES works (id),
+		publisher VARCHAR(100) NOT NULL DEFAULT '',
+		edition VARCHAR(100) NOT NULL DEFAULT '',
+		format VARCHAR(20) NOT NULL DEFAULT '',
+		page_count INTEGER NOT NULL DEFAULT 0,
+		language VARCHAR(2) NOT NULL DEFAULT ''
+	);
+	`
+	_, err = db.ExecContext(ctx, query)
+	if err != nil {
+		return false, false, false, false, fmt.Errorf("faild to init database table: %w", err)
+	}
+
+	// files created before these columns have to be upgraded
+	upgrades := []struct{ column, definition string }{
+		{"version", "INTEGER NOT NULL DEFAULT 1"},
+		{"isbn", "VARCHAR(13)"},
+		{"work_id", "INTEGER REFERENCES works (id)"},
+		{"publisher", "VARCHAR(100) NOT NULL DEFAULT ''"},
+		{"edition", "VARCHAR(100) NOT NULL DEFAULT ''"},
+		{"format", "VARCHAR(20) NOT NULL DEFAULT ''"},
+		{"page_count", "INTEGER NOT NULL DEFAULT 0"},
+		{"language", "VARCHAR(2) NOT NULL DEFAULT ''"},
+	}
+	for _, upgrade := range upgrades {
+		var hasColumn bool
+		err = db.QueryRowContext(ctx,
+			`SELECT COUNT(*) > 0 FROM pragma_table_info('books') WHERE name = ?`, upgrade.column,
+		).Scan(&hasColumn)
+		if err != nil {
+			return false, false, false, false, fmt.Errorf("faild to check database table: %w", err)
+		}
+		if hasColumn {
+			continue
+		}
+		_, err = db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE books ADD COLUMN %s %s`, upgrade.column, upgrade.definition))
+		if err != nil {
+			return false, false, false, false, fmt.Errorf("faild to add %s column: %w", upgrade.column, err)
+		}
+	}
+
+	// books without ISBN have NULL, so the index doesn't count them
+	_, err = db.ExecContext(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS books_isbn_key ON books (isbn) WHERE isbn IS NOT NULL`)
+	if err != nil {
+		return false, false, false, false, fmt.Errorf("faild to create isbn index: %w", err)
+	}
+	_, err = db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS books_work_id_idx ON books (work_id)`)
+	if err != nil {
+		return false, false, false, false, fmt.Errorf("faild to create work index: %w", err)
+	}
+
+	authorsQuery := `
+	CREATE TABLE IF NOT EXISTS authors (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(100) NOT NULL,
+		sort_name VARCHAR(100) NOT NULL,
+		birth_year INTEGER,
+		death_year INTEGER,
+		bio TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS authors_name_idx ON authors (LOWER(name));
+	CREATE INDEX IF NOT EXISTS authors_sort_name_idx ON authors (sort_name, id);
+
+	CREATE TABLE IF NOT EXISTS book_authors (
+		book_id INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+		author_id INTEGER NOT NULL REFERENCES authors (id),
+		position INTEGER NOT NULL,
+		PRIMARY KEY (book_id, author_id)
+	);
+	CREATE INDEX IF NOT EXISTS book_authors_author_id_idx ON book_authors (author_id);
+
+	CREATE TABLE IF NOT EXISTS work_authors (
+		work_id INTEGER NOT NULL REFERENCES works (id) ON DELETE CASCADE,
+		author_id INTEGER NOT NULL REFERENCES authors (id),
+		position INTEGER NOT NULL,
+		PRIMARY KEY (work_id, author_id)
+	);
+	CREATE INDEX IF NOT EXISTS work_authors_author_id_idx ON work_authors (author_id);
+	`
+	if _, err = db.ExecContext(ctx, authorsQuery); err != nil {
+		return false, false, false, false, fmt.Errorf("faild to init authors tables: %w", err)
+	}
+
+	genresQuery := `
+	CREATE TABLE IF NOT EXISTS genres (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		parent_id INTEGER REFERENCES genres (id),
+		slug VARCHAR(100) NOT NULL UNIQUE,
+		name VARCHAR(100) NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS genres_parent_id_idx ON genres (parent_id);
+
+	CREATE TABLE IF NOT EXISTS book_genres (
+		book_id INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+		genre_id INTEGER NOT NULL REFERENCES genres (id),
+		PRIMARY KEY (book_id, genre_id)
+	);
+	CREATE INDEX IF NOT EXISTS book_genres_genre_id_idx ON book_genres (genre_id);
+	`
+	if _, err = db.ExecContext(ctx, genresQuery); err != nil {
+		return false, false, false, false, fmt.Errorf("faild to init genres tables: %w", err)
+	}
+
+	// a book is a volume of one series at most, a position might be fractional like 2.5
+	seriesQuery := `
+	CREATE TABLE IF NOT EXISTS series (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(100) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS series_sort_idx ON series (name, id);
+
+	CREATE TABLE IF NOT EXISTS series_volumes (
+		book_id INTEGER PRIMARY KEY REFERENCES books (id) ON DELETE CASCADE,
+		series_id INTEGER NOT NULL REFERENCES series (id),
+		position REAL NOT NULL,
+		UNIQUE (series_id, position)
+	);
+	`
+	if _, err = db.ExecContext(ctx, seriesQuery); err != nil {
+		return false, false, false, false, fmt.Errorf("faild to init series tables: %w", err)
+	}
+
+	return !hasAuthors, !hasGenres, !hasWorks, !hasWorkAuthors, nil
+}
+
+// bookColumns are columns of a book in the order of scanBook
+const bookColumns = `
+		id,
+		title,
+		author,
+		genre,
+		publication_date,
+		created_at,
+		updated_at,
+		version,
+		COALESCE(isbn, ''),
+		COALESCE(work_id, 0),
+		publisher,
+		edition,
+		format,
+		page_count,
+		language`
+
+// scanBook scans a row of bookColumns, extra are columns after them
+func scanBook(row scanner, extra ...any) (models.Book, error) {
+	var book models.Book
+	dest := []any{
+		&book.General.ID,
+		&book.General.Title,
+		&book.General.Author,
+		&book.General.Genre,
+		&book.General.PublicationDate,
+		&book.CreatedAt,
+		&book.UpdatedAt,
+		&book.Version,
+		&book.General.ISBN,
+		&book.General.WorkID,
+		&book.General.Publisher,
+		&book.General.Edition,
+		&book.General.Format,
+		&book.General.PageCount,
+		&book.General.Language,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return book, err
+}
+
+// GetAll return all books from storage
+func (s *SqliteStorage) GetAll(ctx context.Context) ([]models.Book, error) {
+	query := `
+	SELECT ` + bookColumns + `
+	FROM books
+	ORDER BY id
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.conn.QueryContext(ctx, query)
+	if err != nil {
+		s.logger.Error("Faild to query books", "error", err)
+		return nil, fmt.Errorf("faild to query books: %w", err)
+	}
+	defer rows.Close()
+
+	books := make([]models.Book, 0)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			s.logger.Error("Faild to scan books", "error", err)
+			return nil, fmt.Errorf("faild to scan books: %w", err)
+		}
+		books = append(books, book)
+	}
+
+	// Check for any errors during iteration
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return books, nil
+}
+
+// Find return books that match a query.
+// Pagination is keyset (WHERE (column, id) > (...)) instead of OFFSET,
+// so any page is as fast as the first one
+func (s *SqliteStorage) Find(ctx context.Context, q models.BookQuery) ([]models.Book, error) {
+	query, args, err := buildFindQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Faild to query books", "error", err)
+		return nil, fmt.Errorf("faild to query books: %w", err)
+	}
+	defer rows.Close()
+
+	books := make([]models.Book, 0, q.Limit)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			s.logger.Error("Faild to scan books", "error", err)
+			return nil, fmt.Errorf("faild to scan books: %w", err)
+		}
+		books = append(books, book)
+	}
+
+	// Check for any errors during iteration
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return books, nil
+}
+
+// Stream returns books that match a query one by one as they are read from database,
+// so memory doesn't depend on amount of books. There is no timeout of the storage,
+// a query lasts as long as a caller reads, it is stopped by ctx
+func (s *SqliteStorage) Stream(ctx context.Context, q models.BookQuery) iter.Seq2[models.Book, error] {
+	return func(yield func(models.Book, error) bool) {
+		query, args, err := buildFindQuery(q)
+		if err != nil {
+			yield(models.Book{}, err)
+			return
+		}
+
+		rows, err := s.conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			s.logger.Error("Faild to query books", "error", err)
+			yield(models.Book{}, fmt.Errorf("faild to query books: %w", err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			book, err := scanBook(rows)
+			if err != nil {
+				s.logger.Error("Faild to scan books", "error", err)
+				yield(models.Book{}, fmt.Errorf("faild to scan books: %w", err))
+				return
+			}
+			if !yield(book, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			s.logger.Error("Error iterating rows", "error", err)
+			yield(models.Book{}, fmt.Errorf("error iterating rows: %w", err))
+		}
+	}
+}
+
+// GetById return a book by id
+func (s *SqliteStorage) GetById(ctx context.Context, id uint64) (models.Book, error) {
+	query := `
+	SELECT ` + bookColumns + `
+	FROM books
+	WHERE id = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	book, err := scanBook(s.conn.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Book{}, fmt.Errorf("book with id %d %w", id, apperrors.ErrBookNotFound)
+		}
+		s.logger.Error("Faild to get book", "error", err)
+		return models.Book{}, fmt.Errorf("failed to get book: %w", err)
+	}
+	return book, nil
+}
+
+// GetByISBN return a book by its normalized ISBN
+func (s *SqliteStorage) GetByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	query := `
+	SELECT ` + bookColumns + `
+	FROM books
+	WHERE isbn = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	book, err := scanBook(s.conn.QueryRowContext(ctx, query, isbn))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Book{}, fmt.Errorf("book with isbn %s %w", isbn, apperrors.ErrBookNotFound)
+		}
+		s.logger.Error("Faild to get book", "error", err)
+		return models.Book{}, fmt.Errorf("failed to get book: %w", err)
+	}
+	return book, nil
+}
+
+// Save add a book to database and returns it with id from database
+func (s *SqliteStorage) Save(ctx context.Context, book models.Book) (models.Book, error) {
+	query := `
+	INSERT INTO books (title, author, genre, publication_date, created_at, updated_at, isbn,
+		work_id, publisher, edition, format, page_count, language)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	RETURNING id, version
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := s.conn.QueryRowContext(ctx, query,
+		book.General.Title,
+		book.General.Author,
+		book.General.Genre,
+		book.General.PublicationDate,
+		book.CreatedAt,
+		book.UpdatedAt,
+		nullISBN(book.General.ISBN),
+		nullWorkID(book.General.WorkID),
+		book.General.Publisher,
+		book.General.Edition,
+		book.General.Format,
+		book.General.PageCount,
+		book.General.Language,
+	).Scan(&book.General.ID, &book.Version)
+	if err != nil {
+		if conflict := conflictError(err, book); conflict != nil {
+			return models.Book{}, conflict
+		}
+		s.logger.Error("Failed to save book", "error", err)
+		return models.Book{}, fmt.Errorf("failed to save book: %w", err)
+	}
+
+	return book, nil
+}
+
+// SaveWithID add a book with its own id to database.
+// AUTOINCREMENT remembers the biggest id, so Save never generates the same one
+func (s *SqliteStorage) SaveWithID(ctx context.Context, book models.Book) (models.Book, error) {
+	query := `
+	INSERT INTO books (id, title, author, genre, publication_date, created_at, updated_at, isbn,
+		work_id, publisher, edition, format, page_count, language)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	RETURNING version
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := s.conn.QueryRowContext(ctx, query,
+		book.General.ID,
+		book.General.Title,
+		book.General.Author,
+		book.General.Genre,
+		book.General.PublicationDate,
+		book.CreatedAt,
+		book.UpdatedAt,
+		nullISBN(book.General.ISBN),
+		nullWorkID(book.General.WorkID),
+		book.General.Publisher,
+		book.General.Edition,
+		book.General.Format,
+		book.General.PageCount,
+		book.General.Language,
+	).Scan(&book.Version)
+	if err != nil {
+		if conflict := conflictError(err, book); conflict != nil {
+			return models.Book{}, conflict
+		}
+		s.logger.Error("Failed to save book", "error", err)
+		return models.Book{}, fmt.Errorf("failed to save book: %w", err)
+	}
+
+	return book, nil
+}
+
+// Update update a book into database and increases its version
+func (s *SqliteStorage) Update(ctx context.Context, book models.Book) error {
+	query := `
+	UPDATE books
+	SET
+		title = ?,
+		author = ?,
+		genre = ?,
+		publication_date = ?,
+		updated_at = ?,
+		isbn = ?,
+		work_id = ?,
+		publisher = ?,
+		edition = ?,
+		format = ?,
+		page_count = ?,
+		language = ?,
+		version = version + 1
+	WHERE id = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.conn.ExecContext(ctx, query,
+		book.General.Title,
+		book.General.Author,
+		book.General.Genre,
+		book.General.PublicationDate,
+		book.UpdatedAt,
+		nullISBN(book.General.ISBN),
+		nullWorkID(book.General.WorkID),
+		book.General.Publisher,
+		book.General.Edition,
+		book.General.Format,
+		book.General.PageCount,
+		book.General.Language,
+		book.General.ID,
+	)
+	if err != nil {
+		if conflict := conflictError(err, book); conflict != nil {
+			return conflict
+		}
+		s.logger.Error("Failed to update book", "error", err)
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrBookNotFound)
+	}
+
+	return nil
+}
+
+// UpdateFields update only given fields and updated_at of a book,
+// so other fields changed by somebody else are not overwritten
+func (s *SqliteStorage) UpdateFields(ctx context.Context, book models.Book, fields []models.BookField) error {
+	query, args, err := buildUpdateFieldsQuery(book, fields)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		if conflict := conflictError(err, book); conflict != nil {
+			return conflict
+		}
+		s.logger.Error("Failed to update book", "error", err)
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrBookNotFound)
+	}
+
+	return nil
+}
+
+// Delete delete a book by id
+func (s *SqliteStorage) Delete(ctx context.Context, id uint64) error {
+	query := `
+	DELETE FROM books
+	WHERE id = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.conn.ExecContext(ctx, query, id)
+	if err != nil {
+		s.logger.Error("Failed to delete a book", "error", err)
+		return fmt.Errorf("failed to delete a book: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete a book: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("book with id: %d %w", id, apperrors.ErrBookNotFound)
+	}
+	return nil
+}
+
+// WithTx runs fn in a transaction. Transactions are immediate (see dataSourceName),
+// so the write lock is taken at the beginning and nobody changes data in between.
+// A transaction inside another one just joins it
+func (s *SqliteStorage) WithTx(ctx context.Context, fn func(tx abstraction.Storage) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	// a transaction is rolled back when ctx is done, so it gets
+	// TxTimeout, every statement of fn has its own Timeout
+	var deadline time.Time
+	if s.config.TxTimeout > 0 {
+		deadline = time.Now().Add(s.config.TxTimeout)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// it does nothing after commit
+	defer tx.Rollback()
+
+	err = fn(&SqliteStorage{
+		db:         s.db,
+		conn:       tx,
+		inTx:       true,
+		txDeadline: deadline,
+		config:     s.config,
+		logger:     s.logger,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Close close a storage, a storage of a transaction
+// doesn't own the database, so it does nothing
+func (s *SqliteStorage) Close() error {
+	if s.inTx {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// withTimeout limits one statement by Timeout,
+// but not longer than the deadline of its transaction
+func (s *SqliteStorage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(s.config.Timeout)
+	if !s.txDeadline.IsZero() && s.txDeadline.Before(deadline) {
+		deadline = s.txDeadline
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// nullISBN stores a book without ISBN as NULL, so the unique index skips it
+func nullISBN(isbn string) any {
+	if isbn == "" {
+		return nil
+	}
+	return isbn
+}
+
+// nullWorkID stores a book without a work as NULL, so the foreign key skips it
+func nullWorkID(id uint64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// conflictError converts a constraint error of an id or ISBN
+// to ErrConflict or ErrISBNConflict, it returns nil for other errors
+func conflictError(err error, book models.Book) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return nil
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrConflict)
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		// SQLite has no name of a constraint in an error, only columns
+		if strings.Contains(sqliteErr.Error(), "books.isbn") {
+			return fmt.Errorf("book with isbn: %s %w", book.General.ISBN, apperrors.ErrISBNConflict)
+		}
+		return fmt.Errorf("book with id: %d %w", book.General.ID, apperrors.ErrConflict)
+	}
+	return nil
+}
@@ -0,0 +1,735 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+	"github.com/Talos-hub/BooksRestApi/internal/services"
+	"github.com/Talos-hub/BooksRestApi/internal/storages/config"
+)
+
+// Test data
+var (
+	testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	testTime   = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newTestStorage(t *testing.T) *SqliteStorage {
+	t.Helper()
+
+	conf := &config.DatabaseConfig{
+		MaxConns:        4,
+		MinConns:        1,
+		ConnMaxLifeTime: time.Hour,
+		ConnMaxIdleTime: time.Minute,
+		Timeout:         5 * time.Second,
+		Driver:          config.DriverSqlite,
+		FilePath:        filepath.Join(t.TempDir(), "books.db"),
+	}
+
+	s, err := NewSqliteStorage(conf, testLogger)
+	if err != nil {
+		t.Fatalf("Unexpected error opening storage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func newTestBook(title string) models.Book {
+	return models.Book{
+		General: models.GeneralBook{
+			Title:           title,
+			Genre:           "Programming",
+			Author:          "Robert C. Martin",
+			PublicationDate: testTime,
+		},
+		CreatedAt: testTime,
+		UpdatedAt: testTime,
+	}
+}
+
+func TestSqliteStorage_CRUD(t *testing.T) {
+	s := newTestStorage(t)
+
+	saved, err := s.Save(t.Context(), newTestBook("Clean Code"))
+	if err != nil {
+		t.Fatalf("Unexpected error saving a book: %v", err)
+	}
+	if saved.General.ID != 1 || saved.Version != 1 {
+		t.Errorf("Expected saved book to have id 1 and version 1, got: %+v", saved)
+	}
+
+	book, err := s.GetById(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("Expected book with id 1, got error: %v", err)
+	}
+	if book.General.Title != "Clean Code" || !book.General.PublicationDate.Equal(testTime) {
+		t.Errorf("Unexpected book: %+v", book)
+	}
+
+	book.General.Title = "Clean Code 2"
+	if err := s.Update(t.Context(), book); err != nil {
+		t.Fatalf("Unexpected error updating a book: %v", err)
+	}
+
+	books, err := s.GetAll(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpected error getting all books: %v", err)
+	}
+	if len(books) != 1 || books[0].General.Title != "Clean Code 2" || books[0].Version != 2 {
+		t.Errorf("Expected updated book with version 2, got: %+v", books)
+	}
+
+	if err := s.Delete(t.Context(), 1); err != nil {
+		t.Fatalf("Unexpected error deleting a book: %v", err)
+	}
+	if _, err := s.GetById(t.Context(), 1); err == nil {
+		t.Error("Expected error for deleted book, got nil")
+	}
+}
+
+func TestSqliteStorage_AddsVersionToOldFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "books.db")
+
+	// a file created before the version column
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Unexpected error opening database: %v", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE books (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title VARCHAR(100) NOT NULL,
+		author VARCHAR(100) NOT NULL,
+		genre VARCHAR(100) NOT NULL,
+		publication_date TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+	INSERT INTO books (title, author, genre, publication_date, created_at, updated_at)
+	VALUES ('Clean Code', 'Robert C. Martin', 'Programming', ?, ?, ?);
+	`, testTime, testTime, testTime)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error creating old table: %v", err)
+	}
+
+	s, err := NewSqliteStorage(&config.DatabaseConfig{
+		MaxConns: 1,
+		Timeout:  5 * time.Second,
+		FilePath: path,
+	}, testLogger)
+	if err != nil {
+		t.Fatalf("Unexpected error opening old file: %v", err)
+	}
+	defer s.Close()
+
+	book, err := s.GetById(t.Context(), 1)
+	if err != nil || book.Version != 1 || book.General.ISBN != "" {
+		t.Errorf("Expected old book with version 1 and no ISBN, got: %+v %v", book, err)
+	}
+}
+
+func TestSqliteStorage_ISBN(t *testing.T) {
+	s := newTestStorage(t)
+
+	// books without ISBN are not duplicates
+	for _, title := range []string{"Clean Code", "Refactoring"} {
+		if _, err := s.Save(t.Context(), newTestBook(title)); err != nil {
+			t.Fatalf("Unexpected error saving a book without ISBN: %v", err)
+		}
+	}
+
+	book := newTestBook("The Clean Coder")
+	book.General.ISBN = "9780137081073"
+	saved, err := s.Save(t.Context(), book)
+	if err != nil {
+		t.Fatalf("Unexpected error saving a book: %v", err)
+	}
+	found, err := s.GetByISBN(t.Context(), "9780137081073")
+	if err != nil || found.General.ID != saved.General.ID {
+		t.Errorf("Expected the book by ISBN, got: %+v %v", found, err)
+	}
+	if _, err := s.GetByISBN(t.Context(), "9780132350884"); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+
+	if _, err := s.Save(t.Context(), book); !errors.Is(err, apperrors.ErrISBNConflict) {
+		t.Errorf("Expected ErrISBNConflict saving the same ISBN, got: %v", err)
+	}
+	other := newTestBook("Clean Code")
+	other.General.ID = 1
+	other.General.ISBN = "9780137081073"
+	if err := s.UpdateFields(t.Context(), other, []models.BookField{models.FieldISBN}); !errors.Is(err, apperrors.ErrISBNConflict) {
+		t.Errorf("Expected ErrISBNConflict updating ISBN, got: %v", err)
+	}
+	if _, err := s.SaveWithID(t.Context(), saved); !errors.Is(err, apperrors.ErrConflict) || errors.Is(err, apperrors.ErrISBNConflict) {
+		t.Errorf("Expected ErrConflict of the id, got: %v", err)
+	}
+}
+
+func TestSqliteStorage_Authors(t *testing.T) {
+	s := newTestStorage(t)
+
+	book, err := s.Save(t.Context(), newTestBook("Refactoring"))
+	if err != nil {
+		t.Fatalf("Unexpected error saving a book: %v", err)
+	}
+	book.General.Author = "Martin Fowler, Kent Beck"
+	if err := s.Update(t.Context(), book); err != nil {
+		t.Fatalf("Unexpected error updating a book: %v", err)
+	}
+	if err := s.LinkAuthors(t.Context(), []uint64{book.General.ID}, testTime); err != nil {
+		t.Fatalf("Unexpected error linking authors: %v", err)
+	}
+
+	authors, err := s.BookAuthors(t.Context(), []uint64{book.General.ID})
+	names := authors[book.General.ID]
+	if err != nil || len(names) != 2 || names[0].Name != "Martin Fowler" || names[1].Name != "Kent Beck" {
+		t.Fatalf("Expected two authors in order, got: %+v %v", names, err)
+	}
+
+	// a new page starts after Beck
+	page, err := s.FindAuthors(t.Context(), models.AuthorQuery{After: &models.Author{ID: names[1].ID, SortName: "Beck, Kent"}})
+	if err != nil || len(page) != 1 || page[0].SortName != "Fowler, Martin" {
+		t.Errorf("Expected Fowler after Beck, got: %+v %v", page, err)
+	}
+
+	// the same names in another case are the same authors
+	other := newTestBook("Planning Extreme Programming")
+	other.General.Author = "kent beck & martin fowler"
+	other, _ = s.Save(t.Context(), other)
+	if err := s.LinkAuthors(t.Context(), nil, testTime); err != nil {
+		t.Fatalf("Unexpected error linking authors: %v", err)
+	}
+	if all, _ := s.FindAuthors(t.Context(), models.AuthorQuery{}); len(all) != 2 {
+		t.Errorf("Expected two authors, got: %+v", all)
+	}
+	books, err := s.Find(t.Context(), models.BookQuery{AuthorID: names[1].ID})
+	if err != nil || len(books) != 2 {
+		t.Errorf("Expected two books of Kent Beck, got: %d %v", len(books), err)
+	}
+
+	if err := s.SetBookAuthors(t.Context(), book.General.ID, []uint64{99}); !errors.Is(err, apperrors.ErrAuthorNotFound) {
+		t.Errorf("Expected ErrAuthorNotFound, got: %v", err)
+	}
+	if err := s.DeleteAuthor(t.Context(), names[0].ID); !errors.Is(err, apperrors.ErrAuthorHasBooks) {
+		t.Errorf("Expected ErrAuthorHasBooks, got: %v", err)
+	}
+
+	// links are deleted with books
+	for _, id := range []uint64{book.General.ID, other.General.ID} {
+		if err := s.Delete(t.Context(), id); err != nil {
+			t.Fatalf("Unexpected error deleting a book: %v", err)
+		}
+	}
+	if err := s.DeleteAuthor(t.Context(), names[0].ID); err != nil {
+		t.Errorf("Unexpected error deleting an author without books: %v", err)
+	}
+}
+
+func TestSqliteStorage_LinksAuthorsOfOldFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "books.db")
+
+	// a file created before authors
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Unexpected error opening database: %v", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE books (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title VARCHAR(100) NOT NULL,
+		author VARCHAR(100) NOT NULL,
+		genre VARCHAR(100) NOT NULL,
+		publication_date TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+	INSERT INTO books (title, author, genre, publication_date, created_at, updated_at)
+	VALUES ('Design Patterns', 'Erich Gamma; Richard Helm; Ralph Johnson and John Vlissides', 'Programming', ?, ?, ?);
+	`, testTime, testTime, testTime)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error creating old table: %v", err)
+	}
+
+	s, err := NewSqliteStorage(&config.DatabaseConfig{
+		MaxConns: 1,
+		Timeout:  5 * time.Second,
+		FilePath: path,
+	}, testLogger)
+	if err != nil {
+		t.Fatalf("Unexpected error opening old file: %v", err)
+	}
+	defer s.Close()
+
+	authors, err := s.BookAuthors(t.Context(), []uint64{1})
+	names := authors[1]
+	if err != nil || len(names) != 4 || names[0].Name != "Erich Gamma" || names[3].Name != "John Vlissides" {
+		t.Errorf("Expected four authors of the old book, got: %+v %v", names, err)
+	}
+	genres, err := s.BookGenres(t.Context(), []uint64{1})
+	if err != nil || len(genres[1]) != 1 || genres[1][0].Slug != "programming" {
+		t.Errorf("Expected a genre of the old book, got: %+v %v", genres[1], err)
+	}
+	book, err := s.GetById(t.Context(), 1)
+	if err != nil || book.General.WorkID == 0 {
+		t.Errorf("Expected a work of the old book, got: %+v %v", book.General, err)
+	}
+}
+
+func TestSqliteStorage_Genres(t *testing.T) {
+	s := newTestStorage(t)
+
+	books := []models.Book{newTestBook("Dune"), newTestBook("The Hobbit"), newTestBook("Cosmos")}
+	books[0].General.Genre = "Science Fiction"
+	books[1].General.Genre = "Fantasy"
+	books[2].General.Genre = "science  fiction!"
+	for i := range books {
+		books[i], _ = s.Save(t.Context(), books[i])
+	}
+	if err := s.LinkGenres(t.Context(), nil, testTime); err != nil {
+		t.Fatalf("Unexpected error linking genres: %v", err)
+	}
+
+	// genre strings with the same slug are one genre
+	genres, err := s.Genres(t.Context())
+	if err != nil || len(genres) != 2 || genres[0].Slug != "fantasy" || genres[1].Name != "Science Fiction" {
+		t.Fatalf("Expected two genres ordered by name, got: %+v %v", genres, err)
+	}
+	fantasy, scifi := genres[0], genres[1]
+
+	fiction, err := s.SaveGenre(t.Context(), models.Genre{Slug: "fiction", Name: "Fiction", CreatedAt: testTime, UpdatedAt: testTime})
+	if err != nil {
+		t.Fatalf("Unexpected error saving a genre: %v", err)
+	}
+	if _, err := s.SaveGenre(t.Context(), models.Genre{Slug: "fiction", Name: "Fiction", CreatedAt: testTime}); !errors.Is(err, apperrors.ErrSlugConflict) {
+		t.Errorf("Expected ErrSlugConflict, got: %v", err)
+	}
+	missing := uint64(99)
+	if _, err := s.SaveGenre(t.Context(), models.Genre{Slug: "poetry", Name: "Poetry", ParentID: &missing, CreatedAt: testTime}); !errors.Is(err, apperrors.ErrGenreNotFound) {
+		t.Errorf("Expected ErrGenreNotFound of a parent, got: %v", err)
+	}
+
+	// fiction > fantasy > (science fiction)
+	fantasy.ParentID = &fiction.ID
+	scifi.ParentID = &fantasy.ID
+	for _, genre := range []models.Genre{fantasy, scifi} {
+		if err := s.UpdateGenre(t.Context(), genre); err != nil {
+			t.Fatalf("Unexpected error updating a genre: %v", err)
+		}
+	}
+
+	found, err := s.Find(t.Context(), models.BookQuery{GenreID: fiction.ID})
+	if err != nil || len(found) != 3 {
+		t.Errorf("Expected all books under fiction, got: %d %v", len(found), err)
+	}
+	found, err = s.Find(t.Context(), models.BookQuery{GenreID: scifi.ID})
+	if err != nil || len(found) != 2 {
+		t.Errorf("Expected two books of science fiction, got: %d %v", len(found), err)
+	}
+	// the genre filter is a slug or a name with subgenres, not the genre string
+	found, err = s.Find(t.Context(), models.BookQuery{Genre: "fiction"})
+	if err != nil || len(found) != 3 {
+		t.Errorf("Expected all books under fiction by its slug, got: %d %v", len(found), err)
+	}
+	found, err = s.Find(t.Context(), models.BookQuery{Genre: "FANTASY"})
+	if err != nil || len(found) != 3 {
+		t.Errorf("Expected all books under fantasy by its name, got: %d %v", len(found), err)
+	}
+	found, err = s.Find(t.Context(), models.BookQuery{Genre: "science-fiction"})
+	if err != nil || len(found) != 2 {
+		t.Errorf("Expected two books of science fiction by its slug, got: %d %v", len(found), err)
+	}
+
+	if err := s.SetBookGenres(t.Context(), books[0].General.ID, []uint64{scifi.ID, fiction.ID, fiction.ID}); err != nil {
+		t.Fatalf("Unexpected error setting genres: %v", err)
+	}
+	bookGenres, _ := s.BookGenres(t.Context(), []uint64{books[0].General.ID})
+	if names := bookGenres[books[0].General.ID]; len(names) != 2 || names[0].Slug != "fiction" {
+		t.Errorf("Expected two genres ordered by name, got: %+v", names)
+	}
+	if err := s.SetBookGenres(t.Context(), books[0].General.ID, []uint64{99}); !errors.Is(err, apperrors.ErrGenreNotFound) {
+		t.Errorf("Expected ErrGenreNotFound, got: %v", err)
+	}
+
+	if err := s.DeleteGenre(t.Context(), fiction.ID); !errors.Is(err, apperrors.ErrGenreInUse) {
+		t.Errorf("Expected ErrGenreInUse, got: %v", err)
+	}
+	if err := s.SetBookGenres(t.Context(), books[0].General.ID, nil); err != nil {
+		t.Fatalf("Unexpected error removing genres: %v", err)
+	}
+	// fantasy has a subgenre
+	if err := s.DeleteGenre(t.Context(), fantasy.ID); !errors.Is(err, apperrors.ErrGenreInUse) {
+		t.Errorf("Expected ErrGenreInUse of a parent, got: %v", err)
+	}
+}
+
+func TestSqliteStorage_NonLatinGenres(t *testing.T) {
+	s := newTestStorage(t)
+
+	books := []models.Book{newTestBook("Solaris"), newTestBook("The Inugami Curse"), newTestBook("Roadside Picnic")}
+	books[0].General.Genre = "Фантастика"
+	books[1].General.Genre = "推理"
+	books[2].General.Genre = " Фантастика "
+	for i := range books {
+		books[i], _ = s.Save(t.Context(), books[i])
+	}
+	if err := s.LinkGenres(t.Context(), nil, testTime); err != nil {
+		t.Fatalf("Unexpected error linking genres: %v", err)
+	}
+
+	// a genre without latin letters is kept with a generated slug
+	genres, err := s.Genres(t.Context())
+	if err != nil || len(genres) != 2 || genres[0].Name != "Фантастика" || genres[0].Slug != models.Slugify("Фантастика") {
+		t.Fatalf("Expected two genres with generated slugs, got: %+v %v", genres, err)
+	}
+	bookGenres, err := s.BookGenres(t.Context(), []uint64{books[0].General.ID, books[1].General.ID, books[2].General.ID})
+	if err != nil {
+		t.Fatalf("Unexpected error getting genres of books: %v", err)
+	}
+	for _, book := range books {
+		if names := bookGenres[book.General.ID]; len(names) != 1 || names[0].Slug != models.Slugify(book.General.Genre) {
+			t.Errorf("Expected the genre of %s, got: %+v", book.General.Title, names)
+		}
+	}
+}
+
+func TestSqliteStorage_Works(t *testing.T) {
+	s := newTestStorage(t)
+
+	hobbit := newTestBook("The Hobbit")
+	hobbit.General.Author = "J.R.R. Tolkien"
+	hobbit, _ = s.Save(t.Context(), hobbit)
+	other := newTestBook("the hobbit")
+	other.General.Author = "j.r.r. tolkien"
+	other, _ = s.Save(t.Context(), other)
+	// works are matched by authors of books
+	if err := s.LinkAuthors(t.Context(), nil, testTime); err != nil {
+		t.Fatalf("Unexpected error linking authors: %v", err)
+	}
+	if err := s.LinkWorks(t.Context(), testTime); err != nil {
+		t.Fatalf("Unexpected error linking works: %v", err)
+	}
+
+	// the same title and author in another case is one work
+	works, err := s.FindWorks(t.Context(), models.WorkQuery{})
+	if err != nil || len(works) != 1 || works[0].Title != "The Hobbit" || len(works[0].Authors) != 1 {
+		t.Fatalf("Expected one work of both books, got: %+v %v", works, err)
+	}
+	work := works[0]
+	books, err := s.Find(t.Context(), models.BookQuery{WorkID: work.ID})
+	if err != nil || len(books) != 2 {
+		t.Errorf("Expected two editions of the work, got: %+v %v", books, err)
+	}
+
+	// "Last, First" is the same author
+	found, err := s.EnsureWork(t.Context(), models.Work{Title: "THE HOBBIT", Author: "Tolkien, J.R.R.", CreatedAt: testTime})
+	if err != nil || found.ID != work.ID {
+		t.Errorf("Expected the existing work, got: %+v %v", found, err)
+	}
+	// another author is another work
+	found, err = s.EnsureWork(t.Context(), models.Work{Title: "The Hobbit", Author: "Christopher Tolkien", CreatedAt: testTime})
+	if err != nil || found.ID == work.ID || len(found.Authors) != 1 || found.Authors[0].Name != "Christopher Tolkien" {
+		t.Errorf("Expected a new work of another author, got: %+v %v", found, err)
+	}
+	if err := s.DeleteWork(t.Context(), found.ID); err != nil {
+		t.Fatalf("Unexpected error deleting a work: %v", err)
+	}
+	silmarillion, err := s.SaveWork(t.Context(), models.Work{Title: "Silmarillion", Author: "J.R.R. Tolkien", CreatedAt: testTime})
+	if err != nil {
+		t.Fatalf("Unexpected error saving a work: %v", err)
+	}
+	works, err = s.FindWorks(t.Context(), models.WorkQuery{After: &work, Limit: 1})
+	if err != nil || len(works) != 0 {
+		t.Errorf("Expected no works after The Hobbit, got: %+v %v", works, err)
+	}
+	works, err = s.FindWorks(t.Context(), models.WorkQuery{After: &silmarillion})
+	if err != nil || len(works) != 1 || works[0].ID != work.ID {
+		t.Errorf("Expected The Hobbit after Silmarillion, got: %+v %v", works, err)
+	}
+
+	other.General.WorkID = silmarillion.ID
+	other.General.Publisher = "Allen & Unwin"
+	if err := s.UpdateFields(t.Context(), other, []models.BookField{models.FieldWorkID, models.FieldPublisher}); err != nil {
+		t.Fatalf("Unexpected error updating fields: %v", err)
+	}
+	got, err := s.GetById(t.Context(), other.General.ID)
+	if err != nil || got.General.WorkID != silmarillion.ID || got.General.Publisher != "Allen & Unwin" {
+		t.Errorf("Expected updated fields, got: %+v %v", got.General, err)
+	}
+
+	if _, err := s.GetWork(t.Context(), 99); !errors.Is(err, apperrors.ErrWorkNotFound) {
+		t.Errorf("Expected ErrWorkNotFound, got: %v", err)
+	}
+	if err := s.DeleteWork(t.Context(), work.ID); !errors.Is(err, apperrors.ErrWorkHasEditions) {
+		t.Errorf("Expected ErrWorkHasEditions, got: %v", err)
+	}
+	if err := s.Delete(t.Context(), hobbit.General.ID); err != nil {
+		t.Fatalf("Unexpected error deleting a book: %v", err)
+	}
+	if err := s.DeleteWork(t.Context(), work.ID); err != nil {
+		t.Errorf("Unexpected error deleting a work without editions: %v", err)
+	}
+}
+
+func TestSqliteStorage_Series(t *testing.T) {
+	s := newTestStorage(t)
+
+	series, err := s.SaveSeries(t.Context(), models.Series{Name: "The Lord of the Rings", CreatedAt: testTime, UpdatedAt: testTime})
+	if err != nil {
+		t.Fatalf("Unexpected error saving a series: %v", err)
+	}
+	var books []models.Book
+	for _, title := range []string{"The Two Towers", "The Fellowship of the Ring", "The Return of the King"} {
+		book, _ := s.Save(t.Context(), newTestBook(title))
+		books = append(books, book)
+	}
+	// positions are not in order of ids, one is fractional
+	for i, position := range []float64{2, 1, 2.5} {
+		if err := s.SetBookSeries(t.Context(), books[i].General.ID, series.ID, position); err != nil {
+			t.Fatalf("Unexpected error setting book series: %v", err)
+		}
+	}
+
+	volumes, err := s.SeriesVolumes(t.Context(), series.ID)
+	if err != nil || len(volumes) != 3 || volumes[0].Title != "The Fellowship of the Ring" || volumes[2].Position != 2.5 {
+		t.Fatalf("Expected volumes ordered by position, got: %+v %v", volumes, err)
+	}
+
+	found, err := s.BookSeries(t.Context(), []uint64{books[0].General.ID, books[1].General.ID, 99})
+	if err != nil || len(found) != 2 {
+		t.Fatalf("Expected series of two books, got: %+v %v", found, err)
+	}
+	middle := found[books[0].General.ID]
+	if middle.Name != series.Name || middle.Position != 2 || middle.Previous == nil || middle.Previous.ID != books[1].General.ID ||
+		middle.Next == nil || middle.Next.Position != 2.5 {
+		t.Errorf("Expected both neighbours of the second volume, got: %+v", middle)
+	}
+	if first := found[books[1].General.ID]; first.Previous != nil || first.Next == nil || first.Next.ID != books[0].General.ID {
+		t.Errorf("Expected only the next volume of the first one, got: %+v", first)
+	}
+
+	if err := s.SetBookSeries(t.Context(), books[2].General.ID, series.ID, 1); !errors.Is(err, apperrors.ErrPositionConflict) {
+		t.Errorf("Expected ErrPositionConflict, got: %v", err)
+	}
+	if err := s.SetBookSeries(t.Context(), books[2].General.ID, 99, 1); !errors.Is(err, apperrors.ErrSeriesNotFound) {
+		t.Errorf("Expected ErrSeriesNotFound, got: %v", err)
+	}
+	if err := s.SetBookSeries(t.Context(), 99, series.ID, 3); !errors.Is(err, apperrors.ErrNotFound) || errors.Is(err, apperrors.ErrSeriesNotFound) {
+		t.Errorf("Expected ErrNotFound of a book, got: %v", err)
+	}
+	if err := s.DeleteSeries(t.Context(), series.ID); !errors.Is(err, apperrors.ErrSeriesHasVolumes) {
+		t.Errorf("Expected ErrSeriesHasVolumes, got: %v", err)
+	}
+
+	// a deleted book and a book taken out leave the series
+	if err := s.Delete(t.Context(), books[0].General.ID); err != nil {
+		t.Fatalf("Unexpected error deleting a book: %v", err)
+	}
+	if err := s.SetBookSeries(t.Context(), books[1].General.ID, 0, 0); err != nil {
+		t.Fatalf("Unexpected error removing book series: %v", err)
+	}
+	volumes, err = s.SeriesVolumes(t.Context(), series.ID)
+	if err != nil || len(volumes) != 1 || volumes[0].ID != books[2].General.ID {
+		t.Errorf("Expected one volume left, got: %+v %v", volumes, err)
+	}
+	if err := s.SetBookSeries(t.Context(), books[2].General.ID, 0, 0); err != nil {
+		t.Fatalf("Unexpected error removing book series: %v", err)
+	}
+	if err := s.DeleteSeries(t.Context(), series.ID); err != nil {
+		t.Errorf("Unexpected error deleting a series without volumes: %v", err)
+	}
+	if _, err := s.GetSeries(t.Context(), series.ID); !errors.Is(err, apperrors.ErrSeriesNotFound) {
+		t.Errorf("Expected ErrSeriesNotFound, got: %v", err)
+	}
+}
+
+func TestSqliteStorage_NotFound(t *testing.T) {
+	s := newTestStorage(t)
+
+	book := newTestBook("Missing")
+	book.General.ID = 42
+	if err := s.Update(t.Context(), book); err == nil {
+		t.Error("Expected error updating missing book, got nil")
+	}
+	if err := s.Delete(t.Context(), 42); err == nil {
+		t.Error("Expected error deleting missing book, got nil")
+	}
+}
+
+func TestSqliteStorage_Find(t *testing.T) {
+	s := newTestStorage(t)
+
+	books := []models.Book{newTestBook("The Hobbit"), newTestBook("Dune"), newTestBook("The Silmarillion")}
+	books[0].General.Genre = "Fantasy"
+	books[1].General.Genre = "Science Fiction"
+	books[2].General.Genre = "fantasy"
+	books[2].General.PublicationDate = testTime.AddDate(1, 0, 0)
+	for _, book := range books {
+		if _, err := s.Save(t.Context(), book); err != nil {
+			t.Fatalf("Unexpected error saving a book: %v", err)
+		}
+	}
+	// books are found by tagged genres
+	if err := s.LinkGenres(t.Context(), nil, testTime); err != nil {
+		t.Fatalf("Unexpected error linking genres: %v", err)
+	}
+
+	query := models.BookQuery{Genre: "FANTASY", SortBy: models.SortByPublicationDate, Desc: true, Limit: 1}
+	found, err := s.Find(t.Context(), query)
+	if err != nil {
+		t.Fatalf("Unexpected error finding books: %v", err)
+	}
+	if len(found) != 1 || found[0].General.Title != "The Silmarillion" {
+		t.Fatalf("Expected The Silmarillion first, got: %+v", found)
+	}
+
+	// the next page goes after the last book
+	query.After = &found[0]
+	found, err = s.Find(t.Context(), query)
+	if err != nil {
+		t.Fatalf("Unexpected error finding books: %v", err)
+	}
+	if len(found) != 1 || found[0].General.Title != "The Hobbit" {
+		t.Errorf("Expected The Hobbit on the next page, got: %+v", found)
+	}
+
+	// wildcards are matched literally
+	found, err = s.Find(t.Context(), models.BookQuery{Title: "%"})
+	if err != nil {
+		t.Fatalf("Unexpected error finding books: %v", err)
+	}
+	if len(found) != 0 {
+		t.Errorf("Expected no books with %% in title, got: %+v", found)
+	}
+
+	found, err = s.Find(t.Context(), models.BookQuery{Title: "the", PublishedBefore: testTime.AddDate(0, 6, 0)})
+	if err != nil {
+		t.Fatalf("Unexpected error finding books: %v", err)
+	}
+	if len(found) != 1 || found[0].General.Title != "The Hobbit" {
+		t.Errorf("Expected only The Hobbit, got: %+v", found)
+	}
+}
+
+func TestSqliteStorage_WithTx(t *testing.T) {
+	s := newTestStorage(t)
+	s.Save(t.Context(), newTestBook("Clean Code"))
+
+	errFailed := errors.New("failed")
+	err := s.WithTx(t.Context(), func(tx abstraction.Storage) error {
+		if err := tx.Delete(t.Context(), 1); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("Expected error of fn, got: %v", err)
+	}
+	if _, err := s.GetById(t.Context(), 1); err != nil {
+		t.Errorf("Expected rolled back delete, got error: %v", err)
+	}
+
+	_, err = s.GetById(t.Context(), 42)
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestSqliteStorage_BatchLongerThanTimeout(t *testing.T) {
+	s := newTestStorage(t)
+	s.config.Timeout = 50 * time.Millisecond
+	service := services.NewBookService(testLogger, s)
+
+	// Timeout is for one statement, a big atomic batch takes much longer
+	req := models.BatchRequest{Mode: models.BatchAtomic}
+	for i := range services.MaxBatchSize {
+		book := newTestBook(fmt.Sprintf("Book %d", i)).General
+		req.Operations = append(req.Operations, models.BatchOperation{Op: models.BatchCreate, Book: book})
+	}
+
+	start := time.Now()
+	result, appErr := service.Batch(t.Context(), req, testTime)
+	if appErr != nil || !result.Committed {
+		t.Fatalf("Expected committed batch, got: %v", appErr)
+	}
+	if elapsed := time.Since(start); elapsed <= s.config.Timeout {
+		t.Skipf("Batch took %v, it isn't longer than Timeout", elapsed)
+	}
+
+	books, err := s.GetAll(t.Context())
+	if err != nil || len(books) != services.MaxBatchSize {
+		t.Errorf("Expected %d books, got: %d %v", services.MaxBatchSize, len(books), err)
+	}
+}
+
+func TestSqliteStorage_TxTimeout(t *testing.T) {
+	s := newTestStorage(t)
+	s.config.Timeout = 50 * time.Millisecond
+	s.config.TxTimeout = 200 * time.Millisecond
+
+	// statements are fast, but the whole transaction is longer than Timeout
+	err := s.WithTx(t.Context(), func(tx abstraction.Storage) error {
+		for i := range 3 {
+			time.Sleep(s.config.Timeout)
+			if _, err := tx.Save(t.Context(), newTestBook(fmt.Sprintf("Book %d", i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error of a transaction longer than Timeout: %v", err)
+	}
+
+	// a transaction longer than TxTimeout is rolled back
+	err = s.WithTx(t.Context(), func(tx abstraction.Storage) error {
+		time.Sleep(s.config.TxTimeout)
+		_, err := tx.Save(t.Context(), newTestBook("Too late"))
+		return err
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got: %v", err)
+	}
+	if _, err := s.GetById(t.Context(), 4); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("Expected rolled back book, got: %v", err)
+	}
+}
+
+func TestSqliteStorage_Stream(t *testing.T) {
+	s := newTestStorage(t)
+	for _, title := range []string{"The Hobbit", "Dune", "The Silmarillion"} {
+		s.Save(t.Context(), newTestBook(title))
+	}
+
+	var titles []string
+	for book, err := range s.Stream(t.Context(), models.BookQuery{Title: "the", SortBy: models.SortByTitle, Desc: true}) {
+		if err != nil {
+			t.Fatalf("Unexpected error streaming books: %v", err)
+		}
+		titles = append(titles, book.General.Title)
+	}
+	if len(titles) != 2 || titles[0] != "The Silmarillion" || titles[1] != "The Hobbit" {
+		t.Errorf("Unexpected streamed books: %v", titles)
+	}
+
+	// a caller might stop early, rows are closed and the storage is still usable
+	for range s.Stream(t.Context(), models.BookQuery{}) {
+		break
+	}
+	if _, err := s.Save(t.Context(), newTestBook("Refactoring")); err != nil {
+		t.Errorf("Unexpected error after a stopped stream: %v", err)
+	}
+}
@@ -0,0 +1,447 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Talos-hub/BooksRestApi/internal/abstraction"
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// FindWorks return works ordered by title and id
+func (s *SqliteStorage) FindWorks(ctx context.Context, q models.WorkQuery) ([]models.Work, error) {
+	var args []any
+
+	var sb strings.Builder
+	sb.WriteString(`
+	SELECT id, title, author, created_at, updated_at
+	FROM works
+	`)
+	if q.After != nil {
+		sb.WriteString("WHERE (title, id) > (?, ?)\n")
+		args = append(args, q.After.Title, q.After.ID)
+	}
+	sb.WriteString("ORDER BY title, id\n")
+	if q.Limit > 0 {
+		sb.WriteString("LIMIT ?\n")
+		args = append(args, q.Limit)
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.conn.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		s.logger.Error("Faild to query works", "error", err)
+		return nil, fmt.Errorf("faild to query works: %w", err)
+	}
+	defer rows.Close()
+
+	works := make([]models.Work, 0, q.Limit)
+	for rows.Next() {
+		work, err := scanWork(rows)
+		if err != nil {
+			s.logger.Error("Faild to scan works", "error", err)
+			return nil, fmt.Errorf("faild to scan works: %w", err)
+		}
+		works = append(works, work)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	if err := withWorkAuthors(ctx, s.conn, works); err != nil {
+		s.logger.Error("Faild to query work authors", "error", err)
+		return nil, fmt.Errorf("faild to query work authors: %w", err)
+	}
+	return works, nil
+}
+
+// GetWork return a work by id
+func (s *SqliteStorage) GetWork(ctx context.Context, id uint64) (models.Work, error) {
+	query := `
+	SELECT id, title, author, created_at, updated_at
+	FROM works
+	WHERE id = ?
+	`
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	work, err := scanWork(s.conn.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Work{}, fmt.Errorf("work with id %d %w", id, apperrors.ErrWorkNotFound)
+		}
+		s.logger.Error("Faild to get work", "error", err)
+		return models.Work{}, fmt.Errorf("failed to get work: %w", err)
+	}
+
+	works := []models.Work{work}
+	if err := withWorkAuthors(ctx, s.conn, works); err != nil {
+		s.logger.Error("Faild to get work authors", "error", err)
+		return models.Work{}, fmt.Errorf("failed to get work authors: %w", err)
+	}
+	return works[0], nil
+}
+
+// SaveWork add a work and returns it with id from database,
+// it is linked to authors of its author string
+func (s *SqliteStorage) SaveWork(ctx context.Context, work models.Work) (models.Work, error) {
+	err := s.WithTx(ctx, func(tx abstraction.Storage) error {
+		txs := tx.(*SqliteStorage)
+		ctx, cancel := txs.withTimeout(ctx)
+		defer cancel()
+
+		authorIDs, err := txs.authorIDs(ctx, work.Author, work.CreatedAt)
+		if err != nil {
+			return err
+		}
+		work, err = txs.saveWork(ctx, work, authorIDs)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to save work", "error", err)
+		return models.Work{}, fmt.Errorf("failed to save work: %w", err)
+	}
+	return work, nil
+}
+
+// UpdateWork update a work, created_at is never changed.
+// Authors are linked again from the author string
+func (s *SqliteStorage) UpdateWork(ctx context.Context, work models.Work) error {
+	query := `
+	UPDATE works
+	SET
+		title = ?,
+		author = ?,
+		updated_at = ?
+	WHERE id = ?
+	`
+
+	var affected int64
+	err := s.WithTx(ctx, func(tx abstraction.Storage) error {
+		txs := tx.(*SqliteStorage)
+		ctx, cancel := txs.withTimeout(ctx)
+		defer cancel()
+
+		result, err := txs.conn.ExecContext(ctx, query, work.Title, work.Author, work.UpdatedAt, work.ID)
+		if err != nil {
+			return err
+		}
+		if affected, err = result.RowsAffected(); err != nil || affected == 0 {
+			return err
+		}
+
+		authorIDs, err := txs.authorIDs(ctx, work.Author, work.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return setWorkAuthors(ctx, txs.conn, work.ID, authorIDs)
+	})
+	if err != nil {
+		s.logger.Error("Failed to update work", "error", err)
+		return fmt.Errorf("failed to update work: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("work with id: %d %w", work.ID, apperrors.ErrWorkNotFound)
+	}
+	return nil
+}
+
+// DeleteWork delete a work, the foreign key of books
+// doesn't allow to delete a work with editions
+func (s *SqliteStorage) DeleteWork(ctx context.Context, id uint64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.conn.ExecContext(ctx, `DELETE FROM works WHERE id = ?`, id)
+	if err != nil {
+		if isForeignKeyError(err) {
+			return fmt.Errorf("work with id: %d %w", id, apperrors.ErrWorkHasEditions)
+		}
+		s.logger.Error("Failed to delete work", "error", err)
+		return fmt.Errorf("failed to delete work: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete work: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("work with id: %d %w", id, apperrors.ErrWorkNotFound)
+	}
+	return nil
+}
+
+// EnsureWork returns a work with the same title in any case and the same authors
+// or saves a new one
+func (s *SqliteStorage) EnsureWork(ctx context.Context, work models.Work) (models.Work, error) {
+	var found models.Work
+	err := s.WithTx(ctx, func(tx abstraction.Storage) error {
+		txs := tx.(*SqliteStorage)
+		ctx, cancel := txs.withTimeout(ctx)
+		defer cancel()
+
+		authorIDs, err := txs.authorIDs(ctx, work.Author, work.CreatedAt)
+		if err != nil {
+			return err
+		}
+		found, err = txs.workByTitle(ctx, work.Title, authorIDs)
+		if errors.Is(err, sql.ErrNoRows) {
+			found, err = txs.saveWork(ctx, work, authorIDs)
+		}
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to ensure work", "error", err)
+		return models.Work{}, fmt.Errorf("failed to ensure work: %w", err)
+	}
+	return found, nil
+}
+
+// LinkWorks makes every book without a work an edition of a work,
+// books with the same title in any case and the same authors are editions of one work
+func (s *SqliteStorage) LinkWorks(ctx context.Context, createdAt time.Time) error {
+	err := s.WithTx(ctx, func(tx abstraction.Storage) error {
+		txs := tx.(*SqliteStorage)
+
+		rows, err := txs.conn.QueryContext(ctx, `SELECT id, title, author FROM books WHERE work_id IS NULL ORDER BY id`)
+		if err != nil {
+			return err
+		}
+		var books []models.GeneralBook
+		for rows.Next() {
+			var book models.GeneralBook
+			if err := rows.Scan(&book.ID, &book.Title, &book.Author); err != nil {
+				rows.Close()
+				return err
+			}
+			books = append(books, book)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, book := range books {
+			authorIDs, err := queryIDs(ctx, txs.conn,
+				`SELECT author_id FROM book_authors WHERE book_id = ? ORDER BY position`, book.ID)
+			if err != nil {
+				return err
+			}
+			work, err := txs.workByTitle(ctx, book.Title, authorIDs)
+			if errors.Is(err, sql.ErrNoRows) {
+				work, err = txs.saveWork(ctx, models.Work{
+					Title:     book.Title,
+					Author:    book.Author,
+					CreatedAt: createdAt,
+					UpdatedAt: createdAt,
+				}, authorIDs)
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := txs.conn.ExecContext(ctx, `UPDATE books SET work_id = ? WHERE id = ?`, work.ID, book.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to link works", "error", err)
+		return fmt.Errorf("failed to link works: %w", err)
+	}
+	return nil
+}
+
+// linkWorkAuthors links works of a file created before work_authors
+// to authors of their author strings
+func (s *SqliteStorage) linkWorkAuthors(ctx context.Context, createdAt time.Time) error {
+	err := s.WithTx(ctx, func(tx abstraction.Storage) error {
+		txs := tx.(*SqliteStorage)
+
+		rows, err := txs.conn.QueryContext(ctx,
+			`SELECT id, author FROM works WHERE id NOT IN (SELECT work_id FROM work_authors) ORDER BY id`)
+		if err != nil {
+			return err
+		}
+		var works []models.Work
+		for rows.Next() {
+			var work models.Work
+			if err := rows.Scan(&work.ID, &work.Author); err != nil {
+				rows.Close()
+				return err
+			}
+			works = append(works, work)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, work := range works {
+			authorIDs, err := txs.authorIDs(ctx, work.Author, createdAt)
+			if err != nil {
+				return err
+			}
+			if err := setWorkAuthors(ctx, txs.conn, work.ID, authorIDs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to link work authors", "error", err)
+		return fmt.Errorf("failed to link work authors: %w", err)
+	}
+	return nil
+}
+
+// saveWork inserts a work with authors in order of credits,
+// it has no timeout of its own and the caller runs it in a transaction
+func (s *SqliteStorage) saveWork(ctx context.Context, work models.Work, authorIDs []uint64) (models.Work, error) {
+	query := `
+	INSERT INTO works (title, author, created_at, updated_at)
+	VALUES (?, ?, ?, ?)
+	RETURNING id
+	`
+	err := s.conn.QueryRowContext(ctx, query, work.Title, work.Author, work.CreatedAt, work.UpdatedAt).Scan(&work.ID)
+	if err != nil {
+		return models.Work{}, err
+	}
+	if err := setWorkAuthors(ctx, s.conn, work.ID, authorIDs); err != nil {
+		return models.Work{}, err
+	}
+
+	works := []models.Work{work}
+	if err := withWorkAuthors(ctx, s.conn, works); err != nil {
+		return models.Work{}, err
+	}
+	return works[0], nil
+}
+
+// workByTitle returns the first work with a title in any case and the same authors
+// in any order, it is sql.ErrNoRows if there is no one
+func (s *SqliteStorage) workByTitle(ctx context.Context, title string, authorIDs []uint64) (models.Work, error) {
+	ids := slices.Clone(authorIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	candidates, err := queryIDs(ctx, s.conn, `SELECT id FROM works WHERE LOWER(title) = LOWER(?) ORDER BY id`, title)
+	if err != nil {
+		return models.Work{}, err
+	}
+	for _, id := range candidates {
+		workIDs, err := queryIDs(ctx, s.conn, `SELECT author_id FROM work_authors WHERE work_id = ? ORDER BY author_id`, id)
+		if err != nil {
+			return models.Work{}, err
+		}
+		if !slices.Equal(workIDs, ids) {
+			continue
+		}
+
+		works := []models.Work{{}}
+		works[0], err = scanWork(s.conn.QueryRowContext(ctx,
+			`SELECT id, title, author, created_at, updated_at FROM works WHERE id = ?`, id))
+		if err != nil {
+			return models.Work{}, err
+		}
+		if err := withWorkAuthors(ctx, s.conn, works); err != nil {
+			return models.Work{}, err
+		}
+		return works[0], nil
+	}
+	return models.Work{}, sql.ErrNoRows
+}
+
+// setWorkAuthors replaces links of a work like setBookAuthors does
+func setWorkAuthors(ctx context.Context, conn querier, workID uint64, authorIDs []uint64) error {
+	if _, err := conn.ExecContext(ctx, `DELETE FROM work_authors WHERE work_id = ?`, workID); err != nil {
+		return err
+	}
+	for position, id := range authorIDs {
+		_, err := conn.ExecContext(ctx,
+			`INSERT INTO work_authors (work_id, author_id, position) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`,
+			workID, id, position+1,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withWorkAuthors sets authors of works in order of credits
+func withWorkAuthors(ctx context.Context, conn querier, works []models.Work) error {
+	if len(works) == 0 {
+		return nil
+	}
+
+	args := make([]any, len(works))
+	index := make(map[uint64]int, len(works))
+	for i, work := range works {
+		args[i] = work.ID
+		index[work.ID] = i
+		works[i].Authors = nil
+	}
+	query := `
+	SELECT work_authors.work_id, authors.id, authors.name
+	FROM work_authors
+	JOIN authors ON authors.id = work_authors.author_id
+	WHERE work_authors.work_id IN (?` + strings.Repeat(", ?", len(works)-1) + `)
+	ORDER BY work_authors.work_id, work_authors.position
+	`
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			workID uint64
+			author models.BookAuthor
+		)
+		if err := rows.Scan(&workID, &author.ID, &author.Name); err != nil {
+			return err
+		}
+		works[index[workID]].Authors = append(works[index[workID]].Authors, author)
+	}
+	return rows.Err()
+}
+
+// queryIDs returns ids of the first column of a query
+func queryIDs(ctx context.Context, conn querier, query string, args ...any) ([]uint64, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func scanWork(row scanner) (models.Work, error) {
+	var work models.Work
+	err := row.Scan(&work.ID, &work.Title, &work.Author, &work.CreatedAt, &work.UpdatedAt)
+	return work, err
+}
@@ -0,0 +1,41 @@
+package validations
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// maxBioLength is the max length of a bio of an author in bytes
+const maxBioLength = 2000
+
+// RuleRange is a rule of a value that doesn't fit with another one,
+// for example an author who died before birth
+const RuleRange = "range"
+
+// ValidateAuthor validates an author from a client, an id is given by a storage.
+// A bio is free text, so only XSS patterns are checked there
+func ValidateAuthor(author models.Author) error {
+	var errs []apperrors.FieldError
+
+	errs = append(errs, validateString(reflect.ValueOf(author.Name), "name", "name")...)
+	if author.SortName != "" {
+		errs = append(errs, validateString(reflect.ValueOf(author.SortName), "sortName", "sortName")...)
+	}
+	if len(author.Bio) > maxBioLength {
+		errs = append(errs, fieldError("bio", RuleMaxLength, fmt.Sprintf("bio: cannot be large than %d", maxBioLength)))
+	} else if xssRegex.MatchString(author.Bio) {
+		errs = append(errs, fieldError("bio", RuleSafe, "bio contatins XsS pattern"))
+	}
+	if author.BirthYear != nil && author.DeathYear != nil && *author.DeathYear < *author.BirthYear {
+		errs = append(errs, fieldError("deathYear", RuleRange, "deathYear: cannot be before birthYear"))
+	}
+
+	if len(errs) > 0 {
+		return apperrors.NewFieldsValidateErr("error validation", errs, errors.New("error validation"))
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package validations
+
+import (
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+func TestValidateAuthor(t *testing.T) {
+	birth, death := 1892, 1973
+	author := models.Author{Name: "J.R.R. Tolkien", BirthYear: &birth, DeathYear: &death, Bio: "Wrote about Middle-earth; taught at Oxford."}
+	if err := ValidateAuthor(author); err != nil {
+		t.Errorf("Expected nil error, got: %v", err)
+	}
+
+	author.DeathYear = new(int)
+	*author.DeathYear = 1800
+	author.Name = ""
+	err := ValidateAuthor(author)
+	validateErr, ok := err.(*apperrors.ValidateErr)
+	if !ok || len(validateErr.Details) != 2 {
+		t.Fatalf("Expected two field errors, got: %v", err)
+	}
+	if validateErr.Details[0].Field != "name" || validateErr.Details[0].Rule != RuleRequired {
+		t.Errorf("Expected a required name, got: %+v", validateErr.Details[0])
+	}
+	if validateErr.Details[1].Field != "deathYear" || validateErr.Details[1].Rule != RuleRange {
+		t.Errorf("Expected a range of deathYear, got: %+v", validateErr.Details[1])
+	}
+}
@@ -0,0 +1,88 @@
+package validations
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// names of optional fields of an edition, in lower case like other fields
+const (
+	fieldPublisher = "publisher"
+	fieldEdition   = "edition"
+	fieldFormat    = "format"
+	fieldPageCount = "pagecount"
+	fieldLanguage  = "language"
+)
+
+const (
+	// RuleEnum is a rule of a value that is not one of allowed values
+	RuleEnum = "enum"
+	// RuleLanguage is a rule of a language that is not an ISO 639-1 code
+	RuleLanguage = "language"
+)
+
+// maxPageCount is more than any real book has
+const maxPageCount = 100000
+
+var languageRegex = regexp.MustCompile(`^[a-z]{2}$`)
+
+// ValidateWork validates a work from a client, an id is given by a storage
+func ValidateWork(work models.Work) error {
+	var errs []apperrors.FieldError
+
+	errs = append(errs, validateString(reflect.ValueOf(work.Title), "title", "title")...)
+	errs = append(errs, validateString(reflect.ValueOf(work.Author), "author", "author")...)
+
+	if len(errs) > 0 {
+		return apperrors.NewFieldsValidateErr("error validation", errs, errors.New("error validation"))
+	}
+	return nil
+}
+
+// validateOptionalString is validateString for a string that might be empty
+func validateOptionalString(value reflect.Value, nameField, path string) []apperrors.FieldError {
+	if value.Kind() == reflect.String && value.String() == "" {
+		return nil
+	}
+	return validateString(value, nameField, path)
+}
+
+// validateFormat checks an optional format of an edition
+func validateFormat(value reflect.Value, path string) []apperrors.FieldError {
+	if value.Kind() != reflect.String {
+		return []apperrors.FieldError{fieldError(path, RuleType, "format: must be string")}
+	}
+	if value.String() != "" && !slices.Contains(models.Formats, value.String()) {
+		return []apperrors.FieldError{fieldError(path, RuleEnum, fmt.Sprintf("format: must be one of %s", strings.Join(models.Formats, ", ")))}
+	}
+	return nil
+}
+
+// validatePageCount checks an optional page count, zero is unknown
+func validatePageCount(value reflect.Value, path string) []apperrors.FieldError {
+	if value.Kind() != reflect.Int {
+		return []apperrors.FieldError{fieldError(path, RuleType, "pageCount: must be int")}
+	}
+	if value.Int() < 0 || value.Int() > maxPageCount {
+		return []apperrors.FieldError{fieldError(path, RuleRange, fmt.Sprintf("pageCount: must be from 0 to %d", maxPageCount))}
+	}
+	return nil
+}
+
+// validateLanguage checks an optional language, it is a lower case ISO 639-1 code
+func validateLanguage(value reflect.Value, path string) []apperrors.FieldError {
+	if value.Kind() != reflect.String {
+		return []apperrors.FieldError{fieldError(path, RuleType, "language: must be string")}
+	}
+	if value.String() != "" && !languageRegex.MatchString(value.String()) {
+		return []apperrors.FieldError{fieldError(path, RuleLanguage, "language: must be ISO 639-1 code like en")}
+	}
+	return nil
+}
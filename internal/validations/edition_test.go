@@ -0,0 +1,59 @@
+package validations
+
+import (
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+func TestValidate_Edition(t *testing.T) {
+	book := models.GeneralBook{
+		ID:              1,
+		Title:           "The Hobbit",
+		Genre:           "Fantasy",
+		Author:          "J.R.R. Tolkien",
+		PublicationDate: validTime,
+		Publisher:       "George Allen & Unwin",
+		Edition:         "2nd edition",
+		Format:          "hardcover",
+		PageCount:       310,
+		Language:        "en",
+	}
+	if err := Validate(book); err != nil {
+		t.Fatalf("Expected nil error for a valid edition, got: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		edit  func(*models.GeneralBook)
+		field string
+		rule  string
+	}{
+		{"empty fields", func(b *models.GeneralBook) { b.Publisher, b.Format, b.PageCount, b.Language = "", "", 0, "" }, "", ""},
+		{"unknown format", func(b *models.GeneralBook) { b.Format = "scroll" }, "format", RuleEnum},
+		{"negative page count", func(b *models.GeneralBook) { b.PageCount = -1 }, "pageCount", RuleRange},
+		{"language name", func(b *models.GeneralBook) { b.Language = "English" }, "language", RuleLanguage},
+		{"unsafe publisher", func(b *models.GeneralBook) { b.Publisher = "<script>" }, "publisher", RuleSafe},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edition := book
+			tt.edit(&edition)
+			err := Validate(edition)
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("Expected nil error, got: %v", err)
+				}
+				return
+			}
+			validateErr, ok := err.(*apperrors.ValidateErr)
+			if !ok || len(validateErr.Details) != 1 {
+				t.Fatalf("Expected one field error, got: %v", err)
+			}
+			if validateErr.Details[0].Field != tt.field || validateErr.Details[0].Rule != tt.rule {
+				t.Errorf("Expected %s of %s, got: %+v", tt.rule, tt.field, validateErr.Details[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package validations
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// RuleSlug is a rule of a slug that has something besides
+// lowercase latin letters, digits and single dashes
+const RuleSlug = "slug"
+
+// ValidateGenre validates a genre from a client. An empty slug is made
+// from the name later (models.Slugify)
+func ValidateGenre(genre models.Genre) error {
+	var errs []apperrors.FieldError
+
+	errs = append(errs, validateString(reflect.ValueOf(genre.Name), "name", "name")...)
+	if genre.Slug != "" {
+		if fieldErrs := validateString(reflect.ValueOf(genre.Slug), "slug", "slug"); len(fieldErrs) > 0 {
+			errs = append(errs, fieldErrs...)
+		} else if models.Slugify(genre.Slug) != genre.Slug {
+			errs = append(errs, fieldError("slug", RuleSlug, "slug: must be lowercase latin letters and digits separated by dashes"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return apperrors.NewFieldsValidateErr("error validation", errs, errors.New("error validation"))
+	}
+	return nil
+}
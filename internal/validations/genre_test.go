@@ -0,0 +1,42 @@
+package validations
+
+import (
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+func TestValidateGenre(t *testing.T) {
+	tests := []struct {
+		name  string
+		genre models.Genre
+		field string
+		rule  string
+	}{
+		{"valid", models.Genre{Name: "Science Fiction", Slug: "science-fiction"}, "", ""},
+		{"slug from name", models.Genre{Name: "Sci-Fi"}, "", ""},
+		{"empty name", models.Genre{Slug: "fantasy"}, "name", RuleRequired},
+		{"bad slug", models.Genre{Name: "Fantasy", Slug: "Fantasy Books"}, "slug", RuleSlug},
+		{"slug from non-latin name", models.Genre{Name: "Фэнтези"}, "", ""},
+		{"non-latin slug", models.Genre{Name: "Фэнтези", Slug: "фэнтези"}, "slug", RuleSlug},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGenre(tt.genre)
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("Expected nil error, got: %v", err)
+				}
+				return
+			}
+			validateErr, ok := err.(*apperrors.ValidateErr)
+			if !ok || len(validateErr.Details) != 1 {
+				t.Fatalf("Expected one field error, got: %v", err)
+			}
+			if validateErr.Details[0].Field != tt.field || validateErr.Details[0].Rule != tt.rule {
+				t.Errorf("Expected %s of %s, got: %+v", tt.rule, tt.field, validateErr.Details[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package validations
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+)
+
+const fieldISBN = "isbn"
+
+// RuleISBN is a rule of an invalid ISBN, a wrong length, a symbol or a check digit
+const RuleISBN = "isbn"
+
+// ErrInvalidISBN is returned by NormalizeISBN
+var ErrInvalidISBN = errors.New("invalid ISBN")
+
+// NormalizeISBN checks an ISBN-10 or ISBN-13 and returns it as ISBN-13 without hyphens,
+// so one book has one ISBN in a storage however a client wrote it.
+// Hyphens and spaces are allowed, a check digit of ISBN-10 might be x or X
+func NormalizeISBN(isbn string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch {
+		case r == '-' || r == ' ':
+			return -1
+		case r == 'x':
+			return 'X'
+		}
+		return r
+	}, isbn)
+
+	switch len(digits) {
+	case 10:
+		if !validISBN10(digits) {
+			return "", ErrInvalidISBN
+		}
+		// ISBN-10 is ISBN-13 with the 978 prefix and another check digit
+		isbn13 := "978" + digits[:9]
+		return isbn13 + string(isbn13CheckDigit(isbn13)), nil
+	case 13:
+		if !validISBN13(digits) {
+			return "", ErrInvalidISBN
+		}
+		return digits, nil
+	}
+	return "", ErrInvalidISBN
+}
+
+// validISBN10 checks a sum of digits multiplied by weights from 10 to 1, it is divisible by 11.
+// The last digit might be X that is 10
+func validISBN10(digits string) bool {
+	sum := 0
+	for i, r := range digits {
+		var digit int
+		switch {
+		case r >= '0' && r <= '9':
+			digit = int(r - '0')
+		case r == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+// validISBN13 checks the prefix and the check digit, it is EAN-13 with weights 1 and 3
+func validISBN13(digits string) bool {
+	if !strings.HasPrefix(digits, "978") && !strings.HasPrefix(digits, "979") {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return isbn13CheckDigit(digits[:12]) == rune(digits[12])
+}
+
+// isbn13CheckDigit computes the last digit of ISBN-13 from the first 12 digits
+func isbn13CheckDigit(digits string) rune {
+	sum := 0
+	for i, r := range digits[:12] {
+		digit := int(r - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return rune('0' + (10-sum%10)%10)
+}
+
+// validateISBN checks an optional ISBN, an empty one is valid
+func validateISBN(value reflect.Value, path string) []apperrors.FieldError {
+	if value.Kind() != reflect.String {
+		return []apperrors.FieldError{fieldError(path, RuleType, "isbn: must be string")}
+	}
+	if value.String() == "" {
+		return nil
+	}
+	if _, err := NormalizeISBN(value.String()); err != nil {
+		return []apperrors.FieldError{fieldError(path, RuleISBN, "isbn: must be a valid ISBN-10 or ISBN-13")}
+	}
+	return nil
+}
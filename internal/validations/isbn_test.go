@@ -0,0 +1,57 @@
+package validations
+
+import (
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		isbn     string
+		expected string
+	}{
+		{"9780132350884", "9780132350884"},
+		{"978-0-13-235088-4", "9780132350884"},
+		{"0132350882", "9780132350884"},
+		{"0-13-235088-2", "9780132350884"},
+		{"080442957x", "9780804429573"},
+		{"979 10 90636 07 1", "9791090636071"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeISBN(tt.isbn)
+		if err != nil || got != tt.expected {
+			t.Errorf("Expected %s for %s, got: %s %v", tt.expected, tt.isbn, got, err)
+		}
+	}
+
+	invalid := []string{"", "9780132350885", "0132350883", "123", "97801323508X4", "X132350882", "1234567890123"}
+	for _, isbn := range invalid {
+		if _, err := NormalizeISBN(isbn); err == nil {
+			t.Errorf("Expected error for %q", isbn)
+		}
+	}
+}
+
+func TestValidate_ISBN(t *testing.T) {
+	book := models.GeneralBook{ID: 1, Title: "Clean Code", Genre: "Programming", Author: "Robert C. Martin"}
+	if err := Validate(book); err != nil {
+		t.Errorf("Expected an empty ISBN to be valid, got: %v", err)
+	}
+
+	book.ISBN = "0-13-235088-2"
+	if err := Validate(book); err != nil {
+		t.Errorf("Expected nil error for a valid ISBN, got: %v", err)
+	}
+
+	book.ISBN = "0-13-235088-3"
+	err := Validate(book)
+	if err == nil {
+		t.Fatal("Expected error for a wrong check digit")
+	}
+	validateErr, ok := err.(*apperrors.ValidateErr)
+	if !ok || len(validateErr.Details) != 1 || validateErr.Details[0].Field != "isbn" || validateErr.Details[0].Rule != RuleISBN {
+		t.Errorf("Expected an isbn rule, got: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package validations
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+// maxDescriptionLength is the max length of a description of a series in bytes
+const maxDescriptionLength = 2000
+
+// maxPosition is more than any real series has
+const maxPosition = 10000
+
+// ValidateSeries validates a series from a client, an id is given by a storage.
+// A description is free text like a bio of an author
+func ValidateSeries(series models.Series) error {
+	var errs []apperrors.FieldError
+
+	errs = append(errs, validateString(reflect.ValueOf(series.Name), "name", "name")...)
+	if len(series.Description) > maxDescriptionLength {
+		errs = append(errs, fieldError("description", RuleMaxLength, fmt.Sprintf("description: cannot be large than %d", maxDescriptionLength)))
+	} else if xssRegex.MatchString(series.Description) {
+		errs = append(errs, fieldError("description", RuleSafe, "description contatins XsS pattern"))
+	}
+
+	if len(errs) > 0 {
+		return apperrors.NewFieldsValidateErr("error validation", errs, errors.New("error validation"))
+	}
+	return nil
+}
+
+// ValidateBookSeries validates a place of a book in a series,
+// a position might be fractional like 2.5 and zero is a prequel
+func ValidateBookSeries(req models.SetBookSeriesRequest) error {
+	var errs []apperrors.FieldError
+
+	if req.SeriesID == 0 {
+		errs = append(errs, fieldError("seriesId", RuleRequired, "seriesId cannot be ziro"))
+	}
+	switch {
+	case req.Position == nil:
+		errs = append(errs, fieldError("position", RuleRequired, "position field is nil"))
+	case *req.Position < 0 || *req.Position > maxPosition:
+		errs = append(errs, fieldError("position", RuleRange, fmt.Sprintf("position: must be from 0 to %d", maxPosition)))
+	}
+
+	if len(errs) > 0 {
+		return apperrors.NewFieldsValidateErr("error validation", errs, errors.New("error validation"))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package validations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Talos-hub/BooksRestApi/internal/apperrors"
+	"github.com/Talos-hub/BooksRestApi/internal/models"
+)
+
+func TestValidateSeries(t *testing.T) {
+	series := models.Series{Name: "The Lord of the Rings", Description: "Three volumes of one novel."}
+	if err := ValidateSeries(series); err != nil {
+		t.Errorf("Expected nil error, got: %v", err)
+	}
+
+	series.Name = ""
+	series.Description = strings.Repeat("a", maxDescriptionLength+1)
+	err := ValidateSeries(series)
+	validateErr, ok := err.(*apperrors.ValidateErr)
+	if !ok || len(validateErr.Details) != 2 {
+		t.Fatalf("Expected two field errors, got: %v", err)
+	}
+	if validateErr.Details[0].Field != "name" || validateErr.Details[0].Rule != RuleRequired {
+		t.Errorf("Expected a required name, got: %+v", validateErr.Details[0])
+	}
+	if validateErr.Details[1].Field != "description" || validateErr.Details[1].Rule != RuleMaxLength {
+		t.Errorf("Expected a max length of description, got: %+v", validateErr.Details[1])
+	}
+}
+
+func TestValidateBookSeries(t *testing.T) {
+	position := func(p float64) *float64 { return &p }
+
+	tests := []struct {
+		name  string
+		req   models.SetBookSeriesRequest
+		field string
+		rule  string
+	}{
+		{"valid", models.SetBookSeriesRequest{SeriesID: 1, Position: position(2)}, "", ""},
+		{"fractional position", models.SetBookSeriesRequest{SeriesID: 1, Position: position(2.5)}, "", ""},
+		{"prequel", models.SetBookSeriesRequest{SeriesID: 1, Position: position(0)}, "", ""},
+		{"no series", models.SetBookSeriesRequest{Position: position(1)}, "seriesId", RuleRequired},
+		{"no position", models.SetBookSeriesRequest{SeriesID: 1}, "position", RuleRequired},
+		{"negative position", models.SetBookSeriesRequest{SeriesID: 1, Position: position(-1)}, "position", RuleRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBookSeries(tt.req)
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("Expected nil error, got: %v", err)
+				}
+				return
+			}
+			validateErr, ok := err.(*apperrors.ValidateErr)
+			if !ok || len(validateErr.Details) != 1 {
+				t.Fatalf("Expected one field error, got: %v", err)
+			}
+			if validateErr.Details[0].Field != tt.field || validateErr.Details[0].Rule != tt.rule {
+				t.Errorf("Expected %s of %s, got: %+v", tt.rule, tt.field, validateErr.Details[0])
+			}
+		})
+	}
+}
@@ -20,6 +20,14 @@ const (
 	fieldAuthor = "author"
 )
 
+// rules of field errors, they are a part of the API, don't rename them
+const (
+	RuleRequired  = "required"   // a value cannot be empty, zero or nil
+	RuleType      = "type"       // a value has a wrong type
+	RuleMaxLength = "max_length" // a string is too long
+	RuleSafe      = "safe"       // a string contains SQL injection or XSS patterns
+)
+
 var (
 	sqlInjectionRegex = regexp.MustCompile(`(?i)(\b(UNION|SELECT|INSERT|DELETE|UPDATE|DROP|ALTER|CREATE|EXEC)\b|--|;|/\*|\*/|xp_)`)
 	xssRegex          = regexp.MustCompile(`(?i)(<script|javascript:|onerror=|onload=|onclick=)`)
@@ -39,6 +47,16 @@ var (
 // Also there is reflection, it might be slow.
 // It is safty function, if you will try to use parameter like a function and stuff It don't panic
 func Validate(book any) error {
+	return validate(book, true)
+}
+
+// ValidateNew validates a new book like Validate, but an id might be zero,
+// because a storage gives it
+func ValidateNew(book any) error {
+	return validate(book, false)
+}
+
+func validate(book any, requireID bool) error {
 	// it cannot validate nil object
 	// book is nil when type and value of inteface{} are nil
 	if book == nil {
@@ -71,12 +89,12 @@ func Validate(book any) error {
 	}
 
 	// it use minimallyFileds for avoid allocation
-	validationErrors := make([]string, 0, minimallyFields)
+	validationErrors := make([]apperrors.FieldError, 0, minimallyFields)
 
-	validationErrors = append(validationErrors, validateBookFields(value)...)
+	validationErrors = append(validationErrors, validateBookFields(value, "", requireID)...)
 
 	if len(validationErrors) > 0 {
-		return apperrors.NewValidateErr("error validation", validationErrors, errors.New("error validation"))
+		return apperrors.NewFieldsValidateErr("error validation", validationErrors, errors.New("error validation"))
 	}
 	// TODO
 	return nil
@@ -84,13 +102,14 @@ func Validate(book any) error {
 
 // validateBookFields checks fields and chooses
 // a specific function for validation
-// if spicific fields is wrong it add it to slice that contains errors message
-func validateBookFields(value reflect.Value) []string {
+// if spicific fields is wrong it add it to slice that contains errors message.
+// path is a json path of the struct, it is empty for the top one
+func validateBookFields(value reflect.Value, path string, requireID bool) []apperrors.FieldError {
 	// here is errors message from validation functions
 	// when validation is finished it function returns the slice to up
-	errorsSlice := make([]string, 0, value.NumField())
+	errorsSlice := make([]apperrors.FieldError, 0, value.NumField())
 	if value.NumField() == 0 {
-		errorsSlice = append(errorsSlice, "struct is empty")
+		errorsSlice = append(errorsSlice, fieldError(path, RuleRequired, "struct is empty"))
 		return errorsSlice
 	}
 
@@ -107,12 +126,17 @@ func validateBookFields(value reflect.Value) []string {
 
 		// get value of a field
 		fieldValue := value.Field(i)
+		fieldPath := joinPath(path, jsonName(field))
 
 		//handle pointers by dereferencing them
 		if fieldValue.Kind() == reflect.Pointer {
-			// if a pointer is nil add to a error slice
+			// if a pointer is nil add to a error slice,
+			// an optional one (omitempty) like a series of a book might be nil
 			if fieldValue.IsNil() {
-				errorsSlice = append(errorsSlice, fmt.Sprintf("%s field is nil", field.Name))
+				if strings.Contains(field.Tag.Get("json"), ",omitempty") {
+					continue
+				}
+				errorsSlice = append(errorsSlice, fieldError(fieldPath, RuleRequired, fmt.Sprintf("%s field is nil", field.Name)))
 				continue
 			}
 			// dereferencing
@@ -126,7 +150,7 @@ func validateBookFields(value reflect.Value) []string {
 				continue
 			}
 			// recursively
-			nestedErrs := validateBookFields(fieldValue)
+			nestedErrs := validateBookFields(fieldValue, fieldPath, requireID)
 			errorsSlice = append(errorsSlice, nestedErrs...)
 			continue
 		}
@@ -136,13 +160,23 @@ func validateBookFields(value reflect.Value) []string {
 
 		switch fieldName {
 		case fieldId:
-			errorsSlice = append(errorsSlice, validateID(fieldValue)...)
+			errorsSlice = append(errorsSlice, validateID(fieldValue, fieldPath, requireID)...)
 		case fieldTitle:
-			errorsSlice = append(errorsSlice, validateString(fieldValue, fieldName)...)
+			errorsSlice = append(errorsSlice, validateString(fieldValue, fieldName, fieldPath)...)
 		case fieldGenre:
-			errorsSlice = append(errorsSlice, validateString(fieldValue, fieldName)...)
+			errorsSlice = append(errorsSlice, validateString(fieldValue, fieldName, fieldPath)...)
 		case fieldAuthor:
-			errorsSlice = append(errorsSlice, validateString(fieldValue, fieldName)...)
+			errorsSlice = append(errorsSlice, validateString(fieldValue, fieldName, fieldPath)...)
+		case fieldISBN:
+			errorsSlice = append(errorsSlice, validateISBN(fieldValue, fieldPath)...)
+		case fieldPublisher, fieldEdition:
+			errorsSlice = append(errorsSlice, validateOptionalString(fieldValue, fieldName, fieldPath)...)
+		case fieldFormat:
+			errorsSlice = append(errorsSlice, validateFormat(fieldValue, fieldPath)...)
+		case fieldPageCount:
+			errorsSlice = append(errorsSlice, validatePageCount(fieldValue, fieldPath)...)
+		case fieldLanguage:
+			errorsSlice = append(errorsSlice, validateLanguage(fieldValue, fieldPath)...)
 		}
 	}
 	return errorsSlice
@@ -150,32 +184,32 @@ func validateBookFields(value reflect.Value) []string {
 }
 
 // field specific validation function
-func validateID(value reflect.Value) []string {
+func validateID(value reflect.Value, path string, required bool) []apperrors.FieldError {
 	if value.Kind() != reflect.Uint64 {
-		return []string{"id must be uint64"}
+		return []apperrors.FieldError{fieldError(path, RuleType, "id must be uint64")}
 	}
-	if value.Uint() == 0 {
-		return []string{"id cannot be ziro"}
+	if required && value.Uint() == 0 {
+		return []apperrors.FieldError{fieldError(path, RuleRequired, "id cannot be ziro")}
 	}
 	return nil
 }
 
 // validateString is specific function that validate string type.
 // When nameField: Author or Gangre etc
-func validateString(value reflect.Value, nameField string) []string {
+func validateString(value reflect.Value, nameField, path string) []apperrors.FieldError {
 	if value.Kind() != reflect.String {
-		return []string{fmt.Sprintf("%s: must be string", nameField)}
+		return []apperrors.FieldError{fieldError(path, RuleType, fmt.Sprintf("%s: must be string", nameField))}
 	}
 	if value.String() == "" {
-		return []string{fmt.Sprintf("%s: cannot be empty", nameField)}
+		return []apperrors.FieldError{fieldError(path, RuleRequired, fmt.Sprintf("%s: cannot be empty", nameField))}
 	}
 	if len(value.String()) > 100 {
-		return []string{fmt.Sprintf("%s: cannot be large than 100", nameField)}
+		return []apperrors.FieldError{fieldError(path, RuleMaxLength, fmt.Sprintf("%s: cannot be large than 100", nameField))}
 	}
 	// check malicious injections
 	message := validateMaliciousInjections(value.String())
 	if len(message) > 0 {
-		return []string{fmt.Sprintf("field: %s, %s", nameField, message)}
+		return []apperrors.FieldError{fieldError(path, RuleSafe, fmt.Sprintf("field: %s, %s", nameField, message))}
 	}
 	return nil
 }
@@ -194,3 +228,27 @@ func validateMaliciousInjections(str string) string {
 	return ""
 
 }
+
+// there are helpers
+
+func fieldError(path, rule, message string) apperrors.FieldError {
+	return apperrors.FieldError{Field: path, Rule: rule, Message: message}
+}
+
+// jsonName returns a name of a field in json,
+// a field without json tag has its name in lower case
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// joinPath joins a path of a struct and a name of its field
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -272,3 +272,41 @@ func TestValidate_Performance(t *testing.T) {
 		}
 	}
 }
+
+func TestValidate_FieldDetails(t *testing.T) {
+	type request struct {
+		Book ValidBook `json:"book"`
+	}
+	err := Validate(request{Book: ValidBook{ID: 1, Title: "", Genre: "Programming", Author: "<script>"}})
+
+	validateErr, ok := err.(*apperrors.ValidateErr)
+	if !ok {
+		t.Fatalf("Expected ValidateErr, got: %T", err)
+	}
+	expected := []apperrors.FieldError{
+		{Field: "book.title", Rule: RuleRequired, Message: "title: cannot be empty"},
+		{Field: "book.author", Rule: RuleSafe, Message: "field: author, <script> contatins XsS pattern"},
+	}
+	if !reflect.DeepEqual(validateErr.Details, expected) {
+		t.Errorf("Expected details %+v, got: %+v", expected, validateErr.Details)
+	}
+	if len(validateErr.Fields) != len(expected) {
+		t.Errorf("Expected Fields with the same messages, got: %v", validateErr.Fields)
+	}
+}
+
+func TestValidateNew(t *testing.T) {
+	book := ValidBook{Title: "Clean Code", Genre: "Programming", Author: "Robert C. Martin", PublicationDate: validTime}
+
+	if err := ValidateNew(book); err != nil {
+		t.Errorf("Expected nil error for a new book without id, got: %v", err)
+	}
+	if err := Validate(book); err == nil {
+		t.Error("Expected error for a book without id")
+	}
+
+	book.Title = ""
+	if err := ValidateNew(book); err == nil {
+		t.Error("Expected error for a new book with empty title")
+	}
+}